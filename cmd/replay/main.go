@@ -0,0 +1,67 @@
+// Команда replay пересобирает чаты, пользователей, составы и счётчики из журнала событий.
+// Используется для восстановления после ошибок, испортивших счётчики.
+// Сервис на время пересборки должен быть остановлен: события, принятые во время
+// пересборки, попадут в журнал, но могут не попасть в пересобранное состояние.
+//
+//	go run ./cmd/replay -dry-run   свернуть журнал и вывести итог, не меняя хранилище
+//	go run ./cmd/replay            заменить текущее состояние результатом свёртки
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"stats-of/internal/config"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+	"stats-of/internal/timeseries"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "fold the event log and print the result without touching storage")
+	flag.Parse()
+
+	logger.InitLogger()
+	logger.Log.Info("Starting event log replay", zap.Bool("dryRun", *dryRun))
+
+	conf, err := config.LoadFromEnv()
+	if err != nil {
+		logger.Log.Fatal("Failed to load configuration", zap.Error(err))
+	}
+	store, err := storage.NewStorage(storage.StorageType(conf.StorageType))
+	if err != nil {
+		logger.Log.Fatal("Failed to initialize storage", zap.Error(err))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := membership.Rebuild(ctx, membership.NewLog(store), storage.NewRepository(store), *dryRun)
+	if err != nil {
+		logger.Log.Fatal("Replay failed", zap.Error(err))
+	}
+	if !*dryRun {
+		// Глобальные счётчики рядов меняются приращениями и после пересборки пересчитываются заново
+		series := timeseries.NewService(store, timeseries.Retention{
+			Minute: conf.TimeSeriesMinuteRetention,
+			Hour:   conf.TimeSeriesHourRetention,
+			Day:    conf.TimeSeriesDayRetention,
+		})
+		if err := series.Recount(ctx); err != nil {
+			logger.Log.Fatal("Failed to recount global counters", zap.Error(err))
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		logger.Log.Fatal("Failed to print result", zap.Error(err))
+	}
+}
@@ -0,0 +1,205 @@
+package anomaly
+
+import (
+	"math"
+	"time"
+
+	"stats-of/internal/timeseries"
+)
+
+// Kind направление аномалии
+type Kind string
+
+const (
+	// KindSpike резкий рост числа участников, например набег спамеров
+	KindSpike Kind = "spike"
+	// KindDrop резкое падение числа участников, например массовый бан
+	KindDrop Kind = "drop"
+)
+
+// Severity уровень аномалии по модулю z-оценки
+type Severity string
+
+const (
+	SeverityLow    Severity = "low"
+	SeverityMedium Severity = "medium"
+	SeverityHigh   Severity = "high"
+)
+
+type (
+	// Options параметры детектора
+	Options struct {
+		// Step шаг, с которым ряд числа участников переводится в ряд приращений
+		Step time.Duration
+		// Lookback глубина истории для EWMA
+		Lookback time.Duration
+		// Seasons число предыдущих суток для сезонного сравнения того же часа
+		Seasons int
+		// Threshold минимальный модуль z-оценки, с которого фиксируется аномалия
+		Threshold float64
+		// MinDelta минимальное абсолютное изменение за шаг; мелкие колебания небольших чатов игнорируются
+		MinDelta float64
+		// Alpha коэффициент сглаживания EWMA
+		Alpha float64
+	}
+
+	// Baseline ожидаемое приращение и его разброс по одному из методов
+	Baseline struct {
+		Method   string  `json:"method"`
+		Expected float64 `json:"expected"`
+		StdDev   float64 `json:"std_dev"`
+		Observed float64 `json:"observed"`
+		Score    float64 `json:"score"`
+	}
+
+	// Evaluation результат оценки последнего шага ряда
+	Evaluation struct {
+		At        time.Time
+		Value     float64
+		Delta     float64
+		Baselines []Baseline
+	}
+)
+
+// DefaultAlpha коэффициент сглаживания EWMA: вклад точки уменьшается вдвое примерно за два шага
+const DefaultAlpha = 0.3
+
+// minHistory минимальное число приращений для оценки EWMA
+const minHistory = 12
+
+// minStdDev нижняя граница разброса: на почти постоянном ряду любой сдвиг давал бы бесконечный z
+const minStdDev = 1.0
+
+// Evaluate оценивает последний шаг ряда points (точки с шагом opts.Step) двумя способами:
+// EWMA/z-оценкой приращения относительно недавней истории и сравнением часового
+// приращения с тем же часом в предыдущие сутки (hourly — часовые точки ряда).
+func Evaluate(points []timeseries.Point, hourly []timeseries.Point, opts Options) *Evaluation {
+	if len(points) < minHistory+2 {
+		return nil
+	}
+
+	deltas := make([]float64, len(points)-1)
+	for i := 1; i < len(points); i++ {
+		deltas[i-1] = points[i].Last - points[i-1].Last
+	}
+	last := len(points) - 1
+	eval := &Evaluation{At: points[last].Time, Value: points[last].Last, Delta: deltas[len(deltas)-1]}
+
+	mean, variance := ewma(deltas[:len(deltas)-1], opts.Alpha)
+	std := math.Max(math.Sqrt(variance), minStdDev)
+	eval.Baselines = append(eval.Baselines, Baseline{
+		Method:   "ewma",
+		Expected: mean,
+		StdDev:   std,
+		Observed: eval.Delta,
+		Score:    (eval.Delta - mean) / std,
+	})
+
+	if seasonal, ok := seasonalBaseline(points, hourly, opts); ok {
+		eval.Baselines = append(eval.Baselines, seasonal)
+	}
+	return eval
+}
+
+// Strongest возвращает базовую линию с наибольшим модулем z-оценки
+func (e *Evaluation) Strongest() Baseline {
+	best := e.Baselines[0]
+	for _, b := range e.Baselines[1:] {
+		if math.Abs(b.Score) > math.Abs(best.Score) {
+			best = b
+		}
+	}
+	return best
+}
+
+// Classify определяет направление и уровень аномалии; ok = false, если аномалии нет
+func (e *Evaluation) Classify(opts Options) (Kind, Severity, bool) {
+	if math.Abs(e.Delta) < opts.MinDelta {
+		return "", "", false
+	}
+
+	score := e.Strongest().Score
+	abs := math.Abs(score)
+	if abs < opts.Threshold {
+		return "", "", false
+	}
+
+	kind := KindSpike
+	if score < 0 {
+		kind = KindDrop
+	}
+	switch {
+	case abs >= 2*opts.Threshold:
+		return kind, SeverityHigh, true
+	case abs >= 1.5*opts.Threshold:
+		return kind, SeverityMedium, true
+	default:
+		return kind, SeverityLow, true
+	}
+}
+
+// ewma экспоненциально взвешенные среднее и дисперсия
+func ewma(values []float64, alpha float64) (mean, variance float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	mean = values[0]
+	for _, x := range values[1:] {
+		diff := x - mean
+		mean += alpha * diff
+		variance = (1 - alpha) * (variance + alpha*diff*diff)
+	}
+	return mean, variance
+}
+
+// seasonalBaseline сравнивает приращение за последний час с приращениями того же часа
+// в предыдущие opts.Seasons суток. Точки рядов отмечены началом интервала, а Last — значение
+// в его конце, поэтому приращение часового интервала hour — это Last(hour) - Last(hour-1h).
+// Наблюдаемый час начинается с интервала, следующего за points[last-stepsPerHour]; с ним сравнивается
+// часовой интервал, в котором это начало лежит.
+func seasonalBaseline(points []timeseries.Point, hourly []timeseries.Point, opts Options) (Baseline, bool) {
+	stepsPerHour := int(time.Hour / opts.Step)
+	last := len(points) - 1
+	if stepsPerHour < 1 || last-stepsPerHour < 0 {
+		return Baseline{}, false
+	}
+	observed := points[last].Last - points[last-stepsPerHour].Last
+
+	byHour := make(map[int64]float64, len(hourly))
+	for _, p := range hourly {
+		byHour[p.Time.Unix()] = p.Last
+	}
+
+	hourStart := points[last-stepsPerHour].Time.Add(opts.Step).Truncate(time.Hour)
+	var samples []float64
+	for day := 1; day <= opts.Seasons; day++ {
+		start := hourStart.Add(-time.Duration(day) * 24 * time.Hour)
+		current, ok1 := byHour[start.Unix()]
+		previous, ok2 := byHour[start.Add(-time.Hour).Unix()]
+		if ok1 && ok2 {
+			samples = append(samples, current-previous)
+		}
+	}
+	if len(samples) < 3 {
+		return Baseline{}, false
+	}
+
+	var sum float64
+	for _, v := range samples {
+		sum += v
+	}
+	mean := sum / float64(len(samples))
+	var squares float64
+	for _, v := range samples {
+		squares += (v - mean) * (v - mean)
+	}
+	std := math.Max(math.Sqrt(squares/float64(len(samples)-1)), minStdDev)
+
+	return Baseline{
+		Method:   "seasonal",
+		Expected: mean,
+		StdDev:   std,
+		Observed: observed,
+		Score:    (observed - mean) / std,
+	}, true
+}
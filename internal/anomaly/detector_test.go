@@ -0,0 +1,53 @@
+package anomaly
+
+import (
+	"testing"
+	"time"
+
+	"stats-of/internal/timeseries"
+)
+
+// TestSeasonalBaselineUsesSameHour проверяет на синтетическом суточном ряде, где за час h
+// число участников растёт на h, что базовая линия берётся из того же часа предыдущих суток
+func TestSeasonalBaselineUsesSameHour(t *testing.T) {
+	day := time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	var hourly []timeseries.Point
+	var value float64
+	for at := day.Add(-5 * 24 * time.Hour); at.Before(day); at = at.Add(time.Hour) {
+		value += float64(at.Hour())
+		hourly = append(hourly, timeseries.Point{Time: at, Last: value})
+	}
+
+	opts := Options{Step: 30 * time.Minute, Seasons: 3}
+	tests := []struct {
+		name string
+		// last начало последнего интервала ряда с шагом opts.Step
+		last     time.Duration
+		expected float64
+	}{
+		{"hour aligned", 10*time.Hour + 30*time.Minute, 10},
+		{"half past", 10 * time.Hour, 9},
+		{"midnight", 30 * time.Minute, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var points []timeseries.Point
+			for i := 2; i >= 0; i-- {
+				at := day.Add(tt.last - time.Duration(i)*opts.Step)
+				points = append(points, timeseries.Point{Time: at, Last: float64(100 + 5*(2-i))})
+			}
+
+			baseline, ok := seasonalBaseline(points, hourly, opts)
+			if !ok {
+				t.Fatal("seasonal baseline is not available")
+			}
+			if baseline.Expected != tt.expected {
+				t.Errorf("expected = %v, want %v", baseline.Expected, tt.expected)
+			}
+			if baseline.Observed != 10 {
+				t.Errorf("observed = %v, want 10", baseline.Observed)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package anomaly
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	defaultSince = 24 * time.Hour
+)
+
+// MakeListHandler обработчик GET /api/v1/anomalies?since=2026-10-01T00:00:00Z&limit=100&cursor=...:
+// последние аномалии всех чатов, новые первыми
+func MakeListHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		respond(w, r, service, cursors, nil)
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/anomalies с теми же параметрами
+func MakeChatHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		chatID := entities.ChatID(id)
+		respond(w, r, service, cursors, &chatID)
+	}
+}
+
+func respond(w http.ResponseWriter, r *http.Request, service *Service, cursors *pagination.Signer, chatID *entities.ChatID) {
+	scope := "anomalies"
+	if chatID != nil {
+		scope = "anomalies:" + strconv.FormatInt(int64(*chatID), 10)
+	}
+	params, err := cursors.Parse(r, scope, defaultLimit, maxLimit)
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	since, err := utils.QueryTime(r, "since", time.Now().UTC().Add(-defaultSince))
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+
+	anomalies, next, err := service.Recent(r.Context(), chatID, since, params.Cursor, params.Limit)
+	if err != nil {
+		logger.Log.Error("Failed to read anomalies", zap.Error(err))
+		utils.RespondWith500(w)
+		return
+	}
+	utils.SuccessRespondWith200(w, pagination.NewPage(cursors, scope, anomalies, params.Limit, next))
+}
@@ -0,0 +1,10 @@
+package anomaly
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewDetectionJob создаёт задачу периодического поиска аномалий
+func NewDetectionJob(service *Service) jobs.Job {
+	return jobs.Func("anomaly-detection", service.Detect)
+}
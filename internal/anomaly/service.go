@@ -0,0 +1,250 @@
+package anomaly
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+	"stats-of/internal/timeseries"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Схема ключей:
+//
+//	anomalies                  отсортированное множество JSON аномалий всех чатов, score — время шага (unix)
+//	anomalies:chat:{id}        то же для одного чата
+//	anomalies:evaluated        хеш: чат -> последний оценённый шаг (unix), чтобы не фиксировать шаг повторно
+const (
+	allKey        = "anomalies"
+	chatKeyPrefix = "anomalies:chat:"
+	evaluatedKey  = "anomalies:evaluated"
+)
+
+type (
+	// Anomaly зафиксированное отклонение числа участников чата от ожидаемого
+	Anomaly struct {
+		ChatID     entities.ChatID `json:"chat_id"`
+		At         time.Time       `json:"at"`
+		DetectedAt time.Time       `json:"detected_at"`
+		Kind       Kind            `json:"kind"`
+		Severity   Severity        `json:"severity"`
+		// Score z-оценка по методу Method; знак совпадает с направлением
+		Score  float64 `json:"score"`
+		Method string  `json:"method"`
+		// Value число участников в конце шага, Delta — изменение за шаг
+		Value     float64    `json:"value"`
+		Delta     float64    `json:"delta"`
+		Baselines []Baseline `json:"baselines"`
+	}
+
+	// Service периодически оценивает ряды числа участников чатов и сохраняет найденные аномалии
+	Service struct {
+		repo      *storage.Repository
+		series    *timeseries.Service
+		opts      Options
+		retention time.Duration
+		detected  *prometheus.CounterVec
+	}
+)
+
+func NewService(repo *storage.Repository, series *timeseries.Service, opts Options, retention time.Duration) *Service {
+	return &Service{
+		repo:      repo,
+		series:    series,
+		opts:      opts,
+		retention: retention,
+		detected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stats_of_anomalies_total",
+			Help: "Number of detected anomalies in chat membership counts.",
+		}, []string{"kind", "severity"}),
+	}
+}
+
+// Collector счётчик найденных аномалий для регистрации в Prometheus
+func (s *Service) Collector() prometheus.Collector {
+	return s.detected
+}
+
+// Detect оценивает последний завершённый шаг ряда каждого чата
+func (s *Service) Detect(ctx context.Context) error {
+	ids, err := s.repo.ChatIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	now := time.Now().UTC()
+	to := now.Truncate(s.opts.Step)
+	from := to.Add(-s.opts.Lookback)
+	// Последний шаг, который закончился к моменту to
+	last := strconv.FormatInt(to.Add(-s.opts.Step).Unix(), 10)
+
+	evaluated, err := s.repo.Storage().HashGetAll(ctx, evaluatedKey)
+	if err != nil {
+		return fmt.Errorf("failed to read evaluated steps: %w", err)
+	}
+
+	var found int
+	for _, id := range ids {
+		field := strconv.FormatInt(int64(id), 10)
+		if evaluated[field] == last {
+			continue
+		}
+
+		anomaly, err := s.evaluate(ctx, id, from, to, now)
+		if err != nil {
+			return err
+		}
+		if anomaly != nil {
+			if err := s.record(ctx, anomaly); err != nil {
+				return err
+			}
+			found++
+		}
+		if err := s.repo.Storage().HashSet(ctx, evaluatedKey, map[string]string{field: last}); err != nil {
+			return fmt.Errorf("failed to mark chat %d as evaluated: %w", id, err)
+		}
+	}
+
+	if err := s.trim(ctx, ids, now); err != nil {
+		return err
+	}
+
+	logger.Log.Info("Anomaly detection finished", zap.Int("chats", len(ids)), zap.Int("anomalies", found))
+	return nil
+}
+
+// Recent возвращает не больше limit последних аномалий начиная с since, новые первыми; chatID = nil — по всем чатам.
+// Страница продолжается с курсора start: Position — время шага последней выданной аномалии,
+// Skip — сколько аномалий с этим временем уже выдано; нулевой курсор — с самых новых.
+// Новые аномалии не сдвигают следующие страницы. Курсор следующей страницы nil, если страница последняя.
+func (s *Service) Recent(ctx context.Context, chatID *entities.ChatID, since time.Time, start pagination.Cursor, limit int) ([]Anomaly, *pagination.Cursor, error) {
+	key := allKey
+	if chatID != nil {
+		key = chatKey(*chatID)
+	}
+
+	max, skip := math.Inf(1), 0
+	if start.Position > 0 {
+		max, skip = float64(start.Position), start.Skip
+	}
+	members, err := s.repo.Storage().SortedSetRevRangeByScore(ctx, key, float64(since.Unix()), max, int64(skip), int64(limit+1))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read anomalies: %w", err)
+	}
+
+	var next *pagination.Cursor
+	if len(members) > limit {
+		members = members[:limit]
+		last := members[len(members)-1].Score
+		next = &pagination.Cursor{Position: uint64(last)}
+		for _, m := range members {
+			if m.Score == last {
+				next.Skip++
+			}
+		}
+		if last == max {
+			next.Skip += skip
+		}
+	}
+
+	result := make([]Anomaly, 0, len(members))
+	for _, m := range members {
+		var a Anomaly
+		if err := json.Unmarshal([]byte(m.Member), &a); err != nil {
+			logger.Log.Warn("Skipping malformed anomaly", zap.String("key", key), zap.Error(err))
+			continue
+		}
+		result = append(result, a)
+	}
+	return result, next, nil
+}
+
+func (s *Service) evaluate(ctx context.Context, id entities.ChatID, from, to, now time.Time) (*Anomaly, error) {
+	series := timeseries.ChatUsersSeries(id)
+	result, err := s.series.Query(ctx, series, from, to, s.opts.Step)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query series of chat %d: %w", id, err)
+	}
+	if len(result.Points) == 0 || !result.Points[len(result.Points)-1].Time.Equal(to.Add(-s.opts.Step)) {
+		return nil, nil
+	}
+
+	hourly, err := s.series.Points(ctx, series, "hour")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read hourly points of chat %d: %w", id, err)
+	}
+
+	eval := Evaluate(result.Points, hourly, s.opts)
+	if eval == nil {
+		return nil, nil
+	}
+	kind, severity, ok := eval.Classify(s.opts)
+	if !ok {
+		return nil, nil
+	}
+
+	strongest := eval.Strongest()
+	return &Anomaly{
+		ChatID:     id,
+		At:         eval.At,
+		DetectedAt: now,
+		Kind:       kind,
+		Severity:   severity,
+		Score:      strongest.Score,
+		Method:     strongest.Method,
+		Value:      eval.Value,
+		Delta:      eval.Delta,
+		Baselines:  eval.Baselines,
+	}, nil
+}
+
+func (s *Service) record(ctx context.Context, a *Anomaly) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to encode anomaly of chat %d: %w", a.ChatID, err)
+	}
+
+	score := float64(a.At.Unix())
+	for _, key := range []string{allKey, chatKey(a.ChatID)} {
+		if err := s.repo.Storage().SortedSetAdd(ctx, key, string(data), score); err != nil {
+			return fmt.Errorf("failed to record anomaly of chat %d: %w", a.ChatID, err)
+		}
+	}
+
+	s.detected.WithLabelValues(string(a.Kind), string(a.Severity)).Inc()
+	logger.Log.Warn("Anomaly detected",
+		zap.Int64("chatID", int64(a.ChatID)),
+		zap.String("kind", string(a.Kind)),
+		zap.String("severity", string(a.Severity)),
+		zap.Float64("delta", a.Delta),
+		zap.Float64("score", a.Score))
+	return nil
+}
+
+// trim удаляет аномалии старше срока хранения
+func (s *Service) trim(ctx context.Context, ids []entities.ChatID, now time.Time) error {
+	cutoff := float64(now.Add(-s.retention).Unix())
+	keys := []string{allKey}
+	for _, id := range ids {
+		keys = append(keys, chatKey(id))
+	}
+	for _, key := range keys {
+		if err := s.repo.Storage().SortedSetRemoveRangeByScore(ctx, key, math.Inf(-1), cutoff); err != nil {
+			return fmt.Errorf("failed to trim %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func chatKey(id entities.ChatID) string {
+	return chatKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
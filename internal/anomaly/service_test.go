@@ -0,0 +1,62 @@
+package anomaly
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// TestRecentPagesThroughTies проверяет, что курсор проходит аномалии с одинаковым временем шага
+// без пропусков и повторов, а аномалии, найденные между запросами, не сдвигают страницы
+func TestRecentPagesThroughTies(t *testing.T) {
+	ctx := context.Background()
+	s := NewService(memtest.NewRepository(t), nil, Options{}, 0)
+
+	base := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 7; i++ {
+		// Шаги по три аномалии: 3 в base+2h, 3 в base+1h, 1 в base
+		at := base.Add(time.Duration(i/3) * time.Hour)
+		if err := s.record(ctx, &Anomaly{ChatID: entities.ChatID(i + 1), At: at}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	seen := make(map[entities.ChatID]bool)
+	var cursor pagination.Cursor
+	var last time.Time
+	for page := 0; ; page++ {
+		if page > 10 {
+			t.Fatal("pagination does not terminate")
+		}
+		items, next, err := s.Recent(ctx, nil, base, cursor, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range items {
+			if seen[a.ChatID] {
+				t.Fatalf("anomaly of chat %d returned twice", a.ChatID)
+			}
+			if !last.IsZero() && a.At.After(last) {
+				t.Fatalf("anomaly at %v returned after %v", a.At, last)
+			}
+			seen[a.ChatID], last = true, a.At
+		}
+		if page == 0 {
+			if err := s.record(ctx, &Anomaly{ChatID: 100, At: base.Add(3 * time.Hour)}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if next == nil {
+			break
+		}
+		cursor = *next
+	}
+
+	if len(seen) != 7 {
+		t.Fatalf("got %d anomalies, want 7", len(seen))
+	}
+}
@@ -10,11 +10,39 @@ import (
 	"syscall"
 	"time"
 
+	"stats-of/internal/anomaly"
+	"stats-of/internal/batch"
+	"stats-of/internal/churn"
+	"stats-of/internal/community"
 	"stats-of/internal/config"
+	"stats-of/internal/directory"
+	"stats-of/internal/distribution"
+	"stats-of/internal/engagement"
 	"stats-of/internal/entities"
+	"stats-of/internal/export"
+	"stats-of/internal/forecast"
+	"stats-of/internal/graphqlapi"
+	"stats-of/internal/grpcapi"
 	"stats-of/internal/healthz"
+	"stats-of/internal/heavyhitters"
+	"stats-of/internal/ingest"
+	"stats-of/internal/jobs"
+	"stats-of/internal/leaderboard"
 	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/middlewares"
+	"stats-of/internal/openapi"
+	"stats-of/internal/pagination"
+	"stats-of/internal/reconcile"
+	"stats-of/internal/sessions"
+	"stats-of/internal/similarity"
+	"stats-of/internal/snapshot"
+	"stats-of/internal/storage"
+	"stats-of/internal/stream"
+	"stats-of/internal/timeseries"
+	"stats-of/internal/uniques"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 )
@@ -28,7 +56,13 @@ var appInfo = &entities.AppInfo{
 }
 
 type App struct {
-	server *http.Server
+	server    *http.Server
+	grpc      *grpcapi.Server
+	stream    *stream.Hub
+	reconcile *reconcile.Service
+	members   *membership.Service
+	storage   storage.Storage
+	scheduler *jobs.Scheduler
 }
 
 func New(config *config.Config) (*App, error) {
@@ -41,10 +75,165 @@ func New(config *config.Config) (*App, error) {
 	)
 
 	app := new(App)
-	mux := http.NewServeMux()
+	mux := newRouter()
+
+	store, err := storage.NewStorage(storage.StorageType(config.StorageType))
+	if err != nil {
+		logger.Log.Error("Failed to initialize storage", zap.Error(err))
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+	app.storage = store
+	repo := storage.NewRepository(store)
+	app.scheduler = jobs.NewScheduler()
+	eventLog := membership.NewLog(store)
+	members := membership.NewService(repo, eventLog)
+	app.members = members
+
+	leaderboards := leaderboard.NewService(repo)
+	members.Subscribe(leaderboards)
+	app.scheduler.Add(leaderboard.NewRebuildJob(leaderboards), config.LeaderboardRebuildInterval)
+
+	series := timeseries.NewService(store, timeseries.Retention{
+		Minute: config.TimeSeriesMinuteRetention,
+		Hour:   config.TimeSeriesHourRetention,
+		Day:    config.TimeSeriesDayRetention,
+	})
+	members.Subscribe(series)
+	app.scheduler.Add(timeseries.NewRollupJob(series), config.TimeSeriesRollupInterval)
+	app.scheduler.Add(timeseries.NewRecountJob(series, members.Locker()), config.TimeSeriesRecountInterval)
+
+	// Хаб подписывается после рядов, чтобы публиковать уже обновлённые глобальные счётчики
+	app.stream = stream.NewHub(repo, series, config.StreamBufferSize)
+	members.Subscribe(app.stream)
+
+	forecasts := forecast.NewService(series)
+
+	anomalies := anomaly.NewService(repo, series, anomaly.Options{
+		Step:      config.AnomalyStep,
+		Lookback:  config.AnomalyLookback,
+		Seasons:   config.AnomalySeasons,
+		Threshold: config.AnomalyThreshold,
+		MinDelta:  config.AnomalyMinDelta,
+		Alpha:     anomaly.DefaultAlpha,
+	}, config.AnomalyRetention)
+	app.scheduler.Add(anomaly.NewDetectionJob(anomalies), config.AnomalyInterval)
+	prometheus.MustRegister(anomalies.Collector())
+
+	churns := churn.NewService(repo, churn.Thresholds{
+		AtRisk:  config.ChurnAtRiskAfter,
+		Churned: config.ChurnAfter,
+	}, config.ChurnRetention)
+	members.Subscribe(churns)
+	app.scheduler.Add(churn.NewEvaluationJob(churns), config.ChurnInterval)
+
+	activity := sessions.NewService(store, config.SessionIdleGap)
+	members.Subscribe(activity)
+	app.scheduler.Add(sessions.NewFlushJob(activity), config.SessionFlushInterval)
+
+	engagements := engagement.NewService(repo, activity, engagement.Model{
+		Weights:         engagement.WeightsFrom(config.EngagementWeights),
+		RecencyHalfLife: config.EngagementRecencyHalfLife,
+		FrequencyScale:  config.EngagementFrequencyScale,
+		ChatsScale:      config.EngagementChatsScale,
+		ActivityScale:   config.EngagementActivityScale,
+	})
+	members.Subscribe(engagements)
+	app.scheduler.Add(engagement.NewComputeJob(engagements), config.EngagementInterval)
+
+	uniqueUsers := uniques.NewService(store, config.UniquesRetention)
+	members.Subscribe(uniqueUsers)
+
+	trending := heavyhitters.NewService(store, config.HeavyHittersCapacity, config.HeavyHittersWindows)
+	if err := trending.Restore(context.Background()); err != nil {
+		// Без сохранённого состояния окна просто начинаются заново
+		logger.Log.Warn("Failed to restore heavy hitters", zap.Error(err))
+	}
+	members.Subscribe(trending)
+	app.scheduler.Add(heavyhitters.NewPersistJob(trending), config.HeavyHittersPersistInterval)
+
+	reconciler := reconcile.NewService(repo, members.Locker(), config.ReconcileRepair, config.ReconcileRate)
+	app.reconcile = reconciler
+	app.scheduler.Add(reconcile.NewReconcileJob(reconciler), config.ReconcileInterval)
+	prometheus.MustRegister(reconciler.Collectors()...)
+
+	snapshots := snapshot.NewService(repo, config.SnapshotRetention)
+	app.scheduler.Add(snapshot.NewSnapshotJob(snapshots), config.SnapshotInterval)
+
+	similarityIndex := similarity.NewIndex(similarity.DefaultNumHashes, similarity.DefaultBands)
+	app.scheduler.Add(similarity.NewRefreshJob(repo, similarityIndex), config.SimilarityRefreshInterval)
+
+	communities := community.NewService(repo)
+	app.scheduler.Add(community.NewDetectionJob(communities), config.CommunityDetectionInterval)
+
+	distributions := distribution.NewService(repo, config.DistributionBuckets)
+	app.scheduler.Add(distribution.NewComputeJob(distributions), config.DistributionInterval)
+	prometheus.MustRegister(distribution.NewCollector(distributions))
+
+	chatsAndUsers := directory.NewService(repo, leaderboards)
+	cursors := pagination.NewSigner(config.PaginationSecret)
+	exports := export.NewService(repo)
+	graphQL := graphqlapi.NewService(repo, chatsAndUsers, cursors, graphqlapi.Limits{
+		MaxDepth:      config.GraphQLMaxDepth,
+		MaxComplexity: config.GraphQLMaxComplexity,
+	})
+	batches := batch.NewService(repo, leaderboards)
 
 	mux.Handle("/metrics", promhttp.Handler())
 	mux.HandleFunc("/healthz", healthz.MakeHandler(appInfo))
+	mux.HandleFunc("GET /openapi.json", openapi.MakeSpecHandler(openapi.Build(appInfo)))
+	mux.Handle("GET /docs/", openapi.DocsHandler())
+	mux.HandleFunc("GET /api/v1/chats", directory.MakeChatsHandler(chatsAndUsers, cursors))
+	mux.HandleFunc("GET /api/v1/chats/{id}", directory.MakeChatHandler(chatsAndUsers))
+	mux.HandleFunc("GET /api/v1/chats/{id}/users", directory.MakeChatUsersHandler(chatsAndUsers, cursors))
+	mux.HandleFunc("GET /api/v1/users", directory.MakeUsersHandler(chatsAndUsers, cursors))
+	mux.HandleFunc("GET /api/v1/users/{id}", directory.MakeUserHandler(chatsAndUsers))
+	mux.HandleFunc("GET /api/v1/users/{id}/chats", directory.MakeUserChatsHandler(chatsAndUsers, cursors))
+	mux.HandleFunc("GET /api/v1/export/{dataset}", export.MakeHandler(exports))
+	mux.HandleFunc("GET /graphql", graphqlapi.MakeHandler(graphQL))
+	mux.HandleFunc("POST /graphql", graphqlapi.MakeHandler(graphQL))
+	mux.HandleFunc("POST /api/v1/batch", batch.MakeHandler(batches))
+	mux.HandleFunc("GET /api/v1/chats/{id}/similar", similarity.MakeHandler(similarityIndex, repo))
+	mux.HandleFunc("GET /api/v1/chats/{id}/community", community.MakeChatHandler(communities))
+	mux.HandleFunc("GET /api/v1/communities", community.MakeListHandler(communities, cursors))
+	mux.HandleFunc("GET /api/v1/communities/{id}", community.MakeGetHandler(communities))
+	mux.HandleFunc("GET /api/v1/leaderboards/{board}", leaderboard.MakeTopHandler(leaderboards, cursors))
+	mux.HandleFunc("GET /api/v1/leaderboards/{board}/{id}", leaderboard.MakeRankHandler(leaderboards))
+	mux.HandleFunc("POST /api/v1/events", middlewares.AdminOnly(config.AdminToken, ingest.MakeHandler(members)))
+	mux.HandleFunc("GET /api/v1/events", membership.MakeLogHandler(eventLog, cursors))
+	mux.HandleFunc("GET /api/v1/stream", stream.MakeSSEHandler(app.stream, config.StreamHeartbeatInterval))
+	mux.HandleFunc("GET /api/v1/stream/ws", stream.MakeWebSocketHandler(app.stream, config.WebSocketMaxSubscriptions))
+	mux.HandleFunc("GET /api/v1/stats/distributions", distribution.MakeHandler(distributions))
+	mux.HandleFunc("GET /api/v1/timeseries", timeseries.MakeHandler(series))
+	mux.HandleFunc("GET /api/v1/chats/{id}/timeseries", timeseries.MakeChatHandler(series))
+	mux.HandleFunc("GET /api/v1/uniques", uniques.MakeHandler(uniqueUsers))
+	mux.HandleFunc("GET /api/v1/trending/{kind}", heavyhitters.MakeHandler(trending))
+	mux.HandleFunc("GET /api/v1/anomalies", anomaly.MakeListHandler(anomalies, cursors))
+	mux.HandleFunc("GET /api/v1/chats/{id}/anomalies", anomaly.MakeChatHandler(anomalies, cursors))
+	mux.HandleFunc("GET /api/v1/forecast", forecast.MakeHandler(forecasts))
+	mux.HandleFunc("GET /api/v1/chats/{id}/forecast", forecast.MakeChatHandler(forecasts))
+	mux.HandleFunc("GET /api/v1/churn", churn.MakeReportHandler(churns))
+	mux.HandleFunc("GET /api/v1/churn/users", churn.MakeInactiveHandler(churns, cursors))
+	mux.HandleFunc("GET /api/v1/churn/transitions", churn.MakeTransitionsHandler(churns))
+	mux.HandleFunc("GET /api/v1/chats/{id}/churn", churn.MakeChatHandler(churns))
+	mux.HandleFunc("GET /api/v1/users/{id}/sessions", sessions.MakeUserHandler(activity))
+	mux.HandleFunc("GET /api/v1/chats/{id}/sessions", sessions.MakeChatHandler(activity))
+	mux.HandleFunc("GET /api/v1/engagement", engagement.MakeReportHandler(engagements, cursors))
+	mux.HandleFunc("GET /api/v1/users/{id}/engagement", engagement.MakeUserHandler(engagements))
+	mux.HandleFunc("GET /api/v1/chats/{id}/engagement", engagement.MakeChatHandler(engagements))
+	mux.HandleFunc("GET /api/v1/snapshots", snapshot.MakeListHandler(snapshots, cursors))
+	mux.HandleFunc("GET /api/v1/snapshots/diff", snapshot.MakeDiffHandler(snapshots, cursors))
+	mux.HandleFunc("GET /api/v1/chats/{id}/diff", snapshot.MakeChatDiffHandler(snapshots, cursors))
+	mux.HandleFunc("GET /api/v1/admin/reconcile", reconcile.MakeStatusHandler(reconciler))
+	mux.HandleFunc("POST /api/v1/admin/reconcile", middlewares.AdminOnly(config.AdminToken, reconcile.MakeRunHandler(reconciler)))
+
+	app.grpc = grpcapi.NewServer(":"+strconv.Itoa(config.GRPCPort), grpcapi.Services{
+		Directory:    chatsAndUsers,
+		Leaderboards: leaderboards,
+		TimeSeries:   series,
+		Uniques:      uniqueUsers,
+		Trending:     trending,
+		Cursors:      cursors,
+	})
 
 	app.server = &http.Server{
 		Handler:      mux,
@@ -65,7 +254,22 @@ func (a *App) Run() error {
 	// Логирование попытки запуска сервера
 	logger.Log.Info("Starting HTTP server", zap.String("address", a.server.Addr))
 
-	err := a.server.ListenAndServe()
+	// gRPC-порт открывается до запуска фоновых задач, чтобы занятый порт остановил запуск сразу
+	listener, err := a.grpc.Listen()
+	if err != nil {
+		logger.Log.Error("Failed to start gRPC server", zap.Error(err))
+		return err
+	}
+	go func() {
+		logger.Log.Info("Starting gRPC server", zap.String("address", a.grpc.Addr()))
+		if err := a.grpc.Serve(listener); err != nil {
+			logger.Log.Error("gRPC server stopped with error", zap.Error(err))
+		}
+	}()
+
+	a.scheduler.Start(context.Background())
+
+	err = a.server.ListenAndServe()
 	if err != nil && err != http.ErrServerClosed {
 		// Логирование ошибки, если сервер не был закрыт нормально
 		logger.Log.Error("HTTP server stopped with error", zap.Error(err))
@@ -81,6 +285,10 @@ func (a *App) stop(ctx context.Context) error {
 	// Логирование начала процесса остановки сервера
 	logger.Log.Info("Initiating server shutdown")
 
+	// Потоки событий закрываются первыми: Shutdown ждёт завершения всех обработчиков,
+	// а соединения WebSocket после Upgrade он не отслеживает вовсе
+	a.stream.Close()
+
 	err := a.server.Shutdown(ctx)
 	if err != nil {
 		// Логирование ошибки при попытке остановить сервер
@@ -88,6 +296,17 @@ func (a *App) stop(ctx context.Context) error {
 		return fmt.Errorf("server was shutdown with error: %w", err)
 	}
 
+	// gRPC-сервер останавливается после HTTP в пределах того же тайм-аута
+	a.grpc.Stop(ctx)
+	logger.Log.Info("gRPC server stopped")
+
+	// Остановка фоновых задач после того, как сервер перестал принимать запросы
+	a.reconcile.Close()
+	if err := a.members.Release(ctx); err != nil {
+		logger.Log.Error("Failed to release event log", zap.Error(err))
+	}
+	a.scheduler.Stop()
+
 	// Логирование успешного завершения остановки сервера
 	logger.Log.Info("Server shutdown successfully")
 	return nil
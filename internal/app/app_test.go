@@ -0,0 +1,268 @@
+package app
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"stats-of/internal/config"
+	"stats-of/internal/logger"
+	"stats-of/internal/openapi"
+
+	"go.uber.org/zap"
+)
+
+// TestOpenAPIMatchesRoutes сверяет документ OpenAPI с маршрутами, которые регистрирует New:
+// каждый маршрут описан и каждая описанная операция зарегистрирована
+func TestOpenAPIMatchesRoutes(t *testing.T) {
+	logger.Log = zap.NewNop()
+	t.Setenv("STORAGE_TYPE", "memory")
+
+	conf, err := config.LoadFromEnv()
+	if err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+	app, err := New(conf)
+	if err != nil {
+		t.Fatalf("create app: %v", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, pattern := range app.server.Handler.(*router).patterns {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			// Шаблон без метода принимает любой метод; документируется как GET
+			method, path = "GET", pattern
+		}
+		registered[method+" "+path] = true
+	}
+
+	documented := make(map[string]bool)
+	for path, item := range openapi.Build(appInfo).Paths {
+		for method, op := range item.Operations() {
+			documented[method+" "+path] = true
+			if len(op.Responses) == 0 {
+				t.Errorf("%s %s: no responses documented", method, path)
+			}
+		}
+	}
+
+	for _, route := range difference(registered, documented) {
+		t.Errorf("route %s is registered but missing from the OpenAPI document", route)
+	}
+	for _, route := range difference(documented, registered) {
+		t.Errorf("operation %s is documented but not registered", route)
+	}
+}
+
+func difference(a, b map[string]bool) []string {
+	var result []string
+	for key := range a {
+		if !b[key] {
+			result = append(result, key)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// TestOpenAPIResponseSchemasMatchJSON проверяет, что каждая схема ответа описывает JSON, в который
+// encoding/json кодирует названный схемой тип Go. Документ строится через openapi.Build: второй
+// вызов New в одном тестовом бинарнике паникует на повторной регистрации метрик.
+func TestOpenAPIResponseSchemasMatchJSON(t *testing.T) {
+	doc := openapi.Build(appInfo)
+
+	names := make(map[string]bool)
+	for path, item := range doc.Paths {
+		for method, op := range item.Operations() {
+			for status, response := range op.Responses {
+				for _, media := range response.Content {
+					if name := schemaRef(media.Schema); name != "" {
+						names[name] = true
+					} else if media.Schema != nil && media.Schema.Type != "string" {
+						t.Errorf("%s %s %s: response schema names no Go type", method, path, status)
+					}
+				}
+			}
+		}
+	}
+	if len(names) == 0 {
+		t.Fatal("no response schemas documented")
+	}
+
+	for name := range names {
+		t.Run(name, func(t *testing.T) {
+			typ := doc.Type(name)
+			if typ == nil {
+				t.Fatalf("schema %s is not built from a Go type", name)
+			}
+			data, err := json.Marshal(sample(typ, 0).Interface())
+			if err != nil {
+				t.Fatalf("marshal %s: %v", typ, err)
+			}
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				t.Fatal(err)
+			}
+			for _, problem := range validate(doc, doc.Components.Schemas[name], value, name) {
+				t.Errorf("%s\n%s", problem, data)
+			}
+		})
+	}
+}
+
+// schemaRef имя схемы из Components.Schemas, на которую ссылается схема ответа или её элементы
+func schemaRef(schema *openapi.Schema) string {
+	for schema != nil && schema.Type == "array" {
+		schema = schema.Items
+	}
+	if schema == nil {
+		return ""
+	}
+	return strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+}
+
+// maxSampleDepth ограничивает заполнение рекурсивных типов
+const maxSampleDepth = 6
+
+// sample значение типа, в котором заполнены все экспортируемые поля, указатели, срезы и словари,
+// чтобы в JSON попало каждое поле, а не только нулевые значения
+func sample(t reflect.Type, depth int) reflect.Value {
+	v := reflect.New(t).Elem()
+	if depth > maxSampleDepth {
+		return v
+	}
+	switch t {
+	case reflect.TypeOf(time.Time{}):
+		return reflect.ValueOf(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC))
+	case reflect.TypeOf(time.Duration(0)):
+		return reflect.ValueOf(time.Minute)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(1)
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(1.5)
+	case reflect.String:
+		v.SetString("x")
+	case reflect.Pointer:
+		elem := reflect.New(t.Elem())
+		elem.Elem().Set(sample(t.Elem(), depth+1))
+		v.Set(elem)
+	case reflect.Slice:
+		v.Set(reflect.Append(reflect.MakeSlice(t, 0, 1), sample(t.Elem(), depth+1)))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			v.Index(i).Set(sample(t.Elem(), depth+1))
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(t))
+		v.SetMapIndex(sample(t.Key(), depth+1), sample(t.Elem(), depth+1))
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if field := t.Field(i); field.IsExported() {
+				v.Field(i).Set(sample(field.Type, depth+1))
+			}
+		}
+	}
+	return v
+}
+
+// validate сверяет декодированный JSON со схемой и возвращает описания расхождений
+func validate(doc *openapi.Document, schema *openapi.Schema, value any, where string) []string {
+	if schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		resolved, ok := doc.Components.Schemas[name]
+		if !ok {
+			return []string{where + ": unknown schema " + schema.Ref}
+		}
+		return validate(doc, resolved, value, where)
+	}
+	if value == nil {
+		if schema.Nullable || schema.Type == "" {
+			return nil
+		}
+		return []string{where + ": null, but the schema is not nullable"}
+	}
+
+	mismatch := []string{where + ": schema type " + schema.Type + ", got " + reflect.TypeOf(value).String()}
+	switch schema.Type {
+	case "":
+		return nil
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return mismatch
+		}
+	case "integer":
+		if n, ok := value.(json.Number); !ok {
+			return mismatch
+		} else if _, err := n.Int64(); err != nil {
+			return []string{where + ": " + n.String() + " is not an integer"}
+		}
+	case "number":
+		if _, ok := value.(json.Number); !ok {
+			return mismatch
+		}
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return mismatch
+		}
+		switch schema.Format {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339Nano, s); err != nil {
+				return []string{where + ": " + s + " is not a date-time"}
+			}
+		case "byte":
+			if _, err := base64.StdEncoding.DecodeString(s); err != nil {
+				return []string{where + ": " + s + " is not base64"}
+			}
+		}
+	case "array":
+		items, ok := value.([]any)
+		if !ok {
+			return mismatch
+		}
+		var problems []string
+		for _, item := range items {
+			problems = append(problems, validate(doc, schema.Items, item, where+"[]")...)
+		}
+		return problems
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return mismatch
+		}
+		var problems []string
+		for _, name := range schema.Required {
+			if _, ok := object[name]; !ok {
+				problems = append(problems, where+"."+name+": required, but not marshaled")
+			}
+		}
+		for name, field := range object {
+			if property, ok := schema.Properties[name]; ok {
+				problems = append(problems, validate(doc, property, field, where+"."+name)...)
+			} else if schema.AdditionalProperties != nil {
+				problems = append(problems, validate(doc, schema.AdditionalProperties, field, where+"."+name)...)
+			} else {
+				problems = append(problems, where+"."+name+": marshaled, but not documented")
+			}
+		}
+		return problems
+	default:
+		return []string{where + ": unsupported schema type " + schema.Type}
+	}
+	return nil
+}
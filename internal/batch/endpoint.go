@@ -0,0 +1,38 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"stats-of/internal/utils"
+)
+
+// maxRequestBodySize наибольший размер тела пакетного запроса
+const maxRequestBodySize = 1 << 20
+
+// MakeHandler обработчик POST /api/v1/batch. Ответ 200 содержит результат каждого подзапроса
+// со своим статусом; 400 возвращается только для неразбираемого, пустого или слишком большого пакета.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&req); err != nil {
+			utils.RespondWith400(w, "invalid batch request body: "+err.Error())
+			return
+		}
+
+		queries := req.Expand()
+		if len(queries) == 0 {
+			utils.RespondWith400(w, "at least one of chat_ids, user_ids or queries is required")
+			return
+		}
+		if len(queries) > MaxQueries {
+			utils.RespondWith400(w, fmt.Sprintf("batch has %d queries; at most %d are allowed", len(queries), MaxQueries))
+			return
+		}
+
+		utils.SuccessRespondWith200(w, Response{Results: service.Run(r.Context(), queries)})
+	}
+}
@@ -0,0 +1,66 @@
+package batch
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stats-of/internal/utils"
+)
+
+func post(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/batch", strings.NewReader(body)))
+	return rec
+}
+
+// TestHandlerRejectsInvalidBatches проверяет ответ 400 на неразбираемый, пустой и слишком большой пакет
+func TestHandlerRejectsInvalidBatches(t *testing.T) {
+	handler := MakeHandler(newTestService(t))
+	ids := strings.TrimSuffix(strings.Repeat("1,", MaxQueries), ",")
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"not json", `{"chat_ids":`, "invalid batch request body"},
+		{"unknown field", `{"chats":[1]}`, `invalid batch request body: json: unknown field "chats"`},
+		{"empty", `{}`, "at least one of chat_ids, user_ids or queries is required"},
+		{"too many queries", `{"chat_ids":[` + ids + `],"user_ids":[1]}`,
+			fmt.Sprintf("batch has %d queries; at most %d are allowed", MaxQueries+1, MaxQueries)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(handler, tt.body)
+			var body utils.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusBadRequest || !strings.HasPrefix(body.Error, tt.message) {
+				t.Errorf("status %d, error %q; want 400 starting with %q", rec.Code, body.Error, tt.message)
+			}
+		})
+	}
+}
+
+// TestHandlerAcceptsMaxQueries проверяет, что пакет ровно из MaxQueries подзапросов выполняется
+func TestHandlerAcceptsMaxQueries(t *testing.T) {
+	handler := MakeHandler(newTestService(t))
+	ids := strings.TrimSuffix(strings.Repeat("1,", MaxQueries), ",")
+
+	rec := post(handler, `{"chat_ids":[`+ids+`]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", rec.Code, rec.Body)
+	}
+	var response Response
+	if err := json.NewDecoder(rec.Body).Decode(&response); err != nil {
+		t.Fatal(err)
+	}
+	if len(response.Results) != MaxQueries {
+		t.Errorf("got %d results, want %d", len(response.Results), MaxQueries)
+	}
+}
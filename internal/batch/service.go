@@ -0,0 +1,339 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+const (
+	OpChat      = "chat"
+	OpUser      = "user"
+	OpChatUsers = "chat_users"
+	OpUserChats = "user_chats"
+	OpChatRank  = "chat_rank"
+	OpUserRank  = "user_rank"
+
+	// MaxQueries наибольшее число подзапросов в одном пакете, включая chat_ids и user_ids
+	MaxQueries = 1000
+
+	defaultIDsLimit = 100
+	maxIDsLimit     = 1000
+
+	// concurrency сколько обращений к хранилищу пакет выполняет одновременно
+	concurrency = 16
+)
+
+type (
+	// Query подзапрос пакета. chat_users и user_chats возвращают не больше limit идентификаторов
+	// участников или чатов: множество читается порциями SSCAN, пока их не наберётся limit,
+	// поэтому это первые идентификаторы в порядке обхода множества, а не наименьшие.
+	Query struct {
+		Op    string `json:"op"`
+		ID    int64  `json:"id"`
+		Limit int    `json:"limit,omitempty"`
+	}
+
+	// Request тело POST /api/v1/batch. chat_ids и user_ids — сокращённая запись подзапросов
+	// chat и user; результаты идут в порядке chat_ids, user_ids, queries.
+	Request struct {
+		ChatIDs []entities.ChatID `json:"chat_ids,omitempty"`
+		UserIDs []entities.UserID `json:"user_ids,omitempty"`
+		Queries []Query           `json:"queries,omitempty"`
+	}
+
+	// Result результат одного подзапроса. Status повторяет код ответа, который вернул бы
+	// отдельный запрос: при 200 заполнено data, иначе error.
+	Result struct {
+		Index  int    `json:"index"`
+		Op     string `json:"op"`
+		ID     int64  `json:"id"`
+		Status int    `json:"status"`
+		Data   any    `json:"data,omitempty"`
+		Error  string `json:"error,omitempty"`
+	}
+
+	// Response результаты в порядке подзапросов
+	Response struct {
+		Results []Result `json:"results"`
+	}
+
+	// IDs идентификаторы участников чата или чатов пользователя по возрастанию; Total — размер
+	// связи по счётчику чата или пользователя
+	IDs struct {
+		IDs   []int64 `json:"ids"`
+		Total int64   `json:"total"`
+	}
+
+	// Service выполняет пакеты подзапросов. Чаты, пользователи и позиции в каждом рейтинге
+	// всего пакета читаются групповыми запросами к хранилищу, составы — параллельно.
+	// Состав читается ограниченным SSCAN, поэтому пакет не держит в памяти большие множества целиком.
+	Service struct {
+		repo         *storage.Repository
+		leaderboards *leaderboard.Service
+	}
+)
+
+func NewService(repo *storage.Repository, leaderboards *leaderboard.Service) *Service {
+	return &Service{repo: repo, leaderboards: leaderboards}
+}
+
+// Expand раскрывает chat_ids и user_ids в подзапросы
+func (r Request) Expand() []Query {
+	queries := make([]Query, 0, len(r.ChatIDs)+len(r.UserIDs)+len(r.Queries))
+	for _, id := range r.ChatIDs {
+		queries = append(queries, Query{Op: OpChat, ID: int64(id)})
+	}
+	for _, id := range r.UserIDs {
+		queries = append(queries, Query{Op: OpUser, ID: int64(id)})
+	}
+	return append(queries, r.Queries...)
+}
+
+// Run выполняет подзапросы. Ошибка одного подзапроса, в том числе ошибка хранилища,
+// попадает в его результат и не мешает остальным.
+func (s *Service) Run(ctx context.Context, queries []Query) []Result {
+	results := make([]Result, len(queries))
+	// totals размер связи по счётчику записи для подзапросов chat_users и user_chats
+	totals := make([]int64, len(queries))
+	var chats, users, chatRanks, userRanks, relations []int
+	for i, q := range queries {
+		results[i] = Result{Index: i, Op: q.Op, ID: q.ID}
+		if err := q.validate(); err != nil {
+			results[i].fail(http.StatusBadRequest, err.Error())
+			continue
+		}
+		switch q.Op {
+		case OpChat, OpChatUsers:
+			chats = append(chats, i)
+		case OpUser, OpUserChats:
+			users = append(users, i)
+		case OpChatRank:
+			chatRanks = append(chatRanks, i)
+		case OpUserRank:
+			userRanks = append(userRanks, i)
+		}
+		if q.Op == OpChatUsers || q.Op == OpUserChats {
+			relations = append(relations, i)
+		}
+	}
+
+	// Задачи пишут в непересекающиеся элементы results и totals
+	var tasks []func()
+	if len(chats) > 0 {
+		tasks = append(tasks, func() { s.chats(ctx, queries, results, totals, chats) })
+	}
+	if len(users) > 0 {
+		tasks = append(tasks, func() { s.users(ctx, queries, results, totals, users) })
+	}
+	if len(chatRanks) > 0 {
+		tasks = append(tasks, func() { s.ranks(ctx, leaderboard.BoardChats, queries, results, chatRanks) })
+	}
+	if len(userRanks) > 0 {
+		tasks = append(tasks, func() { s.ranks(ctx, leaderboard.BoardUsers, queries, results, userRanks) })
+	}
+	parallel(tasks)
+
+	// Составы читаются только для найденных чатов и пользователей: у остальных результат уже записан
+	var scans []func()
+	for _, i := range relations {
+		if results[i].Status == 0 {
+			scans = append(scans, func() { s.relation(ctx, queries[i], totals[i], &results[i]) })
+		}
+	}
+	parallel(scans)
+
+	return results
+}
+
+// chats читает чаты подзапросов chat и chat_users одним групповым чтением
+func (s *Service) chats(ctx context.Context, queries []Query, results []Result, totals []int64, indexes []int) {
+	ids := make([]entities.ChatID, len(indexes))
+	for n, i := range indexes {
+		ids[n] = entities.ChatID(queries[i].ID)
+	}
+
+	found, err := s.repo.Chats(ctx, unique(ids))
+	if err != nil {
+		logger.Log.Error("Failed to read chats for batch", zap.Int("chats", len(ids)), zap.Error(err))
+		failAll(results, indexes, http.StatusInternalServerError)
+		return
+	}
+	for _, i := range indexes {
+		chat, ok := found[entities.ChatID(queries[i].ID)]
+		switch {
+		case !ok:
+			results[i].fail(http.StatusNotFound, "")
+		case queries[i].Op == OpChat:
+			results[i].succeed(chat)
+		default:
+			totals[i] = chat.CountOfUsers
+		}
+	}
+}
+
+// users читает пользователей подзапросов user и user_chats одним групповым чтением
+func (s *Service) users(ctx context.Context, queries []Query, results []Result, totals []int64, indexes []int) {
+	ids := make([]entities.UserID, len(indexes))
+	for n, i := range indexes {
+		ids[n] = entities.UserID(queries[i].ID)
+	}
+
+	found, err := s.repo.Users(ctx, unique(ids))
+	if err != nil {
+		logger.Log.Error("Failed to read users for batch", zap.Int("users", len(ids)), zap.Error(err))
+		failAll(results, indexes, http.StatusInternalServerError)
+		return
+	}
+	for _, i := range indexes {
+		user, ok := found[entities.UserID(queries[i].ID)]
+		switch {
+		case !ok:
+			results[i].fail(http.StatusNotFound, "")
+		case queries[i].Op == OpUser:
+			results[i].succeed(user)
+		default:
+			totals[i] = user.CountOfChats
+		}
+	}
+}
+
+// relation отвечает на подзапрос chat_users или user_chats
+func (s *Service) relation(ctx context.Context, q Query, total int64, result *Result) {
+	limit := q.Limit
+	if limit == 0 {
+		limit = defaultIDsLimit
+	}
+
+	var ids []int64
+	var err error
+	if q.Op == OpChatUsers {
+		ids, err = scanIDs(ctx, limit, func(ctx context.Context, cursor uint64, count int64) ([]entities.UserID, uint64, error) {
+			return s.repo.ScanChatUserIDs(ctx, entities.ChatID(q.ID), cursor, count)
+		})
+	} else {
+		ids, err = scanIDs(ctx, limit, func(ctx context.Context, cursor uint64, count int64) ([]entities.ChatID, uint64, error) {
+			return s.repo.ScanUserChatIDs(ctx, entities.UserID(q.ID), cursor, count)
+		})
+	}
+	if err != nil {
+		logger.Log.Error("Failed to scan relation for batch", zap.String("op", q.Op), zap.Int64("id", q.ID), zap.Error(err))
+		result.fail(http.StatusInternalServerError, "")
+		return
+	}
+	result.succeed(IDs{IDs: ids, Total: total})
+}
+
+// ranks читает позиции подзапросов chat_rank или user_rank одного рейтинга одним групповым чтением
+func (s *Service) ranks(ctx context.Context, board leaderboard.Board, queries []Query, results []Result, indexes []int) {
+	ids := make([]int64, len(indexes))
+	for n, i := range indexes {
+		ids[n] = queries[i].ID
+	}
+
+	found, err := s.leaderboards.Ranks(ctx, board, unique(ids))
+	if err != nil {
+		logger.Log.Error("Failed to read leaderboard ranks for batch", zap.String("board", string(board)), zap.Int("ids", len(ids)), zap.Error(err))
+		failAll(results, indexes, http.StatusInternalServerError)
+		return
+	}
+	for _, i := range indexes {
+		if entry, ok := found[queries[i].ID]; ok {
+			results[i].succeed(entry)
+		} else {
+			results[i].fail(http.StatusNotFound, "")
+		}
+	}
+}
+
+func (q Query) validate() error {
+	switch q.Op {
+	case OpChat, OpUser, OpChatRank, OpUserRank:
+		if q.Limit != 0 {
+			return fmt.Errorf("limit is not supported by %s", q.Op)
+		}
+	case OpChatUsers, OpUserChats:
+		if q.Limit < 0 || q.Limit > maxIDsLimit {
+			return fmt.Errorf("limit must be between 0 and %d; 0 or no limit means %d", maxIDsLimit, defaultIDsLimit)
+		}
+	default:
+		return fmt.Errorf("unknown op %q; expected one of chat, user, chat_users, user_chats, chat_rank, user_rank", q.Op)
+	}
+	return nil
+}
+
+func (r *Result) succeed(data any) {
+	r.Status = http.StatusOK
+	r.Data = data
+}
+
+// fail записывает ошибку подзапроса; пустое сообщение заменяется текстом статуса, как в utils.RespondWithError
+func (r *Result) fail(status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	r.Status = status
+	r.Error = message
+}
+
+func failAll(results []Result, indexes []int, status int) {
+	for _, i := range indexes {
+		results[i].fail(status, "")
+	}
+}
+
+// parallel выполняет задачи, не больше concurrency одновременно
+func parallel(tasks []func()) {
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for _, task := range tasks {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			task()
+		}()
+	}
+	wg.Wait()
+}
+
+func unique[T ~int64](ids []T) []T {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	return slices.Compact(ids)
+}
+
+// scanIDs читает множество порциями SSCAN, пока не наберёт limit элементов или не обойдёт его целиком,
+// и возвращает не больше limit различных идентификаторов по возрастанию
+func scanIDs[T ~int64](ctx context.Context, limit int, scan func(ctx context.Context, cursor uint64, count int64) ([]T, uint64, error)) ([]int64, error) {
+	var found []T
+	var cursor uint64
+	for {
+		page, next, err := scan(ctx, cursor, int64(limit-len(found)))
+		if err != nil {
+			return nil, err
+		}
+		// SSCAN может вернуть элемент повторно, поэтому порции сразу очищаются от повторов
+		found = unique(append(found, page...))
+		if next == 0 || len(found) >= limit {
+			break
+		}
+		cursor = next
+	}
+
+	ids := make([]int64, 0, min(limit, len(found)))
+	for _, id := range found[:min(limit, len(found))] {
+		ids = append(ids, int64(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,154 @@
+package batch
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"slices"
+	"testing"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// newTestService создаёт чат 1 с пользователями 10, 11, 12 и чат 2 с пользователем 10
+// и пересобирает рейтинги
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	repo := memtest.NewRepository(t)
+	ctx := context.Background()
+	links := []struct {
+		chat entities.ChatID
+		user entities.UserID
+	}{{1, 10}, {1, 11}, {1, 12}, {2, 10}}
+	for _, link := range links {
+		if _, err := repo.AddMembership(ctx, link.chat, link.user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	leaderboards := leaderboard.NewService(repo)
+	if err := leaderboards.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return NewService(repo, leaderboards)
+}
+
+// TestRunMixesStatuses проверяет, что ошибки отдельных подзапросов не мешают остальным
+// и что статус каждого совпадает со статусом отдельного запроса
+func TestRunMixesStatuses(t *testing.T) {
+	service := newTestService(t)
+
+	queries := []Query{
+		{Op: OpChat, ID: 1},
+		{Op: OpChat, ID: 9},
+		{Op: OpUser, ID: 10},
+		{Op: OpChat, ID: 1},
+		{Op: OpChatUsers, ID: 1, Limit: 2},
+		{Op: OpUserChats, ID: 10},
+		{Op: OpUserChats, ID: 99},
+		{Op: OpChatRank, ID: 1},
+		{Op: OpChatRank, ID: 2},
+		{Op: OpChatRank, ID: 9},
+		{Op: OpUserRank, ID: 10},
+		{Op: "poke", ID: 1},
+		{Op: OpChat, ID: 1, Limit: 5},
+		{Op: OpChatUsers, ID: 1, Limit: maxIDsLimit + 1},
+	}
+	want := []int{200, 404, 200, 200, 200, 200, 404, 200, 200, 404, 200, 400, 400, 400}
+
+	results := service.Run(context.Background(), queries)
+	if len(results) != len(queries) {
+		t.Fatalf("got %d results for %d queries", len(results), len(queries))
+	}
+	for i, result := range results {
+		if result.Index != i || result.Op != queries[i].Op || result.ID != queries[i].ID {
+			t.Errorf("result %d = %+v does not match query %+v", i, result, queries[i])
+		}
+		if result.Status != want[i] {
+			t.Errorf("query %+v: status %d (%s), want %d", queries[i], result.Status, result.Error, want[i])
+		}
+		if (result.Status == http.StatusOK) != (result.Error == "") {
+			t.Errorf("query %+v: status %d with error %q", queries[i], result.Status, result.Error)
+		}
+	}
+
+	if chat, ok := results[0].Data.(*entities.Chat); !ok || chat.CountOfUsers != 3 {
+		t.Errorf("chat 1 = %#v, want a chat with 3 users", results[0].Data)
+	}
+	if ids, ok := results[4].Data.(IDs); !ok || len(ids.IDs) != 2 || ids.Total != 3 {
+		t.Errorf("chat_users 1 = %#v, want 2 of 3 ids", results[4].Data)
+	}
+	if ids, ok := results[5].Data.(IDs); !ok || !slices.Equal(ids.IDs, []int64{1, 2}) || ids.Total != 2 {
+		t.Errorf("user_chats 10 = %#v, want [1 2] of 2", results[5].Data)
+	}
+	ranks := map[int]leaderboard.Entry{7: {Rank: 1, ID: 1, Score: 3}, 8: {Rank: 2, ID: 2, Score: 1}, 10: {Rank: 1, ID: 10, Score: 2}}
+	for i, entry := range ranks {
+		if got, ok := results[i].Data.(*leaderboard.Entry); !ok || *got != entry {
+			t.Errorf("%s %d = %#v, want %+v", queries[i].Op, queries[i].ID, results[i].Data, entry)
+		}
+	}
+}
+
+// TestUnique проверяет сортировку и удаление повторов без изменения исходного среза
+func TestUnique(t *testing.T) {
+	tests := []struct {
+		ids, want []int64
+	}{
+		{nil, []int64{}},
+		{[]int64{3}, []int64{3}},
+		{[]int64{3, 1, 3, 2, 1}, []int64{1, 2, 3}},
+		{[]int64{-5, 7, -5}, []int64{-5, 7}},
+	}
+	for _, tt := range tests {
+		original := slices.Clone(tt.ids)
+		if got := unique(tt.ids); !slices.Equal(got, tt.want) {
+			t.Errorf("unique(%v) = %v, want %v", tt.ids, got, tt.want)
+		}
+		if !slices.Equal(tt.ids, original) {
+			t.Errorf("unique changed its argument to %v", tt.ids)
+		}
+	}
+}
+
+// TestScanIDs проверяет, что обход множества останавливается, как только набрано limit
+// различных идентификаторов, и запрашивает только недостающее число элементов
+func TestScanIDs(t *testing.T) {
+	// Порции SSCAN по курсору; повторы имитируют элементы, которые SSCAN возвращает дважды
+	pages := [][]entities.UserID{{5, 3}, {3, 1}, {9, 7}, {2}}
+
+	tests := []struct {
+		name   string
+		limit  int
+		want   []int64
+		counts []int64
+	}{
+		{"stops at limit", 3, []int64{1, 3, 5}, []int64{3, 1}},
+		{"truncates the last page", 4, []int64{1, 3, 5, 7}, []int64{4, 2, 1}},
+		{"whole set", 10, []int64{1, 2, 3, 5, 7, 9}, []int64{10, 8, 7, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var counts []int64
+			scan := func(_ context.Context, cursor uint64, count int64) ([]entities.UserID, uint64, error) {
+				counts = append(counts, count)
+				next := cursor + 1
+				if next == uint64(len(pages)) {
+					next = 0
+				}
+				return pages[cursor], next, nil
+			}
+
+			got, err := scanIDs(context.Background(), tt.limit, scan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ids = %v, want %v", got, tt.want)
+			}
+			if !slices.Equal(counts, tt.counts) {
+				t.Errorf("requested counts %v, want %v", counts, tt.counts)
+			}
+		})
+	}
+}
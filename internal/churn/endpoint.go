@@ -0,0 +1,147 @@
+package churn
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageLimit         = 100
+	maxPageLimit             = 1000
+	maxOffset                = 1 << 30
+	defaultTransitionsWindow = 30 * 24 * time.Hour
+)
+
+// MakeReportHandler обработчик GET /api/v1/churn?chat_type=1: доля ушедших пользователей
+// в целом и по типам чатов на момент последней оценки
+func MakeReportHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := service.Report(r.Context())
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+
+		chatType := r.URL.Query().Get("chat_type")
+		if chatType == "" {
+			utils.SuccessRespondWith200(w, report)
+			return
+		}
+		counts, ok := report.ByChatType[chatType]
+		if !ok {
+			utils.RespondWith404(w)
+			return
+		}
+		filtered := *report
+		filtered.ByChatType = map[string]Counts{chatType: counts}
+		utils.SuccessRespondWith200(w, filtered)
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/churn: состояния участников чата
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		counts, err := service.Chat(r.Context(), entities.ChatID(id))
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		utils.SuccessRespondWith200(w, counts)
+	}
+}
+
+// MakeInactiveHandler обработчик GET /api/v1/churn/users?status=at_risk&limit=100&cursor=...:
+// неактивные пользователи, дольше всех молчащие первыми
+func MakeInactiveHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status := Status(r.URL.Query().Get("status"))
+		if status != "" && status != StatusAtRisk && status != StatusChurned {
+			utils.RespondWith400(w, "status must be one of: at_risk, churned")
+			return
+		}
+		scope := "churn_users:" + string(status)
+		offset, err := cursors.Offset(r, scope, maxOffset)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		limit, err := utils.QueryInt(r, "limit", defaultPageLimit, 1, maxPageLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		users, total, err := service.Inactive(r.Context(), status, offset, limit)
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewOffsetPage(cursors, scope, users, offset, limit, total))
+	}
+}
+
+// MakeTransitionsHandler обработчик GET /api/v1/churn/transitions?from=2026-10-01&to=2026-10-07:
+// число переходов между состояниями по дням; по умолчанию — последние 30 дней
+func MakeTransitionsHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		to, err := parseDate(query.Get("to"), time.Now().UTC())
+		if err != nil {
+			utils.RespondWith400(w, "to must be a date in YYYY-MM-DD format")
+			return
+		}
+		from, err := parseDate(query.Get("from"), to.Add(-defaultTransitionsWindow+24*time.Hour))
+		if err != nil {
+			utils.RespondWith400(w, "from must be a date in YYYY-MM-DD format")
+			return
+		}
+
+		if to.Before(from) {
+			utils.RespondWith400(w, "from must not be after to")
+			return
+		}
+		if to.Sub(from) >= maxTransitionDays*24*time.Hour {
+			utils.RespondWith400(w, "range must not exceed 366 days")
+			return
+		}
+
+		transitions, err := service.Transitions(r.Context(), from, to)
+		if err != nil {
+			logger.Log.Error("Failed to read churn transitions", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, transitions)
+	}
+}
+
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, apperrors.ErrNotFound) {
+		utils.RespondWith404(w)
+		return
+	}
+	logger.Log.Error("Failed to read churn report", zap.Error(err))
+	utils.RespondWith500(w)
+}
+
+func parseDate(raw string, defaultValue time.Time) (time.Time, error) {
+	if raw == "" {
+		return defaultValue.UTC().Truncate(24 * time.Hour), nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
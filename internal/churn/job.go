@@ -0,0 +1,10 @@
+package churn
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewEvaluationJob создаёт задачу периодической классификации пользователей
+func NewEvaluationJob(service *Service) jobs.Job {
+	return jobs.Func("churn-evaluation", service.Evaluate)
+}
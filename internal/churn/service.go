@@ -0,0 +1,451 @@
+package churn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Схема ключей:
+//
+//	churn:status                   хеш: пользователь -> состояние на момент последней оценки
+//	churn:transitions:{yyyymmdd}   хеш: "from:to" -> число переходов за сутки (UTC)
+//	churn:report                   хеш последней оценки: report -> JSON Report, {chat} -> JSON Counts
+//	churn:inactive                 отсортированное множество последней оценки: "{user}:{status}" -> -last_time (unix)
+//	churn:inactive:{status}        то же для одного состояния: пользователь -> -last_time (unix)
+//
+// Вес неактивных — время активности со знаком минус, поэтому в порядке убывания веса дольше всех
+// неактивные идут первыми, а пользователи без времени активности — раньше всех.
+const (
+	statusKey             = "churn:status"
+	transitionsKeyPrefix  = "churn:transitions:"
+	transitionsDateLayout = "20060102"
+	reportKey             = "churn:report"
+	inactiveKey           = "churn:inactive"
+	fieldReport           = "report"
+	// draftSuffix сюда записывается новая оценка, прежде чем заменить ключ целиком
+	draftSuffix = ":draft"
+
+	// maxTransitionDays наибольший диапазон запроса переходов
+	maxTransitionDays = 366
+)
+
+type (
+	// Inactive пользователь в зоне риска или ушедший
+	Inactive struct {
+		UserID   entities.UserID `json:"user_id"`
+		LastTime time.Time       `json:"last_time"`
+		// InactiveFor nil, если время последней активности неизвестно
+		InactiveFor *string `json:"inactive_for"`
+		Status      Status  `json:"status"`
+	}
+
+	// Report состояние пользователей на момент последней оценки
+	Report struct {
+		ComputedAt   time.Time         `json:"computed_at"`
+		AtRiskAfter  string            `json:"at_risk_after"`
+		ChurnedAfter string            `json:"churned_after"`
+		Users        Counts            `json:"users"`
+		ByChatType   map[string]Counts `json:"by_chat_type"`
+	}
+
+	// Transitions число переходов между состояниями за сутки
+	Transitions struct {
+		Date   string         `json:"date"`
+		Counts map[string]int `json:"counts"`
+	}
+
+	// Service классифицирует пользователей по давности активности, отслеживает
+	// переходы между состояниями и считает долю ушедших по чатам и типам чатов.
+	// Результат оценки хранится рядом с churn:status и переживает перезапуск.
+	Service struct {
+		repo       *storage.Repository
+		thresholds Thresholds
+		retention  time.Duration
+	}
+)
+
+func NewService(repo *storage.Repository, thresholds Thresholds, retention time.Duration) *Service {
+	return &Service{repo: repo, thresholds: thresholds, retention: retention}
+}
+
+// Evaluate классифицирует всех пользователей, записывает переходы с прошлой оценки
+// и строит отчёт по чатам и типам чатов
+func (s *Service) Evaluate(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	userIDs, err := s.repo.UserIDs(ctx)
+	if err != nil {
+		return err
+	}
+	previous, err := s.repo.Storage().HashGetAll(ctx, statusKey)
+	if err != nil {
+		return fmt.Errorf("failed to read user statuses: %w", err)
+	}
+
+	report := &Report{
+		ComputedAt:   now,
+		AtRiskAfter:  s.thresholds.AtRisk.String(),
+		ChurnedAfter: s.thresholds.Churned.String(),
+		ByChatType:   make(map[string]Counts),
+	}
+	statuses := make(map[entities.UserID]Status, len(userIDs))
+	changed := make(map[string]string)
+	transitions := make(map[string]int64)
+	var inactive []Inactive
+	for _, userID := range userIDs {
+		user, err := s.repo.User(ctx, userID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		status := s.thresholds.Classify(user.LastTime, now)
+		statuses[userID] = status
+		report.Users.add(status)
+		if status != StatusActive {
+			inactive = append(inactive, Inactive{UserID: userID, LastTime: user.LastTime, Status: status})
+		}
+
+		field := strconv.FormatInt(int64(userID), 10)
+		if old := Status(previous[field]); old != status {
+			changed[field] = string(status)
+			// Первая классификация пользователя — не переход
+			if old != "" {
+				transitions[transitionField(old, status)]++
+			}
+		}
+	}
+	report.Users.finish()
+
+	chats, err := s.chatCounts(ctx, statuses, report)
+	if err != nil {
+		return err
+	}
+
+	if len(changed) > 0 {
+		if err := s.repo.Storage().HashSet(ctx, statusKey, changed); err != nil {
+			return fmt.Errorf("failed to save user statuses: %w", err)
+		}
+	}
+	for field, count := range transitions {
+		if err := s.recordTransition(ctx, now, field, count); err != nil {
+			return err
+		}
+	}
+
+	if err := s.saveInactive(ctx, inactive); err != nil {
+		return err
+	}
+	if err := s.saveReport(ctx, report, chats); err != nil {
+		return err
+	}
+
+	logger.Log.Info("Churn evaluated", zap.Int("users", report.Users.Members),
+		zap.Int("atRisk", report.Users.AtRisk), zap.Int("churned", report.Users.Churned),
+		zap.Int("statusChanges", len(changed)))
+	return nil
+}
+
+// OnChange сразу возвращает в активные пользователя, который проявил активность
+// после попадания в зону риска или ухода, и учитывает этот переход
+func (s *Service) OnChange(ctx context.Context, change *membership.Change) error {
+	if change.Event.Type.Removes() {
+		return nil
+	}
+
+	status := s.thresholds.Classify(change.User.LastTime, time.Now().UTC())
+	field := strconv.FormatInt(int64(change.Event.UserID), 10)
+	old, err := s.repo.Storage().HashGet(ctx, statusKey, field)
+	if err != nil {
+		return fmt.Errorf("failed to read status of user %d: %w", change.Event.UserID, err)
+	}
+	if old == "" || Status(old) == status {
+		return nil
+	}
+
+	if err := s.repo.Storage().HashSet(ctx, statusKey, map[string]string{field: string(status)}); err != nil {
+		return fmt.Errorf("failed to save status of user %d: %w", change.Event.UserID, err)
+	}
+	return s.recordTransition(ctx, time.Now().UTC(), transitionField(Status(old), status), 1)
+}
+
+// saveInactive записывает неактивных пользователей в черновики и заменяет ими прежние множества
+func (s *Service) saveInactive(ctx context.Context, inactive []Inactive) error {
+	store := s.repo.Storage()
+	keys := []string{inactiveKey, inactiveStatusKey(StatusAtRisk), inactiveStatusKey(StatusChurned)}
+	for _, key := range keys {
+		if err := store.Delete(ctx, key+draftSuffix); err != nil {
+			return fmt.Errorf("failed to reset churn draft %s: %w", key, err)
+		}
+	}
+
+	filled := make(map[string]bool, len(keys))
+	for _, u := range inactive {
+		score := -float64(u.LastTime.Unix())
+		id := strconv.FormatInt(int64(u.UserID), 10)
+		if err := store.SortedSetAdd(ctx, inactiveKey+draftSuffix, id+":"+string(u.Status), score); err != nil {
+			return fmt.Errorf("failed to save inactive user %d: %w", u.UserID, err)
+		}
+		if err := store.SortedSetAdd(ctx, inactiveStatusKey(u.Status)+draftSuffix, id, score); err != nil {
+			return fmt.Errorf("failed to save inactive user %d: %w", u.UserID, err)
+		}
+		filled[inactiveKey] = true
+		filled[inactiveStatusKey(u.Status)] = true
+	}
+
+	for _, key := range keys {
+		if err := s.publish(ctx, key, filled[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveReport записывает отчёт и состояния участников по чатам в черновик и одним переименованием
+// заменяет им прежний отчёт, поэтому читатели не видят смесь двух оценок
+func (s *Service) saveReport(ctx context.Context, report *Report, chats map[entities.ChatID]Counts) error {
+	fields := make(map[string]string, len(chats)+1)
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode churn report: %w", err)
+	}
+	fields[fieldReport] = string(data)
+	for chatID, counts := range chats {
+		data, err := json.Marshal(counts)
+		if err != nil {
+			return fmt.Errorf("failed to encode churn of chat %d: %w", chatID, err)
+		}
+		fields[strconv.FormatInt(int64(chatID), 10)] = string(data)
+	}
+
+	store := s.repo.Storage()
+	if err := store.Delete(ctx, reportKey+draftSuffix); err != nil {
+		return fmt.Errorf("failed to reset churn report draft: %w", err)
+	}
+	if err := store.HashSet(ctx, reportKey+draftSuffix, fields); err != nil {
+		return fmt.Errorf("failed to save churn report: %w", err)
+	}
+	return s.publish(ctx, reportKey, true)
+}
+
+// publish заменяет ключ его черновиком; пустой черновик в хранилище не создаётся,
+// поэтому вместо переименования ключ удаляется
+func (s *Service) publish(ctx context.Context, key string, filled bool) error {
+	store := s.repo.Storage()
+	if !filled {
+		if err := store.Delete(ctx, key); err != nil {
+			return fmt.Errorf("failed to clear %s: %w", key, err)
+		}
+		return nil
+	}
+	if err := store.Rename(ctx, key+draftSuffix, key); err != nil {
+		return fmt.Errorf("failed to publish %s: %w", key, err)
+	}
+	return nil
+}
+
+// Report возвращает последний отчёт или ErrNotFound, если оценка ещё не выполнялась
+func (s *Service) Report(ctx context.Context) (*Report, error) {
+	var report Report
+	if err := s.readReport(ctx, fieldReport, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Chat возвращает состояние участников чата на момент последней оценки
+func (s *Service) Chat(ctx context.Context, id entities.ChatID) (Counts, error) {
+	var counts Counts
+	if err := s.readReport(ctx, strconv.FormatInt(int64(id), 10), &counts); err != nil {
+		return Counts{}, err
+	}
+	return counts, nil
+}
+
+// Inactive возвращает страницу неактивных пользователей, дольше всех неактивные первыми, и их общее число;
+// status = "" — в зоне риска и ушедшие вместе. Пользователи с одинаковым временем активности
+// идут в порядке хранилища.
+func (s *Service) Inactive(ctx context.Context, status Status, offset, limit int) ([]Inactive, int64, error) {
+	report, err := s.Report(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	key := inactiveKey
+	if status != "" {
+		key = inactiveStatusKey(status)
+	}
+	store := s.repo.Storage()
+	total, err := store.SortedSetCard(ctx, key)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count inactive users: %w", err)
+	}
+	members, err := store.SortedSetRevRange(ctx, key, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read inactive users: %w", err)
+	}
+
+	users := make([]Inactive, 0, len(members))
+	for _, m := range members {
+		raw, userStatus := m.Member, string(status)
+		if status == "" {
+			var found bool
+			raw, userStatus, found = strings.Cut(m.Member, ":")
+			if !found {
+				logger.Log.Warn("Skipping malformed inactive user", zap.String("member", m.Member))
+				continue
+			}
+		}
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed inactive user", zap.String("member", m.Member))
+			continue
+		}
+
+		u := Inactive{UserID: entities.UserID(id), LastTime: time.Unix(-int64(m.Score), 0).UTC(), Status: Status(userStatus)}
+		if !u.LastTime.IsZero() {
+			inactiveFor := report.ComputedAt.Sub(u.LastTime).Truncate(time.Second).String()
+			u.InactiveFor = &inactiveFor
+		}
+		users = append(users, u)
+	}
+	return users, total, nil
+}
+
+func (s *Service) readReport(ctx context.Context, field string, dest any) error {
+	data, err := s.repo.Storage().HashGet(ctx, reportKey, field)
+	if err != nil {
+		return fmt.Errorf("failed to read churn report: %w", err)
+	}
+	if data == "" {
+		return apperrors.ErrNotFound
+	}
+	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		return fmt.Errorf("failed to decode churn report %s: %w", field, err)
+	}
+	return nil
+}
+
+// Transitions возвращает переходы между состояниями по дням в диапазоне [from, to] (UTC)
+func (s *Service) Transitions(ctx context.Context, from, to time.Time) ([]Transitions, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	if to.Before(from) {
+		return nil, fmt.Errorf("from must not be after to")
+	}
+	if to.Sub(from) >= maxTransitionDays*24*time.Hour {
+		return nil, fmt.Errorf("range must not exceed %d days", maxTransitionDays)
+	}
+
+	result := make([]Transitions, 0)
+	for day := from; !day.After(to); day = day.Add(24 * time.Hour) {
+		fields, err := s.repo.Storage().HashGetAll(ctx, transitionsKey(day))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read churn transitions: %w", err)
+		}
+
+		counts := make(map[string]int, len(fields))
+		for field, value := range fields {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				logger.Log.Warn("Skipping malformed churn transition", zap.String("field", field), zap.String("value", value))
+				continue
+			}
+			counts[field] = n
+		}
+		result = append(result, Transitions{Date: day.Format(time.DateOnly), Counts: counts})
+	}
+	return result, nil
+}
+
+// chatCounts считает состояния участников каждого чата и, без повторов, пользователей каждого типа чатов
+func (s *Service) chatCounts(ctx context.Context, statuses map[entities.UserID]Status, report *Report) (map[entities.ChatID]Counts, error) {
+	chatIDs, err := s.repo.ChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	chats := make(map[entities.ChatID]Counts, len(chatIDs))
+	usersByType := make(map[string]map[entities.UserID]struct{})
+	for _, chatID := range chatIDs {
+		chat, err := s.repo.Chat(ctx, chatID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		members, err := s.repo.ChatUserIDs(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+
+		chatType := strconv.FormatUint(uint64(chat.ChatType), 10)
+		seen, ok := usersByType[chatType]
+		if !ok {
+			seen = make(map[entities.UserID]struct{})
+			usersByType[chatType] = seen
+		}
+		byType := report.ByChatType[chatType]
+
+		var counts Counts
+		for _, userID := range members {
+			status, ok := statuses[userID]
+			if !ok {
+				// Участник без хеша пользователя: время активности неизвестно
+				status = StatusChurned
+			}
+			counts.add(status)
+			if _, dup := seen[userID]; !dup {
+				seen[userID] = struct{}{}
+				byType.add(status)
+			}
+		}
+		counts.finish()
+		chats[chatID] = counts
+		report.ByChatType[chatType] = byType
+	}
+
+	for chatType, counts := range report.ByChatType {
+		counts.finish()
+		report.ByChatType[chatType] = counts
+	}
+	return chats, nil
+}
+
+func (s *Service) recordTransition(ctx context.Context, at time.Time, field string, count int64) error {
+	key := transitionsKey(at)
+	if _, err := s.repo.Storage().HashIncrBy(ctx, key, field, count); err != nil {
+		return fmt.Errorf("failed to record churn transition %s: %w", field, err)
+	}
+	if err := s.repo.Storage().Expire(ctx, key, s.retention); err != nil {
+		return fmt.Errorf("failed to set ttl of %s: %w", key, err)
+	}
+	return nil
+}
+
+// transitionField имя поля перехода, например "active:at_risk"
+func transitionField(from, to Status) string {
+	return strings.Join([]string{string(from), string(to)}, ":")
+}
+
+func inactiveStatusKey(status Status) string {
+	return inactiveKey + ":" + string(status)
+}
+
+func transitionsKey(day time.Time) string {
+	return transitionsKeyPrefix + day.UTC().Format(transitionsDateLayout)
+}
@@ -0,0 +1,161 @@
+package churn
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// TestInactiveWithoutLastTime проверяет, что у пользователя без времени активности
+// inactive_for равен null, а не длительности от нулевого времени
+func TestInactiveWithoutLastTime(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	s := NewService(repo, Thresholds{AtRisk: time.Hour, Churned: 24 * time.Hour}, time.Hour)
+
+	if err := repo.SaveUser(ctx, entities.User{UserID: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUser(ctx, entities.User{UserID: 2, LastTime: time.Now().Add(-2 * time.Hour)}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	users, _, err := s.Inactive(ctx, "", 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[entities.UserID]map[string]any)
+	for _, u := range users {
+		data, err := json.Marshal(u)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var fields map[string]any
+		if err := json.Unmarshal(data, &fields); err != nil {
+			t.Fatal(err)
+		}
+		got[u.UserID] = fields
+	}
+
+	if v, ok := got[1]["inactive_for"]; !ok || v != nil {
+		t.Errorf("user without last time: inactive_for = %v, want null", v)
+	}
+	if v, _ := got[2]["inactive_for"].(string); v != "2h0m0s" {
+		t.Errorf("user active 2h ago: inactive_for = %q, want 2h0m0s", v)
+	}
+}
+
+// TestReportSurvivesRestart проверяет, что отчёт, состояния чатов и неактивные пользователи
+// читаются новым экземпляром сервиса над тем же хранилищем
+func TestReportSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	thresholds := Thresholds{AtRisk: time.Hour, Churned: 24 * time.Hour}
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for id, ago := range map[entities.UserID]time.Duration{1: 2 * time.Hour, 2: 48 * time.Hour, 3: time.Minute} {
+		if err := repo.SaveUser(ctx, entities.User{UserID: id, LastTime: now.Add(-ago)}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := repo.AddMembership(ctx, 7, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewService(repo, thresholds, time.Hour).Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := NewService(repo, thresholds, time.Hour)
+	report, err := restarted.Report(ctx)
+	if err != nil {
+		t.Fatalf("report after restart: %v", err)
+	}
+	if report.Users.Members != 3 || report.Users.AtRisk != 1 || report.Users.Churned != 1 {
+		t.Errorf("users = %+v, want 3 members, 1 at risk and 1 churned", report.Users)
+	}
+	chat, err := restarted.Chat(ctx, 7)
+	if err != nil || chat.Members != 3 || chat.Churned != 1 {
+		t.Errorf("chat 7 = %+v, %v; want 3 members and 1 churned", chat, err)
+	}
+	if _, err := restarted.Chat(ctx, 8); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("chat 8: error = %v, want ErrNotFound", err)
+	}
+}
+
+// TestInactivePages проверяет порядок, фильтр по состоянию и общее число неактивных пользователей
+// и то, что следующая оценка заменяет их целиком
+func TestInactivePages(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	s := NewService(repo, Thresholds{AtRisk: time.Hour, Churned: 24 * time.Hour}, time.Hour)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for id, ago := range map[entities.UserID]time.Duration{1: 2 * time.Hour, 2: 72 * time.Hour, 3: 3 * time.Hour, 4: 48 * time.Hour, 5: time.Minute} {
+		if err := repo.SaveUser(ctx, entities.User{UserID: id, LastTime: now.Add(-ago)}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := s.Inactive(ctx, "", 0, 10); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("inactive before evaluation: error = %v, want ErrNotFound", err)
+	}
+	if err := s.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		status        Status
+		offset, limit int
+		want          []entities.UserID
+	}{
+		{"", 0, 10, []entities.UserID{2, 4, 3, 1}},
+		{"", 1, 2, []entities.UserID{4, 3}},
+		{"", 4, 2, []entities.UserID{}},
+		{StatusAtRisk, 0, 10, []entities.UserID{3, 1}},
+		{StatusChurned, 1, 10, []entities.UserID{4}},
+	}
+	for _, tt := range tests {
+		users, total, err := s.Inactive(ctx, tt.status, tt.offset, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := make([]entities.UserID, 0, len(users))
+		for _, u := range users {
+			ids = append(ids, u.UserID)
+			if (u.Status == StatusChurned) != (u.UserID == 2 || u.UserID == 4) {
+				t.Errorf("user %d has status %s", u.UserID, u.Status)
+			}
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("Inactive(%q, %d, %d) = %v, want %v", tt.status, tt.offset, tt.limit, ids, tt.want)
+		}
+		if want := map[Status]int64{"": 4, StatusAtRisk: 2, StatusChurned: 2}[tt.status]; total != want {
+			t.Errorf("Inactive(%q) total = %d, want %d", tt.status, total, want)
+		}
+	}
+
+	// Пользователи в зоне риска вернулись: прежний список состояния не должен остаться
+	for _, id := range []entities.UserID{1, 3} {
+		if err := repo.TouchUser(ctx, id, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Evaluate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if users, total, err := s.Inactive(ctx, StatusAtRisk, 0, 10); err != nil || total != 0 || len(users) != 0 {
+		t.Errorf("at risk after return = %v, total %d, %v; want none", users, total, err)
+	}
+	if _, total, err := s.Inactive(ctx, "", 0, 10); err != nil || total != 2 {
+		t.Errorf("inactive after return: total %d, %v; want 2", total, err)
+	}
+}
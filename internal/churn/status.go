@@ -0,0 +1,72 @@
+package churn
+
+import (
+	"time"
+)
+
+// Status состояние пользователя по давности последней активности
+type Status string
+
+const (
+	StatusActive  Status = "active"
+	StatusAtRisk  Status = "at_risk"
+	StatusChurned Status = "churned"
+)
+
+// Valid сообщает, известно ли состояние
+func (s Status) Valid() bool {
+	return s == StatusActive || s == StatusAtRisk || s == StatusChurned
+}
+
+// Thresholds пороги неактивности: после AtRisk пользователь в зоне риска, после Churned — ушёл
+type Thresholds struct {
+	AtRisk  time.Duration
+	Churned time.Duration
+}
+
+// Classify определяет состояние пользователя на момент now.
+// Пользователь без известного времени активности считается ушедшим.
+func (t Thresholds) Classify(lastTime, now time.Time) Status {
+	if lastTime.IsZero() {
+		return StatusChurned
+	}
+	inactive := now.Sub(lastTime)
+	switch {
+	case inactive >= t.Churned:
+		return StatusChurned
+	case inactive >= t.AtRisk:
+		return StatusAtRisk
+	default:
+		return StatusActive
+	}
+}
+
+// Counts число участников в каждом состоянии и доли ушедших и находящихся в зоне риска
+type Counts struct {
+	Members    int     `json:"members"`
+	Active     int     `json:"active"`
+	AtRisk     int     `json:"at_risk"`
+	Churned    int     `json:"churned"`
+	ChurnRate  float64 `json:"churn_rate"`
+	AtRiskRate float64 `json:"at_risk_rate"`
+}
+
+func (c *Counts) add(status Status) {
+	c.Members++
+	switch status {
+	case StatusActive:
+		c.Active++
+	case StatusAtRisk:
+		c.AtRisk++
+	case StatusChurned:
+		c.Churned++
+	}
+}
+
+func (c *Counts) finish() {
+	if c.Members == 0 {
+		return
+	}
+	c.ChurnRate = float64(c.Churned) / float64(c.Members)
+	c.AtRiskRate = float64(c.AtRisk) / float64(c.Members)
+}
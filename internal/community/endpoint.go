@@ -0,0 +1,97 @@
+package community
+
+import (
+	"errors"
+	"net/http"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+	maxListOffset    = 1 << 30
+)
+
+// MakeListHandler обработчик GET /api/v1/communities?limit=100&cursor=...: сообщества и их размеры,
+// крупнейшие первыми; вместо cursor можно передать offset
+func MakeListHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const scope = "communities"
+		offset, err := cursors.Offset(r, scope, maxListOffset)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		limit, err := utils.QueryInt(r, "limit", defaultListLimit, 1, maxListLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		overview, err := service.Communities(r.Context(), offset, limit)
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		overview.Next = cursors.NextOffset(scope, offset, limit, *overview.Total)
+		utils.SuccessRespondWith200(w, overview)
+	}
+}
+
+// MakeGetHandler обработчик GET /api/v1/communities/{id}: сообщество и его чаты
+func MakeGetHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		community, err := service.Community(r.Context(), CommunityID(id))
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		utils.SuccessRespondWith200(w, community)
+	}
+}
+
+// ChatCommunity сообщество вместе с чатом, по которому оно запрошено
+type ChatCommunity struct {
+	ChatID entities.ChatID `json:"chat_id"`
+	*Community
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/community: сообщество, в которое входит чат
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		community, err := service.ChatCommunity(r.Context(), entities.ChatID(id))
+		if err != nil {
+			respondWithServiceError(w, err)
+			return
+		}
+		utils.SuccessRespondWith200(w, ChatCommunity{ChatID: entities.ChatID(id), Community: community})
+	}
+}
+
+func respondWithServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, apperrors.ErrNotFound) {
+		utils.RespondWith404(w)
+		return
+	}
+	logger.Log.Error("Failed to query communities", zap.Error(err))
+	utils.RespondWith500(w)
+}
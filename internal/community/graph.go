@@ -0,0 +1,81 @@
+package community
+
+import (
+	"sort"
+
+	"stats-of/internal/entities"
+)
+
+// Graph взвешенный неориентированный граф чатов: вес ребра равен числу общих пользователей
+type Graph struct {
+	adjacency map[entities.ChatID]map[entities.ChatID]float64
+}
+
+// Project строит проекцию двудольного графа пользователь–чат на чаты.
+// Пользователи, состоящие более чем в maxChatsPerUser чатах, пропускаются:
+// они добавляют квадратичное число рёбер и почти не несут информации о сообществах.
+func Project(list entities.ChatList, maxChatsPerUser int) *Graph {
+	g := &Graph{adjacency: make(map[entities.ChatID]map[entities.ChatID]float64, len(list))}
+
+	userChats := make(map[entities.UserID][]entities.ChatID)
+	for chatID, users := range list {
+		g.addNode(chatID)
+		for _, user := range users {
+			userChats[user.UserID] = append(userChats[user.UserID], chatID)
+		}
+	}
+
+	for _, chats := range userChats {
+		if maxChatsPerUser > 0 && len(chats) > maxChatsPerUser {
+			continue
+		}
+		for i := 0; i < len(chats); i++ {
+			for j := i + 1; j < len(chats); j++ {
+				g.addEdge(chats[i], chats[j], 1)
+			}
+		}
+	}
+	return g
+}
+
+// Nodes возвращает идентификаторы всех чатов графа в порядке возрастания
+func (g *Graph) Nodes() []entities.ChatID {
+	nodes := make([]entities.ChatID, 0, len(g.adjacency))
+	for id := range g.adjacency {
+		nodes = append(nodes, id)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	return nodes
+}
+
+// Neighbors возвращает соседей чата с весами рёбер
+func (g *Graph) Neighbors(id entities.ChatID) map[entities.ChatID]float64 {
+	return g.adjacency[id]
+}
+
+// TotalWeight возвращает сумму весов всех рёбер
+func (g *Graph) TotalWeight() float64 {
+	var total float64
+	for _, neighbors := range g.adjacency {
+		for _, w := range neighbors {
+			total += w
+		}
+	}
+	return total / 2
+}
+
+func (g *Graph) addNode(id entities.ChatID) {
+	if _, ok := g.adjacency[id]; !ok {
+		g.adjacency[id] = make(map[entities.ChatID]float64)
+	}
+}
+
+func (g *Graph) addEdge(a, b entities.ChatID, weight float64) {
+	if a == b {
+		return
+	}
+	g.addNode(a)
+	g.addNode(b)
+	g.adjacency[a][b] += weight
+	g.adjacency[b][a] += weight
+}
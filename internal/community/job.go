@@ -0,0 +1,10 @@
+package community
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewDetectionJob создаёт задачу периодического поиска сообществ
+func NewDetectionJob(service *Service) jobs.Job {
+	return jobs.Func("community-detection", service.Detect)
+}
@@ -0,0 +1,100 @@
+package community
+
+import (
+	"math/rand"
+
+	"stats-of/internal/entities"
+)
+
+// CommunityID идентификатор сообщества: наименьший идентификатор входящего в него чата
+type CommunityID = entities.ChatID
+
+// DetectLabelPropagation находит сообщества взвешенным распространением меток.
+// Каждый чат на каждой итерации принимает метку с наибольшим суммарным весом среди соседей;
+// при равенстве весов сохраняется текущая метка, иначе выбирается наименьшая.
+// Порядок обхода перемешивается детерминированно от seed, поэтому результат воспроизводим.
+func DetectLabelPropagation(g *Graph, maxIterations int, seed int64) map[entities.ChatID]CommunityID {
+	nodes := g.Nodes()
+	labels := make(map[entities.ChatID]entities.ChatID, len(nodes))
+	for _, id := range nodes {
+		labels[id] = id
+	}
+
+	rnd := rand.New(rand.NewSource(seed))
+	for iteration := 0; iteration < maxIterations; iteration++ {
+		rnd.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+
+		changed := false
+		for _, id := range nodes {
+			neighbors := g.Neighbors(id)
+			if len(neighbors) == 0 {
+				continue
+			}
+
+			weights := make(map[entities.ChatID]float64, len(neighbors))
+			for neighbor, w := range neighbors {
+				weights[labels[neighbor]] += w
+			}
+
+			current := labels[id]
+			best, bestWeight := current, weights[current]
+			for label, w := range weights {
+				if w > bestWeight || (w == bestWeight && label < best && best != current) {
+					best, bestWeight = label, w
+				}
+			}
+			if best != current {
+				labels[id] = best
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	return canonicalize(labels)
+}
+
+// Modularity вычисляет модульность разбиения: Q = Σ_c [in_c/2m − (tot_c/2m)²]
+func Modularity(g *Graph, communities map[entities.ChatID]CommunityID) float64 {
+	m2 := 2 * g.TotalWeight()
+	if m2 == 0 {
+		return 0
+	}
+
+	in := make(map[CommunityID]float64)
+	tot := make(map[CommunityID]float64)
+	for _, id := range g.Nodes() {
+		c := communities[id]
+		for neighbor, w := range g.Neighbors(id) {
+			tot[c] += w
+			if communities[neighbor] == c {
+				in[c] += w
+			}
+		}
+	}
+
+	var q float64
+	for c, t := range tot {
+		q += in[c]/m2 - (t/m2)*(t/m2)
+	}
+	return q
+}
+
+// canonicalize переименовывает сообщества в наименьший идентификатор чата внутри каждого из них,
+// чтобы идентификаторы сообществ были стабильны между запусками
+func canonicalize(labels map[entities.ChatID]entities.ChatID) map[entities.ChatID]CommunityID {
+	minimum := make(map[entities.ChatID]entities.ChatID)
+	for id, label := range labels {
+		if current, ok := minimum[label]; !ok || id < current {
+			minimum[label] = id
+		}
+	}
+
+	result := make(map[entities.ChatID]CommunityID, len(labels))
+	for id, label := range labels {
+		result[id] = minimum[label]
+	}
+	return result
+}
@@ -0,0 +1,254 @@
+package community
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// Хеш chat_id -> community_id последнего разбиения
+	assignmentsKey = "community:chats"
+	// assignmentsDraftKey сюда записывается новое разбиение, прежде чем заменить assignmentsKey целиком
+	assignmentsDraftKey = "community:chats:draft"
+	// Хеш с полями computed_at и modularity последнего разбиения
+	metaKey = "community:meta"
+
+	defaultMaxIterations   = 20
+	defaultMaxChatsPerUser = 500
+	labelPropagationSeed   = 1
+)
+
+type (
+	// Community сообщество чатов с общей аудиторией
+	Community struct {
+		ID      CommunityID       `json:"community_id"`
+		Size    int               `json:"size"`
+		Members []entities.ChatID `json:"members,omitempty"`
+	}
+
+	// Overview сводка по последнему разбиению на сообщества и страница сообществ от крупных к мелким
+	Overview struct {
+		ComputedAt time.Time `json:"computed_at"`
+		Modularity float64   `json:"modularity"`
+		pagination.Page[Community]
+	}
+
+	partition struct {
+		computedAt  time.Time
+		modularity  float64
+		assignments map[entities.ChatID]CommunityID
+		members     map[CommunityID][]entities.ChatID
+	}
+
+	// Service выполняет поиск сообществ, сохраняет разбиение в хранилище и отдаёт его API
+	Service struct {
+		repo *storage.Repository
+
+		mu        sync.RWMutex
+		partition *partition
+	}
+)
+
+func NewService(repo *storage.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Detect строит проекцию графа участия, находит сообщества и сохраняет результат
+func (s *Service) Detect(ctx context.Context) error {
+	list, err := s.repo.ChatList(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load chat list: %w", err)
+	}
+
+	graph := Project(list, defaultMaxChatsPerUser)
+	assignments := DetectLabelPropagation(graph, defaultMaxIterations, labelPropagationSeed)
+	p := newPartition(time.Now().UTC(), Modularity(graph, assignments), assignments)
+
+	if err := s.save(ctx, p); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.partition = p
+	s.mu.Unlock()
+
+	logger.Log.Info("Communities detected", zap.Int("chats", len(assignments)),
+		zap.Int("communities", len(p.members)), zap.Float64("modularity", p.modularity))
+	return nil
+}
+
+// Communities возвращает сообщества [offset, offset+limit) в порядке убывания размера без списка участников.
+// Курсор следующей страницы заполняет обработчик.
+func (s *Service) Communities(ctx context.Context, offset, limit int) (*Overview, error) {
+	p, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	communities := make([]Community, 0, len(p.members))
+	for id, members := range p.members {
+		communities = append(communities, Community{ID: id, Size: len(members)})
+	}
+	sort.Slice(communities, func(i, j int) bool {
+		if communities[i].Size != communities[j].Size {
+			return communities[i].Size > communities[j].Size
+		}
+		return communities[i].ID < communities[j].ID
+	})
+	total := int64(len(communities))
+	communities = communities[min(offset, len(communities)):min(offset+limit, len(communities))]
+
+	return &Overview{
+		ComputedAt: p.computedAt,
+		Modularity: p.modularity,
+		Page:       pagination.Page[Community]{Items: communities, Limit: limit, Total: &total},
+	}, nil
+}
+
+// Community возвращает сообщество вместе с его чатами
+func (s *Service) Community(ctx context.Context, id CommunityID) (*Community, error) {
+	p, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	members, ok := p.members[id]
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	return &Community{ID: id, Size: len(members), Members: members}, nil
+}
+
+// ChatCommunity возвращает сообщество, в которое входит чат
+func (s *Service) ChatCommunity(ctx context.Context, chatID entities.ChatID) (*Community, error) {
+	p, err := s.current(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := p.assignments[chatID]
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	return &Community{ID: id, Size: len(p.members[id])}, nil
+}
+
+// current возвращает последнее разбиение, при необходимости загружая его из хранилища
+func (s *Service) current(ctx context.Context) (*partition, error) {
+	s.mu.RLock()
+	p := s.partition
+	s.mu.RUnlock()
+	if p != nil {
+		return p, nil
+	}
+
+	p, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if s.partition == nil {
+		s.partition = p
+	}
+	p = s.partition
+	s.mu.Unlock()
+	return p, nil
+}
+
+func (s *Service) save(ctx context.Context, p *partition) error {
+	fields := make(map[string]string, len(p.assignments))
+	for chatID, communityID := range p.assignments {
+		fields[strconv.FormatInt(int64(chatID), 10)] = strconv.FormatInt(int64(communityID), 10)
+	}
+
+	store := s.repo.Storage()
+	if len(fields) == 0 {
+		if err := store.Delete(ctx, assignmentsKey); err != nil {
+			return fmt.Errorf("failed to clear communities: %w", err)
+		}
+	} else {
+		// Читатели видят либо прежнее разбиение, либо новое целиком
+		if err := store.Delete(ctx, assignmentsDraftKey); err != nil {
+			return fmt.Errorf("failed to reset communities draft: %w", err)
+		}
+		if err := store.HashSet(ctx, assignmentsDraftKey, fields); err != nil {
+			return fmt.Errorf("failed to save communities: %w", err)
+		}
+		if err := store.Rename(ctx, assignmentsDraftKey, assignmentsKey); err != nil {
+			return fmt.Errorf("failed to publish communities: %w", err)
+		}
+	}
+	err := store.HashSet(ctx, metaKey, map[string]string{
+		"computed_at": strconv.FormatInt(p.computedAt.Unix(), 10),
+		"modularity":  strconv.FormatFloat(p.modularity, 'f', -1, 64),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save communities metadata: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) load(ctx context.Context) (*partition, error) {
+	store := s.repo.Storage()
+
+	meta, err := store.HashGetAll(ctx, metaKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load communities metadata: %w", err)
+	}
+	if len(meta) == 0 {
+		// Поиск сообществ ещё ни разу не выполнялся
+		return nil, apperrors.ErrNotFound
+	}
+
+	fields, err := store.HashGetAll(ctx, assignmentsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load communities: %w", err)
+	}
+
+	assignments := make(map[entities.ChatID]CommunityID, len(fields))
+	for field, value := range fields {
+		chatID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		communityID, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		assignments[entities.ChatID(chatID)] = CommunityID(communityID)
+	}
+
+	computedAt, _ := strconv.ParseInt(meta["computed_at"], 10, 64)
+	modularity, _ := strconv.ParseFloat(meta["modularity"], 64)
+	return newPartition(time.Unix(computedAt, 0).UTC(), modularity, assignments), nil
+}
+
+func newPartition(computedAt time.Time, modularity float64, assignments map[entities.ChatID]CommunityID) *partition {
+	members := make(map[CommunityID][]entities.ChatID)
+	for chatID, communityID := range assignments {
+		members[communityID] = append(members[communityID], chatID)
+	}
+	for _, chats := range members {
+		sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	}
+
+	return &partition{
+		computedAt:  computedAt,
+		modularity:  modularity,
+		assignments: assignments,
+		members:     members,
+	}
+}
@@ -0,0 +1,67 @@
+package community
+
+import (
+	"context"
+	"testing"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// TestDetectReplacesPartition проверяет, что повторный поиск заменяет сохранённое разбиение целиком,
+// а черновик не остаётся в хранилище
+func TestDetectReplacesPartition(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+
+	link := func(chatID entities.ChatID, users ...entities.UserID) {
+		for _, userID := range users {
+			if _, err := repo.AddMembership(ctx, chatID, userID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	link(1, 1, 2, 3)
+	link(2, 1, 2, 3)
+	link(3, 7, 8)
+	link(4, 7, 8)
+
+	if err := NewService(repo).Detect(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []entities.UserID{7, 8} {
+		for _, chatID := range []entities.ChatID{3, 4} {
+			if _, err := repo.RemoveMembership(ctx, chatID, userID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := NewService(repo).Detect(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Новый сервис читает разбиение из хранилища
+	s := NewService(repo)
+	first, err := s.ChatCommunity(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Size != 2 {
+		t.Errorf("community of chat 1 has %d chats, want 2", first.Size)
+	}
+	// У чатов 3 и 4 больше нет общих участников
+	third, err := s.ChatCommunity(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third.Size != 1 {
+		t.Errorf("community of chat 3 has %d chats, want 1", third.Size)
+	}
+	draft, err := repo.Storage().HashGetAll(ctx, assignmentsDraftKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(draft) != 0 {
+		t.Errorf("draft key left behind: %v", draft)
+	}
+}
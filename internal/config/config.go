@@ -5,16 +5,157 @@ import (
 	"os"
 	"stats-of/internal/logger"
 	"strconv"
+	"strings"
+	"time"
 
 	"go.uber.org/zap"
 )
 
 const (
-	defaultServerPort = "8080"
+	defaultServerPort  = "8080"
+	defaultGRPCPort    = "9090"
+	defaultStorageType = "redis"
+
+	defaultSimilarityRefreshInterval  = 10 * time.Minute
+	defaultCommunityDetectionInterval = time.Hour
+	defaultLeaderboardRebuildInterval = 24 * time.Hour
+	defaultDistributionInterval       = 15 * time.Minute
+
+	defaultTimeSeriesRollupInterval  = 5 * time.Minute
+	defaultTimeSeriesRecountInterval = time.Hour
+	defaultTimeSeriesMinuteRetention = 48 * time.Hour
+	defaultTimeSeriesHourRetention   = 90 * 24 * time.Hour
+	defaultTimeSeriesDayRetention    = 5 * 365 * 24 * time.Hour
+
+	defaultUniquesRetention = 400 * 24 * time.Hour
+
+	defaultHeavyHittersCapacity        = "1000"
+	defaultHeavyHittersWindows         = "5m,1h,24h"
+	defaultHeavyHittersPersistInterval = time.Minute
+
+	defaultAnomalyInterval  = 5 * time.Minute
+	defaultAnomalyStep      = 5 * time.Minute
+	defaultAnomalyLookback  = 24 * time.Hour
+	defaultAnomalyRetention = 30 * 24 * time.Hour
+	defaultAnomalySeasons   = "7"
+	defaultAnomalyThreshold = "3"
+	defaultAnomalyMinDelta  = "10"
+
+	defaultChurnInterval    = time.Hour
+	defaultChurnAtRiskAfter = 14 * 24 * time.Hour
+	defaultChurnAfter       = 30 * 24 * time.Hour
+	defaultChurnRetention   = 400 * 24 * time.Hour
+
+	defaultReconcileInterval = 6 * time.Hour
+	defaultReconcileRepair   = "false"
+	defaultReconcileRate     = "1000"
+
+	defaultSnapshotInterval  = 24 * time.Hour
+	defaultSnapshotRetention = 90 * 24 * time.Hour
+
+	defaultSessionIdleGap       = 30 * time.Minute
+	defaultSessionFlushInterval = time.Minute
+
+	defaultEngagementInterval        = time.Hour
+	defaultEngagementRecencyHalfLife = 7 * 24 * time.Hour
+	defaultEngagementFrequencyScale  = "50"
+	defaultEngagementChatsScale      = "10"
+	defaultEngagementActivityScale   = "100"
+
+	defaultGraphQLMaxDepth      = "8"
+	defaultGraphQLMaxComplexity = "5000"
+
+	defaultStreamBufferSize        = "1000"
+	defaultStreamHeartbeatInterval = 15 * time.Second
+	defaultWebSocketSubscriptions  = "100"
 )
 
 type Config struct {
-	ServerPort int
+	ServerPort  int
+	GRPCPort    int
+	StorageType string
+
+	SimilarityRefreshInterval  time.Duration
+	CommunityDetectionInterval time.Duration
+	LeaderboardRebuildInterval time.Duration
+
+	DistributionInterval time.Duration
+	// DistributionBuckets верхние границы корзин гистограмм; пусто — границы по умолчанию
+	DistributionBuckets []float64
+
+	TimeSeriesRollupInterval  time.Duration
+	TimeSeriesRecountInterval time.Duration
+	TimeSeriesMinuteRetention time.Duration
+	TimeSeriesHourRetention   time.Duration
+	TimeSeriesDayRetention    time.Duration
+
+	// UniquesRetention срок хранения суточных HyperLogLog уникальных пользователей
+	UniquesRetention time.Duration
+
+	// HeavyHittersCapacity число счётчиков Space-Saving в каждом окне
+	HeavyHittersCapacity        int
+	HeavyHittersWindows         []time.Duration
+	HeavyHittersPersistInterval time.Duration
+
+	AnomalyInterval  time.Duration
+	AnomalyStep      time.Duration
+	AnomalyLookback  time.Duration
+	AnomalyRetention time.Duration
+	// AnomalySeasons число предыдущих суток для сезонной базовой линии
+	AnomalySeasons int
+	// AnomalyThreshold минимальный модуль z-оценки аномалии
+	AnomalyThreshold float64
+	// AnomalyMinDelta минимальное изменение числа участников за шаг
+	AnomalyMinDelta float64
+
+	ChurnInterval time.Duration
+	// ChurnAtRiskAfter и ChurnAfter пороги неактивности для зоны риска и ухода
+	ChurnAtRiskAfter time.Duration
+	ChurnAfter       time.Duration
+	// ChurnRetention срок хранения суточных счётчиков переходов
+	ChurnRetention time.Duration
+
+	ReconcileInterval time.Duration
+	// ReconcileRepair исправлять ли найденные расхождения автоматически
+	ReconcileRepair bool
+	// ReconcileRate сколько чатов и пользователей проверяется в секунду; 0 — без ограничения
+	ReconcileRate int
+
+	SnapshotInterval  time.Duration
+	SnapshotRetention time.Duration
+
+	// SessionIdleGap пауза в активности, после которой начинается новая сессия
+	SessionIdleGap       time.Duration
+	SessionFlushInterval time.Duration
+
+	EngagementInterval time.Duration
+	// EngagementWeights веса давности, частоты, числа чатов и активности в чатах; nil — веса по умолчанию
+	EngagementWeights         []float64
+	EngagementRecencyHalfLife time.Duration
+	// EngagementFrequencyScale, EngagementChatsScale и EngagementActivityScale значения,
+	// при которых соответствующая составляющая оценки достигает максимума
+	EngagementFrequencyScale float64
+	EngagementChatsScale     float64
+	EngagementActivityScale  float64
+
+	// GraphQLMaxDepth наибольшая вложенность полей запроса GraphQL
+	GraphQLMaxDepth int
+	// GraphQLMaxComplexity наибольшая оценка стоимости запроса GraphQL с учётом размеров списков
+	GraphQLMaxComplexity int
+
+	// StreamBufferSize сколько последних обновлений хранится для возобновления потока по Last-Event-ID
+	StreamBufferSize int
+	// StreamHeartbeatInterval период комментариев-пульсов в потоке событий без обновлений
+	StreamHeartbeatInterval time.Duration
+	// WebSocketMaxSubscriptions наибольшее число чатов и шаблонов в подписках одного клиента WebSocket
+	WebSocketMaxSubscriptions int
+
+	// AdminToken токен административных операций и приёма событий в заголовке Authorization: Bearer;
+	// пустой — они отключены
+	AdminToken string
+
+	// PaginationSecret ключ подписи курсоров постраничных списков; пустой — случайный ключ процесса
+	PaginationSecret string
 }
 
 func LoadFromEnv() (*Config, error) {
@@ -37,7 +178,380 @@ func LoadFromEnv() (*Config, error) {
 		return nil, fmt.Errorf("failed to parse %s as int: %w", serverPort, err)
 	}
 
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		logger.Log.Info("GRPC_PORT not set, using default", zap.String("defaultGRPCPort", defaultGRPCPort))
+		grpcPort = defaultGRPCPort
+	}
+	conf.GRPCPort, err = strconv.Atoi(grpcPort)
+	if err != nil {
+		logger.Log.Error("Failed to parse GRPC_PORT as integer", zap.String("grpcPort", grpcPort), zap.Error(err))
+		return nil, fmt.Errorf("failed to parse %s as int: %w", grpcPort, err)
+	}
+
+	conf.StorageType = os.Getenv("STORAGE_TYPE")
+	if conf.StorageType == "" {
+		logger.Log.Info("STORAGE_TYPE not set, using default", zap.String("defaultStorageType", defaultStorageType))
+		conf.StorageType = defaultStorageType
+	}
+
+	conf.SimilarityRefreshInterval, err = durationFromEnv("SIMILARITY_REFRESH_INTERVAL", defaultSimilarityRefreshInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.CommunityDetectionInterval, err = durationFromEnv("COMMUNITY_DETECTION_INTERVAL", defaultCommunityDetectionInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.LeaderboardRebuildInterval, err = durationFromEnv("LEADERBOARD_REBUILD_INTERVAL", defaultLeaderboardRebuildInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.DistributionInterval, err = durationFromEnv("DISTRIBUTION_INTERVAL", defaultDistributionInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.DistributionBuckets, err = floatsFromEnv("DISTRIBUTION_BUCKETS")
+	if err != nil {
+		return nil, err
+	}
+
+	conf.TimeSeriesRollupInterval, err = durationFromEnv("TIMESERIES_ROLLUP_INTERVAL", defaultTimeSeriesRollupInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.TimeSeriesRecountInterval, err = durationFromEnv("TIMESERIES_RECOUNT_INTERVAL", defaultTimeSeriesRecountInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.TimeSeriesMinuteRetention, err = durationFromEnv("TIMESERIES_MINUTE_RETENTION", defaultTimeSeriesMinuteRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.TimeSeriesHourRetention, err = durationFromEnv("TIMESERIES_HOUR_RETENTION", defaultTimeSeriesHourRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.TimeSeriesDayRetention, err = durationFromEnv("TIMESERIES_DAY_RETENTION", defaultTimeSeriesDayRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.UniquesRetention, err = durationFromEnv("UNIQUES_RETENTION", defaultUniquesRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.HeavyHittersCapacity, err = positiveIntFromEnv("HEAVY_HITTERS_CAPACITY", defaultHeavyHittersCapacity)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.HeavyHittersWindows, err = durationsFromEnv("HEAVY_HITTERS_WINDOWS", defaultHeavyHittersWindows)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.HeavyHittersPersistInterval, err = durationFromEnv("HEAVY_HITTERS_PERSIST_INTERVAL", defaultHeavyHittersPersistInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalyInterval, err = durationFromEnv("ANOMALY_INTERVAL", defaultAnomalyInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalyStep, err = durationFromEnv("ANOMALY_STEP", defaultAnomalyStep)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalyLookback, err = durationFromEnv("ANOMALY_LOOKBACK", defaultAnomalyLookback)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalyRetention, err = durationFromEnv("ANOMALY_RETENTION", defaultAnomalyRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalySeasons, err = nonNegativeIntFromEnv("ANOMALY_SEASONS", defaultAnomalySeasons)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalyThreshold, err = positiveFloatFromEnv("ANOMALY_THRESHOLD", defaultAnomalyThreshold)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AnomalyMinDelta, err = positiveFloatFromEnv("ANOMALY_MIN_DELTA", defaultAnomalyMinDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.ChurnInterval, err = durationFromEnv("CHURN_INTERVAL", defaultChurnInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.ChurnAtRiskAfter, err = durationFromEnv("CHURN_AT_RISK_AFTER", defaultChurnAtRiskAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.ChurnAfter, err = durationFromEnv("CHURN_AFTER", defaultChurnAfter)
+	if err != nil {
+		return nil, err
+	}
+	if conf.ChurnAtRiskAfter >= conf.ChurnAfter {
+		logger.Log.Error("CHURN_AT_RISK_AFTER must be less than CHURN_AFTER",
+			zap.Duration("atRiskAfter", conf.ChurnAtRiskAfter), zap.Duration("churnAfter", conf.ChurnAfter))
+		return nil, fmt.Errorf("CHURN_AT_RISK_AFTER=%s must be less than CHURN_AFTER=%s", conf.ChurnAtRiskAfter, conf.ChurnAfter)
+	}
+
+	conf.ChurnRetention, err = durationFromEnv("CHURN_RETENTION", defaultChurnRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.ReconcileInterval, err = durationFromEnv("RECONCILE_INTERVAL", defaultReconcileInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileRepair := os.Getenv("RECONCILE_REPAIR")
+	if reconcileRepair == "" {
+		logger.Log.Info("RECONCILE_REPAIR not set, using default", zap.String("default", defaultReconcileRepair))
+		reconcileRepair = defaultReconcileRepair
+	}
+	conf.ReconcileRepair, err = strconv.ParseBool(reconcileRepair)
+	if err != nil {
+		logger.Log.Error("Failed to parse RECONCILE_REPAIR as boolean", zap.String("value", reconcileRepair))
+		return nil, fmt.Errorf("failed to parse RECONCILE_REPAIR=%q as boolean", reconcileRepair)
+	}
+
+	conf.ReconcileRate, err = nonNegativeIntFromEnv("RECONCILE_RATE", defaultReconcileRate)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.SnapshotInterval, err = durationFromEnv("SNAPSHOT_INTERVAL", defaultSnapshotInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.SnapshotRetention, err = durationFromEnv("SNAPSHOT_RETENTION", defaultSnapshotRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.SessionIdleGap, err = durationFromEnv("SESSION_IDLE_GAP", defaultSessionIdleGap)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.SessionFlushInterval, err = durationFromEnv("SESSION_FLUSH_INTERVAL", defaultSessionFlushInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.EngagementInterval, err = durationFromEnv("ENGAGEMENT_INTERVAL", defaultEngagementInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.EngagementWeights, err = floatsFromEnv("ENGAGEMENT_WEIGHTS")
+	if err != nil {
+		return nil, err
+	}
+	if conf.EngagementWeights != nil {
+		var total float64
+		for _, w := range conf.EngagementWeights {
+			if w < 0 {
+				total = -1
+				break
+			}
+			total += w
+		}
+		if len(conf.EngagementWeights) != 4 || total <= 0 {
+			logger.Log.Error("Invalid engagement weights", zap.Float64s("weights", conf.EngagementWeights))
+			return nil, fmt.Errorf("ENGAGEMENT_WEIGHTS must be four non-negative numbers recency,frequency,chats,activity with a positive sum")
+		}
+	}
+
+	conf.EngagementRecencyHalfLife, err = durationFromEnv("ENGAGEMENT_RECENCY_HALF_LIFE", defaultEngagementRecencyHalfLife)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.EngagementFrequencyScale, err = positiveFloatFromEnv("ENGAGEMENT_FREQUENCY_SCALE", defaultEngagementFrequencyScale)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.EngagementChatsScale, err = positiveFloatFromEnv("ENGAGEMENT_CHATS_SCALE", defaultEngagementChatsScale)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.EngagementActivityScale, err = positiveFloatFromEnv("ENGAGEMENT_ACTIVITY_SCALE", defaultEngagementActivityScale)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.GraphQLMaxDepth, err = positiveIntFromEnv("GRAPHQL_MAX_DEPTH", defaultGraphQLMaxDepth)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.GraphQLMaxComplexity, err = positiveIntFromEnv("GRAPHQL_MAX_COMPLEXITY", defaultGraphQLMaxComplexity)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.StreamBufferSize, err = positiveIntFromEnv("STREAM_BUFFER_SIZE", defaultStreamBufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.StreamHeartbeatInterval, err = durationFromEnv("STREAM_HEARTBEAT_INTERVAL", defaultStreamHeartbeatInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.WebSocketMaxSubscriptions, err = positiveIntFromEnv("WEBSOCKET_MAX_SUBSCRIPTIONS", defaultWebSocketSubscriptions)
+	if err != nil {
+		return nil, err
+	}
+
+	conf.AdminToken = os.Getenv("ADMIN_TOKEN")
+	if conf.AdminToken == "" {
+		logger.Log.Info("ADMIN_TOKEN not set, admin operations and event ingestion are disabled")
+	}
+
+	conf.PaginationSecret = os.Getenv("PAGINATION_SECRET")
+	if conf.PaginationSecret == "" {
+		logger.Log.Warn("PAGINATION_SECRET not set, cursors will be signed with a random key and expire on restart")
+	}
+
 	// Логирование успешной загрузки конфигурации
 	logger.Log.Info("Configuration loaded successfully", zap.Int("serverPort", conf.ServerPort))
 	return conf, nil
 }
+
+// durationFromEnv читает длительность в формате time.ParseDuration или возвращает значение по умолчанию
+func durationFromEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		logger.Log.Info(name+" not set, using default", zap.Duration("default", defaultValue))
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d <= 0 {
+		logger.Log.Error("Failed to parse "+name+" as positive duration", zap.String("value", value), zap.Error(err))
+		return 0, fmt.Errorf("failed to parse %s=%q as positive duration", name, value)
+	}
+	return d, nil
+}
+
+// floatsFromEnv читает список чисел через запятую; если переменная не задана, возвращает nil
+func floatsFromEnv(name string) ([]float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		logger.Log.Info(name + " not set, using default")
+		return nil, nil
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]float64, 0, len(parts))
+	for _, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			logger.Log.Error("Failed to parse "+name+" as list of numbers", zap.String("value", value), zap.Error(err))
+			return nil, fmt.Errorf("failed to parse %s=%q as list of numbers: %w", name, value, err)
+		}
+		result = append(result, f)
+	}
+	return result, nil
+}
+
+// positiveFloatFromEnv читает положительное число или возвращает значение по умолчанию
+func positiveFloatFromEnv(name, defaultValue string) (float64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		logger.Log.Info(name+" not set, using default", zap.String("default", defaultValue))
+		value = defaultValue
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f <= 0 {
+		logger.Log.Error("Failed to parse "+name+" as positive number", zap.String("value", value), zap.Error(err))
+		return 0, fmt.Errorf("failed to parse %s=%q as positive number", name, value)
+	}
+	return f, nil
+}
+
+// durationsFromEnv читает список длительностей через запятую, например "5m,1h"
+func durationsFromEnv(name, defaultValue string) ([]time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		logger.Log.Info(name+" not set, using default", zap.String("default", defaultValue))
+		value = defaultValue
+	}
+
+	parts := strings.Split(value, ",")
+	result := make([]time.Duration, 0, len(parts))
+	for _, part := range parts {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			logger.Log.Error("Failed to parse "+name+" as list of durations", zap.String("value", value), zap.Error(err))
+			return nil, fmt.Errorf("failed to parse %s=%q as list of positive durations", name, value)
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+// positiveIntFromEnv читает положительное целое число или возвращает значение по умолчанию
+func positiveIntFromEnv(name, defaultValue string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		logger.Log.Info(name+" not set, using default", zap.String("default", defaultValue))
+		value = defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		logger.Log.Error("Failed to parse "+name+" as positive integer", zap.String("value", value), zap.Error(err))
+		return 0, fmt.Errorf("failed to parse %s=%q as positive integer", name, value)
+	}
+	return n, nil
+}
+
+// nonNegativeIntFromEnv читает неотрицательное целое число или возвращает значение по умолчанию
+func nonNegativeIntFromEnv(name, defaultValue string) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		logger.Log.Info(name+" not set, using default", zap.String("default", defaultValue))
+		value = defaultValue
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		logger.Log.Error("Failed to parse "+name+" as non-negative integer", zap.String("value", value), zap.Error(err))
+		return 0, fmt.Errorf("failed to parse %s=%q as non-negative integer", name, value)
+	}
+	return n, nil
+}
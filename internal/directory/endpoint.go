@@ -0,0 +1,192 @@
+package directory
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/query"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+)
+
+// MakeChatsHandler обработчик GET /api/v1/chats?filter=...&sort=...&limit=100&cursor=...: чаты постранично.
+// filter — выражение вида "chat_type = 4 AND count_of_users > 1000", sort — поля через запятую,
+// "-" перед полем означает убывание, например sort=-count_of_users.
+func MakeChatsHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, params, scope, ok := parseListQuery(w, r, cursors, "chats", query.ChatSchema)
+		if !ok {
+			return
+		}
+
+		chats, next, err := service.FindChats(r.Context(), q, params)
+		if IsQueryError(err) {
+			utils.RespondWith400(w, err.Error())
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to list chats", zap.String("query", q.String()), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewPage(cursors, scope, chats, params.Limit, next))
+	}
+}
+
+// MakeUsersHandler обработчик GET /api/v1/users?filter=...&sort=...&limit=100&cursor=...: пользователи постранично.
+// Поле last_time сравнивается с датами и относительным временем, например "last_time > now-7d".
+func MakeUsersHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, params, scope, ok := parseListQuery(w, r, cursors, "users", query.UserSchema)
+		if !ok {
+			return
+		}
+
+		users, next, err := service.FindUsers(r.Context(), q, params)
+		if IsQueryError(err) {
+			utils.RespondWith400(w, err.Error())
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to list users", zap.String("query", q.String()), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewPage(cursors, scope, users, params.Limit, next))
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		chat, err := service.Chat(r.Context(), entities.ChatID(id))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read chat", zap.Int64("chatID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, chat)
+	}
+}
+
+// MakeChatUsersHandler обработчик GET /api/v1/chats/{id}/users?limit=100&cursor=...: участники чата постранично
+func MakeChatUsersHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		scope := "chat_users:" + strconv.FormatInt(id, 10)
+		params, err := cursors.Parse(r, scope, defaultPageLimit, maxPageLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		users, next, err := service.ChatUsers(r.Context(), entities.ChatID(id), params)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read chat members", zap.Int64("chatID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewPage(cursors, scope, users, params.Limit, next))
+	}
+}
+
+// MakeUserHandler обработчик GET /api/v1/users/{id}
+func MakeUserHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		user, err := service.User(r.Context(), entities.UserID(id))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read user", zap.Int64("userID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, user)
+	}
+}
+
+// MakeUserChatsHandler обработчик GET /api/v1/users/{id}/chats?limit=100&cursor=...: чаты пользователя постранично
+func MakeUserChatsHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		scope := "user_chats:" + strconv.FormatInt(id, 10)
+		params, err := cursors.Parse(r, scope, defaultPageLimit, maxPageLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		chats, next, err := service.UserChats(r.Context(), entities.UserID(id), params)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read user chats", zap.Int64("userID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewPage(cursors, scope, chats, params.Limit, next))
+	}
+}
+
+// ListScope область курсоров списка list с фильтром и сортировкой. Курсор привязан к тексту запроса,
+// а не к вычисленным значениям: относительное время вроде now-7d отсчитывается заново на каждой странице.
+func ListScope(list, filter, sort string) string {
+	return list + "?filter=" + filter + "&sort=" + sort
+}
+
+// parseListQuery разбирает filter, sort и параметры страницы.
+// При ошибке отвечает 400 и возвращает ok = false.
+func parseListQuery(w http.ResponseWriter, r *http.Request, cursors *pagination.Signer, list string, schema *query.Schema) (*query.Query, pagination.Params, string, bool) {
+	values := r.URL.Query()
+	q, err := query.New(schema, values.Get("filter"), values.Get("sort"), time.Now().UTC())
+	if err != nil {
+		utils.RespondWith400(w, "invalid filter or sort: "+err.Error())
+		return nil, pagination.Params{}, "", false
+	}
+
+	scope := ListScope(list, values.Get("filter"), values.Get("sort"))
+	params, err := cursors.Parse(r, scope, defaultPageLimit, maxPageLimit)
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return nil, pagination.Params{}, "", false
+	}
+	return q, params, scope, true
+}
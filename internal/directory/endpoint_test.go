@@ -0,0 +1,105 @@
+package directory
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/pagination"
+)
+
+// newTestMux регистрирует обработчики справочника по тем же путям, что и приложение,
+// и сохраняет чат 1 с участником 10
+func newTestMux(t *testing.T) (*http.ServeMux, *pagination.Signer) {
+	t.Helper()
+	ctx := context.Background()
+	service, repo, _ := newTestService(t)
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 1, CountOfUsers: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddMembership(ctx, 1, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUser(ctx, entities.User{UserID: 10, CountOfChats: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	cursors := pagination.NewSigner("test")
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/chats", MakeChatsHandler(service, cursors))
+	mux.HandleFunc("GET /api/v1/chats/{id}", MakeChatHandler(service))
+	mux.HandleFunc("GET /api/v1/chats/{id}/users", MakeChatUsersHandler(service, cursors))
+	mux.HandleFunc("GET /api/v1/users", MakeUsersHandler(service, cursors))
+	mux.HandleFunc("GET /api/v1/users/{id}", MakeUserHandler(service))
+	mux.HandleFunc("GET /api/v1/users/{id}/chats", MakeUserChatsHandler(service, cursors))
+	return mux, cursors
+}
+
+// TestHandlersErrors проверяет ответы 400 на неверные параметры и 404 на отсутствующие записи
+func TestHandlersErrors(t *testing.T) {
+	mux, cursors := newTestMux(t)
+	otherChat := cursors.Encode("chat_users:2", pagination.Cursor{Position: 1})
+	otherFilter := cursors.Encode(ListScope("chats", "chat_id = 1", ""), pagination.Cursor{Position: 1})
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		// message начало текста ошибки; пустое значение не проверяется
+		message string
+	}{
+		{name: "chat", target: "/api/v1/chats/1", status: http.StatusOK},
+		{name: "chat id not a number", target: "/api/v1/chats/abc", status: http.StatusBadRequest, message: "id must be an integer"},
+		{name: "chat id overflow", target: "/api/v1/chats/9223372036854775808", status: http.StatusBadRequest, message: "id must be an integer"},
+		{name: "missing chat", target: "/api/v1/chats/2", status: http.StatusNotFound},
+
+		{name: "chat users", target: "/api/v1/chats/1/users", status: http.StatusOK},
+		{name: "chat users of bad id", target: "/api/v1/chats/x/users", status: http.StatusBadRequest, message: "id must be an integer"},
+		{name: "chat users of missing chat", target: "/api/v1/chats/2/users", status: http.StatusNotFound},
+		{name: "chat users zero limit", target: "/api/v1/chats/1/users?limit=0", status: http.StatusBadRequest, message: "limit"},
+		{name: "chat users limit too large", target: "/api/v1/chats/1/users?limit=1001", status: http.StatusBadRequest, message: "limit"},
+		{name: "chat users bad cursor", target: "/api/v1/chats/1/users?cursor=garbage", status: http.StatusBadRequest},
+		{name: "chat users cursor of another chat", target: "/api/v1/chats/1/users?cursor=" + otherChat, status: http.StatusBadRequest},
+
+		{name: "user", target: "/api/v1/users/10", status: http.StatusOK},
+		{name: "user id not a number", target: "/api/v1/users/abc", status: http.StatusBadRequest, message: "id must be an integer"},
+		{name: "missing user", target: "/api/v1/users/11", status: http.StatusNotFound},
+		{name: "user chats", target: "/api/v1/users/10/chats", status: http.StatusOK},
+		{name: "user chats of bad id", target: "/api/v1/users/1.5/chats", status: http.StatusBadRequest, message: "id must be an integer"},
+		{name: "user chats of missing user", target: "/api/v1/users/11/chats", status: http.StatusNotFound},
+
+		{name: "chats", target: "/api/v1/chats?filter=" + url.QueryEscape("count_of_users > 0"), status: http.StatusOK},
+		{name: "chats bad filter", target: "/api/v1/chats?filter=" + url.QueryEscape("count_of_users >"), status: http.StatusBadRequest, message: "invalid filter or sort"},
+		{name: "chats unknown field", target: "/api/v1/chats?filter=" + url.QueryEscape("last_time > now-7d"), status: http.StatusBadRequest, message: "invalid filter or sort"},
+		{name: "chats unknown sort", target: "/api/v1/chats?sort=name", status: http.StatusBadRequest, message: "invalid filter or sort"},
+		{name: "chats cursor of another filter", target: "/api/v1/chats?cursor=" + otherFilter, status: http.StatusBadRequest},
+		{name: "users bad limit", target: "/api/v1/users?limit=many", status: http.StatusBadRequest, message: "limit"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+			if rec.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status == http.StatusOK {
+				return
+			}
+
+			var body struct {
+				Error string `json:"error"`
+			}
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error == "" {
+				t.Fatalf("body %q is not an error response", rec.Body)
+			}
+			if !strings.HasPrefix(body.Error, tt.message) {
+				t.Errorf("error %q, want prefix %q", body.Error, tt.message)
+			}
+		})
+	}
+}
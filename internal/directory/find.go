@@ -0,0 +1,202 @@
+package directory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/query"
+
+	"go.uber.org/zap"
+)
+
+// maxMatches наибольшее число записей, которые отбираются в память для сортировки
+const maxMatches = 100000
+
+// ErrTooManyMatches запрос требует сортировки слишком большого числа записей
+var ErrTooManyMatches = fmt.Errorf("query matches more than %d records; narrow the filter or remove sort", maxMatches)
+
+type (
+	// source способы чтения одного вида записей
+	source[T any] struct {
+		board leaderboard.Board
+		scan  func(ctx context.Context, cursor uint64) ([]int64, uint64, error)
+		load  func(ctx context.Context, ids []int64) ([]T, error)
+		row   func(T) query.Row
+	}
+
+	match[T any] struct {
+		item T
+		row  query.Row
+	}
+)
+
+// FindChats возвращает страницу чатов, подходящих под запрос
+func (s *Service) FindChats(ctx context.Context, q *query.Query, params pagination.Params) ([]entities.Chat, *pagination.Cursor, error) {
+	return find(ctx, s, q, params, source[entities.Chat]{
+		board: leaderboard.BoardChats,
+		scan: func(ctx context.Context, cursor uint64) ([]int64, uint64, error) {
+			ids, next, err := s.repo.ScanChatIDs(ctx, cursor, pagination.ScanCount)
+			return int64s(ids), next, err
+		},
+		load: func(ctx context.Context, ids []int64) ([]entities.Chat, error) {
+			return s.chats(ctx, typed[entities.ChatID](ids))
+		},
+		row: query.ChatRow,
+	})
+}
+
+// FindUsers возвращает страницу пользователей, подходящих под запрос
+func (s *Service) FindUsers(ctx context.Context, q *query.Query, params pagination.Params) ([]entities.User, *pagination.Cursor, error) {
+	return find(ctx, s, q, params, source[entities.User]{
+		board: leaderboard.BoardUsers,
+		scan: func(ctx context.Context, cursor uint64) ([]int64, uint64, error) {
+			ids, next, err := s.repo.ScanUserIDs(ctx, cursor, pagination.ScanCount)
+			return int64s(ids), next, err
+		},
+		load: func(ctx context.Context, ids []int64) ([]entities.User, error) {
+			return s.users(ctx, typed[entities.UserID](ids))
+		},
+		row: query.UserRow,
+	})
+}
+
+// find выбирает способ выполнения запроса:
+//   - условие на идентификатор — чтение только перечисленных записей;
+//   - ограничение счётчика снизу положительным числом вместе с сортировкой или ограничением
+//     сверху — диапазон рейтинга как индекс; из него читается не больше maxMatches+1 записей;
+//   - без сортировки — обход SCAN с фильтрацией, курсор страницы продолжает обход;
+//     так выполняется и открытый сверху диапазон счётчика, чтобы не читать рейтинг целиком;
+//   - иначе — полный обход с отбором в память и сортировкой.
+//
+// В первых двух и последнем случаях курсор страницы — смещение в отсортированном результате.
+// Фильтр всегда проверяется по прочитанным записям, поэтому индекс лишь сужает перебор.
+func find[T any](ctx context.Context, s *Service, q *query.Query, params pagination.Params, src source[T]) ([]T, *pagination.Cursor, error) {
+	var candidates []int64
+	plan := "full_scan"
+	if ids, ok := q.IDs(); ok {
+		candidates, plan = ids, "ids"
+	} else if min, max, ok := q.Range(q.Schema.Count); ok && min > 0 && (len(q.Sort) > 0 || !math.IsInf(max, 1)) {
+		ids, err := s.leaderboards.Range(ctx, src.board, min, max, maxMatches+1)
+		if err != nil {
+			return nil, nil, err
+		}
+		candidates, plan = ids, "index"
+	} else if len(q.Sort) == 0 {
+		logger.Log.Debug("Executing list query", zap.String("query", q.String()), zap.String("plan", "scan"))
+		return pagination.Collect(params.Cursor, params.Limit, func(cursor uint64) ([]T, uint64, error) {
+			ids, next, err := src.scan(ctx, cursor)
+			if err != nil {
+				return nil, 0, err
+			}
+			matches, err := filter(ctx, q, src, ids)
+			if err != nil {
+				return nil, 0, err
+			}
+			items := make([]T, len(matches))
+			for i, m := range matches {
+				items[i] = m.item
+			}
+			return items, next, nil
+		})
+	}
+	logger.Log.Debug("Executing list query", zap.String("query", q.String()), zap.String("plan", plan))
+
+	var matches []match[T]
+	if plan != "full_scan" {
+		if len(candidates) > maxMatches {
+			return nil, nil, ErrTooManyMatches
+		}
+		var err error
+		if matches, err = filter(ctx, q, src, dedupe(candidates)); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		for cursor := uint64(0); ; {
+			ids, next, err := src.scan(ctx, cursor)
+			if err != nil {
+				return nil, nil, err
+			}
+			batch, err := filter(ctx, q, src, ids)
+			if err != nil {
+				return nil, nil, err
+			}
+			if matches = append(matches, batch...); len(matches) > maxMatches {
+				return nil, nil, ErrTooManyMatches
+			}
+			if next == 0 {
+				break
+			}
+			cursor = next
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return q.Less(matches[i].row, matches[j].row) })
+
+	offset := min(int(params.Cursor.Position), len(matches))
+	end := min(offset+params.Limit, len(matches))
+	items := make([]T, 0, end-offset)
+	for _, m := range matches[offset:end] {
+		items = append(items, m.item)
+	}
+	if end == len(matches) {
+		return items, nil, nil
+	}
+	return items, &pagination.Cursor{Position: uint64(end)}, nil
+}
+
+// filter загружает записи и оставляет подходящие под фильтр
+func filter[T any](ctx context.Context, q *query.Query, src source[T], ids []int64) ([]match[T], error) {
+	items, err := src.load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]match[T], 0, len(items))
+	for _, item := range items {
+		if row := src.row(item); q.Match(row) {
+			matches = append(matches, match[T]{item: item, row: row})
+		}
+	}
+	return matches, nil
+}
+
+// dedupe убирает повторы; SCAN и списки IN могут возвращать один идентификатор дважды
+func dedupe(ids []int64) []int64 {
+	seen := make(map[int64]struct{}, len(ids))
+	result := ids[:0:0]
+	for _, id := range ids {
+		if _, ok := seen[id]; !ok {
+			seen[id] = struct{}{}
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func int64s[T ~int64](ids []T) []int64 {
+	result := make([]int64, len(ids))
+	for i, id := range ids {
+		result[i] = int64(id)
+	}
+	return result
+}
+
+func typed[T ~int64](ids []int64) []T {
+	result := make([]T, len(ids))
+	for i, id := range ids {
+		result[i] = T(id)
+	}
+	return result
+}
+
+// IsQueryError сообщает, что ошибка вызвана самим запросом и должна вернуться клиенту как 400
+func IsQueryError(err error) bool {
+	var syntax *query.SyntaxError
+	return errors.As(err, &syntax) || errors.Is(err, ErrTooManyMatches)
+}
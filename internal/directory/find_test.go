@@ -0,0 +1,119 @@
+package directory
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/pagination"
+	"stats-of/internal/query"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+func newTestService(t *testing.T) (*Service, *storage.Repository, *leaderboard.Service) {
+	t.Helper()
+	repo := memtest.NewRepository(t)
+	leaderboards := leaderboard.NewService(repo)
+	return NewService(repo, leaderboards), repo, leaderboards
+}
+
+// TestFindChooseCountPlan проверяет выбор плана по условию на счётчик. Рейтинг не перестраивается
+// после записи чата 3, поэтому его находит только обход SCAN, но не индекс.
+func TestFindChoosesCountPlan(t *testing.T) {
+	ctx := context.Background()
+	service, repo, leaderboards := newTestService(t)
+	for id, count := range map[entities.ChatID]int64{1: 1, 2: 5} {
+		if err := repo.SaveChat(ctx, entities.Chat{ChatID: id, CountOfUsers: count}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := leaderboards.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 3, CountOfUsers: 7}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter string
+		sort   string
+		want   []entities.ChatID
+	}{
+		{filter: "count_of_users > 1", want: []entities.ChatID{2, 3}},
+		{filter: "count_of_users > 1", sort: "-count_of_users", want: []entities.ChatID{2}},
+		{filter: "count_of_users >= 2 and count_of_users <= 10", want: []entities.ChatID{2}},
+		{filter: "count_of_users >= 1 and chat_id in (1, 3)", want: []entities.ChatID{1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter+" "+tt.sort, func(t *testing.T) {
+			q, err := query.New(query.ChatSchema, tt.filter, tt.sort, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			chats, _, err := service.FindChats(ctx, q, pagination.Params{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids := make([]entities.ChatID, len(chats))
+			for i, chat := range chats {
+				ids[i] = chat.ChatID
+			}
+			slices.Sort(ids)
+			if !slices.Equal(ids, tt.want) {
+				t.Errorf("chats = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+// TestFindLargeIDs проверяет, что идентификаторы больше 2^53, неразличимые во float64,
+// сравниваются и сортируются точно
+func TestFindLargeIDs(t *testing.T) {
+	ctx := context.Background()
+	service, repo, _ := newTestService(t)
+	// -1001234567890123457 и -1001234567890123456 дают одно и то же float64
+	ids := []entities.ChatID{-1001234567890123457, -1001234567890123456, 1 << 53, 1<<53 + 1}
+	for _, id := range ids {
+		if err := repo.SaveChat(ctx, entities.Chat{ChatID: id, CountOfUsers: 1}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		filter string
+		sort   string
+		want   []entities.ChatID
+	}{
+		{filter: "chat_id = -1001234567890123457", want: ids[:1]},
+		{filter: "chat_id IN (9007199254740993)", want: ids[3:]},
+		{filter: "chat_id > 9007199254740992", want: ids[3:]},
+		{filter: "chat_id != -1001234567890123456 AND chat_id < 0", want: ids[:1]},
+		{filter: "chat_type = 0", sort: "-chat_id", want: []entities.ChatID{ids[3], ids[2], ids[1], ids[0]}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter+" "+tt.sort, func(t *testing.T) {
+			q, err := query.New(query.ChatSchema, tt.filter, tt.sort, time.Now())
+			if err != nil {
+				t.Fatal(err)
+			}
+			chats, _, err := service.FindChats(ctx, q, pagination.Params{Limit: 10})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]entities.ChatID, len(chats))
+			for i, chat := range chats {
+				got[i] = chat.ChatID
+			}
+			if tt.sort == "" {
+				slices.Sort(got)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("chats = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
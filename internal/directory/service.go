@@ -0,0 +1,98 @@
+package directory
+
+import (
+	"context"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Service отдаёт чаты и пользователей из хранилища вместе со связями между ними.
+// Списки читаются порциями SCAN/SSCAN; без сортировки порядок элементов определяется хранилищем.
+type Service struct {
+	repo         *storage.Repository
+	leaderboards *leaderboard.Service
+}
+
+func NewService(repo *storage.Repository, leaderboards *leaderboard.Service) *Service {
+	return &Service{repo: repo, leaderboards: leaderboards}
+}
+
+// Chat возвращает чат или ErrNotFound
+func (s *Service) Chat(ctx context.Context, id entities.ChatID) (*entities.Chat, error) {
+	return s.repo.Chat(ctx, id)
+}
+
+// User возвращает пользователя или ErrNotFound
+func (s *Service) User(ctx context.Context, id entities.UserID) (*entities.User, error) {
+	return s.repo.User(ctx, id)
+}
+
+// ChatUsers возвращает страницу участников чата или ErrNotFound, если чата нет
+func (s *Service) ChatUsers(ctx context.Context, id entities.ChatID, params pagination.Params) ([]entities.User, *pagination.Cursor, error) {
+	if _, err := s.repo.Chat(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	ids, next, err := pagination.Collect(params.Cursor, params.Limit, func(cursor uint64) ([]entities.UserID, uint64, error) {
+		return s.repo.ScanChatUserIDs(ctx, id, cursor, pagination.ScanCount)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	users, err := s.users(ctx, ids)
+	return users, next, err
+}
+
+// UserChats возвращает страницу чатов пользователя или ErrNotFound, если пользователя нет
+func (s *Service) UserChats(ctx context.Context, id entities.UserID, params pagination.Params) ([]entities.Chat, *pagination.Cursor, error) {
+	if _, err := s.repo.User(ctx, id); err != nil {
+		return nil, nil, err
+	}
+	ids, next, err := pagination.Collect(params.Cursor, params.Limit, func(cursor uint64) ([]entities.ChatID, uint64, error) {
+		return s.repo.ScanUserChatIDs(ctx, id, cursor, pagination.ScanCount)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	chats, err := s.chats(ctx, ids)
+	return chats, next, err
+}
+
+// loadBatch сколько записей читается одним групповым запросом к хранилищу
+const loadBatch = 1000
+
+// chats загружает чаты групповыми чтениями, пропуская удалённые между чтением списка и чтением записи
+func (s *Service) chats(ctx context.Context, ids []entities.ChatID) ([]entities.Chat, error) {
+	return load(ctx, ids, s.repo.Chats, "chat")
+}
+
+// users загружает пользователей групповыми чтениями, пропуская удалённых между чтением списка и чтением записи
+func (s *Service) users(ctx context.Context, ids []entities.UserID) ([]entities.User, error) {
+	return load(ctx, ids, s.repo.Users, "user")
+}
+
+// load читает записи порциями по loadBatch и возвращает найденные в порядке ids
+func load[ID ~int64, T any](ctx context.Context, ids []ID, read func(context.Context, []ID) (map[ID]*T, error), kind string) ([]T, error) {
+	items := make([]T, 0, len(ids))
+	for start := 0; start < len(ids); start += loadBatch {
+		batch := ids[start:min(start+loadBatch, len(ids))]
+		found, err := read(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range batch {
+			item, ok := found[id]
+			if !ok {
+				logger.Log.Warn("Skipping missing record", zap.String("kind", kind), zap.Int64("id", int64(id)))
+				continue
+			}
+			items = append(items, *item)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,63 @@
+package distribution
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	metricNames = map[Metric]string{
+		MetricChatUsers: "stats_of_chat_users",
+		MetricUserChats: "stats_of_user_chats",
+	}
+	metricHelp = map[Metric]string{
+		MetricChatUsers: "Distribution of the number of users per chat.",
+		MetricUserChats: "Distribution of the number of chats per user.",
+	}
+)
+
+// Collector публикует последние рассчитанные распределения как гистограммы и сводки Prometheus
+type Collector struct {
+	service    *Service
+	histograms map[Metric]*prometheus.Desc
+	summaries  map[Metric]*prometheus.Desc
+}
+
+func NewCollector(service *Service) *Collector {
+	c := &Collector{
+		service:    service,
+		histograms: make(map[Metric]*prometheus.Desc),
+		summaries:  make(map[Metric]*prometheus.Desc),
+	}
+	for metric, name := range metricNames {
+		c.histograms[metric] = prometheus.NewDesc(name, metricHelp[metric], []string{"chat_type"}, nil)
+		c.summaries[metric] = prometheus.NewDesc(name+"_quantiles", metricHelp[metric]+" Quantiles are estimated with a DDSketch.", []string{"chat_type"}, nil)
+	}
+	return c
+}
+
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.histograms {
+		ch <- desc
+	}
+	for _, desc := range c.summaries {
+		ch <- desc
+	}
+}
+
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.service.mu.RLock()
+	snapshot := c.service.snapshot
+	c.service.mu.RUnlock()
+
+	if snapshot == nil {
+		return
+	}
+
+	for metric := range metricNames {
+		for _, chatType := range snapshot.chatTypes(metric) {
+			d := snapshot.distributions[metric][chatType]
+			ch <- prometheus.MustNewConstHistogram(c.histograms[metric], d.Count(), d.Sum(), d.CumulativeBuckets(), chatType)
+			ch <- prometheus.MustNewConstSummary(c.summaries[metric], d.Count(), d.Sum(), d.QuantileValues(), chatType)
+		}
+	}
+}
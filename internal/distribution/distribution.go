@@ -0,0 +1,125 @@
+package distribution
+
+import (
+	"sort"
+
+	"stats-of/internal/sketch"
+)
+
+// DefaultBuckets верхние границы корзин гистограмм по умолчанию
+var DefaultBuckets = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000, 2000, 5000, 10000, 50000, 100000}
+
+// Quantiles квантили, которые публикуются в сводках и метриках
+var Quantiles = []float64{0.5, 0.9, 0.99}
+
+type (
+	// Bucket накопительная корзина гистограммы: число значений не больше UpperBound
+	Bucket struct {
+		UpperBound float64 `json:"le"`
+		Count      uint64  `json:"count"`
+	}
+
+	// Summary сводка распределения: гистограмма, перцентили и экстремумы
+	Summary struct {
+		Count   uint64   `json:"count"`
+		Sum     float64  `json:"sum"`
+		Mean    float64  `json:"mean"`
+		Min     float64  `json:"min"`
+		Max     float64  `json:"max"`
+		P50     float64  `json:"p50"`
+		P90     float64  `json:"p90"`
+		P99     float64  `json:"p99"`
+		Buckets []Bucket `json:"buckets"`
+	}
+
+	// Distribution накапливает значения одновременно в гистограмму с фиксированными
+	// корзинами и в квантильный скетч. Оба представления сливаются без потерь.
+	Distribution struct {
+		bounds []float64
+		counts []uint64
+		sketch *sketch.DDSketch
+	}
+)
+
+func New(bounds []float64) *Distribution {
+	sorted := append([]float64(nil), bounds...)
+	sort.Float64s(sorted)
+	return &Distribution{
+		bounds: sorted,
+		counts: make([]uint64, len(sorted)),
+		sketch: sketch.NewDDSketch(sketch.DefaultRelativeAccuracy),
+	}
+}
+
+// Observe добавляет значение
+func (d *Distribution) Observe(value float64) {
+	if i := sort.SearchFloat64s(d.bounds, value); i < len(d.bounds) {
+		d.counts[i]++
+	}
+	d.sketch.Add(value)
+}
+
+// Merge добавляет значения другого распределения с теми же границами корзин
+func (d *Distribution) Merge(other *Distribution) error {
+	if len(other.bounds) != len(d.bounds) {
+		return sketch.ErrIncompatible
+	}
+	for i := range d.counts {
+		d.counts[i] += other.counts[i]
+	}
+	return d.sketch.Merge(other.sketch)
+}
+
+// Count возвращает число значений
+func (d *Distribution) Count() uint64 {
+	return d.sketch.Count()
+}
+
+// Sum возвращает сумму значений
+func (d *Distribution) Sum() float64 {
+	return d.sketch.Sum()
+}
+
+// CumulativeBuckets возвращает накопительные счётчики корзин без корзины +Inf
+func (d *Distribution) CumulativeBuckets() map[float64]uint64 {
+	buckets := make(map[float64]uint64, len(d.bounds))
+	var total uint64
+	for i, bound := range d.bounds {
+		total += d.counts[i]
+		buckets[bound] = total
+	}
+	return buckets
+}
+
+// QuantileValues возвращает оценки квантилей из Quantiles
+func (d *Distribution) QuantileValues() map[float64]float64 {
+	values := make(map[float64]float64, len(Quantiles))
+	for _, q := range Quantiles {
+		values[q] = d.sketch.Quantile(q)
+	}
+	return values
+}
+
+// Summary строит сводку распределения
+func (d *Distribution) Summary() Summary {
+	summary := Summary{
+		Count:   d.sketch.Count(),
+		Sum:     d.sketch.Sum(),
+		Min:     d.sketch.Min(),
+		Max:     d.sketch.Max(),
+		P50:     d.sketch.Quantile(0.5),
+		P90:     d.sketch.Quantile(0.9),
+		P99:     d.sketch.Quantile(0.99),
+		Buckets: make([]Bucket, 0, len(d.bounds)),
+	}
+	if summary.Count > 0 {
+		summary.Mean = summary.Sum / float64(summary.Count)
+	}
+
+	var total uint64
+	for i, bound := range d.bounds {
+		total += d.counts[i]
+		summary.Buckets = append(summary.Buckets, Bucket{UpperBound: bound, Count: total})
+	}
+	return summary
+}
@@ -0,0 +1,66 @@
+package distribution
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"stats-of/internal/sketch"
+)
+
+// TestSummary проверяет накопительные корзины, среднее и квантили сводки
+func TestSummary(t *testing.T) {
+	d := New([]float64{10, 1, 100})
+	for v := 1; v <= 200; v++ {
+		d.Observe(float64(v))
+	}
+
+	s := d.Summary()
+	wantBuckets := []Bucket{{UpperBound: 1, Count: 1}, {UpperBound: 10, Count: 10}, {UpperBound: 100, Count: 100}}
+	if !reflect.DeepEqual(s.Buckets, wantBuckets) {
+		t.Errorf("buckets %v, want %v", s.Buckets, wantBuckets)
+	}
+	if s.Count != 200 || s.Sum != 20100 || s.Mean != 100.5 || s.Min != 1 || s.Max != 200 {
+		t.Errorf("count %d, sum %v, mean %v, min %v, max %v", s.Count, s.Sum, s.Mean, s.Min, s.Max)
+	}
+	for q, got := range map[float64]float64{0.5: s.P50, 0.9: s.P90, 0.99: s.P99} {
+		want := float64(int(q*199) + 1)
+		if math.Abs(got-want) > sketch.DefaultRelativeAccuracy*want {
+			t.Errorf("p%v = %v, want %v within 1%%", q*100, got, want)
+		}
+	}
+}
+
+// TestMerge проверяет, что слияние распределений частей равно распределению всех значений
+func TestMerge(t *testing.T) {
+	whole := New(DefaultBuckets)
+	parts := []*Distribution{New(DefaultBuckets), New(DefaultBuckets), New(DefaultBuckets)}
+	for v := 0; v < 3000; v++ {
+		value := float64(v*v%7919) + 0.5
+		whole.Observe(value)
+		parts[v%len(parts)].Observe(value)
+	}
+
+	merged := New(DefaultBuckets)
+	for _, part := range parts {
+		if err := merged.Merge(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := merged.Summary(), whole.Summary(); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged summary %+v, want %+v", got, want)
+	}
+	if got, want := merged.CumulativeBuckets(), whole.CumulativeBuckets(); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged buckets %v, want %v", got, want)
+	}
+}
+
+// TestMergeIncompatible проверяет, что распределения с разными корзинами не сливаются
+func TestMergeIncompatible(t *testing.T) {
+	d := New([]float64{1, 2})
+	if err := d.Merge(New([]float64{1, 2, 3})); !errors.Is(err, sketch.ErrIncompatible) {
+		t.Fatalf("error = %v, want ErrIncompatible", err)
+	}
+}
@@ -0,0 +1,51 @@
+package distribution
+
+import (
+	"net/http"
+
+	"stats-of/internal/utils"
+)
+
+// MakeHandler обработчик GET /api/v1/stats/distributions?metric=chat_users&chat_type=1.
+// Без параметров возвращает все распределения; metric и chat_type сужают ответ.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := service.Report()
+		if err != nil {
+			utils.RespondWith404(w)
+			return
+		}
+
+		query := r.URL.Query()
+		metric := Metric(query.Get("metric"))
+		chatType := query.Get("chat_type")
+		if metric != "" && !metric.Valid() {
+			utils.RespondWith400(w, "metric must be one of: chat_users, user_chats")
+			return
+		}
+		if metric == "" && chatType == "" {
+			utils.SuccessRespondWith200(w, report)
+			return
+		}
+
+		filtered := &Report{ComputedAt: report.ComputedAt, Metrics: make(map[Metric]Group)}
+		for m, group := range report.Metrics {
+			if metric != "" && m != metric {
+				continue
+			}
+			if chatType != "" && chatType != AllChatTypes {
+				summary, ok := group.ByChatType[chatType]
+				if !ok {
+					continue
+				}
+				group = Group{Global: group.Global, ByChatType: map[string]Summary{chatType: summary}}
+			}
+			filtered.Metrics[m] = group
+		}
+		if len(filtered.Metrics) == 0 {
+			utils.RespondWith404(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, filtered)
+	}
+}
@@ -0,0 +1,10 @@
+package distribution
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewComputeJob создаёт задачу периодического пересчёта распределений
+func NewComputeJob(service *Service) jobs.Job {
+	return jobs.Func("distributions", service.Compute)
+}
@@ -0,0 +1,195 @@
+package distribution
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Metric распределение, по которому строятся сводки
+type Metric string
+
+const (
+	// MetricChatUsers распределение Chat.CountOfUsers
+	MetricChatUsers Metric = "chat_users"
+	// MetricUserChats распределение User.CountOfChats
+	MetricUserChats Metric = "user_chats"
+)
+
+// Valid сообщает, поддерживается ли распределение
+func (m Metric) Valid() bool {
+	return m == MetricChatUsers || m == MetricUserChats
+}
+
+type (
+	// Group сводки одного распределения: по всем данным и по типам чатов
+	Group struct {
+		Global     Summary            `json:"global"`
+		ByChatType map[string]Summary `json:"by_chat_type"`
+	}
+
+	// Report сводки всех распределений на момент последнего расчёта
+	Report struct {
+		ComputedAt time.Time        `json:"computed_at"`
+		Metrics    map[Metric]Group `json:"metrics"`
+	}
+
+	// snapshot распределения, из которых построен отчёт; используются метриками Prometheus
+	snapshot struct {
+		report        *Report
+		distributions map[Metric]map[string]*Distribution
+	}
+
+	// Service периодически пересчитывает распределения по счётчикам в хранилище
+	Service struct {
+		repo    *storage.Repository
+		buckets []float64
+
+		mu       sync.RWMutex
+		snapshot *snapshot
+	}
+)
+
+// AllChatTypes метка распределения по всем типам чатов
+const AllChatTypes = "all"
+
+func NewService(repo *storage.Repository, buckets []float64) *Service {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	return &Service{repo: repo, buckets: buckets}
+}
+
+// Compute пересчитывает распределения размеров чатов и числа чатов у пользователей.
+// Размеры чатов берутся из Chat.CountOfUsers, число чатов пользователя в целом — из
+// User.CountOfChats, а в разрезе типов чатов — из множеств участников.
+func (s *Service) Compute(ctx context.Context) error {
+	chatIDs, err := s.repo.ChatIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	chatSizes := make(map[string]*Distribution)
+	userChatsByType := make(map[string]map[entities.UserID]int)
+	for _, chatID := range chatIDs {
+		chat, err := s.repo.Chat(ctx, chatID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		chatType := strconv.FormatUint(uint64(chat.ChatType), 10)
+		s.distribution(chatSizes, chatType).Observe(float64(chat.CountOfUsers))
+
+		members, err := s.repo.ChatUserIDs(ctx, chatID)
+		if err != nil {
+			return err
+		}
+		counts, ok := userChatsByType[chatType]
+		if !ok {
+			counts = make(map[entities.UserID]int)
+			userChatsByType[chatType] = counts
+		}
+		for _, userID := range members {
+			counts[userID]++
+		}
+	}
+
+	userChats := make(map[string]*Distribution)
+	for chatType, counts := range userChatsByType {
+		d := s.distribution(userChats, chatType)
+		for _, count := range counts {
+			d.Observe(float64(count))
+		}
+	}
+
+	userIDs, err := s.repo.UserIDs(ctx)
+	if err != nil {
+		return err
+	}
+	global := s.distribution(userChats, AllChatTypes)
+	for _, userID := range userIDs {
+		user, err := s.repo.User(ctx, userID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		global.Observe(float64(user.CountOfChats))
+	}
+
+	// Общее распределение размеров чатов получается слиянием распределений по типам
+	allChats := New(s.buckets)
+	for _, d := range chatSizes {
+		if err := allChats.Merge(d); err != nil {
+			return fmt.Errorf("failed to merge chat size distributions: %w", err)
+		}
+	}
+	chatSizes[AllChatTypes] = allChats
+
+	distributions := map[Metric]map[string]*Distribution{
+		MetricChatUsers: chatSizes,
+		MetricUserChats: userChats,
+	}
+	report := &Report{ComputedAt: time.Now().UTC(), Metrics: make(map[Metric]Group, len(distributions))}
+	for metric, byType := range distributions {
+		group := Group{ByChatType: make(map[string]Summary, len(byType))}
+		for chatType, d := range byType {
+			if chatType == AllChatTypes {
+				group.Global = d.Summary()
+				continue
+			}
+			group.ByChatType[chatType] = d.Summary()
+		}
+		report.Metrics[metric] = group
+	}
+
+	s.mu.Lock()
+	s.snapshot = &snapshot{report: report, distributions: distributions}
+	s.mu.Unlock()
+
+	logger.Log.Info("Distributions computed", zap.Int("chats", len(chatIDs)), zap.Int("users", len(userIDs)))
+	return nil
+}
+
+// Report возвращает последний отчёт или ErrNotFound, если расчёт ещё не выполнялся
+func (s *Service) Report() (*Report, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.snapshot == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	return s.snapshot.report, nil
+}
+
+// chatTypes возвращает отсортированные метки типов чатов распределения
+func (s *snapshot) chatTypes(metric Metric) []string {
+	types := make([]string, 0, len(s.distributions[metric]))
+	for chatType := range s.distributions[metric] {
+		types = append(types, chatType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func (s *Service) distribution(byType map[string]*Distribution, chatType string) *Distribution {
+	d, ok := byType[chatType]
+	if !ok {
+		d = New(s.buckets)
+		byType[chatType] = d
+	}
+	return d
+}
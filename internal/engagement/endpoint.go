@@ -0,0 +1,109 @@
+package engagement
+
+import (
+	"errors"
+	"net/http"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+	maxOffset        = 1 << 30
+)
+
+// ReportPage ответ GET /api/v1/engagement: распределение оценок и страница пользователей
+type ReportPage struct {
+	*Report
+	pagination.Page[UserScore]
+}
+
+// MakeReportHandler обработчик GET /api/v1/engagement?limit=100&cursor=...: параметры модели,
+// распределение оценок всех пользователей и пользователи по убыванию оценки
+func MakeReportHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := service.Report(r.Context())
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read engagement report", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+
+		const scope = "engagement"
+		offset, err := cursors.Offset(r, scope, maxOffset)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		limit, err := utils.QueryInt(r, "limit", defaultPageLimit, 1, maxPageLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		page, err := service.Top(r.Context(), offset, limit)
+		if err != nil {
+			logger.Log.Error("Failed to read engagement scores", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, ReportPage{
+			Report: report,
+			Page:   pagination.NewOffsetPage(cursors, scope, page.Users, offset, limit, page.Total),
+		})
+	}
+}
+
+// MakeUserHandler обработчик GET /api/v1/users/{id}/engagement: оценка пользователя и её составляющие
+func MakeUserHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		score, err := service.User(r.Context(), entities.UserID(id))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read user engagement", zap.Int64("userID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, score)
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/engagement: распределение оценок участников чата
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		report, err := service.Chat(r.Context(), entities.ChatID(id))
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read chat engagement", zap.Int64("chatID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, report)
+	}
+}
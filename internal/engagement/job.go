@@ -0,0 +1,10 @@
+package engagement
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewComputeJob создаёт задачу периодического пересчёта оценок вовлечённости
+func NewComputeJob(service *Service) jobs.Job {
+	return jobs.Func("engagement-compute", service.Compute)
+}
@@ -0,0 +1,110 @@
+package engagement
+
+import (
+	"math"
+	"time"
+)
+
+type (
+	// Weights веса составляющих оценки; сумма весов не обязана быть равна единице
+	Weights struct {
+		Recency   float64 `json:"recency"`
+		Frequency float64 `json:"frequency"`
+		Chats     float64 `json:"chats"`
+		Activity  float64 `json:"activity"`
+	}
+
+	// Model параметры оценки вовлечённости
+	Model struct {
+		Weights Weights `json:"weights"`
+		// RecencyHalfLife время без активности, за которое вклад давности падает вдвое
+		RecencyHalfLife time.Duration `json:"-"`
+		// FrequencyScale число сессий, при котором частота даёт полный вклад
+		FrequencyScale float64 `json:"frequency_scale"`
+		// ChatsScale число чатов, при котором охват даёт полный вклад
+		ChatsScale float64 `json:"chats_scale"`
+		// ActivityScale число сообщений в чате, при котором активность в нём даёт полный вклад
+		ActivityScale float64 `json:"activity_scale"`
+	}
+
+	// Input данные пользователя, по которым считается оценка
+	Input struct {
+		LastTime     time.Time
+		Sessions     int64
+		CountOfChats int64
+		// ChatMessages число сообщений пользователя в каждом из его чатов
+		ChatMessages []int64
+	}
+
+	// Components вклад каждой составляющей в диапазоне [0, 1]
+	Components struct {
+		Recency   float64 `json:"recency"`
+		Frequency float64 `json:"frequency"`
+		Chats     float64 `json:"chats"`
+		Activity  float64 `json:"activity"`
+	}
+)
+
+// DefaultWeights веса по умолчанию: давность и частота важнее охвата
+var DefaultWeights = Weights{Recency: 0.4, Frequency: 0.3, Chats: 0.1, Activity: 0.2}
+
+// WeightsFrom собирает веса из списка "recency,frequency,chats,activity"; пустой список — веса по умолчанию
+func WeightsFrom(values []float64) Weights {
+	if len(values) == 0 {
+		return DefaultWeights
+	}
+	return Weights{Recency: values[0], Frequency: values[1], Chats: values[2], Activity: values[3]}
+}
+
+// MaxScore верхняя граница оценки
+const MaxScore = 100
+
+// Score считает оценку вовлечённости в диапазоне [0, MaxScore] как взвешенное среднее составляющих.
+// Частота, охват и активность растут логарифмически и насыщаются на своих масштабах, чтобы
+// несколько сверхактивных пользователей не сжимали оценки всех остальных к нулю.
+// Активность — среднее по чатам пользователя, поэтому чаты, где он молчит, её снижают.
+func (m Model) Score(in Input, now time.Time) (float64, Components) {
+	var c Components
+	if !in.LastTime.IsZero() {
+		age := math.Max(now.Sub(in.LastTime).Seconds(), 0)
+		c.Recency = math.Exp(-math.Ln2 * age / m.RecencyHalfLife.Seconds())
+	}
+	c.Frequency = saturate(float64(in.Sessions), m.FrequencyScale)
+	c.Chats = saturate(float64(in.CountOfChats), m.ChatsScale)
+	if len(in.ChatMessages) > 0 {
+		var sum float64
+		for _, messages := range in.ChatMessages {
+			sum += saturate(float64(messages), m.ActivityScale)
+		}
+		c.Activity = sum / float64(len(in.ChatMessages))
+	}
+
+	c = Components{
+		Recency:   round(c.Recency, 4),
+		Frequency: round(c.Frequency, 4),
+		Chats:     round(c.Chats, 4),
+		Activity:  round(c.Activity, 4),
+	}
+
+	w := m.Weights
+	total := w.Recency + w.Frequency + w.Chats + w.Activity
+	if total <= 0 {
+		return 0, c
+	}
+	score := (w.Recency*c.Recency + w.Frequency*c.Frequency + w.Chats*c.Chats + w.Activity*c.Activity) / total
+	return round(MaxScore*score, 2), c
+}
+
+// saturate переводит неотрицательное значение в [0, 1] по логарифмической шкале с насыщением на scale
+func saturate(value, scale float64) float64 {
+	if value <= 0 || scale <= 0 {
+		return 0
+	}
+	return math.Min(math.Log1p(value)/math.Log1p(scale), 1)
+}
+
+// round округляет до digits знаков после запятой
+func round(value float64, digits int) float64 {
+	scale := math.Pow10(digits)
+	return math.Round(value*scale) / scale
+}
@@ -0,0 +1,371 @@
+package engagement
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"stats-of/internal/distribution"
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/sessions"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Схема ключей:
+//
+//	engagement:scores             отсортированное множество: пользователь -> оценка на момент последнего расчёта
+//	engagement:components         хеш: пользователь -> JSON составляющих оценки
+//	engagement:activity:{user}    хеш: чат -> число сообщений пользователя в чате
+//	engagement:report             хеш последнего расчёта: report -> JSON Report, {chat} -> JSON ChatReport
+const (
+	scoresKey         = "engagement:scores"
+	componentsKey     = "engagement:components"
+	activityKeyPrefix = "engagement:activity:"
+	reportKey         = "engagement:report"
+	// reportDraftKey сюда записывается новый отчёт, прежде чем заменить reportKey целиком
+	reportDraftKey = "engagement:report:draft"
+	fieldReport    = "report"
+
+	rebuildPageSize = 1000
+)
+
+// ScoreBuckets верхние границы корзин гистограмм оценок
+var ScoreBuckets = []float64{10, 20, 30, 40, 50, 60, 70, 80, 90, MaxScore}
+
+type (
+	// UserScore оценка вовлечённости пользователя; Rank начинается с единицы
+	UserScore struct {
+		Rank       int64           `json:"rank"`
+		UserID     entities.UserID `json:"user_id"`
+		Score      float64         `json:"score"`
+		Components *Components     `json:"components,omitempty"`
+	}
+
+	// Page страница пользователей по убыванию оценки
+	Page struct {
+		Total  int64       `json:"total"`
+		Offset int         `json:"offset"`
+		Limit  int         `json:"limit"`
+		Users  []UserScore `json:"users"`
+	}
+
+	// Report распределение оценок всех пользователей на момент последнего расчёта
+	Report struct {
+		ComputedAt      time.Time            `json:"computed_at"`
+		Model           Model                `json:"model"`
+		RecencyHalfLife string               `json:"recency_half_life"`
+		Scores          distribution.Summary `json:"scores"`
+	}
+
+	// ChatReport распределение оценок участников чата
+	ChatReport struct {
+		ChatID     entities.ChatID      `json:"chat_id"`
+		ComputedAt time.Time            `json:"computed_at"`
+		Members    distribution.Summary `json:"members"`
+	}
+
+	// Service ведёт счётчики сообщений пользователей по чатам и периодически пересчитывает
+	// оценки вовлечённости всех пользователей и их распределения по чатам. Результат расчёта
+	// хранится рядом с оценками и переживает перезапуск.
+	Service struct {
+		repo     *storage.Repository
+		sessions *sessions.Service
+		model    Model
+	}
+)
+
+func NewService(repo *storage.Repository, sessions *sessions.Service, model Model) *Service {
+	return &Service{repo: repo, sessions: sessions, model: model}
+}
+
+// OnChange учитывает сообщение пользователя в чате; при выходе из чата счётчик чата сбрасывается
+func (s *Service) OnChange(ctx context.Context, change *membership.Change) error {
+	key := activityKey(change.Event.UserID)
+	field := strconv.FormatInt(int64(change.Event.ChatID), 10)
+
+	switch {
+	case change.Event.Type == membership.EventMessage:
+		if _, err := s.repo.Storage().HashIncrBy(ctx, key, field, 1); err != nil {
+			return fmt.Errorf("failed to count message of user %d: %w", change.Event.UserID, err)
+		}
+	case change.Event.Type.Removes():
+		if err := s.repo.Storage().HashDelete(ctx, key, field); err != nil {
+			return fmt.Errorf("failed to reset activity of user %d: %w", change.Event.UserID, err)
+		}
+	}
+	return nil
+}
+
+// Compute пересчитывает оценки всех пользователей, сохраняет их в хранилище
+// и строит распределения оценок по чатам
+func (s *Service) Compute(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	userIDs, err := s.repo.UserIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	global := distribution.New(ScoreBuckets)
+	byChat := make(map[entities.ChatID]*distribution.Distribution)
+	scores := make(map[string]float64, len(userIDs))
+	for _, userID := range userIDs {
+		user, err := s.repo.User(ctx, userID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		chatIDs, err := s.repo.UserChatIDs(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		input, err := s.input(ctx, user, chatIDs)
+		if err != nil {
+			return err
+		}
+		score, components := s.model.Score(input, now)
+
+		field := strconv.FormatInt(int64(userID), 10)
+		data, err := json.Marshal(components)
+		if err != nil {
+			return fmt.Errorf("failed to encode engagement of user %d: %w", userID, err)
+		}
+		if err := s.repo.Storage().HashSet(ctx, componentsKey, map[string]string{field: string(data)}); err != nil {
+			return fmt.Errorf("failed to save engagement of user %d: %w", userID, err)
+		}
+		if err := s.repo.Storage().SortedSetAdd(ctx, scoresKey, field, score); err != nil {
+			return fmt.Errorf("failed to save engagement of user %d: %w", userID, err)
+		}
+		scores[field] = score
+
+		global.Observe(score)
+		for _, chatID := range chatIDs {
+			d, ok := byChat[chatID]
+			if !ok {
+				d = distribution.New(ScoreBuckets)
+				byChat[chatID] = d
+			}
+			d.Observe(score)
+		}
+	}
+
+	if err := s.removeStale(ctx, scores); err != nil {
+		return err
+	}
+
+	report := &Report{
+		ComputedAt:      now,
+		Model:           s.model,
+		RecencyHalfLife: s.model.RecencyHalfLife.String(),
+		Scores:          global.Summary(),
+	}
+	if err := s.saveReport(ctx, report, byChat); err != nil {
+		return err
+	}
+
+	logger.Log.Info("Engagement scores computed", zap.Int("users", len(scores)), zap.Int("chats", len(byChat)))
+	return nil
+}
+
+// saveReport записывает отчёт и распределения по чатам в черновик и одним переименованием
+// заменяет им прежний отчёт, поэтому читатели не видят смесь двух расчётов
+func (s *Service) saveReport(ctx context.Context, report *Report, byChat map[entities.ChatID]*distribution.Distribution) error {
+	fields := make(map[string]string, len(byChat)+1)
+	data, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to encode engagement report: %w", err)
+	}
+	fields[fieldReport] = string(data)
+	for chatID, d := range byChat {
+		data, err := json.Marshal(ChatReport{ChatID: chatID, ComputedAt: report.ComputedAt, Members: d.Summary()})
+		if err != nil {
+			return fmt.Errorf("failed to encode engagement of chat %d: %w", chatID, err)
+		}
+		fields[strconv.FormatInt(int64(chatID), 10)] = string(data)
+	}
+
+	store := s.repo.Storage()
+	if err := store.Delete(ctx, reportDraftKey); err != nil {
+		return fmt.Errorf("failed to reset engagement report draft: %w", err)
+	}
+	if err := store.HashSet(ctx, reportDraftKey, fields); err != nil {
+		return fmt.Errorf("failed to save engagement report: %w", err)
+	}
+	if err := store.Rename(ctx, reportDraftKey, reportKey); err != nil {
+		return fmt.Errorf("failed to publish engagement report: %w", err)
+	}
+	return nil
+}
+
+// Report возвращает последний отчёт или ErrNotFound, если расчёт ещё не выполнялся
+func (s *Service) Report(ctx context.Context) (*Report, error) {
+	var report Report
+	if err := s.readReport(ctx, fieldReport, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Chat возвращает распределение оценок участников чата или ErrNotFound
+func (s *Service) Chat(ctx context.Context, id entities.ChatID) (*ChatReport, error) {
+	var report ChatReport
+	if err := s.readReport(ctx, strconv.FormatInt(int64(id), 10), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (s *Service) readReport(ctx context.Context, field string, dest any) error {
+	data, err := s.repo.Storage().HashGet(ctx, reportKey, field)
+	if err != nil {
+		return fmt.Errorf("failed to read engagement report: %w", err)
+	}
+	if data == "" {
+		return apperrors.ErrNotFound
+	}
+	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		return fmt.Errorf("failed to decode engagement report %s: %w", field, err)
+	}
+	return nil
+}
+
+// Top возвращает limit пользователей с наибольшей оценкой начиная с offset
+func (s *Service) Top(ctx context.Context, offset, limit int) (*Page, error) {
+	store := s.repo.Storage()
+
+	total, err := store.SortedSetCard(ctx, scoresKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count engagement scores: %w", err)
+	}
+	members, err := store.SortedSetRevRange(ctx, scoresKey, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read engagement scores: %w", err)
+	}
+
+	users := make([]UserScore, 0, len(members))
+	for i, member := range members {
+		id, err := strconv.ParseInt(member.Member, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed engagement member", zap.String("member", member.Member))
+			continue
+		}
+		components, err := s.components(ctx, member.Member)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, UserScore{
+			Rank:       int64(offset+i) + 1,
+			UserID:     entities.UserID(id),
+			Score:      member.Score,
+			Components: components,
+		})
+	}
+	return &Page{Total: total, Offset: offset, Limit: limit, Users: users}, nil
+}
+
+// User возвращает оценку пользователя или ErrNotFound
+func (s *Service) User(ctx context.Context, id entities.UserID) (*UserScore, error) {
+	field := strconv.FormatInt(int64(id), 10)
+	rank, score, err := s.repo.Storage().SortedSetRevRank(ctx, scoresKey, field)
+	if err != nil {
+		return nil, err
+	}
+	components, err := s.components(ctx, field)
+	if err != nil {
+		return nil, err
+	}
+	return &UserScore{Rank: rank + 1, UserID: id, Score: score, Components: components}, nil
+}
+
+// input собирает данные пользователя для расчёта оценки. Частота — число сессий
+// пользователя за всё время, включая текущую.
+func (s *Service) input(ctx context.Context, user *entities.User, chatIDs entities.ChatIds) (Input, error) {
+	stats, err := s.sessions.UserStats(ctx, user.UserID)
+	if err != nil {
+		return Input{}, err
+	}
+	sessionCount := stats.Sessions
+	if stats.CurrentSession != nil {
+		sessionCount++
+	}
+
+	activity, err := s.repo.Storage().HashGetAll(ctx, activityKey(user.UserID))
+	if err != nil {
+		return Input{}, fmt.Errorf("failed to read activity of user %d: %w", user.UserID, err)
+	}
+	messages := make([]int64, 0, len(chatIDs))
+	for _, chatID := range chatIDs {
+		// Чат без сообщений даёт нулевую активность
+		n, _ := strconv.ParseInt(activity[strconv.FormatInt(int64(chatID), 10)], 10, 64)
+		messages = append(messages, n)
+	}
+
+	return Input{
+		LastTime:     user.LastTime,
+		Sessions:     sessionCount,
+		CountOfChats: user.CountOfChats,
+		ChatMessages: messages,
+	}, nil
+}
+
+func (s *Service) components(ctx context.Context, field string) (*Components, error) {
+	data, err := s.repo.Storage().HashGet(ctx, componentsKey, field)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read engagement components of user %s: %w", field, err)
+	}
+	if data == "" {
+		return nil, nil
+	}
+	var c Components
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		logger.Log.Warn("Skipping malformed engagement components", zap.String("userID", field), zap.Error(err))
+		return nil, nil
+	}
+	return &c, nil
+}
+
+// removeStale удаляет оценки пользователей, которых больше нет
+func (s *Service) removeStale(ctx context.Context, scores map[string]float64) error {
+	var stale []string
+	for start := int64(0); ; start += rebuildPageSize {
+		members, err := s.repo.Storage().SortedSetRevRange(ctx, scoresKey, start, start+rebuildPageSize-1)
+		if err != nil {
+			return fmt.Errorf("failed to read engagement scores: %w", err)
+		}
+		for _, member := range members {
+			if _, ok := scores[member.Member]; !ok {
+				stale = append(stale, member.Member)
+			}
+		}
+		if len(members) < rebuildPageSize {
+			break
+		}
+	}
+
+	if len(stale) == 0 {
+		return nil
+	}
+	if err := s.repo.Storage().SortedSetRemove(ctx, scoresKey, stale...); err != nil {
+		return fmt.Errorf("failed to remove stale engagement scores: %w", err)
+	}
+	if err := s.repo.Storage().HashDelete(ctx, componentsKey, stale...); err != nil {
+		return fmt.Errorf("failed to remove stale engagement components: %w", err)
+	}
+	return nil
+}
+
+func activityKey(id entities.UserID) string {
+	return activityKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+var _ membership.Observer = (*Service)(nil)
@@ -0,0 +1,69 @@
+package engagement
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/sessions"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+var testModel = Model{
+	Weights:         Weights{Recency: 1, Frequency: 1, Chats: 1, Activity: 1},
+	RecencyHalfLife: 24 * time.Hour,
+	FrequencyScale:  10,
+	ChatsScale:      5,
+	ActivityScale:   100,
+}
+
+func newTestService(store storage.Storage) *Service {
+	return NewService(storage.NewRepository(store), sessions.NewService(store, 30*time.Minute), testModel)
+}
+
+// TestReportSurvivesRestart проверяет, что отчёт читается из хранилища сервисом,
+// который сам расчёт не выполнял
+func TestReportSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	store := memtest.NewStorage(t)
+	repo := storage.NewRepository(store)
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 1, CountOfUsers: 2}, []entities.UserID{10, 11}); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []entities.UserID{10, 11} {
+		user := entities.User{UserID: userID, LastTime: time.Now().Add(-time.Hour), CountOfChats: 1}
+		if err := repo.SaveUser(ctx, user, []entities.ChatID{1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := newTestService(store).Report(ctx); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Fatalf("report before the first computation: %v, want ErrNotFound", err)
+	}
+	if err := newTestService(store).Compute(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	restarted := newTestService(store)
+	report, err := restarted.Report(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Scores.Count != 2 || report.RecencyHalfLife != "24h0m0s" || report.Model.ChatsScale != 5 {
+		t.Errorf("report = %+v; want 2 scores computed with the test model", report)
+	}
+	chat, err := restarted.Chat(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.Members.Count != 2 || !chat.ComputedAt.Equal(report.ComputedAt) {
+		t.Errorf("chat report = %+v; want 2 members computed at %s", chat, report.ComputedAt)
+	}
+	if _, err := restarted.Chat(ctx, 2); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("unknown chat: %v, want ErrNotFound", err)
+	}
+}
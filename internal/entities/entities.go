@@ -11,15 +11,15 @@ type (
 	ChatIds []ChatID // нужно подумать как этот тип можно использовать
 
 	User struct {
-		UserID       UserID
-		LastTime     time.Time
-		CountOfChats int64
+		UserID       UserID    `json:"user_id"`
+		LastTime     time.Time `json:"last_time"`
+		CountOfChats int64     `json:"count_of_chats"`
 	}
 
 	Chat struct {
-		ChatID       ChatID
-		ChatType     uint
-		CountOfUsers int64
+		ChatID       ChatID `json:"chat_id"`
+		ChatType     uint   `json:"chat_type"`
+		CountOfUsers int64  `json:"count_of_users"`
 	}
 
 	ChatList map[ChatID][]User
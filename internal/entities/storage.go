@@ -0,0 +1,26 @@
+package entities
+
+type (
+	// ScoredMember элемент упорядоченного множества вместе с его весом
+	ScoredMember struct {
+		Member string
+		Score  float64
+	}
+
+	// SetLink связь, которая хранится в двух множествах и учитывается счётчиками в хешах,
+	// например участник в chat_users:{id} и чат в user_chats:{id}. Хранилище меняет её целиком.
+	SetLink struct {
+		Key           string
+		Member        string
+		ReverseKey    string
+		ReverseMember string
+		// Counters поля хешей, которые увеличиваются на 1 при добавлении связи и уменьшаются при удалении
+		Counters []HashField
+	}
+
+	// HashField поле хеша
+	HashField struct {
+		Key   string
+		Field string
+	}
+)
@@ -1 +1,32 @@
 package errors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound возвращается, когда запрошенная сущность отсутствует в хранилище
+	ErrNotFound = errors.New("not found")
+)
+
+// InvalidArgumentError ошибка в параметрах запроса. Текст предназначен клиенту:
+// HTTP API отвечает на неё 400, gRPC API — InvalidArgument.
+type InvalidArgumentError struct {
+	Message string
+}
+
+func (e *InvalidArgumentError) Error() string {
+	return e.Message
+}
+
+// InvalidArgument создаёт ошибку параметров запроса
+func InvalidArgument(format string, args ...any) error {
+	return &InvalidArgumentError{Message: fmt.Sprintf(format, args...)}
+}
+
+// IsInvalidArgument сообщает, что ошибка вызвана параметрами запроса
+func IsInvalidArgument(err error) bool {
+	var invalid *InvalidArgumentError
+	return errors.As(err, &invalid)
+}
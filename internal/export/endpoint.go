@@ -0,0 +1,109 @@
+package export
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/query"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// writeWindow время на отправку одной порции; срок записи продлевается после каждой порции,
+	// поэтому общий WriteTimeout сервера не обрывает длинную выгрузку
+	writeWindow = 30 * time.Second
+
+	// statusTrailer трейлер с итогом выгрузки: complete или error. Код ответа уже отправлен
+	// к моменту ошибки, поэтому по трейлеру клиент отличает полную выгрузку от оборванной.
+	statusTrailer = "X-Export-Status"
+)
+
+// MakeHandler обработчик GET /api/v1/export/{dataset}?columns=...&filter=...&format=csv|ndjson,
+// где dataset — chats, users или memberships. Формат выбирается параметром format или заголовком Accept
+// (text/csv, application/x-ndjson), по умолчанию NDJSON. filter — выражение языка запросов списков,
+// доступно для chats и users.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		dataset := Dataset(r.PathValue("dataset"))
+		if !dataset.Valid() {
+			utils.RespondWith404(w)
+			return
+		}
+
+		if raw := Format(r.URL.Query().Get("format")); raw != "" && !raw.Valid() {
+			utils.RespondWith400(w, "format must be one of: csv, ndjson")
+			return
+		}
+		format, ok := Negotiate(r)
+		if !ok {
+			utils.RespondWithError(w, http.StatusNotAcceptable, "supported formats: text/csv, application/x-ndjson")
+			return
+		}
+
+		var columns []string
+		if raw := r.URL.Query().Get("columns"); raw != "" {
+			for _, name := range strings.Split(raw, ",") {
+				columns = append(columns, strings.TrimSpace(name))
+			}
+		}
+		if err := Columns(dataset, columns); err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		filter, err := parseFilter(dataset, r.URL.Query().Get("filter"))
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		controller := http.NewResponseController(w)
+		header := w.Header()
+		header.Set("Content-Type", format.ContentType())
+		header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, dataset, format))
+		header.Set("Cache-Control", "no-store")
+		header.Set("Trailer", statusTrailer)
+		w.WriteHeader(http.StatusOK)
+
+		flush := func() error {
+			if err := controller.Flush(); err != nil {
+				return err
+			}
+			// Не все ResponseWriter умеют продлевать срок записи; выгрузка продолжается и без этого
+			_ = controller.SetWriteDeadline(time.Now().Add(writeWindow))
+			return nil
+		}
+		_ = controller.SetWriteDeadline(time.Now().Add(writeWindow))
+
+		rows, err := service.Export(r.Context(), dataset, newRowWriter(format, w), columns, filter, flush)
+		if err != nil {
+			header.Set(statusTrailer, "error")
+			logger.Log.Error("Export interrupted", zap.String("dataset", string(dataset)), zap.Int("rows", rows), zap.Error(err))
+			return
+		}
+		header.Set(statusTrailer, "complete")
+		logger.Log.Info("Export completed", zap.String("dataset", string(dataset)), zap.String("format", string(format)), zap.Int("rows", rows))
+	}
+}
+
+// parseFilter разбирает filter набора; сортировка не поддерживается — записи идут в порядке обхода ключей
+func parseFilter(dataset Dataset, raw string) (*query.Query, error) {
+	var schema *query.Schema
+	switch dataset {
+	case DatasetChats:
+		schema = query.ChatSchema
+	case DatasetUsers:
+		schema = query.UserSchema
+	default:
+		if strings.TrimSpace(raw) != "" {
+			return nil, fmt.Errorf("filter is not supported for %s", dataset)
+		}
+		return nil, nil
+	}
+	return query.New(schema, raw, "", time.Now())
+}
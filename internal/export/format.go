@@ -0,0 +1,184 @@
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Format формат выгрузки
+type Format string
+
+const (
+	FormatCSV    Format = "csv"
+	FormatNDJSON Format = "ndjson"
+)
+
+// Valid сообщает, что формат поддерживается
+func (f Format) Valid() bool {
+	return f == FormatCSV || f == FormatNDJSON
+}
+
+// ContentType тип содержимого ответа
+func (f Format) ContentType() string {
+	if f == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// mediaTypes типы из Accept, которым соответствуют форматы; пустой формат — любой, по умолчанию NDJSON
+var mediaTypes = map[string]Format{
+	"text/csv":             FormatCSV,
+	"application/x-ndjson": FormatNDJSON,
+	"application/ndjson":   FormatNDJSON,
+	"application/jsonl":    FormatNDJSON,
+	"application/*":        FormatNDJSON,
+	"text/*":               FormatCSV,
+	"*/*":                  "",
+}
+
+// Negotiate выбирает формат по параметру format, а без него — по заголовку Accept с учётом весов q.
+// ok = false, если клиент не принимает ни один из форматов. Параметр format проверяется заранее через Valid.
+func Negotiate(r *http.Request) (Format, bool) {
+	if format := Format(r.URL.Query().Get("format")); format != "" {
+		return format, format.Valid()
+	}
+
+	accept := r.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return FormatNDJSON, true
+	}
+
+	best, bestQ, found := FormatNDJSON, 0.0, false
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		format, known := mediaTypes[mediaType]
+		if !known {
+			continue
+		}
+		q := 1.0
+		if raw, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(raw, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 || q <= bestQ {
+			continue
+		}
+		if format == "" {
+			format = FormatNDJSON
+		}
+		best, bestQ, found = format, q, true
+	}
+	return best, found
+}
+
+// rowWriter пишет строки выгрузки в выбранном формате
+type rowWriter interface {
+	Header(columns []string) error
+	Row(values []any) error
+	Flush() error
+}
+
+func newRowWriter(format Format, w io.Writer) rowWriter {
+	if format == FormatCSV {
+		return &csvWriter{w: csv.NewWriter(w)}
+	}
+	return &ndjsonWriter{w: bufio.NewWriter(w)}
+}
+
+type csvWriter struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvWriter) Header(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Row(values []any) error {
+	c.record = c.record[:0]
+	for _, v := range values {
+		c.record = append(c.record, formatCSV(v))
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonWriter struct {
+	w       *bufio.Writer
+	columns [][]byte
+}
+
+// Header запоминает закодированные имена колонок, чтобы сохранить их порядок в каждом объекте
+func (n *ndjsonWriter) Header(columns []string) error {
+	n.columns = make([][]byte, len(columns))
+	for i, column := range columns {
+		name, err := json.Marshal(column)
+		if err != nil {
+			return err
+		}
+		n.columns[i] = name
+	}
+	return nil
+}
+
+func (n *ndjsonWriter) Row(values []any) error {
+	n.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		n.w.Write(n.columns[i])
+		n.w.WriteByte(':')
+		// Неизвестное время, например у пользователя без активности, выгружается как null
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			n.w.WriteString("null")
+			continue
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		n.w.Write(data)
+	}
+	n.w.WriteString("}\n")
+	return nil
+}
+
+func (n *ndjsonWriter) Flush() error {
+	return n.w.Flush()
+}
+
+func formatCSV(v any) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint:
+		return strconv.FormatUint(uint64(v), 10)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestNDJSONZeroTimeIsNull проверяет, что неизвестное время выгружается в NDJSON как null
+func TestNDJSONZeroTimeIsNull(t *testing.T) {
+	var buf bytes.Buffer
+	w := newRowWriter(FormatNDJSON, &buf)
+	if err := w.Header([]string{"user_id", "last_time"}); err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	for _, row := range [][]any{{int64(1), time.Time{}}, {int64(2), at}} {
+		if err := w.Row(row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"user_id":1,"last_time":null}` + "\n" + `{"user_id":2,"last_time":"2026-10-01T12:00:00Z"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
+
+// TestNegotiate проверяет выбор формата по параметру format и заголовку Accept
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   Format
+		ok     bool
+	}{
+		{name: "default", want: FormatNDJSON, ok: true},
+		{name: "format overrides accept", query: "?format=csv", accept: "application/x-ndjson", want: FormatCSV, ok: true},
+		{name: "unknown format", query: "?format=xml", ok: false},
+		{name: "accept csv", accept: "text/csv", want: FormatCSV, ok: true},
+		{name: "accept by weight", accept: "text/csv;q=0.5, application/x-ndjson", want: FormatNDJSON, ok: true},
+		{name: "accept anything", accept: "*/*", want: FormatNDJSON, ok: true},
+		{name: "not acceptable", accept: "application/xml", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/api/v1/export/users"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, ok := Negotiate(r)
+			if ok != tt.ok || (ok && got != tt.want) {
+				t.Errorf("got %q, %v; want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
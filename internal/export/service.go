@@ -0,0 +1,271 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/pagination"
+	"stats-of/internal/query"
+	"stats-of/internal/storage"
+)
+
+// Dataset выгружаемый набор записей
+type Dataset string
+
+const (
+	DatasetChats       Dataset = "chats"
+	DatasetUsers       Dataset = "users"
+	DatasetMemberships Dataset = "memberships"
+)
+
+type (
+	column[T any] struct {
+		name  string
+		value func(T) any
+	}
+
+	membership struct {
+		chatID entities.ChatID
+		userID entities.UserID
+	}
+
+	// Service выгружает чаты, пользователей и участие в чатах потоком, порция за порцией SCAN,
+	// поэтому расход памяти не зависит от объёма данных
+	Service struct {
+		repo *storage.Repository
+	}
+)
+
+var chatColumns = []column[entities.Chat]{
+	{"chat_id", func(c entities.Chat) any { return int64(c.ChatID) }},
+	{"chat_type", func(c entities.Chat) any { return c.ChatType }},
+	{"count_of_users", func(c entities.Chat) any { return c.CountOfUsers }},
+}
+
+var userColumns = []column[entities.User]{
+	{"user_id", func(u entities.User) any { return int64(u.UserID) }},
+	{"last_time", func(u entities.User) any { return u.LastTime }},
+	{"count_of_chats", func(u entities.User) any { return u.CountOfChats }},
+}
+
+var membershipColumns = []column[membership]{
+	{"chat_id", func(m membership) any { return int64(m.chatID) }},
+	{"user_id", func(m membership) any { return int64(m.userID) }},
+}
+
+func NewService(repo *storage.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Valid сообщает, поддерживается ли набор
+func (d Dataset) Valid() bool {
+	return d == DatasetChats || d == DatasetUsers || d == DatasetMemberships
+}
+
+// Export пишет набор в w: заголовок с выбранными колонками и по строке на запись.
+// columns = nil — все колонки; filter применяется к чатам и пользователям. flush вызывается
+// после каждой порции, чтобы данные уходили клиенту по мере чтения. Возвращает число строк.
+func (s *Service) Export(ctx context.Context, dataset Dataset, w rowWriter, columns []string, filter *query.Query, flush func() error) (int, error) {
+	switch dataset {
+	case DatasetChats:
+		return exportRows(ctx, w, chatColumns, columns, flush, func(cursor uint64) ([]entities.Chat, uint64, error) {
+			ids, next, err := s.repo.ScanChatIDs(ctx, cursor, pagination.ScanCount)
+			if err != nil {
+				return nil, 0, err
+			}
+			chats, err := s.chats(ctx, ids, filter)
+			return chats, next, err
+		})
+	case DatasetUsers:
+		return exportRows(ctx, w, userColumns, columns, flush, func(cursor uint64) ([]entities.User, uint64, error) {
+			ids, next, err := s.repo.ScanUserIDs(ctx, cursor, pagination.ScanCount)
+			if err != nil {
+				return nil, 0, err
+			}
+			users, err := s.users(ctx, ids, filter)
+			return users, next, err
+		})
+	default:
+		return s.exportMemberships(ctx, w, columns, flush)
+	}
+}
+
+// Columns проверяет выбранные колонки набора; пустой список означает все колонки
+func Columns(dataset Dataset, selected []string) error {
+	var available []string
+	switch dataset {
+	case DatasetChats:
+		available = names(chatColumns)
+	case DatasetUsers:
+		available = names(userColumns)
+	default:
+		available = names(membershipColumns)
+	}
+	for _, name := range selected {
+		if !contains(available, name) {
+			return fmt.Errorf("unknown %s column %q; available columns: %s", dataset, name, strings.Join(available, ", "))
+		}
+	}
+	return nil
+}
+
+// exportMemberships обходит чаты SCAN, а участников каждого чата — SSCAN
+func (s *Service) exportMemberships(ctx context.Context, w rowWriter, columns []string, flush func() error) (int, error) {
+	selected := pick(membershipColumns, columns)
+	if err := w.Header(names(selected)); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	values := make([]any, len(selected))
+	for cursor := uint64(0); ; {
+		chatIDs, next, err := s.repo.ScanChatIDs(ctx, cursor, pagination.ScanCount)
+		if err != nil {
+			return rows, err
+		}
+		sort.Slice(chatIDs, func(i, j int) bool { return chatIDs[i] < chatIDs[j] })
+		for _, chatID := range chatIDs {
+			for memberCursor := uint64(0); ; {
+				userIDs, nextMember, err := s.repo.ScanChatUserIDs(ctx, chatID, memberCursor, pagination.ScanCount)
+				if err != nil {
+					return rows, err
+				}
+				for _, userID := range userIDs {
+					m := membership{chatID: chatID, userID: userID}
+					for i, c := range selected {
+						values[i] = c.value(m)
+					}
+					if err := w.Row(values); err != nil {
+						return rows, err
+					}
+					rows++
+				}
+				if err := flushBatch(ctx, w, flush); err != nil {
+					return rows, err
+				}
+				if nextMember == 0 {
+					break
+				}
+				memberCursor = nextMember
+			}
+		}
+		if next == 0 {
+			return rows, nil
+		}
+		cursor = next
+	}
+}
+
+// exportRows пишет записи, которые порциями возвращает scan
+func exportRows[T any](ctx context.Context, w rowWriter, all []column[T], columns []string, flush func() error, scan pagination.ScanFunc[T]) (int, error) {
+	selected := pick(all, columns)
+	if err := w.Header(names(selected)); err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	values := make([]any, len(selected))
+	for cursor := uint64(0); ; {
+		items, next, err := scan(cursor)
+		if err != nil {
+			return rows, err
+		}
+		for _, item := range items {
+			for i, c := range selected {
+				values[i] = c.value(item)
+			}
+			if err := w.Row(values); err != nil {
+				return rows, err
+			}
+			rows++
+		}
+		if err := flushBatch(ctx, w, flush); err != nil {
+			return rows, err
+		}
+		if next == 0 {
+			return rows, nil
+		}
+		cursor = next
+	}
+}
+
+// flushBatch отправляет накопленные строки и прекращает выгрузку, если клиент отключился
+func flushBatch(ctx context.Context, w rowWriter, flush func() error) error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+func (s *Service) chats(ctx context.Context, ids []entities.ChatID, filter *query.Query) ([]entities.Chat, error) {
+	chats := make([]entities.Chat, 0, len(ids))
+	for _, id := range ids {
+		chat, err := s.repo.Chat(ctx, id)
+		// Чат удалён между SCAN и чтением
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if filter.Match(query.ChatRow(*chat)) {
+			chats = append(chats, *chat)
+		}
+	}
+	return chats, nil
+}
+
+func (s *Service) users(ctx context.Context, ids []entities.UserID, filter *query.Query) ([]entities.User, error) {
+	users := make([]entities.User, 0, len(ids))
+	for _, id := range ids {
+		user, err := s.repo.User(ctx, id)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		if filter.Match(query.UserRow(*user)) {
+			users = append(users, *user)
+		}
+	}
+	return users, nil
+}
+
+func pick[T any](all []column[T], selected []string) []column[T] {
+	if len(selected) == 0 {
+		return all
+	}
+	result := make([]column[T], 0, len(selected))
+	for _, name := range selected {
+		for _, c := range all {
+			if c.name == name {
+				result = append(result, c)
+			}
+		}
+	}
+	return result
+}
+
+func names[T any](columns []column[T]) []string {
+	result := make([]string, len(columns))
+	for i, c := range columns {
+		result[i] = c.name
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
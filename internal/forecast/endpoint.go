@@ -0,0 +1,116 @@
+package forecast
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/timeseries"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultStep    = time.Hour
+	defaultHistory = 28 * 24 * time.Hour
+	defaultHorizon = 7 * 24 * time.Hour
+	defaultSeason  = 24 * time.Hour
+	defaultLevel   = 0.95
+
+	maxHistoryPoints = 10000
+	maxHorizonPoints = 1000
+)
+
+// MakeHandler обработчик GET /api/v1/forecast?series=global:users&horizon=168h&step=1h&history=672h&method=auto&season=24h&level=0.95.
+// По умолчанию прогнозируется общее число пользователей.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		series := r.URL.Query().Get("series")
+		if series == "" {
+			series = timeseries.SeriesUsers
+		}
+		forecast(w, r, service, series)
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/forecast с теми же параметрами
+// для ряда числа участников чата
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		forecast(w, r, service, timeseries.ChatUsersSeries(entities.ChatID(id)))
+	}
+}
+
+func forecast(w http.ResponseWriter, r *http.Request, service *Service, series string) {
+	req, err := parseRequest(r, series)
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+
+	result, err := service.Forecast(r.Context(), *req)
+	if err != nil {
+		if errors.Is(err, ErrNotEnoughData) {
+			utils.RespondWith404(w)
+			return
+		}
+		logger.Log.Error("Failed to build forecast", zap.String("series", series), zap.Error(err))
+		utils.RespondWith500(w)
+		return
+	}
+	utils.SuccessRespondWith200(w, result)
+}
+
+func parseRequest(r *http.Request, series string) (*Request, error) {
+	req := &Request{Series: series, Method: MethodAuto, Level: defaultLevel}
+	query := r.URL.Query()
+
+	if raw := query.Get("method"); raw != "" {
+		req.Method = Method(raw)
+		if !req.Method.Valid() {
+			return nil, errors.New("method must be one of: auto, linear, holt_winters")
+		}
+	}
+	if raw := query.Get("level"); raw != "" {
+		level, err := strconv.ParseFloat(raw, 64)
+		if err != nil || level <= 0 || level >= 1 {
+			return nil, errors.New("level must be a number between 0 and 1")
+		}
+		req.Level = level
+	}
+
+	var err error
+	if req.Step, err = utils.QueryDuration(r, "step", defaultStep); err != nil {
+		return nil, err
+	}
+	if req.History, err = utils.QueryDuration(r, "history", defaultHistory); err != nil {
+		return nil, err
+	}
+	if req.Horizon, err = utils.QueryDuration(r, "horizon", defaultHorizon); err != nil {
+		return nil, err
+	}
+	if req.Season, err = utils.QueryDuration(r, "season", defaultSeason); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.History/req.Step > maxHistoryPoints:
+		return nil, errors.New("history is too long for the requested step")
+	case req.Horizon < req.Step:
+		return nil, errors.New("horizon must be at least one step")
+	case req.Horizon/req.Step > maxHorizonPoints:
+		return nil, errors.New("horizon is too long for the requested step")
+	case req.Method == MethodHoltWinters && req.Season/req.Step < 2:
+		return nil, errors.New("season must span at least two steps")
+	}
+	return req, nil
+}
@@ -0,0 +1,191 @@
+package forecast
+
+import (
+	"fmt"
+	"math"
+)
+
+// Method модель прогноза
+type Method string
+
+const (
+	// MethodLinear линейный тренд методом наименьших квадратов
+	MethodLinear Method = "linear"
+	// MethodHoltWinters аддитивная модель Холта-Винтерса с трендом и сезонностью
+	MethodHoltWinters Method = "holt_winters"
+	// MethodAuto Холт-Винтерс, если истории хватает на два сезона, иначе линейный тренд
+	MethodAuto Method = "auto"
+)
+
+// Valid сообщает, поддерживается ли модель
+func (m Method) Valid() bool {
+	return m == MethodLinear || m == MethodHoltWinters || m == MethodAuto
+}
+
+type (
+	// Estimate точечный прогноз на шаг вперёд и границы интервала
+	Estimate struct {
+		Value float64
+		Lower float64
+		Upper float64
+	}
+
+	// Fit результат подбора модели
+	Fit struct {
+		Method Method
+		// Params параметры модели: коэффициенты тренда или сглаживания
+		Params map[string]float64
+		// RMSE среднеквадратичная ошибка модели на истории
+		RMSE      float64
+		Estimates []Estimate
+	}
+)
+
+// minLinearPoints наименьшая история для линейного тренда
+const minLinearPoints = 3
+
+// hwGrid значения параметров сглаживания, среди которых подбирается лучшая комбинация
+var hwGrid = []float64{0.05, 0.1, 0.2, 0.3, 0.5, 0.7, 0.9}
+
+// Linear строит прогноз линейного тренда на horizon шагов вперёд.
+// Интервал учитывает и шум вокруг тренда, и неопределённость оценки самого тренда.
+func Linear(values []float64, horizon int, level float64) (*Fit, error) {
+	n := len(values)
+	if n < minLinearPoints {
+		return nil, fmt.Errorf("linear trend needs at least %d points, got %d", minLinearPoints, n)
+	}
+
+	var sumX, sumY float64
+	for i, v := range values {
+		sumX += float64(i)
+		sumY += v
+	}
+	meanX, meanY := sumX/float64(n), sumY/float64(n)
+
+	var sxx, sxy float64
+	for i, v := range values {
+		dx := float64(i) - meanX
+		sxx += dx * dx
+		sxy += dx * (v - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanX
+
+	var sse float64
+	for i, v := range values {
+		r := v - (intercept + slope*float64(i))
+		sse += r * r
+	}
+	sigma := math.Sqrt(sse / float64(n-2))
+	z := normalQuantile(level)
+
+	fit := &Fit{
+		Method:    MethodLinear,
+		Params:    map[string]float64{"intercept": intercept, "slope": slope},
+		RMSE:      math.Sqrt(sse / float64(n)),
+		Estimates: make([]Estimate, horizon),
+	}
+	for h := 1; h <= horizon; h++ {
+		x := float64(n - 1 + h)
+		value := intercept + slope*x
+		margin := z * sigma * math.Sqrt(1+1/float64(n)+(x-meanX)*(x-meanX)/sxx)
+		fit.Estimates[h-1] = Estimate{Value: value, Lower: value - margin, Upper: value + margin}
+	}
+	return fit, nil
+}
+
+// HoltWinters строит прогноз аддитивной моделью Холта-Винтерса с периодом season шагов.
+// Параметры сглаживания подбираются перебором по сетке по минимуму ошибки прогноза на шаг вперёд.
+func HoltWinters(values []float64, season, horizon int, level float64) (*Fit, error) {
+	if season < 2 {
+		return nil, fmt.Errorf("season must be at least 2 steps")
+	}
+	if len(values) < 2*season {
+		return nil, fmt.Errorf("holt-winters needs at least two seasons (%d points), got %d", 2*season, len(values))
+	}
+
+	var best *hwState
+	for _, alpha := range hwGrid {
+		for _, beta := range hwGrid {
+			for _, gamma := range hwGrid {
+				state := runHoltWinters(values, season, alpha, beta, gamma)
+				if best == nil || state.sse < best.sse {
+					best = state
+				}
+			}
+		}
+	}
+
+	// Ошибки первого сезона уходят на инициализацию и не учитываются
+	fitted := len(values) - season
+	sigma := math.Sqrt(best.sse / float64(fitted))
+	z := normalQuantile(level)
+
+	fit := &Fit{
+		Method:    MethodHoltWinters,
+		Params:    map[string]float64{"alpha": best.alpha, "beta": best.beta, "gamma": best.gamma, "season": float64(season)},
+		RMSE:      sigma,
+		Estimates: make([]Estimate, horizon),
+	}
+	// Дисперсия ошибки на h шагов: σ²(1 + Σ c_j²), c_j = α(1 + jβ) + γ·[j кратно сезону]
+	variance := 1.0
+	for h := 1; h <= horizon; h++ {
+		if h > 1 {
+			j := h - 1
+			c := best.alpha * (1 + float64(j)*best.beta)
+			if j%season == 0 {
+				c += best.gamma
+			}
+			variance += c * c
+		}
+		index := (len(values) + h - 1) % season
+		value := best.level + float64(h)*best.trend + best.seasonal[index]
+		margin := z * sigma * math.Sqrt(variance)
+		fit.Estimates[h-1] = Estimate{Value: value, Lower: value - margin, Upper: value + margin}
+	}
+	return fit, nil
+}
+
+type hwState struct {
+	alpha, beta, gamma float64
+	level, trend       float64
+	// seasonal сезонные поправки; индекс — номер шага по модулю сезона
+	seasonal []float64
+	sse      float64
+}
+
+func runHoltWinters(values []float64, season int, alpha, beta, gamma float64) *hwState {
+	s := &hwState{alpha: alpha, beta: beta, gamma: gamma, seasonal: make([]float64, season)}
+
+	// Начальные уровень и тренд — по средним двух первых сезонов, сезонность — отклонения первого сезона
+	var first, second float64
+	for i := 0; i < season; i++ {
+		first += values[i]
+		second += values[season+i]
+	}
+	first /= float64(season)
+	second /= float64(season)
+	s.trend = (second - first) / float64(season)
+	s.level = first + s.trend*float64(season-1)/2
+	for i := 0; i < season; i++ {
+		s.seasonal[i] = values[i] - (first + s.trend*(float64(i)-float64(season-1)/2))
+	}
+
+	for t := season; t < len(values); t++ {
+		idx := t % season
+		predicted := s.level + s.trend + s.seasonal[idx]
+		err := values[t] - predicted
+		s.sse += err * err
+
+		prevLevel := s.level
+		s.level = alpha*(values[t]-s.seasonal[idx]) + (1-alpha)*(s.level+s.trend)
+		s.trend = beta*(s.level-prevLevel) + (1-beta)*s.trend
+		s.seasonal[idx] = gamma*(values[t]-s.level) + (1-gamma)*s.seasonal[idx]
+	}
+	return s
+}
+
+// normalQuantile двусторонний квантиль стандартного нормального распределения для уровня доверия level
+func normalQuantile(level float64) float64 {
+	return math.Sqrt2 * math.Erfinv(level)
+}
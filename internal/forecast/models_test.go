@@ -0,0 +1,179 @@
+package forecast
+
+import (
+	"math"
+	"testing"
+)
+
+const tolerance = 1e-9
+
+func near(got, want float64) bool {
+	return math.Abs(got-want) <= tolerance*math.Max(1, math.Abs(want))
+}
+
+func checkEstimates(t *testing.T, got, want []Estimate) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d estimates, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !near(got[i].Value, want[i].Value) || !near(got[i].Lower, want[i].Lower) || !near(got[i].Upper, want[i].Upper) {
+			t.Errorf("estimate %d = %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+// TestNormalQuantile проверяет двусторонние квантили нормального распределения для типичных уровней
+func TestNormalQuantile(t *testing.T) {
+	for level, want := range map[float64]float64{0.8: 1.2815515655, 0.95: 1.9599639845, 0.99: 2.5758293035} {
+		if got := normalQuantile(level); math.Abs(got-want) > 1e-9 {
+			t.Errorf("normalQuantile(%v) = %v, want %v", level, got, want)
+		}
+	}
+}
+
+// TestLinear сверяет тренд, ошибку и интервалы с расчётом вручную на фиксированных рядах
+func TestLinear(t *testing.T) {
+	z := normalQuantile(0.95)
+	// Ряд 2, 4, 5, 4, 5: наклон 0.6, сдвиг 2.8, SSE 2.4, σ² = 2.4/3 = 0.8, Sxx = 10, среднее x = 2
+	margin := func(x float64) float64 { return z * math.Sqrt(0.8*(1+0.2+(x-2)*(x-2)/10)) }
+
+	tests := []struct {
+		name             string
+		values           []float64
+		slope, intercept float64
+		rmse             float64
+		want             []Estimate
+	}{
+		{
+			name:   "exact line",
+			values: []float64{1, 3, 5, 7, 9},
+			slope:  2, intercept: 1,
+			want: []Estimate{{11, 11, 11}, {13, 13, 13}, {15, 15, 15}},
+		},
+		{
+			name:   "flat",
+			values: []float64{4, 4, 4},
+			slope:  0, intercept: 4,
+			want: []Estimate{{4, 4, 4}, {4, 4, 4}, {4, 4, 4}},
+		},
+		{
+			name:   "noisy",
+			values: []float64{2, 4, 5, 4, 5},
+			slope:  0.6, intercept: 2.8,
+			rmse: math.Sqrt(2.4 / 5),
+			want: []Estimate{
+				{5.8, 5.8 - margin(5), 5.8 + margin(5)},
+				{6.4, 6.4 - margin(6), 6.4 + margin(6)},
+				{7.0, 7.0 - margin(7), 7.0 + margin(7)},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit, err := Linear(tt.values, 3, 0.95)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fit.Method != MethodLinear || !near(fit.Params["slope"], tt.slope) || !near(fit.Params["intercept"], tt.intercept) {
+				t.Errorf("fit %s, params %v; want slope %v, intercept %v", fit.Method, fit.Params, tt.slope, tt.intercept)
+			}
+			if !near(fit.RMSE, tt.rmse) {
+				t.Errorf("rmse = %v, want %v", fit.RMSE, tt.rmse)
+			}
+			checkEstimates(t, fit.Estimates, tt.want)
+		})
+	}
+}
+
+// TestLinearTooShort проверяет, что тренд не строится меньше чем по трём точкам
+func TestLinearTooShort(t *testing.T) {
+	if _, err := Linear([]float64{1, 2}, 1, 0.95); err == nil {
+		t.Fatal("linear fit on two points succeeded")
+	}
+}
+
+// TestRunHoltWinters сверяет один проход сглаживания с расчётом вручную при α = β = γ = 0.5
+func TestRunHoltWinters(t *testing.T) {
+	// Начало: средние сезонов 2 и 4, тренд 1, уровень 2.5, сезонность [-0.5, 0.5].
+	// Шаги 2 и 3 предсказаны точно, на шагах 4 и 5 ошибки -1 и 1.75.
+	s := runHoltWinters([]float64{1, 3, 3, 5, 4, 8}, 2, 0.5, 0.5, 0.5)
+
+	if !near(s.level, 6.625) || !near(s.trend, 1.1875) || !near(s.sse, 4.0625) {
+		t.Errorf("level %v, trend %v, sse %v; want 6.625, 1.1875, 4.0625", s.level, s.trend, s.sse)
+	}
+	if !near(s.seasonal[0], -0.75) || !near(s.seasonal[1], 0.9375) {
+		t.Errorf("seasonal %v, want [-0.75 0.9375]", s.seasonal)
+	}
+}
+
+// TestHoltWintersExactSeries проверяет, что ряд из тренда и сезонности продолжается без ошибки
+// и с интервалом нулевой ширины
+func TestHoltWintersExactSeries(t *testing.T) {
+	pattern := []float64{3, -1, -4, 2}
+	series := func(from, to int) []float64 {
+		values := make([]float64, 0, to-from)
+		for i := from; i < to; i++ {
+			values = append(values, 100+0.5*float64(i)+pattern[i%len(pattern)])
+		}
+		return values
+	}
+
+	fit, err := HoltWinters(series(0, 12), len(pattern), 6, 0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fit.Method != MethodHoltWinters || fit.Params["season"] != 4 || fit.RMSE > tolerance {
+		t.Errorf("fit %s, params %v, rmse %v; want season 4 and zero error", fit.Method, fit.Params, fit.RMSE)
+	}
+
+	want := make([]Estimate, 0, 6)
+	for _, v := range series(12, 18) {
+		want = append(want, Estimate{v, v, v})
+	}
+	checkEstimates(t, fit.Estimates, want)
+}
+
+// TestHoltWintersIntervalsWiden проверяет, что на зашумлённом ряду интервал расширяется с горизонтом
+// и содержит точечный прогноз
+func TestHoltWintersIntervalsWiden(t *testing.T) {
+	values := []float64{10, 14, 9, 5, 11, 16, 10, 4, 12, 15, 12, 6, 13, 17, 11, 7}
+	fit, err := HoltWinters(values, 4, 8, 0.9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fit.RMSE <= 0 {
+		t.Fatalf("rmse = %v, want positive on a noisy series", fit.RMSE)
+	}
+
+	prev := 0.0
+	for i, e := range fit.Estimates {
+		width := e.Upper - e.Lower
+		if e.Lower > e.Value || e.Upper < e.Value || width < prev {
+			t.Errorf("estimate %d = %+v: width %v after %v", i+1, e, width, prev)
+		}
+		prev = width
+	}
+	if first := fit.Estimates[0]; !near(first.Upper-first.Value, normalQuantile(0.9)*fit.RMSE) {
+		t.Errorf("one-step margin = %v, want z·rmse = %v", first.Upper-first.Value, normalQuantile(0.9)*fit.RMSE)
+	}
+}
+
+// TestHoltWintersErrors проверяет отказ при коротком сезоне и истории меньше двух сезонов
+func TestHoltWintersErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		season int
+	}{
+		{"season too short", []float64{1, 2, 3, 4}, 1},
+		{"one season", []float64{1, 2, 3, 4, 5}, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := HoltWinters(tt.values, tt.season, 1, 0.95); err == nil {
+				t.Fatal("fit succeeded")
+			}
+		})
+	}
+}
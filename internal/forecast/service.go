@@ -0,0 +1,124 @@
+package forecast
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"stats-of/internal/timeseries"
+)
+
+type (
+	// Request параметры прогноза ряда
+	Request struct {
+		Series string
+		Method Method
+		// Step шаг истории и прогноза, History — глубина истории, Horizon — дальность прогноза
+		Step    time.Duration
+		History time.Duration
+		Horizon time.Duration
+		// Season период сезонности для Холта-Винтерса
+		Season time.Duration
+		// Level уровень доверия интервалов, например 0.95
+		Level float64
+	}
+
+	// Point прогноз на момент Time с доверительным интервалом
+	Point struct {
+		Time  time.Time `json:"time"`
+		Value float64   `json:"value"`
+		Lower float64   `json:"lower"`
+		Upper float64   `json:"upper"`
+	}
+
+	// Result ответ API
+	Result struct {
+		Series  string             `json:"series"`
+		Method  Method             `json:"method"`
+		Step    string             `json:"step"`
+		Level   float64            `json:"level"`
+		Params  map[string]float64 `json:"params"`
+		RMSE    float64            `json:"rmse"`
+		History int                `json:"history_points"`
+		// LastValue последнее наблюдённое значение, от которого строится прогноз
+		LastValue float64 `json:"last_value"`
+		Points    []Point `json:"points"`
+	}
+
+	// Service строит прогнозы по сохранённым временным рядам
+	Service struct {
+		series *timeseries.Service
+	}
+)
+
+// ErrNotEnoughData истории ряда недостаточно для выбранной модели
+var ErrNotEnoughData = errors.New("not enough history to build forecast")
+
+func NewService(series *timeseries.Service) *Service {
+	return &Service{series: series}
+}
+
+// Forecast строит прогноз ряда от последнего завершённого шага.
+// Значения ряда — число участников или пользователей, поэтому прогноз и границы не опускаются ниже нуля.
+func (s *Service) Forecast(ctx context.Context, req Request) (*Result, error) {
+	to := time.Now().UTC().Truncate(req.Step)
+	history, err := s.series.Query(ctx, req.Series, to.Add(-req.History), to, req.Step)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]float64, len(history.Points))
+	for i, p := range history.Points {
+		values[i] = p.Last
+	}
+	if len(values) < minLinearPoints {
+		return nil, ErrNotEnoughData
+	}
+
+	horizon := int(req.Horizon / req.Step)
+	season := int(req.Season / req.Step)
+	method := req.Method
+	if method == MethodAuto {
+		method = MethodLinear
+		if season >= 2 && len(values) >= 2*season {
+			method = MethodHoltWinters
+		}
+	}
+
+	var fit *Fit
+	switch method {
+	case MethodHoltWinters:
+		if len(values) < 2*season {
+			return nil, ErrNotEnoughData
+		}
+		fit, err = HoltWinters(values, season, horizon, req.Level)
+	default:
+		fit, err = Linear(values, horizon, req.Level)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	last := history.Points[len(history.Points)-1]
+	result := &Result{
+		Series:    req.Series,
+		Method:    fit.Method,
+		Step:      req.Step.String(),
+		Level:     req.Level,
+		Params:    fit.Params,
+		RMSE:      fit.RMSE,
+		History:   len(values),
+		LastValue: last.Last,
+		Points:    make([]Point, len(fit.Estimates)),
+	}
+	for i, e := range fit.Estimates {
+		result.Points[i] = Point{
+			Time:  last.Time.Add(time.Duration(i+1) * req.Step),
+			Value: math.Max(e.Value, 0),
+			Lower: math.Max(e.Lower, 0),
+			Upper: math.Max(e.Upper, 0),
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"stats-of/internal/utils"
+)
+
+// maxRequestBodySize наибольший размер тела запроса GraphQL
+const maxRequestBodySize = 1 << 20
+
+// MakeHandler обработчик /graphql. POST принимает JSON {"query", "operationName", "variables"},
+// GET — те же поля в параметрах строки запроса, variables в виде JSON. Ответ всегда 200 с полями
+// data и errors, кроме неразбираемого тела или параметров — тогда 400.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if r.Method == http.MethodGet {
+			values := r.URL.Query()
+			req.Query = values.Get("query")
+			req.OperationName = values.Get("operationName")
+			if raw := values.Get("variables"); raw != "" {
+				if err := json.Unmarshal([]byte(raw), &req.Variables); err != nil {
+					utils.RespondWith400(w, "invalid variables: "+err.Error())
+					return
+				}
+			}
+		} else {
+			decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBodySize))
+			if err := decoder.Decode(&req); err != nil {
+				utils.RespondWith400(w, "invalid GraphQL request body: "+err.Error())
+				return
+			}
+		}
+		if req.Query == "" {
+			utils.RespondWith400(w, "query is required")
+			return
+		}
+
+		utils.SuccessRespondWith200(w, service.Execute(r.Context(), req))
+	}
+}
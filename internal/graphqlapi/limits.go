@@ -0,0 +1,198 @@
+package graphqlapi
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// maxComplexity насыщение оценки стоимости, чтобы огромные first не переполняли int
+const maxComplexity = math.MaxInt32
+
+type (
+	// Limits ограничения запроса, проверяемые до выполнения
+	Limits struct {
+		// MaxDepth наибольшая вложенность полей
+		MaxDepth int
+		// MaxComplexity наибольшая стоимость: каждое поле стоит единицу, а стоимость выборки
+		// внутри поля со списком умножается на его аргумент first
+		MaxComplexity int
+	}
+
+	// cost оценка запроса
+	cost struct {
+		depth      int
+		complexity int
+	}
+
+	// measurer обходит выбранную операцию, подставляя фрагменты и значения переменных
+	measurer struct {
+		schema    *graphql.Schema
+		fragments map[string]*ast.FragmentDefinition
+		variables map[string]interface{}
+	}
+)
+
+// measure оценивает операцию operationName документа. Служебные поля интроспекции (__schema,
+// __type, __typename) не учитываются: они читают только схему. Если операция не найдена,
+// возвращается нулевая оценка — ошибку сообщит исполнитель.
+func measure(schema *graphql.Schema, doc *ast.Document, operationName string, variables map[string]interface{}) cost {
+	m := &measurer{
+		schema:    schema,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		variables: make(map[string]interface{}, len(variables)),
+	}
+
+	var operation *ast.OperationDefinition
+	operations := 0
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.FragmentDefinition:
+			m.fragments[def.Name.Value] = def
+		case *ast.OperationDefinition:
+			operations++
+			if operationName == "" || (def.Name != nil && def.Name.Value == operationName) {
+				operation = def
+			}
+		}
+	}
+	if operation == nil || (operationName == "" && operations > 1) {
+		return cost{}
+	}
+
+	for _, def := range operation.VariableDefinitions {
+		if def.DefaultValue != nil {
+			m.variables[def.Variable.Name.Value] = def.DefaultValue.GetValue()
+		}
+	}
+	for name, value := range variables {
+		m.variables[name] = value
+	}
+
+	var root *graphql.Object
+	switch operation.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+	if root == nil {
+		return cost{}
+	}
+	return m.selections(root, operation.SelectionSet, make(map[string]bool))
+}
+
+// selections оценивает выборку из объекта parent. visiting защищает от циклов фрагментов,
+// которые иначе отклонит только валидация.
+func (m *measurer) selections(parent *graphql.Object, set *ast.SelectionSet, visiting map[string]bool) cost {
+	var total cost
+	if set == nil {
+		return total
+	}
+	for _, selection := range set.Selections {
+		var c cost
+		switch selection := selection.(type) {
+		case *ast.Field:
+			c = m.field(parent, selection, visiting)
+		case *ast.InlineFragment:
+			c = m.selections(m.fragmentType(parent, selection.TypeCondition), selection.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := m.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			c = m.selections(m.fragmentType(parent, fragment.TypeCondition), fragment.SelectionSet, visiting)
+			delete(visiting, name)
+		}
+		total.depth = max(total.depth, c.depth)
+		total.complexity = min(total.complexity+c.complexity, maxComplexity)
+	}
+	return total
+}
+
+func (m *measurer) field(parent *graphql.Object, field *ast.Field, visiting map[string]bool) cost {
+	if strings.HasPrefix(field.Name.Value, "__") || parent == nil {
+		return cost{}
+	}
+	def, ok := parent.Fields()[field.Name.Value]
+	if !ok {
+		return cost{}
+	}
+
+	child, _ := unwrap(def.Type).(*graphql.Object)
+	inner := m.selections(child, field.SelectionSet, visiting)
+	return cost{
+		depth:      inner.depth + 1,
+		complexity: min(1+m.multiplier(def, field)*inner.complexity, maxComplexity),
+	}
+}
+
+// multiplier сколько раз выполняется выборка внутри поля: аргумент first или его значение по умолчанию
+func (m *measurer) multiplier(def *graphql.FieldDefinition, field *ast.Field) int {
+	first := 1
+	for _, arg := range def.Args {
+		if arg.Name() == "first" {
+			if n, ok := arg.DefaultValue.(int); ok {
+				first = n
+			}
+		}
+	}
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		value := arg.Value.GetValue()
+		if variable, ok := arg.Value.(*ast.Variable); ok {
+			value = m.variables[variable.Name.Value]
+		}
+		if n, ok := toInt(value); ok {
+			first = n
+		}
+	}
+	return min(max(first, 1), maxComplexity)
+}
+
+// fragmentType объект, к которому относится фрагмент; без условия типа — объект родителя
+func (m *measurer) fragmentType(parent *graphql.Object, condition *ast.Named) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+	object, _ := m.schema.Type(condition.Name.Value).(*graphql.Object)
+	return object
+}
+
+func unwrap(t graphql.Type) graphql.Type {
+	for {
+		switch wrapped := t.(type) {
+		case *graphql.NonNull:
+			t = wrapped.OfType
+		case *graphql.List:
+			t = wrapped.OfType
+		default:
+			return t
+		}
+	}
+}
+
+// toInt значение first из литерала запроса (строка цифр), переменной из JSON (float64) или значения по умолчанию
+func toInt(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, true
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	case ast.Value:
+		return toInt(v.GetValue())
+	}
+	return 0, false
+}
@@ -0,0 +1,88 @@
+package graphqlapi
+
+import (
+	"context"
+	"sync"
+)
+
+type (
+	// batchFunc загружает значения сразу для нескольких ключей; ключей, которых нет в хранилище,
+	// нет и в результате
+	batchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+	// loader откладывает чтения до первого обращения к результату и выполняет их одним запросом.
+	// Исполнитель graphql-go вызывает отложенные значения по уровням дерева ответа, поэтому
+	// все ключи одного уровня попадают в один пакет. Результаты кешируются на время запроса.
+	loader[K comparable, V any] struct {
+		mu      sync.Mutex
+		batch   batchFunc[K, V]
+		pending []K
+		results map[K]*loaded[V]
+	}
+
+	loaded[V any] struct {
+		done  bool
+		found bool
+		value V
+		err   error
+	}
+)
+
+func newLoader[K comparable, V any](batch batchFunc[K, V]) *loader[K, V] {
+	return &loader[K, V]{batch: batch, results: make(map[K]*loaded[V])}
+}
+
+// load ставит ключ в очередь и возвращает функцию, которая при первом вызове загружает
+// всю накопленную очередь. found ложно, если значения с таким ключом нет.
+func (l *loader[K, V]) load(ctx context.Context, key K) func() (V, bool, error) {
+	l.mu.Lock()
+	if _, ok := l.results[key]; !ok {
+		l.results[key] = &loaded[V]{}
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, bool, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		result := l.results[key]
+		if !result.done {
+			l.dispatch(ctx)
+		}
+		return result.value, result.found, result.err
+	}
+}
+
+// prime кладёт в кеш значение, уже прочитанное другим загрузчиком
+func (l *loader[K, V]) prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if result, ok := l.results[key]; ok && result.done {
+		return
+	}
+	l.results[key] = &loaded[V]{done: true, found: true, value: value}
+}
+
+// dispatch выполняет пакетную загрузку всех ключей из очереди; вызывается под l.mu
+func (l *loader[K, V]) dispatch(ctx context.Context) {
+	keys := make([]K, 0, len(l.pending))
+	for _, key := range l.pending {
+		if !l.results[key].done {
+			keys = append(keys, key)
+		}
+	}
+	l.pending = nil
+	if len(keys) == 0 {
+		return
+	}
+
+	values, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		result := l.results[key]
+		result.done = true
+		result.err = err
+		result.value, result.found = values[key]
+	}
+}
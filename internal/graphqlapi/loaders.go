@@ -0,0 +1,135 @@
+package graphqlapi
+
+import (
+	"context"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/storage"
+)
+
+type (
+	// loaders загрузчики одного запроса. Участники чата и чаты пользователя читаются вместе
+	// с записями: два запроса к хранилищу на весь уровень дерева ответа, а прочитанные записи
+	// попадают в кеш загрузчиков chats и users.
+	loaders struct {
+		chats     *loader[entities.ChatID, *entities.Chat]
+		users     *loader[entities.UserID, *entities.User]
+		members   *loader[entities.ChatID, relation[entities.User]]
+		userChats *loader[entities.UserID, relation[entities.Chat]]
+	}
+
+	// relation участники чата или чаты пользователя. У связи больше maxRelationSize записи
+	// не читаются, известен только размер.
+	relation[V any] struct {
+		records []*V
+		size    int
+	}
+
+	loadersKey struct{}
+)
+
+func newLoaders(repo *storage.Repository) *loaders {
+	l := &loaders{
+		chats: newLoader(repo.Chats),
+		users: newLoader(repo.Users),
+	}
+	l.members = newLoader(func(ctx context.Context, ids []entities.ChatID) (map[entities.ChatID]relation[entities.User], error) {
+		memberIDs, err := repo.ChatUserIDsMany(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		oversized := dropOversized(memberIDs)
+		users, err := repo.Users(ctx, union(memberIDs))
+		if err != nil {
+			return nil, err
+		}
+		for id, user := range users {
+			l.users.prime(id, user)
+		}
+		return relations(resolveIDs(memberIDs, users), oversized), nil
+	})
+	l.userChats = newLoader(func(ctx context.Context, ids []entities.UserID) (map[entities.UserID]relation[entities.Chat], error) {
+		chatIDs, err := repo.UserChatIDsMany(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		oversized := dropOversized(chatIDs)
+		chats, err := repo.Chats(ctx, union(chatIDs))
+		if err != nil {
+			return nil, err
+		}
+		for id, chat := range chats {
+			l.chats.prime(id, chat)
+		}
+		return relations(resolveIDs(chatIDs, chats), oversized), nil
+	})
+	return l
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// oversized связь больше maxRelationSize, записи которой не читались
+func (r relation[V]) oversized() bool {
+	return r.size > maxRelationSize
+}
+
+// dropOversized убирает из lists связи больше maxRelationSize и возвращает их размеры:
+// счётчик, по которому резолвер отсёк большие связи заранее, мог разойтись с множеством
+func dropOversized[K comparable, L ~[]T, T any](lists map[K]L) map[K]int {
+	oversized := make(map[K]int)
+	for key, list := range lists {
+		if len(list) > maxRelationSize {
+			oversized[key] = len(list)
+			delete(lists, key)
+		}
+	}
+	return oversized
+}
+
+// relations собирает связи из прочитанных записей и размеров больших связей
+func relations[K comparable, V any](resolved map[K][]*V, oversized map[K]int) map[K]relation[V] {
+	result := make(map[K]relation[V], len(resolved)+len(oversized))
+	for key, records := range resolved {
+		result[key] = relation[V]{records: records, size: len(records)}
+	}
+	for key, size := range oversized {
+		result[key] = relation[V]{size: size}
+	}
+	return result
+}
+
+// union объединяет списки идентификаторов без повторов
+func union[K comparable, L ~[]T, T comparable](lists map[K]L) []T {
+	seen := make(map[T]struct{})
+	var result []T
+	for _, list := range lists {
+		for _, id := range list {
+			if _, ok := seen[id]; !ok {
+				seen[id] = struct{}{}
+				result = append(result, id)
+			}
+		}
+	}
+	return result
+}
+
+// resolveIDs заменяет идентификаторы записями, пропуская удалённые между чтением множества и записи
+func resolveIDs[K comparable, L ~[]T, T comparable, V any](lists map[K]L, records map[T]*V) map[K][]*V {
+	result := make(map[K][]*V, len(lists))
+	for key, list := range lists {
+		resolved := make([]*V, 0, len(list))
+		for _, id := range list {
+			if record, ok := records[id]; ok {
+				resolved = append(resolved, record)
+			}
+		}
+		result[key] = resolved
+	}
+	return result
+}
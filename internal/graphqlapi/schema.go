@@ -0,0 +1,403 @@
+package graphqlapi
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"stats-of/internal/directory"
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/query"
+
+	"github.com/graphql-go/graphql"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultPageLimit и maxPageLimit размер страниц списков chats и users, как в HTTP API
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+
+	// defaultRelationLimit и maxRelationLimit сколько участников чата или чатов пользователя
+	// возвращается во вложенных полях members и chats
+	defaultRelationLimit = 10
+	maxRelationLimit     = 100
+
+	// maxRelationSize наибольший чат или пользователь, для которого поля members и chats читают
+	// все записи связи и сортируют их: на большем чате одно поле стоило бы миллиона чтений
+	// при first в несколько записей. Такие чаты листаются через GET /api/v1/chats/{id}/users.
+	maxRelationSize = 1000
+
+	orderCountOfChats = "count_of_chats"
+	orderCountOfUsers = "count_of_users"
+	orderLastTime     = "last_time"
+	orderUserID       = "user_id"
+	orderChatID       = "chat_id"
+)
+
+// errInternal отдаётся клиенту вместо ошибок хранилища; подробности пишутся в журнал
+var errInternal = errors.New("internal error")
+
+// page страница списка верхнего уровня
+type page[T any] struct {
+	nodes []T
+	next  string
+}
+
+func (s *Service) newSchema() (graphql.Schema, error) {
+	memberOrder := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "MemberOrder",
+		Description: "Порядок участников чата",
+		Values: graphql.EnumValueConfigMap{
+			"COUNT_OF_CHATS": {Value: orderCountOfChats, Description: "по числу чатов пользователя, по убыванию"},
+			"LAST_TIME":      {Value: orderLastTime, Description: "по времени последней активности, сначала недавние"},
+			"USER_ID":        {Value: orderUserID, Description: "по идентификатору"},
+		},
+	})
+	chatOrder := graphql.NewEnum(graphql.EnumConfig{
+		Name:        "ChatOrder",
+		Description: "Порядок чатов пользователя",
+		Values: graphql.EnumValueConfigMap{
+			"COUNT_OF_USERS": {Value: orderCountOfUsers, Description: "по числу участников, по убыванию"},
+			"CHAT_ID":        {Value: orderChatID, Description: "по идентификатору"},
+		},
+	})
+
+	chatType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Chat",
+		Description: "Чат и число его участников",
+		Fields: graphql.Fields{
+			"id": {
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return strconv.FormatInt(int64(p.Source.(*entities.Chat).ChatID), 10), nil
+				},
+			},
+			"chatType": {
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*entities.Chat).ChatType, nil
+				},
+			},
+			"countOfUsers": {
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*entities.Chat).CountOfUsers, nil
+				},
+			},
+		},
+	})
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "User",
+		Description: "Пользователь, время его последней активности и число его чатов",
+		Fields: graphql.Fields{
+			"id": {
+				Type: graphql.NewNonNull(graphql.ID),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return strconv.FormatInt(int64(p.Source.(*entities.User).UserID), 10), nil
+				},
+			},
+			"lastTime": {
+				Type: graphql.DateTime,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					user := p.Source.(*entities.User)
+					if user.LastTime.IsZero() {
+						return nil, nil
+					}
+					return user.LastTime, nil
+				},
+			},
+			"countOfChats": {
+				Type: graphql.NewNonNull(graphql.Int),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*entities.User).CountOfChats, nil
+				},
+			},
+		},
+	})
+
+	// Связи добавляются после создания обоих типов, потому что ссылаются друг на друга
+	chatType.AddFieldConfig("members", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(userType))),
+		Description: "Участники чата; для чата больше чем из 1000 участников поле возвращает ошибку",
+		Args: graphql.FieldConfigArgument{
+			"first":   {Type: graphql.Int, DefaultValue: defaultRelationLimit},
+			"orderBy": {Type: memberOrder, DefaultValue: orderCountOfChats},
+		},
+		Resolve: resolveMembers,
+	})
+	userType.AddFieldConfig("chats", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(chatType))),
+		Description: "Чаты, в которых состоит пользователь; для пользователя больше чем в 1000 чатах поле возвращает ошибку",
+		Args: graphql.FieldConfigArgument{
+			"first":   {Type: graphql.Int, DefaultValue: defaultRelationLimit},
+			"orderBy": {Type: chatOrder, DefaultValue: orderCountOfUsers},
+		},
+		Resolve: resolveUserChats,
+	})
+
+	listArgs := graphql.FieldConfigArgument{
+		"filter": {Type: graphql.String, Description: "выражение языка запросов, как в GET /api/v1/chats"},
+		"sort":   {Type: graphql.String},
+		"first":  {Type: graphql.Int, DefaultValue: defaultPageLimit},
+		"after":  {Type: graphql.String, Description: "nextCursor предыдущей страницы"},
+	}
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"chat": {
+				Type: chatType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return resolveOne(p, loadersFrom(p.Context).chats.load(p.Context, entities.ChatID(id))), nil
+				},
+			},
+			"user": {
+				Type: userType,
+				Args: graphql.FieldConfigArgument{"id": {Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					id, err := parseID(p.Args["id"])
+					if err != nil {
+						return nil, err
+					}
+					return resolveOne(p, loadersFrom(p.Context).users.load(p.Context, entities.UserID(id))), nil
+				},
+			},
+			"chats": {
+				Type:    graphql.NewNonNull(pageType[*entities.Chat]("ChatPage", chatType)),
+				Args:    listArgs,
+				Resolve: s.resolveChats,
+			},
+			"users": {
+				Type:    graphql.NewNonNull(pageType[*entities.User]("UserPage", userType)),
+				Args:    listArgs,
+				Resolve: s.resolveUsers,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// pageType тип страницы списка верхнего уровня
+func pageType[T any](name string, node graphql.Type) *graphql.Object {
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name: name,
+		Fields: graphql.Fields{
+			"nodes": {
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(node))),
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					return p.Source.(*page[T]).nodes, nil
+				},
+			},
+			"nextCursor": {
+				Type:        graphql.String,
+				Description: "курсор следующей страницы для аргумента after; null на последней странице",
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					if next := p.Source.(*page[T]).next; next != "" {
+						return next, nil
+					}
+					return nil, nil
+				},
+			},
+		},
+	})
+}
+
+// resolveOne превращает отложенную загрузку одной записи в значение поля; отсутствующая запись — null
+func resolveOne[V any](p graphql.ResolveParams, thunk func() (*V, bool, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		record, found, err := thunk()
+		if err != nil {
+			return nil, internalError(p, err)
+		}
+		if !found {
+			return nil, nil
+		}
+		return record, nil
+	}
+}
+
+func resolveMembers(p graphql.ResolveParams) (interface{}, error) {
+	first, err := limitArg(p, maxRelationLimit)
+	if err != nil {
+		return nil, err
+	}
+	order := p.Args["orderBy"].(string)
+	chat := p.Source.(*entities.Chat)
+	// Счётчик отсекает большие чаты до чтения множества; загрузчик перепроверяет настоящий размер
+	if chat.CountOfUsers > maxRelationSize {
+		return nil, errMembersTooLarge(chat.ChatID, chat.CountOfUsers)
+	}
+	thunk := loadersFrom(p.Context).members.load(p.Context, chat.ChatID)
+
+	return func() (interface{}, error) {
+		rel, _, err := thunk()
+		if err != nil {
+			return nil, internalError(p, err)
+		}
+		if rel.oversized() {
+			return nil, errMembersTooLarge(chat.ChatID, int64(rel.size))
+		}
+		members := slices.Clone(rel.records)
+		slices.SortFunc(members, func(a, b *entities.User) int {
+			switch order {
+			case orderCountOfChats:
+				if c := cmp.Compare(b.CountOfChats, a.CountOfChats); c != 0 {
+					return c
+				}
+			case orderLastTime:
+				if c := b.LastTime.Compare(a.LastTime); c != 0 {
+					return c
+				}
+			}
+			return cmp.Compare(a.UserID, b.UserID)
+		})
+		return members[:min(first, len(members))], nil
+	}, nil
+}
+
+func resolveUserChats(p graphql.ResolveParams) (interface{}, error) {
+	first, err := limitArg(p, maxRelationLimit)
+	if err != nil {
+		return nil, err
+	}
+	order := p.Args["orderBy"].(string)
+	user := p.Source.(*entities.User)
+	if user.CountOfChats > maxRelationSize {
+		return nil, errChatsTooLarge(user.UserID, user.CountOfChats)
+	}
+	thunk := loadersFrom(p.Context).userChats.load(p.Context, user.UserID)
+
+	return func() (interface{}, error) {
+		rel, _, err := thunk()
+		if err != nil {
+			return nil, internalError(p, err)
+		}
+		if rel.oversized() {
+			return nil, errChatsTooLarge(user.UserID, int64(rel.size))
+		}
+		chats := slices.Clone(rel.records)
+		slices.SortFunc(chats, func(a, b *entities.Chat) int {
+			if order == orderCountOfUsers {
+				if c := cmp.Compare(b.CountOfUsers, a.CountOfUsers); c != 0 {
+					return c
+				}
+			}
+			return cmp.Compare(a.ChatID, b.ChatID)
+		})
+		return chats[:min(first, len(chats))], nil
+	}, nil
+}
+
+func errMembersTooLarge(id entities.ChatID, size int64) error {
+	return fmt.Errorf("chat %d has %d members; members can be listed only for chats with at most %d members, "+
+		"use GET /api/v1/chats/%d/users instead", id, size, maxRelationSize, id)
+}
+
+func errChatsTooLarge(id entities.UserID, size int64) error {
+	return fmt.Errorf("user %d is in %d chats; chats can be listed only for users in at most %d chats, "+
+		"use GET /api/v1/users/%d/chats instead", id, size, maxRelationSize, id)
+}
+
+func (s *Service) resolveChats(p graphql.ResolveParams) (interface{}, error) {
+	q, scope, params, err := s.listQuery(p, "chats", query.ChatSchema)
+	if err != nil {
+		return nil, err
+	}
+	chats, next, err := s.directory.FindChats(p.Context, q, params)
+	if directory.IsQueryError(err) {
+		return nil, err
+	} else if err != nil {
+		return nil, internalError(p, err)
+	}
+
+	result := &page[*entities.Chat]{nodes: make([]*entities.Chat, len(chats))}
+	for i := range chats {
+		result.nodes[i] = &chats[i]
+		loadersFrom(p.Context).chats.prime(chats[i].ChatID, &chats[i])
+	}
+	if next != nil {
+		result.next = s.cursors.Encode(scope, *next)
+	}
+	return result, nil
+}
+
+func (s *Service) resolveUsers(p graphql.ResolveParams) (interface{}, error) {
+	q, scope, params, err := s.listQuery(p, "users", query.UserSchema)
+	if err != nil {
+		return nil, err
+	}
+	users, next, err := s.directory.FindUsers(p.Context, q, params)
+	if directory.IsQueryError(err) {
+		return nil, err
+	} else if err != nil {
+		return nil, internalError(p, err)
+	}
+
+	result := &page[*entities.User]{nodes: make([]*entities.User, len(users))}
+	for i := range users {
+		result.nodes[i] = &users[i]
+		loadersFrom(p.Context).users.prime(users[i].UserID, &users[i])
+	}
+	if next != nil {
+		result.next = s.cursors.Encode(scope, *next)
+	}
+	return result, nil
+}
+
+// listQuery разбирает аргументы списка верхнего уровня. Области курсоров совпадают с HTTP API.
+func (s *Service) listQuery(p graphql.ResolveParams, list string, schema *query.Schema) (*query.Query, string, pagination.Params, error) {
+	filter, _ := p.Args["filter"].(string)
+	sort, _ := p.Args["sort"].(string)
+	q, err := query.New(schema, filter, sort, time.Now().UTC())
+	if err != nil {
+		return nil, "", pagination.Params{}, fmt.Errorf("invalid filter or sort: %w", err)
+	}
+
+	limit, err := limitArg(p, maxPageLimit)
+	if err != nil {
+		return nil, "", pagination.Params{}, err
+	}
+	scope := directory.ListScope(list, filter, sort)
+	params := pagination.Params{Limit: limit}
+	if after, _ := p.Args["after"].(string); after != "" {
+		if params.Cursor, err = s.cursors.Decode(scope, after); err != nil {
+			return nil, "", pagination.Params{}, err
+		}
+	}
+	return q, scope, params, nil
+}
+
+// limitArg значение аргумента first
+func limitArg(p graphql.ResolveParams, max int) (int, error) {
+	first, _ := p.Args["first"].(int)
+	if first < 0 || first > max {
+		return 0, fmt.Errorf("first must be between 0 and %d", max)
+	}
+	return first, nil
+}
+
+func parseID(value interface{}) (int64, error) {
+	raw, _ := value.(string)
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("id must be an integer, got %q", raw)
+	}
+	return id, nil
+}
+
+// internalError пишет ошибку хранилища в журнал и возвращает клиенту errInternal
+func internalError(p graphql.ResolveParams, err error) error {
+	logger.Log.Error("Failed to resolve GraphQL field", zap.String("field", p.Info.ParentType.Name()+"."+p.Info.FieldName), zap.Error(err))
+	return errInternal
+}
@@ -0,0 +1,86 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+
+	"stats-of/internal/directory"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+	"go.uber.org/zap"
+)
+
+type (
+	// Service выполняет запросы GraphQL к чатам, пользователям и связям между ними.
+	// Чтения из хранилища откладываются и выполняются пакетами по уровням дерева ответа,
+	// поэтому чат, его участники и чаты каждого участника читаются за несколько запросов к хранилищу
+	// независимо от числа участников.
+	Service struct {
+		repo      *storage.Repository
+		directory *directory.Service
+		cursors   *pagination.Signer
+		limits    Limits
+		schema    graphql.Schema
+	}
+
+	// Request тело запроса GraphQL по HTTP
+	Request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName,omitempty"`
+		Variables     map[string]interface{} `json:"variables,omitempty"`
+	}
+)
+
+func NewService(repo *storage.Repository, directory *directory.Service, cursors *pagination.Signer, limits Limits) *Service {
+	s := &Service{repo: repo, directory: directory, cursors: cursors, limits: limits}
+	schema, err := s.newSchema()
+	if err != nil {
+		panic("invalid GraphQL schema: " + err.Error())
+	}
+	s.schema = schema
+	return s
+}
+
+// Execute разбирает, проверяет и выполняет запрос. Ошибки запроса, в том числе превышение
+// ограничений глубины и стоимости, возвращаются в поле errors результата.
+func (s *Service) Execute(ctx context.Context, req Request) *graphql.Result {
+	doc, err := parser.Parse(parser.ParseParams{Source: source.NewSource(&source.Source{Body: []byte(req.Query), Name: "GraphQL request"})})
+	if err != nil {
+		return &graphql.Result{Errors: gqlerrors.FormatErrors(err)}
+	}
+	if validation := graphql.ValidateDocument(&s.schema, doc, nil); !validation.IsValid {
+		return &graphql.Result{Errors: validation.Errors}
+	}
+
+	c := measure(&s.schema, doc, req.OperationName, req.Variables)
+	if c.depth > s.limits.MaxDepth {
+		return errorResult(fmt.Sprintf("query depth %d exceeds the limit of %d", c.depth, s.limits.MaxDepth))
+	}
+	if c.complexity > s.limits.MaxComplexity {
+		return errorResult(fmt.Sprintf("query complexity %d exceeds the limit of %d", c.complexity, s.limits.MaxComplexity))
+	}
+
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema:        s.schema,
+		AST:           doc,
+		OperationName: req.OperationName,
+		Args:          req.Variables,
+		Context:       withLoaders(ctx, newLoaders(s.repo)),
+	})
+	logger.Log.Debug("GraphQL query executed",
+		zap.String("operation", req.OperationName),
+		zap.Int("depth", c.depth),
+		zap.Int("complexity", c.complexity),
+		zap.Int("errors", len(result.Errors)))
+	return result
+}
+
+func errorResult(message string) *graphql.Result {
+	return &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError(message)}}
+}
@@ -0,0 +1,76 @@
+package graphqlapi
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"stats-of/internal/directory"
+	"stats-of/internal/entities"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+func newTestService(t *testing.T) (*Service, *storage.Repository) {
+	t.Helper()
+	repo := memtest.NewRepository(t)
+	leaderboards := leaderboard.NewService(repo)
+	return NewService(repo, directory.NewService(repo, leaderboards), pagination.NewSigner("test"),
+		Limits{MaxDepth: 8, MaxComplexity: 5000}), repo
+}
+
+func saveChat(t *testing.T, repo *storage.Repository, id entities.ChatID, count int64, members int) {
+	t.Helper()
+	ids := make([]entities.UserID, members)
+	for i := range ids {
+		ids[i] = entities.UserID(i + 1)
+	}
+	if err := repo.SaveChat(context.Background(), entities.Chat{ChatID: id, CountOfUsers: count}, ids); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMembersOfLargeChatsAreRejected(t *testing.T) {
+	service, repo := newTestService(t)
+	saveChat(t, repo, 1, 3, 3)
+	for id := entities.UserID(1); id <= 3; id++ {
+		if err := repo.SaveUser(context.Background(), entities.User{UserID: id, CountOfChats: 3}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveChat(t, repo, 2, maxRelationSize+1, maxRelationSize+1)
+	// Счётчик чата 3 разошёлся с множеством: большой размер обнаруживает загрузчик
+	saveChat(t, repo, 3, 1, maxRelationSize+1)
+
+	tests := []struct {
+		chat    string
+		members int
+		err     string
+	}{
+		{chat: "1", members: 3},
+		{chat: "2", err: "chat 2 has 1001 members"},
+		{chat: "3", err: "chat 3 has 1001 members"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.chat, func(t *testing.T) {
+			result := service.Execute(context.Background(), Request{
+				Query: `{ chat(id: "` + tt.chat + `") { id members(first: 5, orderBy: USER_ID) { id } } }`,
+			})
+			if tt.err != "" {
+				if len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Message, tt.err) {
+					t.Fatalf("errors = %v, want one containing %q", result.Errors, tt.err)
+				}
+				return
+			}
+			if len(result.Errors) != 0 {
+				t.Fatalf("unexpected errors: %v", result.Errors)
+			}
+			chat := result.Data.(map[string]interface{})["chat"].(map[string]interface{})
+			if members := chat["members"].([]interface{}); len(members) != tt.members {
+				t.Errorf("got %d members, want %d", len(members), tt.members)
+			}
+		})
+	}
+}
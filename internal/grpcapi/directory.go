@@ -0,0 +1,179 @@
+package grpcapi
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"stats-of/internal/directory"
+	"stats-of/internal/entities"
+	"stats-of/internal/grpcapi/statsofv1"
+	"stats-of/internal/pagination"
+	"stats-of/internal/query"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+type (
+	chatService struct {
+		statsofv1.UnimplementedChatServiceServer
+		services Services
+	}
+
+	userService struct {
+		statsofv1.UnimplementedUserServiceServer
+		services Services
+	}
+)
+
+func (s *chatService) GetChat(ctx context.Context, req *statsofv1.GetChatRequest) (*statsofv1.Chat, error) {
+	chat, err := s.services.Directory.Chat(ctx, entities.ChatID(req.ChatId))
+	if err != nil {
+		return nil, err
+	}
+	return chatMessage(chat), nil
+}
+
+func (s *chatService) ListChats(ctx context.Context, req *statsofv1.ListChatsRequest) (*statsofv1.ListChatsResponse, error) {
+	q, err := query.New(query.ChatSchema, req.Filter, req.Sort, time.Now().UTC())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid filter or sort: "+err.Error())
+	}
+	scope := directory.ListScope("chats", req.Filter, req.Sort)
+	params, err := pageParams(s.services.Cursors, scope, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	chats, next, err := s.services.Directory.FindChats(ctx, q, params)
+	if err != nil {
+		return nil, err
+	}
+	return chatsResponse(s.services.Cursors, scope, chats, next), nil
+}
+
+func (s *chatService) ListChatMembers(ctx context.Context, req *statsofv1.ListChatMembersRequest) (*statsofv1.ListUsersResponse, error) {
+	scope := "chat_users:" + strconv.FormatInt(req.ChatId, 10)
+	params, err := pageParams(s.services.Cursors, scope, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	users, next, err := s.services.Directory.ChatUsers(ctx, entities.ChatID(req.ChatId), params)
+	if err != nil {
+		return nil, err
+	}
+	return usersResponse(s.services.Cursors, scope, users, next), nil
+}
+
+func (s *userService) GetUser(ctx context.Context, req *statsofv1.GetUserRequest) (*statsofv1.User, error) {
+	user, err := s.services.Directory.User(ctx, entities.UserID(req.UserId))
+	if err != nil {
+		return nil, err
+	}
+	return userMessage(user), nil
+}
+
+func (s *userService) ListUsers(ctx context.Context, req *statsofv1.ListUsersRequest) (*statsofv1.ListUsersResponse, error) {
+	q, err := query.New(query.UserSchema, req.Filter, req.Sort, time.Now().UTC())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid filter or sort: "+err.Error())
+	}
+	scope := directory.ListScope("users", req.Filter, req.Sort)
+	params, err := pageParams(s.services.Cursors, scope, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	users, next, err := s.services.Directory.FindUsers(ctx, q, params)
+	if err != nil {
+		return nil, err
+	}
+	return usersResponse(s.services.Cursors, scope, users, next), nil
+}
+
+func (s *userService) ListUserChats(ctx context.Context, req *statsofv1.ListUserChatsRequest) (*statsofv1.ListChatsResponse, error) {
+	scope := "user_chats:" + strconv.FormatInt(req.UserId, 10)
+	params, err := pageParams(s.services.Cursors, scope, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	chats, next, err := s.services.Directory.UserChats(ctx, entities.UserID(req.UserId), params)
+	if err != nil {
+		return nil, err
+	}
+	return chatsResponse(s.services.Cursors, scope, chats, next), nil
+}
+
+// pageParams разбирает размер страницы и токен. Области курсоров совпадают с HTTP API,
+// поэтому токен, полученный по одному протоколу, продолжает список и по другому.
+func pageParams(cursors *pagination.Signer, scope string, size int32, token string) (pagination.Params, error) {
+	limit, err := pageSize(size)
+	if err != nil {
+		return pagination.Params{}, err
+	}
+	params := pagination.Params{Limit: limit}
+	if token != "" {
+		if params.Cursor, err = cursors.Decode(scope, token); err != nil {
+			return pagination.Params{}, err
+		}
+	}
+	return params, nil
+}
+
+// pageSize размер страницы; 0 — размер по умолчанию
+func pageSize(size int32) (int, error) {
+	if size == 0 {
+		return defaultPageSize, nil
+	}
+	if size < 0 || size > maxPageSize {
+		return 0, status.Errorf(codes.InvalidArgument, "page_size must be between 1 and %d", maxPageSize)
+	}
+	return int(size), nil
+}
+
+func chatsResponse(cursors *pagination.Signer, scope string, chats []entities.Chat, next *pagination.Cursor) *statsofv1.ListChatsResponse {
+	resp := &statsofv1.ListChatsResponse{Chats: make([]*statsofv1.Chat, len(chats))}
+	for i := range chats {
+		resp.Chats[i] = chatMessage(&chats[i])
+	}
+	if next != nil {
+		resp.NextPageToken = cursors.Encode(scope, *next)
+	}
+	return resp
+}
+
+func usersResponse(cursors *pagination.Signer, scope string, users []entities.User, next *pagination.Cursor) *statsofv1.ListUsersResponse {
+	resp := &statsofv1.ListUsersResponse{Users: make([]*statsofv1.User, len(users))}
+	for i := range users {
+		resp.Users[i] = userMessage(&users[i])
+	}
+	if next != nil {
+		resp.NextPageToken = cursors.Encode(scope, *next)
+	}
+	return resp
+}
+
+func chatMessage(chat *entities.Chat) *statsofv1.Chat {
+	return &statsofv1.Chat{
+		ChatId:       int64(chat.ChatID),
+		ChatType:     uint32(chat.ChatType),
+		CountOfUsers: chat.CountOfUsers,
+	}
+}
+
+func userMessage(user *entities.User) *statsofv1.User {
+	msg := &statsofv1.User{UserId: int64(user.UserID), CountOfChats: user.CountOfChats}
+	if !user.LastTime.IsZero() {
+		msg.LastTime = timestamppb.New(user.LastTime)
+	}
+	return msg
+}
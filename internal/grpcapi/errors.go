@@ -0,0 +1,63 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+
+	"stats-of/internal/directory"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus переводит ошибку сервиса в статус gRPC так же, как обработчики HTTP выбирают код ответа
+func toStatus(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, apperrors.ErrNotFound):
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, pagination.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, "invalid page_token")
+	case directory.IsQueryError(err), apperrors.IsInvalidArgument(err):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	return status.Error(codes.Internal, "internal error")
+}
+
+// logErrors журналирует внутренние ошибки до того, как их текст будет скрыт от клиента
+func logErrors(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	resp, err := handler(ctx, req)
+	if err == nil {
+		return resp, nil
+	}
+	st := toStatus(err)
+	if status.Code(st) == codes.Internal {
+		logger.Log.Error("gRPC call failed", zap.String("method", info.FullMethod), zap.Error(err))
+	}
+	return resp, st
+}
+
+// recoverPanics отвечает Internal на панику в обработчике. В отличие от net/http, gRPC-сервер
+// не перехватывает паники сам, и одна ошибка в обработчике остановила бы весь процесс.
+func recoverPanics(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Log.Error("gRPC call panicked", zap.String("method", info.FullMethod), zap.Any("panic", r), zap.Stack("stack"))
+			resp, err = nil, status.Error(codes.Internal, "internal error")
+		}
+	}()
+	return handler(ctx, req)
+}
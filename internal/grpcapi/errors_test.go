@@ -0,0 +1,33 @@
+package grpcapi
+
+import (
+	"context"
+	"testing"
+
+	"stats-of/internal/logger"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRecoverPanics проверяет, что паника обработчика превращается в Internal
+func TestRecoverPanics(t *testing.T) {
+	logger.Log = zap.NewNop()
+	info := &grpc.UnaryServerInfo{FullMethod: "/statsof.v1.StatsService/GetRank"}
+
+	resp, err := recoverPanics(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		panic("boom")
+	})
+	if resp != nil || status.Code(err) != codes.Internal {
+		t.Fatalf("got %v, %v; want nil, Internal", resp, err)
+	}
+
+	resp, err = recoverPanics(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return "ok", nil
+	})
+	if resp != "ok" || err != nil {
+		t.Fatalf("got %v, %v; want ok, nil", resp, err)
+	}
+}
@@ -0,0 +1,104 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"stats-of/internal/directory"
+	"stats-of/internal/grpcapi/statsofv1"
+	"stats-of/internal/heavyhitters"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/timeseries"
+	"stats-of/internal/uniques"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type (
+	// Services сервисы, которые отдаёт gRPC API; те же экземпляры обслуживают HTTP API
+	Services struct {
+		Directory    *directory.Service
+		Leaderboards *leaderboard.Service
+		TimeSeries   *timeseries.Service
+		Uniques      *uniques.Service
+		Trending     *heavyhitters.Service
+		Cursors      *pagination.Signer
+	}
+
+	// Server gRPC-сервер с сервисами чатов, пользователей и статистики, стандартной
+	// проверкой состояния grpc.health.v1 и рефлексией для grpcurl и подобных клиентов
+	Server struct {
+		addr   string
+		server *grpc.Server
+		health *health.Server
+	}
+)
+
+func NewServer(addr string, services Services) *Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(recoverPanics, logErrors))
+
+	statsofv1.RegisterChatServiceServer(server, &chatService{services: services})
+	statsofv1.RegisterUserServiceServer(server, &userService{services: services})
+	statsofv1.RegisterStatsServiceServer(server, &statsService{services: services})
+
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+
+	return &Server{addr: addr, server: server, health: healthServer}
+}
+
+// Addr адрес, на котором сервер принимает соединения
+func (s *Server) Addr() string {
+	return s.addr
+}
+
+// Listen открывает порт. Ошибка возвращается сразу, чтобы приложение не запускалось с недоступным gRPC API.
+func (s *Server) Listen() (net.Listener, error) {
+	listener, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.addr, err)
+	}
+	return listener, nil
+}
+
+// Serve обслуживает соединения до остановки сервера. Все сервисы отмечаются как SERVING.
+func (s *Server) Serve(listener net.Listener) error {
+	s.health.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	for name := range s.server.GetServiceInfo() {
+		s.health.SetServingStatus(name, healthpb.HealthCheckResponse_SERVING)
+	}
+
+	err := s.server.Serve(listener)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return fmt.Errorf("grpc server stopped with error: %w", err)
+	}
+	return nil
+}
+
+// Stop переводит проверку состояния в NOT_SERVING и дожидается завершения текущих вызовов.
+// Если ctx истекает раньше, оставшиеся вызовы обрываются.
+func (s *Server) Stop(ctx context.Context) {
+	s.health.Shutdown()
+
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		logger.Log.Warn("gRPC graceful stop timed out, closing remaining calls")
+		s.server.Stop()
+		<-done
+	}
+}
@@ -0,0 +1,197 @@
+package grpcapi
+
+import (
+	"context"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/grpcapi/statsofv1"
+	"stats-of/internal/heavyhitters"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/timeseries"
+	"stats-of/internal/uniques"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	maxOffset = 1 << 30
+)
+
+type statsService struct {
+	statsofv1.UnimplementedStatsServiceServer
+	services Services
+}
+
+var boards = map[statsofv1.Board]leaderboard.Board{
+	statsofv1.Board_BOARD_CHATS: leaderboard.BoardChats,
+	statsofv1.Board_BOARD_USERS: leaderboard.BoardUsers,
+}
+
+var kinds = map[statsofv1.TrendingKind]heavyhitters.Kind{
+	statsofv1.TrendingKind_TRENDING_KIND_CHATS: heavyhitters.KindChats,
+	statsofv1.TrendingKind_TRENDING_KIND_USERS: heavyhitters.KindUsers,
+}
+
+func (s *statsService) GetLeaderboard(ctx context.Context, req *statsofv1.GetLeaderboardRequest) (*statsofv1.Leaderboard, error) {
+	board, ok := boards[req.Board]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "board must be BOARD_CHATS or BOARD_USERS")
+	}
+	limit, err := pageSize(req.PageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	scope := "leaderboard:" + string(board)
+	offset := int(req.Offset)
+	if req.PageToken != "" {
+		c, err := s.services.Cursors.Decode(scope, req.PageToken)
+		if err != nil {
+			return nil, err
+		}
+		if c.Position > maxOffset {
+			return nil, status.Error(codes.InvalidArgument, "invalid page_token")
+		}
+		offset = int(c.Position)
+	} else if offset < 0 || offset > maxOffset {
+		return nil, status.Errorf(codes.InvalidArgument, "offset must be between 0 and %d", maxOffset)
+	}
+
+	page, err := s.services.Leaderboards.Top(ctx, board, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	resp := &statsofv1.Leaderboard{
+		Board:         req.Board,
+		Total:         page.Total,
+		Offset:        int32(page.Offset),
+		Entries:       make([]*statsofv1.LeaderboardEntry, len(page.Entries)),
+		NextPageToken: s.services.Cursors.NextOffset(scope, offset, limit, page.Total),
+	}
+	for i, entry := range page.Entries {
+		resp.Entries[i] = &statsofv1.LeaderboardEntry{Rank: entry.Rank, Id: entry.ID, Score: entry.Score}
+	}
+	return resp, nil
+}
+
+func (s *statsService) GetRank(ctx context.Context, req *statsofv1.GetRankRequest) (*statsofv1.LeaderboardEntry, error) {
+	board, ok := boards[req.Board]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "board must be BOARD_CHATS or BOARD_USERS")
+	}
+	entry, err := s.services.Leaderboards.Rank(ctx, board, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &statsofv1.LeaderboardEntry{Rank: entry.Rank, Id: entry.ID, Score: entry.Score}, nil
+}
+
+func (s *statsService) QueryTimeSeries(ctx context.Context, req *statsofv1.QueryTimeSeriesRequest) (*statsofv1.TimeSeries, error) {
+	if req.Series == "" {
+		return nil, status.Error(codes.InvalidArgument, "series is required")
+	}
+	var from, to time.Time
+	var step time.Duration
+	if req.From != nil {
+		from = req.From.AsTime()
+	}
+	if req.To != nil {
+		to = req.To.AsTime()
+	}
+	if req.Step != nil {
+		step = req.Step.AsDuration()
+	}
+	rng, err := timeseries.NewRange(from, to, step, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.services.TimeSeries.Query(ctx, req.Series, rng.From, rng.To, rng.Step)
+	if err != nil {
+		return nil, err
+	}
+	resp := &statsofv1.TimeSeries{
+		Series: result.Series,
+		Tier:   result.Tier,
+		From:   timestamppb.New(result.From),
+		To:     timestamppb.New(result.To),
+		Step:   durationpb.New(rng.Step),
+		Points: make([]*statsofv1.TimeSeriesPoint, len(result.Points)),
+	}
+	for i, p := range result.Points {
+		resp.Points[i] = &statsofv1.TimeSeriesPoint{
+			Time:   timestamppb.New(p.Time),
+			Last:   p.Last,
+			Min:    p.Min,
+			Max:    p.Max,
+			Avg:    p.Avg,
+			Filled: p.Filled,
+		}
+	}
+	return resp, nil
+}
+
+func (s *statsService) CountUniqueUsers(ctx context.Context, req *statsofv1.CountUniqueUsersRequest) (*statsofv1.UniqueUsers, error) {
+	chats := make([]entities.ChatID, len(req.ChatIds))
+	for i, id := range req.ChatIds {
+		chats[i] = entities.ChatID(id)
+	}
+	params, err := uniques.NewRequest(chats, req.From, req.To, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	estimate, err := s.services.Uniques.Count(ctx, params.Chats, params.From, params.To)
+	if err != nil {
+		return nil, err
+	}
+	resp := &statsofv1.UniqueUsers{
+		ChatIds:               make([]int64, len(estimate.Chats)),
+		From:                  estimate.From,
+		To:                    estimate.To,
+		Days:                  int32(estimate.Days),
+		Estimate:              estimate.Estimate,
+		RelativeStandardError: estimate.RelativeStandardError,
+	}
+	for i, id := range estimate.Chats {
+		resp.ChatIds[i] = int64(id)
+	}
+	return resp, nil
+}
+
+func (s *statsService) GetTrending(ctx context.Context, req *statsofv1.GetTrendingRequest) (*statsofv1.Trending, error) {
+	kind, ok := kinds[req.Kind]
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "kind must be TRENDING_KIND_CHATS or TRENDING_KIND_USERS")
+	}
+	var window time.Duration
+	if req.Window != nil {
+		window = req.Window.AsDuration()
+	}
+	window, k, err := s.services.Trending.Params(window, int(req.K))
+	if err != nil {
+		return nil, err
+	}
+
+	trending, err := s.services.Trending.Trending(kind, window, k, req.Completed)
+	if err != nil {
+		return nil, err
+	}
+	resp := &statsofv1.Trending{
+		Kind:      req.Kind,
+		Window:    durationpb.New(window),
+		Completed: trending.Completed,
+		Start:     timestamppb.New(trending.Start),
+		End:       timestamppb.New(trending.End),
+		Total:     trending.Total,
+		Top:       make([]*statsofv1.TrendingItem, len(trending.Top)),
+	}
+	for i, c := range trending.Top {
+		resp.Top[i] = &statsofv1.TrendingItem{Id: c.Item, Count: c.Count, Error: c.Error}
+	}
+	return resp, nil
+}
@@ -0,0 +1,5 @@
+// Package statsofv1 сгенерированные типы и заглушки gRPC API stats-of.
+// Источник — statsof.proto; после его изменения код нужно сгенерировать заново.
+package statsofv1
+
+//go:generate protoc -I .. --go_out=.. --go_opt=paths=source_relative --go-grpc_out=.. --go-grpc_opt=paths=source_relative statsofv1/statsof.proto
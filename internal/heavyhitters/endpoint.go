@@ -0,0 +1,56 @@
+package heavyhitters
+
+import (
+	"net/http"
+	"strconv"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// MakeHandler обработчик GET /api/v1/trending/{kind}?window=1h&k=10&completed=false,
+// где kind — users или chats, а window — одно из настроенных окон
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		kind := Kind(r.PathValue("kind"))
+		if !kind.Valid() {
+			utils.RespondWith404(w)
+			return
+		}
+
+		window, err := utils.QueryDuration(r, "window", 0)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		k, err := utils.QueryInt(r, "k", 0, 1, MaxTopK)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		window, k, err = service.Params(window, k)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		completed := false
+		if raw := r.URL.Query().Get("completed"); raw != "" {
+			completed, err = strconv.ParseBool(raw)
+			if err != nil {
+				utils.RespondWith400(w, "completed must be a boolean")
+				return
+			}
+		}
+
+		trending, err := service.Trending(kind, window, k, completed)
+		if err != nil {
+			logger.Log.Error("Failed to compute trending", zap.String("kind", string(kind)), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, trending)
+	}
+}
@@ -0,0 +1,10 @@
+package heavyhitters
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewPersistJob создаёт задачу периодического сохранения сводок в хранилище
+func NewPersistJob(service *Service) jobs.Job {
+	return jobs.Func("heavy-hitters-persist", service.Persist)
+}
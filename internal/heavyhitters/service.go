@@ -0,0 +1,282 @@
+package heavyhitters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Kind вид отслеживаемых сущностей
+type Kind string
+
+const (
+	KindUsers Kind = "users"
+	KindChats Kind = "chats"
+
+	// Ключ heavyhitters:{kind}:{window} хранит JSON текущего и предыдущего окна
+	keyPrefix = "heavyhitters:"
+
+	defaultTopK = 10
+	// MaxTopK наибольшее число сущностей в ответе
+	MaxTopK = 1000
+)
+
+var kinds = []Kind{KindUsers, KindChats}
+
+type (
+	// Snapshot сводка окна: самые активные сущности с оценками числа событий
+	Snapshot struct {
+		Start    time.Time `json:"start"`
+		End      time.Time `json:"end"`
+		Total    int64     `json:"total"`
+		Counters []Counter `json:"counters"`
+	}
+
+	// Trending ответ API: окно и самые активные в нём сущности
+	Trending struct {
+		Kind      Kind      `json:"kind"`
+		Window    string    `json:"window"`
+		Completed bool      `json:"completed"`
+		Start     time.Time `json:"start"`
+		End       time.Time `json:"end"`
+		Total     int64     `json:"total"`
+		Top       []Counter `json:"top"`
+	}
+
+	persisted struct {
+		Current  Snapshot  `json:"current"`
+		Previous *Snapshot `json:"previous,omitempty"`
+	}
+
+	// window неперекрывающееся окно фиксированной длины, выровненное по границам длительности
+	window struct {
+		duration time.Duration
+		start    time.Time
+		current  *SpaceSaving
+		previous *Snapshot
+	}
+
+	// Service находит самых активных пользователей и чаты в реальном времени.
+	// Для каждого вида сущностей и каждой длины окна ведётся сводка Space-Saving
+	// фиксированного размера; по окончании окна она сохраняется как предыдущая.
+	Service struct {
+		store    storage.Storage
+		capacity int
+		windows  []time.Duration
+
+		mu       sync.Mutex
+		trackers map[Kind]map[time.Duration]*window
+	}
+)
+
+func NewService(store storage.Storage, capacity int, windows []time.Duration) *Service {
+	s := &Service{
+		store:    store,
+		capacity: capacity,
+		windows:  windows,
+		trackers: make(map[Kind]map[time.Duration]*window, len(kinds)),
+	}
+
+	now := time.Now().UTC()
+	for _, kind := range kinds {
+		s.trackers[kind] = make(map[time.Duration]*window, len(windows))
+		for _, d := range windows {
+			s.trackers[kind][d] = &window{duration: d, start: now.Truncate(d), current: NewSpaceSaving(capacity)}
+		}
+	}
+	return s
+}
+
+// Valid сообщает, отслеживается ли вид сущностей
+func (k Kind) Valid() bool {
+	return k == KindUsers || k == KindChats
+}
+
+// DefaultWindow окно, которое используется, если длина окна не указана
+func (s *Service) DefaultWindow() time.Duration {
+	return s.windows[0]
+}
+
+// HasWindow сообщает, настроено ли окно такой длины
+func (s *Service) HasWindow(d time.Duration) bool {
+	for _, w := range s.windows {
+		if w == d {
+			return true
+		}
+	}
+	return false
+}
+
+// Params подставляет значения по умолчанию вместо нулевых окна и k и проверяет их:
+// окно должно быть настроено, k — от 1 до MaxTopK. Общая проверка для HTTP и gRPC API.
+func (s *Service) Params(window time.Duration, k int) (time.Duration, int, error) {
+	if window == 0 {
+		window = s.DefaultWindow()
+	}
+	if !s.HasWindow(window) {
+		return 0, 0, apperrors.InvalidArgument("window %s is not configured", window)
+	}
+	if k == 0 {
+		k = defaultTopK
+	}
+	if k < 1 || k > MaxTopK {
+		return 0, 0, apperrors.InvalidArgument("k must be between 1 and %d", MaxTopK)
+	}
+	return window, k, nil
+}
+
+// OnChange учитывает событие как активность пользователя и чата.
+// Окна отсчитываются по времени обработки, а не по времени события.
+func (s *Service) OnChange(_ context.Context, change *membership.Change) error {
+	if change.Event.Type.Removes() {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, w := range s.trackers[KindUsers] {
+		w.rotate(now, s.capacity)
+		w.current.Add(int64(change.Event.UserID), 1)
+	}
+	for _, w := range s.trackers[KindChats] {
+		w.rotate(now, s.capacity)
+		w.current.Add(int64(change.Event.ChatID), 1)
+	}
+	return nil
+}
+
+// Trending возвращает k самых активных сущностей текущего окна или, при completed, последнего завершённого
+func (s *Service) Trending(kind Kind, d time.Duration, k int, completed bool) (*Trending, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w, ok := s.trackers[kind][d]
+	if !ok {
+		return nil, fmt.Errorf("window %s is not configured", d)
+	}
+	w.rotate(time.Now().UTC(), s.capacity)
+
+	snapshot := w.snapshot()
+	if completed {
+		if w.previous == nil {
+			snapshot = Snapshot{Start: w.start.Add(-d), End: w.start, Counters: []Counter{}}
+		} else {
+			snapshot = *w.previous
+		}
+	}
+	if len(snapshot.Counters) > k {
+		snapshot.Counters = snapshot.Counters[:k]
+	}
+
+	return &Trending{
+		Kind:      kind,
+		Window:    d.String(),
+		Completed: completed,
+		Start:     snapshot.Start,
+		End:       snapshot.End,
+		Total:     snapshot.Total,
+		Top:       snapshot.Counters,
+	}, nil
+}
+
+// Persist сохраняет сводки всех окон в хранилище
+func (s *Service) Persist(ctx context.Context) error {
+	s.mu.Lock()
+	states := make(map[string]persisted)
+	for kind, windows := range s.trackers {
+		for d, w := range windows {
+			w.rotate(time.Now().UTC(), s.capacity)
+			states[key(kind, d)] = persisted{Current: w.snapshot(), Previous: w.previous}
+		}
+	}
+	s.mu.Unlock()
+
+	for k, state := range states {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s: %w", k, err)
+		}
+		// Состояние нужно не дольше, чем до конца следующего окна
+		ttl := 2 * (state.Current.End.Sub(state.Current.Start))
+		if err := s.store.SetValue(ctx, k, string(data), ttl); err != nil {
+			return fmt.Errorf("failed to persist %s: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// Restore загружает сохранённые сводки; устаревшие окна сдвигаются как при обычной ротации
+func (s *Service) Restore(ctx context.Context) error {
+	now := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for kind, windows := range s.trackers {
+		for d, w := range windows {
+			raw, err := s.store.FindKeyByGetRequest(key(kind, d))
+			if err != nil {
+				return fmt.Errorf("failed to load %s: %w", key(kind, d), err)
+			}
+			if raw == "" {
+				continue
+			}
+
+			var state persisted
+			if err := json.Unmarshal([]byte(raw), &state); err != nil {
+				logger.Log.Warn("Skipping malformed heavy hitters state", zap.String("key", key(kind, d)), zap.Error(err))
+				continue
+			}
+
+			w.start = state.Current.Start
+			w.current = NewSpaceSaving(s.capacity)
+			w.current.restore(state.Current.Counters, state.Current.Total)
+			w.previous = state.Previous
+			w.rotate(now, s.capacity)
+		}
+	}
+	logger.Log.Info("Heavy hitters restored")
+	return nil
+}
+
+// rotate закрывает текущее окно, если время вышло за его границу
+func (w *window) rotate(now time.Time, capacity int) {
+	end := w.start.Add(w.duration)
+	if now.Before(end) {
+		return
+	}
+
+	if now.Before(end.Add(w.duration)) {
+		// Закончилось ровно одно окно: оно становится предыдущим
+		previous := w.snapshot()
+		w.previous = &previous
+	} else {
+		// Пропущено несколько окон: последнее завершённое окно было пустым
+		w.previous = &Snapshot{Start: now.Truncate(w.duration).Add(-w.duration), End: now.Truncate(w.duration), Counters: []Counter{}}
+	}
+	w.start = now.Truncate(w.duration)
+	w.current = NewSpaceSaving(capacity)
+}
+
+func (w *window) snapshot() Snapshot {
+	return Snapshot{
+		Start:    w.start,
+		End:      w.start.Add(w.duration),
+		Total:    w.current.Total(),
+		Counters: w.current.Counters(),
+	}
+}
+
+func key(kind Kind, d time.Duration) string {
+	return keyPrefix + string(kind) + ":" + d.String()
+}
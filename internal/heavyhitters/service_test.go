@@ -0,0 +1,113 @@
+package heavyhitters
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// TestWindowRotation проверяет, что окно закрывается только на своей границе, закрытое окно
+// становится предыдущим, а после нескольких пропущенных окон предыдущее пусто
+func TestWindowRotation(t *testing.T) {
+	start := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart time.Time
+		previous  *Snapshot
+	}{
+		{"inside window", start.Add(59 * time.Second), start, nil},
+		{"next window", start.Add(90 * time.Second), start.Add(time.Minute),
+			&Snapshot{Start: start, End: start.Add(time.Minute), Total: 3, Counters: []Counter{{7, 2, 0}, {8, 1, 0}}}},
+		{"exactly at the end", start.Add(time.Minute), start.Add(time.Minute),
+			&Snapshot{Start: start, End: start.Add(time.Minute), Total: 3, Counters: []Counter{{7, 2, 0}, {8, 1, 0}}}},
+		{"skipped windows", start.Add(5*time.Minute + time.Second), start.Add(5 * time.Minute),
+			&Snapshot{Start: start.Add(4 * time.Minute), End: start.Add(5 * time.Minute), Counters: []Counter{}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := &window{duration: time.Minute, start: start, current: NewSpaceSaving(4)}
+			for _, item := range []int64{7, 8, 7} {
+				w.current.Add(item, 1)
+			}
+
+			w.rotate(tt.now, 4)
+			if !w.start.Equal(tt.wantStart) {
+				t.Errorf("window starts at %v, want %v", w.start, tt.wantStart)
+			}
+			if !reflect.DeepEqual(w.previous, tt.previous) {
+				t.Errorf("previous %+v, want %+v", w.previous, tt.previous)
+			}
+			if tt.previous != nil && w.current.Total() != 0 {
+				t.Errorf("new window has total %d", w.current.Total())
+			}
+		})
+	}
+}
+
+// TestPersistRestore проверяет, что сводки, сохранённые одним сервисом, другой восстанавливает
+// без изменений, вместе с предыдущим окном
+func TestPersistRestore(t *testing.T) {
+	ctx := context.Background()
+	store := memtest.NewStorage(t)
+	windows := []time.Duration{time.Hour, 24 * time.Hour}
+	service := NewService(store, 3, windows)
+
+	events := []struct {
+		chatID entities.ChatID
+		userID entities.UserID
+		typ    membership.EventType
+	}{
+		{1, 10, membership.EventJoin}, {1, 11, membership.EventMessage}, {2, 10, membership.EventMessage},
+		{3, 12, membership.EventJoin}, {4, 13, membership.EventJoin}, {1, 10, membership.EventLeave},
+		{1, 10, membership.EventMessage},
+	}
+	for _, e := range events {
+		change := &membership.Change{Event: membership.Event{Type: e.typ, ChatID: e.chatID, UserID: e.userID}}
+		if err := service.OnChange(ctx, change); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Предыдущее окно задаётся явно, чтобы проверить и его сохранение
+	previous := &Snapshot{Start: time.Unix(0, 0).UTC(), End: time.Unix(3600, 0).UTC(), Total: 5, Counters: []Counter{{9, 5, 1}}}
+	service.trackers[KindChats][24*time.Hour].previous = previous
+
+	if err := service.Persist(ctx); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewService(store, 3, windows)
+	if err := restored.Restore(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, kind := range kinds {
+		for _, d := range windows {
+			for _, completed := range []bool{false, true} {
+				want, err := service.Trending(kind, d, MaxTopK, completed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, err := restored.Trending(kind, d, MaxTopK, completed)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("%s %s completed=%v: restored %+v, want %+v", kind, d, completed, got, want)
+				}
+			}
+		}
+	}
+
+	top, err := restored.Trending(KindChats, time.Hour, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if top.Total != 6 || !reflect.DeepEqual(top.Top, []Counter{{1, 3, 0}}) {
+		t.Errorf("restored top chat %+v, want chat 1 with 3 of 6 events", top)
+	}
+}
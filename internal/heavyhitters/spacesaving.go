@@ -0,0 +1,130 @@
+package heavyhitters
+
+import (
+	"container/heap"
+	"sort"
+)
+
+type (
+	// Counter оценка частоты элемента. Истинная частота лежит в [Count−Error, Count].
+	Counter struct {
+		Item  int64 `json:"id"`
+		Count int64 `json:"count"`
+		Error int64 `json:"error"`
+	}
+
+	// SpaceSaving алгоритм Space-Saving (Metwally et al.): хранит не более capacity счётчиков
+	// и гарантирует, что любой элемент с частотой больше N/capacity присутствует в сводке
+	SpaceSaving struct {
+		capacity int
+		index    map[int64]*entry
+		heap     minHeap
+		total    int64
+	}
+
+	entry struct {
+		Counter
+		pos int
+	}
+
+	// minHeap куча счётчиков по возрастанию Count для быстрого вытеснения минимального
+	minHeap []*entry
+)
+
+func NewSpaceSaving(capacity int) *SpaceSaving {
+	return &SpaceSaving{
+		capacity: capacity,
+		index:    make(map[int64]*entry, capacity),
+		heap:     make(minHeap, 0, capacity),
+	}
+}
+
+// Add учитывает weight появлений элемента
+func (s *SpaceSaving) Add(item int64, weight int64) {
+	s.total += weight
+
+	if e, ok := s.index[item]; ok {
+		e.Count += weight
+		heap.Fix(&s.heap, e.pos)
+		return
+	}
+
+	if len(s.heap) < s.capacity {
+		e := &entry{Counter: Counter{Item: item, Count: weight}}
+		s.index[item] = e
+		heap.Push(&s.heap, e)
+		return
+	}
+
+	// Вытесняем элемент с минимальным счётчиком: новый наследует его значение как ошибку
+	minimum := s.heap[0]
+	delete(s.index, minimum.Item)
+	minimum.Error = minimum.Count
+	minimum.Item = item
+	minimum.Count += weight
+	s.index[item] = minimum
+	heap.Fix(&s.heap, 0)
+}
+
+// Top возвращает k элементов с наибольшими счётчиками
+func (s *SpaceSaving) Top(k int) []Counter {
+	counters := s.Counters()
+	if len(counters) > k {
+		counters = counters[:k]
+	}
+	return counters
+}
+
+// Counters возвращает все счётчики по убыванию
+func (s *SpaceSaving) Counters() []Counter {
+	counters := make([]Counter, 0, len(s.heap))
+	for _, e := range s.heap {
+		counters = append(counters, e.Counter)
+	}
+	sort.Slice(counters, func(i, j int) bool {
+		if counters[i].Count != counters[j].Count {
+			return counters[i].Count > counters[j].Count
+		}
+		return counters[i].Item < counters[j].Item
+	})
+	return counters
+}
+
+// Total возвращает сумму всех учтённых весов
+func (s *SpaceSaving) Total() int64 {
+	return s.total
+}
+
+// restore восстанавливает сводку из сохранённых счётчиков
+func (s *SpaceSaving) restore(counters []Counter, total int64) {
+	for _, c := range counters {
+		if len(s.heap) >= s.capacity {
+			break
+		}
+		e := &entry{Counter: c}
+		s.index[c.Item] = e
+		heap.Push(&s.heap, e)
+	}
+	s.total = total
+}
+
+func (h minHeap) Len() int           { return len(h) }
+func (h minHeap) Less(i, j int) bool { return h[i].Count < h[j].Count }
+func (h minHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].pos = i
+	h[j].pos = j
+}
+
+func (h *minHeap) Push(x any) {
+	e := x.(*entry)
+	e.pos = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *minHeap) Pop() any {
+	old := *h
+	e := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return e
+}
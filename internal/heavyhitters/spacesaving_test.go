@@ -0,0 +1,91 @@
+package heavyhitters
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// TestSpaceSavingEviction проверяет вытеснение на примере, посчитанном вручную: новый элемент
+// занимает счётчик минимального и наследует его значение как ошибку
+func TestSpaceSavingEviction(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []int64
+		want  []Counter
+	}{
+		{"under capacity", []int64{1, 2, 1}, []Counter{{1, 2, 0}, {2, 1, 0}}},
+		{"evicts minimum", []int64{1, 1, 2, 3}, []Counter{{1, 2, 0}, {3, 2, 1}}},
+		{"evicted item returns", []int64{1, 1, 1, 2, 3, 2}, []Counter{{1, 3, 0}, {2, 3, 2}}},
+		{"repeated eviction", []int64{1, 2, 3, 4}, []Counter{{3, 2, 1}, {4, 2, 1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSpaceSaving(2)
+			for _, item := range tt.items {
+				s.Add(item, 1)
+			}
+			if got := s.Counters(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("counters %v, want %v", got, tt.want)
+			}
+			if s.Total() != int64(len(tt.items)) {
+				t.Errorf("total %d, want %d", s.Total(), len(tt.items))
+			}
+		})
+	}
+}
+
+// TestSpaceSavingGuarantees проверяет на потоках с тяжёлым хвостом гарантии алгоритма:
+// истинная частота лежит в [Count−Error, Count], ошибка не больше N/capacity, а каждый
+// элемент с частотой больше N/capacity есть в сводке
+func TestSpaceSavingGuarantees(t *testing.T) {
+	tests := []struct {
+		capacity int
+		items    uint64
+		seed     int64
+	}{
+		{10, 100, 1},
+		{10, 10000, 2},
+		{50, 1000, 3},
+		{100, 100000, 4},
+	}
+	for _, tt := range tests {
+		rng := rand.New(rand.NewSource(tt.seed))
+		zipf := rand.NewZipf(rng, 1.2, 1, tt.items)
+		s := NewSpaceSaving(tt.capacity)
+		truth := make(map[int64]int64)
+		const n = 20000
+		for i := 0; i < n; i++ {
+			item := int64(zipf.Uint64())
+			weight := int64(rng.Intn(3) + 1)
+			truth[item] += weight
+			s.Add(item, weight)
+		}
+
+		bound := s.Total() / int64(tt.capacity)
+		counters := s.Counters()
+		if len(counters) > tt.capacity {
+			t.Errorf("capacity %d: %d counters", tt.capacity, len(counters))
+		}
+		var sum int64
+		found := make(map[int64]bool, len(counters))
+		for _, c := range counters {
+			sum += c.Count
+			found[c.Item] = true
+			if actual := truth[c.Item]; actual < c.Count-c.Error || actual > c.Count {
+				t.Errorf("capacity %d, item %d: true count %d outside [%d, %d]", tt.capacity, c.Item, actual, c.Count-c.Error, c.Count)
+			}
+			if c.Error > bound {
+				t.Errorf("capacity %d, item %d: error %d exceeds N/capacity = %d", tt.capacity, c.Item, c.Error, bound)
+			}
+		}
+		if sum != s.Total() {
+			t.Errorf("capacity %d: counters sum to %d, total %d", tt.capacity, sum, s.Total())
+		}
+		for item, count := range truth {
+			if count > bound && !found[item] {
+				t.Errorf("capacity %d: item %d with %d > %d occurrences is missing", tt.capacity, item, count, bound)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package ingest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// maxEventBodySize наибольший размер тела события
+const maxEventBodySize = 1 << 20
+
+// Result ответ на принятое событие: состояние чата и пользователя после него
+type Result struct {
+	Seq               int64         `json:"seq"`
+	Chat              entities.Chat `json:"chat"`
+	User              entities.User `json:"user"`
+	MembershipChanged bool          `json:"membership_changed"`
+}
+
+// MakeHandler обработчик POST /api/v1/events: приём одного события участия.
+// Состояние меняет membership.Service; рейтинги и остальные подписчики узнают о событии от него.
+func MakeHandler(service *membership.Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var event membership.Event
+		decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxEventBodySize))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&event); err != nil {
+			utils.RespondWith400(w, "invalid event body: "+err.Error())
+			return
+		}
+		if err := event.Validate(); err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		change, err := service.Apply(r.Context(), event)
+		if errors.Is(err, membership.ErrNotWriter) {
+			utils.RespondWithError(w, http.StatusServiceUnavailable, err.Error())
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to apply membership event", zap.String("event", string(event.Type)), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+
+		utils.SuccessRespondWith200(w, &Result{
+			Seq:               change.Seq,
+			Chat:              change.Chat,
+			User:              change.User,
+			MembershipChanged: change.MembershipChanged,
+		})
+	}
+}
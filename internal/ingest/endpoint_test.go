@@ -0,0 +1,79 @@
+package ingest
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+	"stats-of/internal/utils"
+)
+
+func newTestService(t *testing.T) (*membership.Service, storage.Storage) {
+	t.Helper()
+	store := memtest.NewStorage(t)
+	return membership.NewService(storage.NewRepository(store), membership.NewLog(store)), store
+}
+
+func post(handler http.HandlerFunc, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodPost, "/api/v1/events", strings.NewReader(body)))
+	return rec
+}
+
+// TestHandlerRejectsInvalidEvents проверяет ответ 400 на неверное тело и неверное событие
+func TestHandlerRejectsInvalidEvents(t *testing.T) {
+	service, _ := newTestService(t)
+	handler := MakeHandler(service)
+
+	tests := []struct {
+		name    string
+		body    string
+		message string
+	}{
+		{"not json", `{"type":`, "invalid event body"},
+		{"unknown field", `{"type":"join","chat_id":1,"user_id":2,"chat":1}`, `invalid event body: json: unknown field "chat"`},
+		{"wrong field type", `{"type":"join","chat_id":"1","user_id":2}`, "invalid event body"},
+		{"unknown type", `{"type":"poke","chat_id":1,"user_id":2}`, `unknown event type "poke"`},
+		{"no chat", `{"type":"join","user_id":2}`, "chat_id is required"},
+		{"no user", `{"type":"leave","chat_id":1}`, "user_id is required"},
+		{"too large", `{"type":"join","chat_id":1,"user_id":2,"pad":"` + strings.Repeat("x", maxEventBodySize) + `"}`, "invalid event body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := post(handler, tt.body)
+			var body utils.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if rec.Code != http.StatusBadRequest || !strings.HasPrefix(body.Error, tt.message) {
+				t.Errorf("status %d, error %q; want 400 starting with %q", rec.Code, body.Error, tt.message)
+			}
+		})
+	}
+}
+
+// TestHandlerAppliesEvent проверяет ответ на принятое событие и 503, пока события принимает другой процесс
+func TestHandlerAppliesEvent(t *testing.T) {
+	service, store := newTestService(t)
+
+	rec := post(MakeHandler(service), `{"type":"join","chat_id":1,"user_id":2,"time":"2026-10-01T12:00:00Z"}`)
+	var result Result
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || result.Seq != 1 || !result.MembershipChanged || result.Chat.CountOfUsers != 1 ||
+		result.User.CountOfChats != 1 || !result.User.LastTime.Equal(time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("status %d, result %+v; want the applied join", rec.Code, result)
+	}
+
+	other := membership.NewService(storage.NewRepository(store), membership.NewLog(store))
+	if rec := post(MakeHandler(other), `{"type":"join","chat_id":1,"user_id":3}`); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status on the second instance %d, want 503", rec.Code)
+	}
+}
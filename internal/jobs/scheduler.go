@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"stats-of/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+type (
+	// Job фоновая задача, которую планировщик запускает с заданным интервалом
+	Job interface {
+		Name() string
+		Run(ctx context.Context) error
+	}
+
+	funcJob struct {
+		name string
+		fn   func(ctx context.Context) error
+	}
+
+	entry struct {
+		job      Job
+		interval time.Duration
+	}
+
+	// Scheduler периодически запускает зарегистрированные задачи до остановки
+	Scheduler struct {
+		mu      sync.Mutex
+		entries []entry
+		cancel  context.CancelFunc
+		wg      sync.WaitGroup
+	}
+)
+
+// Func оборачивает функцию в Job с указанным именем
+func Func(name string, fn func(ctx context.Context) error) Job {
+	return &funcJob{name: name, fn: fn}
+}
+
+func (j *funcJob) Name() string                  { return j.name }
+func (j *funcJob) Run(ctx context.Context) error { return j.fn(ctx) }
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// Add регистрирует задачу. Задачи, добавленные после Start, не запускаются.
+func (s *Scheduler) Add(job Job, interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = append(s.entries, entry{job: job, interval: interval})
+	logger.Log.Info("Job registered", zap.String("job", job.Name()), zap.Duration("interval", interval))
+}
+
+// Start запускает все задачи: каждая выполняется сразу и затем раз в свой интервал
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, e := range s.entries {
+		s.wg.Add(1)
+		go s.loop(ctx, e)
+	}
+	logger.Log.Info("Job scheduler started", zap.Int("jobs", len(s.entries)))
+}
+
+// Stop останавливает все задачи и дожидается завершения выполняющихся запусков
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	s.wg.Wait()
+	logger.Log.Info("Job scheduler stopped")
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		s.runOnce(ctx, e.job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(ctx context.Context, job Job) {
+	started := time.Now()
+	logger.Log.Info("Running job", zap.String("job", job.Name()))
+
+	if err := job.Run(ctx); err != nil {
+		logger.Log.Error("Job failed", zap.String("job", job.Name()), zap.Error(err))
+		return
+	}
+	logger.Log.Info("Job completed", zap.String("job", job.Name()), zap.Duration("duration", time.Since(started)))
+}
@@ -0,0 +1,79 @@
+package leaderboard
+
+import (
+	"errors"
+	"net/http"
+
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPageLimit = 100
+	maxPageLimit     = 1000
+	maxOffset        = 1 << 30
+)
+
+// MakeTopHandler обработчик GET /api/v1/leaderboards/{board}?limit=100&cursor=...,
+// где board — chats (по CountOfUsers) или users (по CountOfChats); вместо cursor можно передать offset
+func MakeTopHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		board := Board(r.PathValue("board"))
+		if !board.Valid() {
+			utils.RespondWith404(w)
+			return
+		}
+
+		scope := "leaderboard:" + string(board)
+		offset, err := cursors.Offset(r, scope, maxOffset)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		limit, err := utils.QueryInt(r, "limit", defaultPageLimit, 1, maxPageLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		page, err := service.Top(r.Context(), board, offset, limit)
+		if err != nil {
+			logger.Log.Error("Failed to read leaderboard", zap.String("board", string(board)), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewOffsetPage(cursors, scope, page.Entries, offset, limit, page.Total))
+	}
+}
+
+// MakeRankHandler обработчик GET /api/v1/leaderboards/{board}/{id}: позиция чата или пользователя
+func MakeRankHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		board := Board(r.PathValue("board"))
+		if !board.Valid() {
+			utils.RespondWith404(w)
+			return
+		}
+
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		entry, err := service.Rank(r.Context(), board, id)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to read leaderboard rank", zap.String("board", string(board)), zap.Int64("id", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, entry)
+	}
+}
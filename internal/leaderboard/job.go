@@ -0,0 +1,10 @@
+package leaderboard
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewRebuildJob создаёт задачу полного пересчёта рейтингов
+func NewRebuildJob(service *Service) jobs.Job {
+	return jobs.Func("leaderboard-rebuild", service.Rebuild)
+}
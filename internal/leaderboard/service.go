@@ -0,0 +1,236 @@
+package leaderboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Board рейтинг: чаты по числу участников или пользователи по числу чатов
+type Board string
+
+const (
+	BoardChats Board = "chats"
+	BoardUsers Board = "users"
+
+	keyPrefix = "leaderboard:"
+
+	rebuildPageSize = 1000
+)
+
+type (
+	// Entry позиция в рейтинге; Rank начинается с единицы
+	Entry struct {
+		Rank  int64 `json:"rank"`
+		ID    int64 `json:"id"`
+		Score int64 `json:"score"`
+	}
+
+	// Page страница рейтинга
+	Page struct {
+		Board   Board   `json:"board"`
+		Total   int64   `json:"total"`
+		Offset  int     `json:"offset"`
+		Limit   int     `json:"limit"`
+		Entries []Entry `json:"entries"`
+	}
+
+	// Service поддерживает рейтинги в упорядоченных множествах хранилища.
+	// Рейтинги обновляются при каждом изменении состава чатов и периодически перестраиваются целиком.
+	Service struct {
+		repo *storage.Repository
+	}
+)
+
+// Valid сообщает, существует ли рейтинг
+func (b Board) Valid() bool {
+	return b == BoardChats || b == BoardUsers
+}
+
+func (b Board) key() string {
+	return keyPrefix + string(b)
+}
+
+func NewService(repo *storage.Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// OnChange обновляет позиции чата и пользователя после изменения состава чата
+func (s *Service) OnChange(ctx context.Context, change *membership.Change) error {
+	if !change.MembershipChanged {
+		return nil
+	}
+	if err := s.set(ctx, BoardChats, int64(change.Chat.ChatID), change.Chat.CountOfUsers); err != nil {
+		return err
+	}
+	return s.set(ctx, BoardUsers, int64(change.User.UserID), change.User.CountOfChats)
+}
+
+// Top возвращает limit позиций рейтинга начиная с offset
+func (s *Service) Top(ctx context.Context, board Board, offset, limit int) (*Page, error) {
+	store := s.repo.Storage()
+
+	total, err := store.SortedSetCard(ctx, board.key())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s leaderboard: %w", board, err)
+	}
+
+	members, err := store.SortedSetRevRange(ctx, board.key(), int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s leaderboard: %w", board, err)
+	}
+
+	entries := make([]Entry, 0, len(members))
+	for i, member := range members {
+		id, err := strconv.ParseInt(member.Member, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed leaderboard member", zap.String("board", string(board)), zap.String("member", member.Member))
+			continue
+		}
+		entries = append(entries, Entry{Rank: int64(offset+i) + 1, ID: id, Score: int64(member.Score)})
+	}
+
+	return &Page{Board: board, Total: total, Offset: offset, Limit: limit, Entries: entries}, nil
+}
+
+// Rank возвращает позицию чата или пользователя в рейтинге или ErrNotFound
+func (s *Service) Rank(ctx context.Context, board Board, id int64) (*Entry, error) {
+	rank, score, err := s.repo.Storage().SortedSetRevRank(ctx, board.key(), strconv.FormatInt(id, 10))
+	if err != nil {
+		return nil, err
+	}
+	return &Entry{Rank: rank + 1, ID: id, Score: int64(score)}, nil
+}
+
+// Ranks возвращает позиции нескольких чатов или пользователей одним запросом к хранилищу;
+// тех, кого нет в рейтинге, в ответе нет
+func (s *Service) Ranks(ctx context.Context, board Board, ids []int64) (map[int64]*Entry, error) {
+	members := make([]string, len(ids))
+	for i, id := range ids {
+		members[i] = strconv.FormatInt(id, 10)
+	}
+	ranks, scores, err := s.repo.Storage().SortedSetRevRankMany(ctx, board.key(), members...)
+	if err != nil {
+		return nil, err
+	}
+	entries := make(map[int64]*Entry, len(ids))
+	for i, id := range ids {
+		if ranks[i] >= 0 {
+			entries[id] = &Entry{Rank: ranks[i] + 1, ID: id, Score: int64(scores[i])}
+		}
+	}
+	return entries, nil
+}
+
+// Range возвращает не больше limit идентификаторов рейтинга со счётом в диапазоне [min, max]
+// по убыванию счёта. Рейтинг служит индексом счётчиков: чаты и пользователи с нулевым счётом в него не входят.
+func (s *Service) Range(ctx context.Context, board Board, min, max float64, limit int64) ([]int64, error) {
+	members, err := s.repo.Storage().SortedSetRevRangeByScore(ctx, board.key(), min, max, 0, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s leaderboard range: %w", board, err)
+	}
+	ids := make([]int64, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseInt(member.Member, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed leaderboard member", zap.String("board", string(board)), zap.String("member", member.Member))
+			continue
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Rebuild пересчитывает оба рейтинга по текущим счётчикам в хранилище
+// и удаляет из них чаты и пользователей, которых больше нет
+func (s *Service) Rebuild(ctx context.Context) error {
+	chatIDs, err := s.repo.ChatIDs(ctx)
+	if err != nil {
+		return err
+	}
+	chats := make(map[int64]int64, len(chatIDs))
+	for _, id := range chatIDs {
+		chat, err := s.repo.Chat(ctx, id)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		chats[int64(id)] = chat.CountOfUsers
+	}
+	if err := s.replace(ctx, BoardChats, chats); err != nil {
+		return err
+	}
+
+	userIDs, err := s.repo.UserIDs(ctx)
+	if err != nil {
+		return err
+	}
+	users := make(map[int64]int64, len(userIDs))
+	for _, id := range userIDs {
+		user, err := s.repo.User(ctx, id)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return err
+		}
+		users[int64(id)] = user.CountOfChats
+	}
+	if err := s.replace(ctx, BoardUsers, users); err != nil {
+		return err
+	}
+
+	logger.Log.Info("Leaderboards rebuilt", zap.Int("chats", len(chats)), zap.Int("users", len(users)))
+	return nil
+}
+
+func (s *Service) set(ctx context.Context, board Board, id, score int64) error {
+	member := strconv.FormatInt(id, 10)
+	if score <= 0 {
+		return s.repo.Storage().SortedSetRemove(ctx, board.key(), member)
+	}
+	return s.repo.Storage().SortedSetAdd(ctx, board.key(), member, float64(score))
+}
+
+// replace приводит рейтинг к переданным значениям без промежутка, в котором он был бы пуст
+func (s *Service) replace(ctx context.Context, board Board, scores map[int64]int64) error {
+	for id, score := range scores {
+		if err := s.set(ctx, board, id, score); err != nil {
+			return fmt.Errorf("failed to update %s leaderboard: %w", board, err)
+		}
+	}
+
+	var stale []string
+	for start := int64(0); ; start += rebuildPageSize {
+		members, err := s.repo.Storage().SortedSetRevRange(ctx, board.key(), start, start+rebuildPageSize-1)
+		if err != nil {
+			return fmt.Errorf("failed to read %s leaderboard: %w", board, err)
+		}
+		for _, member := range members {
+			id, err := strconv.ParseInt(member.Member, 10, 64)
+			if _, ok := scores[id]; err != nil || !ok {
+				stale = append(stale, member.Member)
+			}
+		}
+		if len(members) < rebuildPageSize {
+			break
+		}
+	}
+
+	if len(stale) > 0 {
+		if err := s.repo.Storage().SortedSetRemove(ctx, board.key(), stale...); err != nil {
+			return fmt.Errorf("failed to remove stale %s leaderboard entries: %w", board, err)
+		}
+	}
+	return nil
+}
+
+var _ membership.Observer = (*Service)(nil)
@@ -0,0 +1,156 @@
+package leaderboard
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// newTestService создаёт рейтинги над чатами 1–5 с 10, 20, …, 50 участниками и пересобирает их
+func newTestService(t *testing.T) (*Service, *storage.Repository) {
+	t.Helper()
+	repo := memtest.NewRepository(t)
+	ctx := context.Background()
+	for id := int64(1); id <= 5; id++ {
+		if err := repo.SaveChat(ctx, entities.Chat{ChatID: entities.ChatID(id), CountOfUsers: id * 10}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	service := NewService(repo)
+	if err := service.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return service, repo
+}
+
+// TestTopPages проверяет позиции, общее число и границы страниц рейтинга
+func TestTopPages(t *testing.T) {
+	service, _ := newTestService(t)
+
+	tests := []struct {
+		offset, limit int
+		want          []Entry
+	}{
+		{0, 2, []Entry{{1, 5, 50}, {2, 4, 40}}},
+		{2, 2, []Entry{{3, 3, 30}, {4, 2, 20}}},
+		{4, 2, []Entry{{5, 1, 10}}},
+		{5, 2, []Entry{}},
+		{1, 10, []Entry{{2, 4, 40}, {3, 3, 30}, {4, 2, 20}, {5, 1, 10}}},
+	}
+	for _, tt := range tests {
+		page, err := service.Top(context.Background(), BoardChats, tt.offset, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if page.Total != 5 || !reflect.DeepEqual(page.Entries, tt.want) {
+			t.Errorf("Top(%d, %d) = total %d, %v; want 5, %v", tt.offset, tt.limit, page.Total, page.Entries, tt.want)
+		}
+	}
+}
+
+// TestTopHandlerCursor проверяет, что курсоры next проходят рейтинг целиком и заканчиваются на последней странице
+func TestTopHandlerCursor(t *testing.T) {
+	service, _ := newTestService(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/leaderboards/{board}", MakeTopHandler(service, pagination.NewSigner("test")))
+
+	var ids []int64
+	query := url.Values{"limit": {"2"}}
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination does not terminate")
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/leaderboards/chats?"+query.Encode(), nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", rec.Code, rec.Body)
+		}
+		var page pagination.Page[Entry]
+		if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range page.Items {
+			if entry.Rank != int64(len(ids)+1) {
+				t.Errorf("entry %+v has rank %d, want %d", entry, entry.Rank, len(ids)+1)
+			}
+			ids = append(ids, entry.ID)
+		}
+		if page.Next == "" {
+			break
+		}
+		query = url.Values{"limit": {"2"}, "cursor": {page.Next}}
+	}
+	if want := []int64{5, 4, 3, 2, 1}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids %v, want %v", ids, want)
+	}
+}
+
+// TestRank проверяет позицию в рейтинге и ErrNotFound для чата вне рейтинга
+func TestRank(t *testing.T) {
+	service, _ := newTestService(t)
+
+	entry, err := service.Rank(context.Background(), BoardChats, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *entry != (Entry{Rank: 3, ID: 3, Score: 30}) {
+		t.Errorf("rank of chat 3 = %+v, want rank 3 with score 30", *entry)
+	}
+	if _, err := service.Rank(context.Background(), BoardChats, 9); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("rank of missing chat: %v, want ErrNotFound", err)
+	}
+	if _, err := service.Rank(context.Background(), BoardUsers, 1); !errors.Is(err, apperrors.ErrNotFound) {
+		t.Errorf("rank in empty board: %v, want ErrNotFound", err)
+	}
+
+	entries, err := service.Ranks(context.Background(), BoardChats, []int64{5, 9, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[int64]*Entry{5: {Rank: 1, ID: 5, Score: 50}, 1: {Rank: 5, ID: 1, Score: 10}}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("ranks of chats 5, 9, 1 = %v, want %v", entries, want)
+	}
+}
+
+// TestRebuildReplacesStaleScores проверяет, что пересборка обновляет счёт, убирает удалённые
+// и опустевшие чаты и записи, которых нет в хранилище
+func TestRebuildReplacesStaleScores(t *testing.T) {
+	ctx := context.Background()
+	service, repo := newTestService(t)
+	if err := service.set(ctx, BoardChats, 77, 1000); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Storage().SortedSetAdd(ctx, BoardChats.key(), "garbage", 500); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetCountOfUsers(ctx, 1, 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SetCountOfUsers(ctx, 2, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Rebuild(ctx); err != nil {
+		t.Fatal(err)
+	}
+	page, err := service.Top(ctx, BoardChats, 0, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{{1, 1, 60}, {2, 5, 50}, {3, 4, 40}, {4, 3, 30}}
+	if page.Total != 4 || !reflect.DeepEqual(page.Entries, want) {
+		t.Errorf("after rebuild total %d, %v; want 4, %v", page.Total, page.Entries, want)
+	}
+}
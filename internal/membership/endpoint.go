@@ -0,0 +1,67 @@
+package membership
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLogLimit = 100
+	maxLogLimit     = 1000
+)
+
+// MakeLogHandler обработчик GET /api/v1/events?from=1&limit=100: события журнала начиная с номера from.
+// Курсор next продолжает чтение с первого непрочитанного события.
+func MakeLogHandler(log *Log, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const scope = "events"
+		from := int64(1)
+		if token := r.URL.Query().Get("cursor"); token != "" {
+			c, err := cursors.Decode(scope, token)
+			if err != nil || c.Position < 1 || c.Position > math.MaxInt64 {
+				utils.RespondWith400(w, pagination.ErrInvalidCursor.Error())
+				return
+			}
+			from = int64(c.Position)
+		} else if raw := r.URL.Query().Get("from"); raw != "" {
+			var err error
+			from, err = strconv.ParseInt(raw, 10, 64)
+			if err != nil || from < 1 {
+				utils.RespondWith400(w, "from must be a positive integer")
+				return
+			}
+		}
+		limit, err := utils.QueryInt(r, "limit", defaultLogLimit, 1, maxLogLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		total, err := log.Len(r.Context())
+		if err != nil {
+			logger.Log.Error("Failed to read event log", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		records, err := log.Read(r.Context(), from, limit)
+		if err != nil {
+			logger.Log.Error("Failed to read event log", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		var next *pagination.Cursor
+		if position := from + int64(limit); position <= total {
+			next = &pagination.Cursor{Position: uint64(position)}
+		}
+		page := pagination.NewPage(cursors, scope, records, limit, next)
+		page.Total = &total
+		utils.SuccessRespondWith200(w, page)
+	}
+}
@@ -0,0 +1,68 @@
+package membership
+
+import (
+	"fmt"
+	"time"
+
+	"stats-of/internal/entities"
+)
+
+// EventType тип события участия пользователя в чате
+type EventType string
+
+const (
+	EventJoin    EventType = "join"
+	EventLeave   EventType = "leave"
+	EventMessage EventType = "message"
+	// EventKick пользователя удалили из чата; он может вступить снова
+	EventKick EventType = "kick"
+	// EventBan пользователя удалили из чата и запретили ему возвращаться
+	EventBan EventType = "ban"
+)
+
+// Removes сообщает, удаляет ли событие пользователя из чата
+func (t EventType) Removes() bool {
+	return t == EventLeave || t == EventKick || t == EventBan
+}
+
+type (
+	// Event событие участия: вступление, выход, удаление, бан или сообщение пользователя в чате
+	Event struct {
+		Type     EventType       `json:"type"`
+		ChatID   entities.ChatID `json:"chat_id"`
+		UserID   entities.UserID `json:"user_id"`
+		ChatType *uint           `json:"chat_type,omitempty"`
+		Time     time.Time       `json:"time"`
+	}
+
+	// Change результат применения события к хранилищу
+	Change struct {
+		// Seq номер события в журнале
+		Seq   int64
+		Event Event
+		// Chat и User содержат состояние после применения события
+		Chat entities.Chat
+		User entities.User
+		// MembershipChanged сообщает, изменился ли состав чата (а значит и счётчики)
+		MembershipChanged bool
+	}
+)
+
+// Validate проверяет событие и подставляет текущее время, если оно не указано
+func (e *Event) Validate() error {
+	switch e.Type {
+	case EventJoin, EventLeave, EventMessage, EventKick, EventBan:
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+	if e.ChatID == 0 {
+		return fmt.Errorf("chat_id is required")
+	}
+	if e.UserID == 0 {
+		return fmt.Errorf("user_id is required")
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// logKey список JSON событий в порядке приёма; номер события — позиция в списке, начиная с 1
+	logKey = "events:log"
+	// metaKey хеш служебных полей журнала
+	metaKey      = "events:meta"
+	fieldApplied = "applied"
+	// writerKey аренда приёма событий: идентификатор процесса, который применяет журнал
+	writerKey = "events:writer"
+)
+
+type (
+	// Record событие журнала с его порядковым номером
+	Record struct {
+		Seq   int64 `json:"seq"`
+		Event Event `json:"event"`
+	}
+
+	// Log журнал событий участия, в который записи только добавляются.
+	// Журнал — источник истины: чаты, пользователи и счётчики можно пересобрать из него.
+	Log struct {
+		store storage.Storage
+	}
+)
+
+func NewLog(store storage.Storage) *Log {
+	return &Log{store: store}
+}
+
+// Append добавляет событие в конец журнала и возвращает его номер
+func (l *Log) Append(ctx context.Context, event Event) (int64, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+	seq, err := l.store.ListAppend(ctx, logKey, string(data))
+	if err != nil {
+		return 0, fmt.Errorf("failed to append event to log: %w", err)
+	}
+	return seq, nil
+}
+
+// Applied возвращает номер последнего события, применённого к хранилищу; ok = false,
+// если отметки ещё нет
+func (l *Log) Applied(ctx context.Context) (seq int64, ok bool, err error) {
+	raw, err := l.store.HashGet(ctx, metaKey, fieldApplied)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read applied event: %w", err)
+	}
+	if raw == "" {
+		return 0, false, nil
+	}
+	seq, err = strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse applied event %q: %w", raw, err)
+	}
+	return seq, true, nil
+}
+
+// MarkApplied отмечает, что события до seq включительно применены; отметка не уменьшается
+func (l *Log) MarkApplied(ctx context.Context, seq int64) error {
+	if _, err := l.store.HashSetMax(ctx, metaKey, fieldApplied, float64(seq)); err != nil {
+		return fmt.Errorf("failed to mark event %d applied: %w", seq, err)
+	}
+	return nil
+}
+
+// Claim занимает аренду приёма событий для owner или продлевает её, если она уже за ним.
+// ok = false, если аренда за другим процессом; fresh = true, если аренда занята заново.
+func (l *Log) Claim(ctx context.Context, owner string, ttl time.Duration) (ok, fresh bool, err error) {
+	acquired, err := l.store.SetValueIfAbsent(ctx, writerKey, owner, ttl)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to claim event log: %w", err)
+	}
+	if acquired {
+		return true, true, nil
+	}
+	holder, err := l.store.FindKeyByGetRequest(writerKey)
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read event log writer: %w", err)
+	}
+	if holder != owner {
+		return false, false, nil
+	}
+	if err := l.store.Expire(ctx, writerKey, ttl); err != nil {
+		return false, false, fmt.Errorf("failed to extend event log claim: %w", err)
+	}
+	return true, false, nil
+}
+
+// Release освобождает аренду приёма, если она за owner
+func (l *Log) Release(ctx context.Context, owner string) error {
+	holder, err := l.store.FindKeyByGetRequest(writerKey)
+	if err != nil {
+		return fmt.Errorf("failed to read event log writer: %w", err)
+	}
+	if holder != owner {
+		return nil
+	}
+	if err := l.store.Delete(ctx, writerKey); err != nil {
+		return fmt.Errorf("failed to release event log: %w", err)
+	}
+	return nil
+}
+
+// Len возвращает число событий в журнале
+func (l *Log) Len(ctx context.Context) (int64, error) {
+	n, err := l.store.ListLength(ctx, logKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read event log length: %w", err)
+	}
+	return n, nil
+}
+
+// Read возвращает не больше limit событий начиная с номера from
+func (l *Log) Read(ctx context.Context, from int64, limit int) ([]Record, error) {
+	if from < 1 {
+		from = 1
+	}
+	values, err := l.store.ListRange(ctx, logKey, from-1, from-1+int64(limit)-1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log: %w", err)
+	}
+
+	records := make([]Record, 0, len(values))
+	for i, value := range values {
+		seq := from + int64(i)
+		var event Event
+		if err := json.Unmarshal([]byte(value), &event); err != nil {
+			// Повреждённую запись нельзя пропустить молча: пересборка дала бы другое состояние
+			return nil, fmt.Errorf("failed to decode event %d: %w", seq, err)
+		}
+		records = append(records, Record{Seq: seq, Event: event})
+	}
+	return records, nil
+}
+
+// Scan последовательно передаёт fn все события журнала, читая его пачками по batch
+func (l *Log) Scan(ctx context.Context, batch int, fn func(Record) error) (int64, error) {
+	var seq int64 = 1
+	for {
+		records, err := l.Read(ctx, seq, batch)
+		if err != nil {
+			return seq - 1, err
+		}
+		for _, record := range records {
+			if err := fn(record); err != nil {
+				return record.Seq, err
+			}
+		}
+		seq += int64(len(records))
+		if len(records) < batch {
+			logger.Log.Debug("Event log scanned", zap.Int64("events", seq-1))
+			return seq - 1, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return seq - 1, err
+		}
+	}
+}
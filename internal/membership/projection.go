@@ -0,0 +1,163 @@
+package membership
+
+import (
+	"sort"
+	"time"
+
+	"stats-of/internal/entities"
+)
+
+type (
+	chatState struct {
+		// exists сообщает, есть ли у чата хеш в хранилище: он появляется с типом или первым участником
+		exists   bool
+		chatType uint
+		members  map[entities.UserID]struct{}
+		banned   map[entities.UserID]struct{}
+	}
+
+	userState struct {
+		lastTime time.Time
+		chats    map[entities.ChatID]struct{}
+	}
+
+	// Projection состояние чатов и пользователей, свёрнутое из событий в памяти.
+	// Правила применения событий совпадают с Service.Apply, поэтому свёртка всего
+	// журнала воспроизводит состояние хранилища.
+	Projection struct {
+		chats map[entities.ChatID]*chatState
+		users map[entities.UserID]*userState
+	}
+)
+
+func NewProjection() *Projection {
+	return &Projection{
+		chats: make(map[entities.ChatID]*chatState),
+		users: make(map[entities.UserID]*userState),
+	}
+}
+
+// Apply применяет событие и сообщает, изменился ли состав чата
+func (p *Projection) Apply(event Event) bool {
+	chat := p.chat(event.ChatID)
+	if event.ChatType != nil {
+		chat.chatType = *event.ChatType
+		chat.exists = true
+	}
+	if touchesUser(event.Type) {
+		user := p.userState(event.UserID)
+		if event.Time.After(user.lastTime) {
+			// Время хранится с точностью до секунды, как в хранилище
+			user.lastTime = event.Time.UTC().Truncate(time.Second)
+		}
+	}
+
+	switch event.Type {
+	case EventJoin, EventMessage:
+		if _, banned := chat.banned[event.UserID]; banned {
+			return false
+		}
+		if _, member := chat.members[event.UserID]; member {
+			return false
+		}
+		chat.members[event.UserID] = struct{}{}
+		chat.exists = true
+		p.userState(event.UserID).chats[event.ChatID] = struct{}{}
+		return true
+	case EventLeave, EventKick, EventBan:
+		if event.Type == EventBan {
+			chat.banned[event.UserID] = struct{}{}
+		}
+		if _, member := chat.members[event.UserID]; !member {
+			return false
+		}
+		delete(chat.members, event.UserID)
+		delete(p.users[event.UserID].chats, event.ChatID)
+		return true
+	}
+	return false
+}
+
+// ChatList возвращает участников каждого чата
+func (p *Projection) ChatList() entities.ChatList {
+	list := make(entities.ChatList, len(p.chats))
+	for chatID, chat := range p.chats {
+		if !chat.exists {
+			continue
+		}
+		members := make([]entities.User, 0, len(chat.members))
+		for userID := range chat.members {
+			members = append(members, p.user(userID))
+		}
+		list[chatID] = members
+	}
+	return list
+}
+
+// UserList возвращает чаты каждого пользователя
+func (p *Projection) UserList() entities.UserList {
+	list := make(entities.UserList, len(p.users))
+	for userID, user := range p.users {
+		chats := make([]entities.Chat, 0, len(user.chats))
+		for chatID := range user.chats {
+			chats = append(chats, p.chatEntity(chatID))
+		}
+		list[userID] = chats
+	}
+	return list
+}
+
+// Counts возвращает число чатов, пользователей и участий
+func (p *Projection) Counts() (chats, users, memberships int) {
+	for _, chat := range p.chats {
+		if chat.exists {
+			chats++
+		}
+		memberships += len(chat.members)
+	}
+	return chats, len(p.users), memberships
+}
+
+func (p *Projection) chat(id entities.ChatID) *chatState {
+	chat, ok := p.chats[id]
+	if !ok {
+		chat = &chatState{members: make(map[entities.UserID]struct{}), banned: make(map[entities.UserID]struct{})}
+		p.chats[id] = chat
+	}
+	return chat
+}
+
+func (p *Projection) userState(id entities.UserID) *userState {
+	user, ok := p.users[id]
+	if !ok {
+		user = &userState{chats: make(map[entities.ChatID]struct{})}
+		p.users[id] = user
+	}
+	return user
+}
+
+func (p *Projection) user(id entities.UserID) entities.User {
+	user := p.users[id]
+	return entities.User{UserID: id, LastTime: user.lastTime, CountOfChats: int64(len(user.chats))}
+}
+
+func (p *Projection) chatEntity(id entities.ChatID) entities.Chat {
+	chat := p.chats[id]
+	return entities.Chat{ChatID: id, ChatType: chat.chatType, CountOfUsers: int64(len(chat.members))}
+}
+
+// sortedIDs возвращает ключи множества по возрастанию
+func sortedIDs[T ~int64](set map[T]struct{}) []T {
+	ids := make([]T, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// touchesUser сообщает, считается ли событие активностью самого пользователя.
+// Удаление и бан совершает администратор, поэтому время активности они не меняют.
+func touchesUser(t EventType) bool {
+	return t != EventKick && t != EventBan
+}
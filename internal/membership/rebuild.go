@@ -0,0 +1,88 @@
+package membership
+
+import (
+	"context"
+	"fmt"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// rebuildBatch сколько событий журнала читается за один запрос
+const rebuildBatch = 10000
+
+// RebuildResult итог пересборки состояния из журнала
+type RebuildResult struct {
+	Events      int64 `json:"events"`
+	Chats       int   `json:"chats"`
+	Users       int   `json:"users"`
+	Memberships int   `json:"memberships"`
+	// DeletedKeys сколько ключей прежнего состояния удалено; 0 при пробном прогоне
+	DeletedKeys int  `json:"deleted_keys"`
+	DryRun      bool `json:"dry_run"`
+}
+
+// Project сворачивает весь журнал в состояние в памяти
+func Project(ctx context.Context, log *Log) (*Projection, int64, error) {
+	projection := NewProjection()
+	events, err := log.Scan(ctx, rebuildBatch, func(record Record) error {
+		projection.Apply(record.Event)
+		return nil
+	})
+	if err != nil {
+		return nil, events, err
+	}
+	return projection, events, nil
+}
+
+// Rebuild пересобирает чаты, пользователей, составы и все счётчики из журнала событий:
+// удаляет текущее состояние и записывает результат свёртки. При dryRun хранилище не меняется.
+// Пересборка не должна выполняться одновременно с приёмом событий. Производные данные
+// наблюдателей (рейтинги, ряды, оценки) не пересобираются и обновляются своими задачами.
+func Rebuild(ctx context.Context, log *Log, repo *storage.Repository, dryRun bool) (*RebuildResult, error) {
+	projection, events, err := Project(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	if events == 0 {
+		return nil, fmt.Errorf("event log is empty, refusing to replace current state")
+	}
+
+	result := &RebuildResult{Events: events, DryRun: dryRun}
+	result.Chats, result.Users, result.Memberships = projection.Counts()
+	if dryRun {
+		return result, nil
+	}
+
+	result.DeletedKeys, err = repo.DeleteAggregates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for chatID, chat := range projection.chats {
+		if chat.exists {
+			if err := repo.SaveChat(ctx, projection.chatEntity(chatID), sortedIDs(chat.members)); err != nil {
+				return nil, err
+			}
+		}
+		for _, userID := range sortedIDs(chat.banned) {
+			if err := repo.Ban(ctx, chatID, userID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for userID, user := range projection.users {
+		if err := repo.SaveUser(ctx, projection.user(userID), sortedIDs(user.chats)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := log.MarkApplied(ctx, events); err != nil {
+		return nil, err
+	}
+
+	logger.Log.Info("State rebuilt from event log", zap.Int64("events", result.Events), zap.Int("chats", result.Chats),
+		zap.Int("users", result.Users), zap.Int("memberships", result.Memberships), zap.Int("deletedKeys", result.DeletedKeys))
+	return result, nil
+}
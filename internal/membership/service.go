@@ -0,0 +1,311 @@
+package membership
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// writerLease срок аренды приёма событий; продлевается при приёме
+const writerLease = 30 * time.Second
+
+// ErrNotWriter события принимает другой процесс
+var ErrNotWriter = errors.New("events are accepted by another instance")
+
+type (
+	// Observer получает уведомление после каждого применённого события. Уведомления приходят
+	// по одному в порядке журнала, поэтому наблюдатель может записывать абсолютные значения.
+	Observer interface {
+		OnChange(ctx context.Context, change *Change) error
+	}
+
+	// ObserverFunc адаптер функции к интерфейсу Observer
+	ObserverFunc func(ctx context.Context, change *Change) error
+
+	// Service записывает события участия в журнал, применяет их к хранилищу и уведомляет наблюдателей.
+	//
+	// Писатель журнала один: процессы с общим хранилищем принимают события, только пока держат
+	// аренду events:writer, остальные отвечают ErrNotWriter. Иначе один процесс применил бы заново
+	// события, уже применённые другим, и наблюдатели со счётчиками учли бы их дважды.
+	Service struct {
+		repo *storage.Repository
+		log  *Log
+
+		// applyMu упорядочивает приём: события применяются по одному в порядке журнала
+		applyMu sync.Mutex
+		// applied номер последнего применённого события; -1 — отметка ещё не прочитана из журнала
+		applied int64
+		// owner идентификатор процесса в аренде приёма; leaseUntil — когда истекает занятая аренда
+		owner      string
+		leaseUntil time.Time
+		// notifyMu захватывается до освобождения applyMu, поэтому наблюдатели получают изменения
+		// по одному в порядке журнала, а следующее событие тем временем уже применяется
+		notifyMu sync.Mutex
+
+		mu        sync.RWMutex
+		observers []Observer
+	}
+)
+
+// quiesce останавливает и применение событий, и уведомления о них; порядок захвата
+// тот же, что при приёме
+type quiesce struct {
+	s *Service
+}
+
+func (q quiesce) Lock() {
+	q.s.applyMu.Lock()
+	q.s.notifyMu.Lock()
+}
+
+func (q quiesce) Unlock() {
+	q.s.notifyMu.Unlock()
+	q.s.applyMu.Unlock()
+}
+
+func (f ObserverFunc) OnChange(ctx context.Context, change *Change) error {
+	return f(ctx, change)
+}
+
+func NewService(repo *storage.Repository, log *Log) *Service {
+	owner := make([]byte, 8)
+	_, _ = rand.Read(owner)
+	return &Service{repo: repo, log: log, applied: -1, owner: hex.EncodeToString(owner)}
+}
+
+// Subscribe регистрирует наблюдателя изменений
+func (s *Service) Subscribe(observer Observer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observers = append(s.observers, observer)
+}
+
+// Locker возвращает блокировку приёма: пока она удерживается, события не применяются,
+// а уведомления о применённых уже доставлены. Через неё исправления сверки и пересчёт
+// производных счётчиков не пересекаются с изменениями состава чатов.
+func (s *Service) Locker() sync.Locker {
+	return quiesce{s}
+}
+
+// Apply записывает событие в журнал и применяет его: обновляет состав чата, счётчики
+// и время активности пользователя. Сообщение от пользователя, которого нет в чате,
+// считается неявным вступлением; забаненный пользователь в чат не возвращается.
+// Правила должны совпадать с Projection.Apply.
+//
+// Каждый шаг применения идемпотентен, а номер последнего применённого события хранится
+// рядом с журналом. Если применение прервалось, событие остаётся в журнале и применяется
+// повторно перед следующим: состояние хранилища всегда равно свёртке журнала.
+// Ошибки наблюдателей логируются и не прерывают обработку события.
+func (s *Service) Apply(ctx context.Context, event Event) (*Change, error) {
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.applyMu.Lock()
+	if err := s.claim(ctx); err != nil {
+		s.applyMu.Unlock()
+		return nil, err
+	}
+	if err := s.loadApplied(ctx); err != nil {
+		s.applyMu.Unlock()
+		return nil, err
+	}
+	seq, err := s.log.Append(ctx, event)
+	if err != nil {
+		s.applyMu.Unlock()
+		return nil, err
+	}
+	if err := s.catchUp(ctx, seq-1); err != nil {
+		s.applyMu.Unlock()
+		return nil, err
+	}
+	change, err := s.apply(ctx, Record{Seq: seq, Event: event})
+	if err != nil {
+		s.applyMu.Unlock()
+		return nil, err
+	}
+
+	s.notifyMu.Lock()
+	s.applyMu.Unlock()
+	s.notify(ctx, change)
+	s.notifyMu.Unlock()
+	return change, nil
+}
+
+// Release освобождает аренду приёма при остановке, чтобы следующий процесс не ждал её истечения
+func (s *Service) Release(ctx context.Context) error {
+	s.applyMu.Lock()
+	defer s.applyMu.Unlock()
+
+	s.leaseUntil = time.Time{}
+	return s.log.Release(ctx, s.owner)
+}
+
+// claim занимает или продлевает аренду приёма; вызывается под applyMu. Хранилище
+// опрашивается, только когда до истечения аренды осталось меньше половины срока.
+func (s *Service) claim(ctx context.Context) error {
+	now := time.Now()
+	if now.Before(s.leaseUntil.Add(-writerLease / 2)) {
+		return nil
+	}
+	ok, fresh, err := s.log.Claim(ctx, s.owner, writerLease)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotWriter
+	}
+	if fresh {
+		// Без аренды события мог применять другой процесс: отметка перечитывается из журнала
+		s.applied = -1
+	}
+	s.leaseUntil = now.Add(writerLease)
+	return nil
+}
+
+// loadApplied читает отметку последнего применённого события при первом приёме.
+// Без отметки (первый запуск с журналом) текущее состояние считается применённым целиком.
+func (s *Service) loadApplied(ctx context.Context) error {
+	if s.applied >= 0 {
+		return nil
+	}
+	applied, ok, err := s.log.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		if applied, err = s.log.Len(ctx); err != nil {
+			return err
+		}
+		if err := s.log.MarkApplied(ctx, applied); err != nil {
+			return err
+		}
+	}
+	s.applied = applied
+	return nil
+}
+
+// catchUp применяет события журнала после последнего применённого до until включительно:
+// те, применение которых прервалось раньше
+func (s *Service) catchUp(ctx context.Context, until int64) error {
+	for s.applied < until {
+		logger.Log.Warn("Replaying unapplied events", zap.Int64("from", s.applied+1), zap.Int64("to", until))
+		records, err := s.log.Read(ctx, s.applied+1, int(min(until-s.applied, rebuildBatch)))
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return fmt.Errorf("event log ends at %d before event %d", s.applied, until)
+		}
+		for _, record := range records {
+			change, err := s.apply(ctx, record)
+			if err != nil {
+				return err
+			}
+			s.notifyMu.Lock()
+			s.notify(ctx, change)
+			s.notifyMu.Unlock()
+		}
+	}
+	return nil
+}
+
+// apply применяет событие журнала к хранилищу и отмечает его применённым
+func (s *Service) apply(ctx context.Context, record Record) (*Change, error) {
+	event := record.Event
+	if event.ChatType != nil {
+		if err := s.repo.SetChatType(ctx, event.ChatID, *event.ChatType); err != nil {
+			return nil, err
+		}
+	}
+
+	var changed bool
+	var err error
+	switch event.Type {
+	case EventJoin, EventMessage:
+		banned, err := s.repo.IsBanned(ctx, event.ChatID, event.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !banned {
+			changed, err = s.repo.AddMembership(ctx, event.ChatID, event.UserID)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case EventLeave, EventKick, EventBan:
+		if event.Type == EventBan {
+			if err := s.repo.Ban(ctx, event.ChatID, event.UserID); err != nil {
+				return nil, err
+			}
+		}
+		changed, err = s.repo.RemoveMembership(ctx, event.ChatID, event.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if touchesUser(event.Type) {
+		if err := s.repo.TouchUser(ctx, event.UserID, event.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.log.MarkApplied(ctx, record.Seq); err != nil {
+		return nil, err
+	}
+	s.applied = record.Seq
+
+	change := &Change{Seq: record.Seq, Event: event, MembershipChanged: changed}
+	if err := s.loadState(ctx, change); err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+func (s *Service) loadState(ctx context.Context, change *Change) error {
+	chat, err := s.repo.Chat(ctx, change.Event.ChatID)
+	switch {
+	case err == nil:
+		change.Chat = *chat
+	case errors.Is(err, apperrors.ErrNotFound):
+		change.Chat = entities.Chat{ChatID: change.Event.ChatID}
+	default:
+		return err
+	}
+
+	user, err := s.repo.User(ctx, change.Event.UserID)
+	switch {
+	case err == nil:
+		change.User = *user
+	case errors.Is(err, apperrors.ErrNotFound):
+		change.User = entities.User{UserID: change.Event.UserID}
+	default:
+		return err
+	}
+	return nil
+}
+
+func (s *Service) notify(ctx context.Context, change *Change) {
+	s.mu.RLock()
+	observers := s.observers
+	s.mu.RUnlock()
+
+	for _, observer := range observers {
+		if err := observer.OnChange(ctx, change); err != nil {
+			logger.Log.Error("Membership observer failed", zap.String("event", string(change.Event.Type)),
+				zap.Int64("chatID", int64(change.Event.ChatID)), zap.Int64("userID", int64(change.Event.UserID)), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package membership
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// flakyStorage отказывает в добавлении связи, пока fail больше нуля
+type flakyStorage struct {
+	storage.Storage
+	fail int
+}
+
+func (f *flakyStorage) SetLinkAdd(ctx context.Context, link entities.SetLink) (bool, error) {
+	if f.fail > 0 {
+		f.fail--
+		return false, errors.New("storage is unavailable")
+	}
+	return f.Storage.SetLinkAdd(ctx, link)
+}
+
+func newTestService(t *testing.T) (*Service, *storage.Repository, *flakyStorage) {
+	t.Helper()
+	store := &flakyStorage{Storage: memtest.NewStorage(t)}
+	repo := storage.NewRepository(store)
+	return NewService(repo, NewLog(store)), repo, store
+}
+
+// randomEvents порождает события небольшого числа чатов и пользователей, чтобы вступления,
+// выходы и баны часто касались одних и тех же пар
+func randomEvents(seed int64, n int) []Event {
+	rng := rand.New(rand.NewSource(seed))
+	types := []EventType{EventJoin, EventJoin, EventMessage, EventMessage, EventLeave, EventKick, EventBan}
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	events := make([]Event, n)
+	for i := range events {
+		events[i] = Event{
+			Type:   types[rng.Intn(len(types))],
+			ChatID: entities.ChatID(rng.Intn(4) + 1),
+			UserID: entities.UserID(rng.Intn(6) + 1),
+			// Время иногда идёт назад: опоздавшие события не должны откатывать last_time
+			Time: start.Add(time.Duration(rng.Intn(1000)) * time.Second),
+		}
+		if rng.Intn(5) == 0 {
+			chatType := uint(rng.Intn(3))
+			events[i].ChatType = &chatType
+		}
+	}
+	return events
+}
+
+// assertMatchesProjection сравнивает хранилище со свёрткой журнала
+func assertMatchesProjection(t *testing.T, repo *storage.Repository, projection *Projection) {
+	t.Helper()
+	ctx := context.Background()
+	for chatID, state := range projection.chats {
+		chat, err := repo.Chat(ctx, chatID)
+		if !state.exists {
+			if err == nil {
+				t.Errorf("chat %d exists in storage but not in projection", chatID)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("chat %d: %v", chatID, err)
+		}
+		if want := projection.chatEntity(chatID); *chat != want {
+			t.Errorf("chat %d = %+v, projection has %+v", chatID, *chat, want)
+		}
+		members, err := repo.ChatUserIDs(ctx, chatID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(members)
+		if want := sortedIDs(state.members); !slices.Equal(members, want) {
+			t.Errorf("chat %d members = %v, projection has %v", chatID, members, want)
+		}
+		for userID := range state.banned {
+			if banned, err := repo.IsBanned(ctx, chatID, userID); err != nil || !banned {
+				t.Errorf("user %d is not banned in chat %d: %v", userID, chatID, err)
+			}
+		}
+	}
+	for userID, state := range projection.users {
+		user, err := repo.User(ctx, userID)
+		if err != nil {
+			t.Fatalf("user %d: %v", userID, err)
+		}
+		if want := projection.user(userID); *user != want {
+			t.Errorf("user %d = %+v, projection has %+v", userID, *user, want)
+		}
+		chats, err := repo.UserChatIDs(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		slices.Sort(chats)
+		if want := sortedIDs(state.chats); !slices.Equal(chats, want) {
+			t.Errorf("user %d chats = %v, projection has %v", userID, chats, want)
+		}
+	}
+}
+
+func TestProjectionMatchesService(t *testing.T) {
+	for _, seed := range []int64{1, 2, 3} {
+		ctx := context.Background()
+		service, repo, _ := newTestService(t)
+		projection := NewProjection()
+
+		for i, event := range randomEvents(seed, 300) {
+			change, err := service.Apply(ctx, event)
+			if err != nil {
+				t.Fatalf("seed %d, event %d: %v", seed, i, err)
+			}
+			if changed := projection.Apply(event); changed != change.MembershipChanged {
+				t.Fatalf("seed %d, event %d %+v: service changed = %v, projection changed = %v",
+					seed, i, event, change.MembershipChanged, changed)
+			}
+			if change.Seq != int64(i+1) {
+				t.Fatalf("seed %d, event %d has seq %d", seed, i, change.Seq)
+			}
+		}
+		assertMatchesProjection(t, repo, projection)
+
+		// Свёртка журнала даёт то же состояние, что и пошаговое применение
+		replayed, events, err := Project(ctx, service.log)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if events != 300 {
+			t.Fatalf("log has %d events, want 300", events)
+		}
+		assertMatchesProjection(t, repo, replayed)
+	}
+}
+
+// TestApplyReplaysInterruptedEvent проверяет, что событие, применение которого прервалось после
+// записи в журнал, применяется перед следующим и состояние снова равно свёртке журнала
+func TestApplyReplaysInterruptedEvent(t *testing.T) {
+	ctx := context.Background()
+	service, repo, store := newTestService(t)
+
+	if _, err := service.Apply(ctx, Event{Type: EventJoin, ChatID: 1, UserID: 10, Time: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	store.fail = 1
+	if _, err := service.Apply(ctx, Event{Type: EventJoin, ChatID: 1, UserID: 11, Time: time.Now()}); err == nil {
+		t.Fatal("expected the storage failure to be reported")
+	}
+	change, err := service.Apply(ctx, Event{Type: EventLeave, ChatID: 1, UserID: 10, Time: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Seq != 3 || change.Chat.CountOfUsers != 1 {
+		t.Errorf("change = %+v; want seq 3 with user 11 left in chat", change)
+	}
+
+	projection, _, err := Project(ctx, service.log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertMatchesProjection(t, repo, projection)
+	if applied, _, err := service.log.Applied(ctx); err != nil || applied != 3 {
+		t.Errorf("applied = %d, %v; want 3", applied, err)
+	}
+}
+
+// TestObserversReceiveChangesInOrder проверяет, что при параллельном приёме наблюдатели
+// получают изменения по одному и в порядке журнала
+func TestObserversReceiveChangesInOrder(t *testing.T) {
+	ctx := context.Background()
+	service, _, _ := newTestService(t)
+
+	var seqs []int64
+	var busy atomic.Bool
+	service.Subscribe(ObserverFunc(func(_ context.Context, change *Change) error {
+		if busy.Swap(true) {
+			t.Error("observer is called concurrently")
+		}
+		seqs = append(seqs, change.Seq)
+		time.Sleep(time.Duration(change.Seq%3) * time.Millisecond)
+		busy.Store(false)
+		return nil
+	}))
+
+	events := randomEvents(4, 200)
+	var wg sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := worker; i < len(events); i += 8 {
+				if _, err := service.Apply(ctx, events[i]); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seqs) != len(events) {
+		t.Fatalf("observer got %d changes, want %d", len(seqs), len(events))
+	}
+	for i, seq := range seqs {
+		if seq != int64(i+1) {
+			t.Fatalf("change %d has seq %d: %v", i, seq, seqs)
+		}
+	}
+}
+
+// TestSingleWriter проверяет, что второй процесс с тем же хранилищем не принимает события,
+// пока аренда за первым, а после её освобождения не применяет и не рассылает заново
+// события, уже применённые первым
+func TestSingleWriter(t *testing.T) {
+	ctx := context.Background()
+	first, repo, store := newTestService(t)
+	second := NewService(repo, NewLog(store))
+	var notified []int64
+	second.Subscribe(ObserverFunc(func(_ context.Context, change *Change) error {
+		notified = append(notified, change.Seq)
+		return nil
+	}))
+
+	// Второй процесс прочитал отметку до того, как первый принял события
+	if err := second.loadApplied(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range []entities.UserID{10, 11} {
+		if _, err := first.Apply(ctx, Event{Type: EventJoin, ChatID: 1, UserID: userID, Time: time.Now()}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := second.Apply(ctx, Event{Type: EventJoin, ChatID: 1, UserID: 12, Time: time.Now()}); !errors.Is(err, ErrNotWriter) {
+		t.Fatalf("apply on the second instance: %v, want ErrNotWriter", err)
+	}
+
+	if err := first.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// Событие, отклонённое вторым процессом, в журнал не попало
+	change, err := second.Apply(ctx, Event{Type: EventJoin, ChatID: 1, UserID: 12, Time: time.Now()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Seq != 3 || change.Chat.CountOfUsers != 3 || !slices.Equal(notified, []int64{3}) {
+		t.Errorf("change %+v, notified %v; want seq 3 with 3 users and only it notified", change, notified)
+	}
+	if _, err := first.Apply(ctx, Event{Type: EventLeave, ChatID: 1, UserID: 12, Time: time.Now()}); !errors.Is(err, ErrNotWriter) {
+		t.Errorf("apply on the first instance after release: %v, want ErrNotWriter", err)
+	}
+}
@@ -1 +1,33 @@
 package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// AdminOnly пропускает запрос к next, только если заголовок Authorization содержит "Bearer {token}".
+// С пустым token административные операции отключены: каждый запрос получает 403.
+func AdminOnly(token string, next func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			utils.RespondWithError(w, http.StatusForbidden, "admin operations are disabled; set ADMIN_TOKEN to enable them")
+			return
+		}
+
+		presented, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+			logger.Log.Warn("Rejected admin request", zap.String("method", r.Method), zap.String("path", r.URL.Path),
+				zap.String("remote", r.RemoteAddr))
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			utils.RespondWithError(w, http.StatusUnauthorized, "admin token is missing or invalid")
+			return
+		}
+		next(w, r)
+	}
+}
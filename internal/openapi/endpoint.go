@@ -0,0 +1,41 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+
+	"stats-of/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+//go:embed ui
+var ui embed.FS
+
+// MakeSpecHandler обработчик GET /openapi.json. Документ кодируется один раз при создании обработчика.
+func MakeSpecHandler(doc *Document) func(w http.ResponseWriter, r *http.Request) {
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		// Документ собирается из типов Go и сериализуется всегда; ошибка здесь — ошибка в Build
+		panic("openapi: failed to encode document: " + err.Error())
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		if _, err := w.Write(data); err != nil {
+			logger.Log.Error("Failed to write OpenAPI document", zap.Error(err))
+		}
+	}
+}
+
+// DocsHandler обработчик GET /docs/: встроенная страница документации, которая читает /openapi.json
+func DocsHandler() http.Handler {
+	files, err := fs.Sub(ui, "ui")
+	if err != nil {
+		panic("openapi: " + err.Error())
+	}
+	return http.StripPrefix("/docs/", http.FileServerFS(files))
+}
@@ -0,0 +1,552 @@
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stats-of/internal/anomaly"
+	"stats-of/internal/batch"
+	"stats-of/internal/churn"
+	"stats-of/internal/community"
+	"stats-of/internal/distribution"
+	"stats-of/internal/engagement"
+	"stats-of/internal/entities"
+	"stats-of/internal/forecast"
+	"stats-of/internal/graphqlapi"
+	"stats-of/internal/heavyhitters"
+	"stats-of/internal/ingest"
+	"stats-of/internal/leaderboard"
+	"stats-of/internal/membership"
+	"stats-of/internal/pagination"
+	"stats-of/internal/reconcile"
+	"stats-of/internal/sessions"
+	"stats-of/internal/similarity"
+	"stats-of/internal/snapshot"
+	"stats-of/internal/timeseries"
+	"stats-of/internal/uniques"
+	"stats-of/internal/utils"
+
+	"github.com/graphql-go/graphql"
+)
+
+const errorSchema = "#/components/schemas/utils.ErrorResponse"
+
+// Build описывает все маршруты сервиса. При добавлении маршрута в app.New его нужно описать здесь:
+// тест пакета app сверяет документ с зарегистрированными маршрутами.
+func Build(info *entities.AppInfo) *Document {
+	b := newBuilder(info)
+
+	b.get("/healthz", "service", "Application build information").
+		returns(entities.AppInfo{})
+	b.get("/metrics", "service", "Prometheus metrics").
+		content(http.StatusOK, "Metrics in the Prometheus text exposition format", "text/plain")
+	b.get("/openapi.json", "service", "This OpenAPI document").
+		content(http.StatusOK, "OpenAPI 3 document", "application/json")
+	b.get("/docs/", "service", "Interactive API documentation").
+		content(http.StatusOK, "Documentation UI", "text/html")
+
+	b.get("/api/v1/chats", "directory", "List chats").
+		describe("Chats matching the filter, page by page. Without sort the order is unspecified but stable across pages.").
+		filter("chat_id, chat_type, count_of_users (aliases: id, size)", "count_of_users > 1000 AND chat_type IN (1, 2)").
+		page().
+		returns(pagination.Page[entities.Chat]{}, http.StatusBadRequest)
+	b.get("/api/v1/chats/{id}", "directory", "Get a chat").
+		id("Chat ID").
+		returns(entities.Chat{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/chats/{id}/users", "directory", "List members of a chat").
+		id("Chat ID").
+		page().
+		returns(pagination.Page[entities.User]{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/users", "directory", "List users").
+		describe("Users matching the filter, page by page. last_time compares with RFC 3339 times and relative times such as now-7d.").
+		filter("user_id, last_time, count_of_chats (aliases: id, chats)", "last_time > now-7d AND count_of_chats >= 3").
+		page().
+		returns(pagination.Page[entities.User]{}, http.StatusBadRequest)
+	b.get("/api/v1/users/{id}", "directory", "Get a user").
+		id("User ID").
+		returns(entities.User{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/users/{id}/chats", "directory", "List chats of a user").
+		id("User ID").
+		page().
+		returns(pagination.Page[entities.Chat]{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/export/{dataset}", "export", "Export a dataset").
+		describe("Streams every record as CSV or NDJSON. The format comes from the format parameter or the Accept header; "+
+			"the X-Export-Status trailer is complete when the export finished and error when it was cut short.").
+		path("dataset", "Dataset to export", enum("chats", "users", "memberships")).
+		query("columns", "Comma-separated columns to include, all by default", str()).
+		query("filter", "Filter for chats and users in the listing query language", str()).
+		query("format", "Output format; overrides Accept", enum("csv", "ndjson")).
+		export().
+		errors(http.StatusBadRequest, http.StatusNotFound, http.StatusNotAcceptable)
+
+	b.post("/graphql", "graphql", "Run a GraphQL query").
+		describe("Queries chats, users, chat members and user chats in one round trip. Storage reads are batched per level "+
+			"of the response. Queries deeper or costlier than the configured limits are rejected before execution; "+
+			"query errors are reported in errors with status 200.").
+		body(graphqlapi.Request{}).
+		returns(graphql.Result{}, http.StatusBadRequest)
+	b.get("/graphql", "graphql", "Run a GraphQL query from URL parameters").
+		required("query", "GraphQL query document", str()).
+		query("operationName", "Operation to run when the document has several", str()).
+		query("variables", "Variables as a JSON object", str()).
+		returns(graphql.Result{}, http.StatusBadRequest)
+	b.post("/api/v1/batch", "batch", "Run many chat and user lookups at once").
+		describe("Runs up to 1000 sub-queries: chat_ids and user_ids fetch records, queries may also ask for "+
+			"chat_users, user_chats (up to limit member or chat IDs read with a bounded SSCAN, not necessarily the smallest; "+
+			"limit 0..1000, default 100), chat_rank and user_rank. "+
+			"Records are read in bulk and sub-queries run concurrently. Each result carries its own status and error; "+
+			"a failed sub-query does not fail the batch.").
+		body(batch.Request{}).
+		returns(batch.Response{}, http.StatusBadRequest)
+
+	b.get("/api/v1/chats/{id}/similar", "similarity", "Chats with a similar audience").
+		id("Chat ID").
+		query("k", "Number of chats to return", integer(10, 1, 100)).
+		query("metric", "Similarity metric", enum("jaccard", "cosine")).
+		query("exact", "Rescore MinHash estimates against the actual member sets", boolean()).
+		returns(similarity.Response{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/chats/{id}/community", "communities", "Community of a chat").
+		id("Chat ID").
+		returns(community.ChatCommunity{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/communities", "communities", "Latest community detection result").
+		offsetPage().
+		returns(community.Overview{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/communities/{id}", "communities", "Get a community").
+		id("Community ID").
+		returns(community.Community{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/leaderboards/{board}", "leaderboards", "Top chats by members or users by chats").
+		path("board", "Leaderboard", enum("chats", "users")).
+		offsetPage().
+		returns(pagination.Page[leaderboard.Entry]{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/leaderboards/{board}/{id}", "leaderboards", "Rank of a chat or a user").
+		path("board", "Leaderboard", enum("chats", "users")).
+		id("Chat or user ID").
+		returns(leaderboard.Entry{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.post("/api/v1/events", "events", "Apply a membership event").
+		describe("Applies a join, leave, kick, ban or message event. time defaults to the moment the event is received. "+
+			"Requires the admin token; disabled with 403 when ADMIN_TOKEN is not configured.").
+		header("Authorization", "Bearer followed by the admin token", str()).
+		body(membership.Event{}).
+		returns(ingest.Result{}, http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden, http.StatusServiceUnavailable)
+	b.get("/api/v1/events", "events", "Read the event log").
+		query("from", "Sequence number to start from", integer(1, 1, 0)).
+		query("limit", "Page size", integer(100, 1, 1000)).
+		query("cursor", "Cursor from the next field of the previous page; overrides from", str()).
+		returns(pagination.Page[membership.Record]{}, http.StatusBadRequest)
+	b.get("/api/v1/stream", "events", "Stream live counters").
+		describe("Server-Sent Events. A snapshot event carries the current global counters and the listed chats; "+
+			"a counters event follows every membership change, with the chat field only for listed chats. "+
+			"Heartbeat comments keep the connection open. Reconnecting with Last-Event-ID replays missed events "+
+			"from a short in-memory buffer, or sends a fresh snapshot when they are gone.").
+		query("chats", "Comma-separated chat IDs to receive updates for", str()).
+		header("Last-Event-ID", "ID of the last event received, to resume the stream", str()).
+		content(http.StatusOK, "Event stream of snapshot and counters events", "text/event-stream").
+		errors(http.StatusBadRequest, http.StatusServiceUnavailable)
+	b.get("/api/v1/stream/ws", "events", "Subscribe to chat counters over WebSocket").
+		describe("WebSocket. Send {\"type\": \"subscribe\" or \"unsubscribe\", \"chat_ids\": [...], \"patterns\": [...]}, "+
+			"where patterns match decimal chat IDs as in path.Match, e.g. \"12*\". The server answers with the current "+
+			"subscriptions or an error and sends deltas messages with member count changes of subscribed chats; deltas "+
+			"of one chat are merged while the client reads slowly. The server pings periodically and closes "+
+			"connections that do not answer within a minute. The number of chats and patterns per client is limited.").
+		content(http.StatusSwitchingProtocols, "WebSocket connection", "application/json").
+		errors(http.StatusBadRequest)
+
+	b.get("/api/v1/stats/distributions", "statistics", "Distributions of chat sizes and user memberships").
+		query("metric", "Only this metric", enum("chat_users", "user_chats")).
+		query("chat_type", "Only this chat type, or all", str()).
+		returns(distribution.Report{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/timeseries", "statistics", "Time series").
+		required("series", "Series name, for example global:users, global:chats or global:memberships", str()).
+		timeRange().
+		returns(timeseries.Result{}, http.StatusBadRequest)
+	b.get("/api/v1/chats/{id}/timeseries", "statistics", "Member count of a chat over time").
+		id("Chat ID").
+		timeRange().
+		returns(timeseries.Result{}, http.StatusBadRequest)
+	b.get("/api/v1/uniques", "statistics", "Estimated number of distinct active users").
+		query("chats", "Comma-separated chat IDs; all chats by default", str()).
+		query("from", "First day, YYYY-MM-DD; six days before to by default", date()).
+		query("to", "Last day, YYYY-MM-DD; today by default", date()).
+		returns(uniques.Estimate{}, http.StatusBadRequest)
+	b.get("/api/v1/trending/{kind}", "statistics", "Most active chats or users in a sliding window").
+		path("kind", "What to rank", enum("chats", "users")).
+		query("window", "One of the configured windows, for example 1h", duration()).
+		query("k", "Number of entries", integer(10, 1, 1000)).
+		query("completed", "Use the last completed window instead of the current one", boolean()).
+		returns(heavyhitters.Trending{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/anomalies", "anomalies", "Recent anomalies").
+		anomalies().
+		returns(pagination.Page[anomaly.Anomaly]{}, http.StatusBadRequest)
+	b.get("/api/v1/chats/{id}/anomalies", "anomalies", "Recent anomalies of a chat").
+		id("Chat ID").
+		anomalies().
+		returns(pagination.Page[anomaly.Anomaly]{}, http.StatusBadRequest)
+	b.get("/api/v1/forecast", "forecast", "Forecast a time series").
+		query("series", "Series name", withDefault(str(), timeseries.SeriesUsers)).
+		forecast().
+		returns(forecast.Result{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/chats/{id}/forecast", "forecast", "Forecast the member count of a chat").
+		id("Chat ID").
+		forecast().
+		returns(forecast.Result{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/churn", "churn", "Churn report").
+		query("chat_type", "Only this chat type", str()).
+		returns(churn.Report{}, http.StatusNotFound)
+	b.get("/api/v1/churn/users", "churn", "Inactive users").
+		query("status", "Only users with this status", enum("at_risk", "churned")).
+		offsetPage().
+		returns(pagination.Page[churn.Inactive]{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/churn/transitions", "churn", "Daily status transitions").
+		query("from", "First day, YYYY-MM-DD; 29 days before to by default", date()).
+		query("to", "Last day, YYYY-MM-DD; today by default", date()).
+		returns([]churn.Transitions{}, http.StatusBadRequest)
+	b.get("/api/v1/chats/{id}/churn", "churn", "Churn of a chat's members").
+		id("Chat ID").
+		returns(churn.Counts{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/users/{id}/sessions", "sessions", "Session statistics of a user").
+		id("User ID").
+		returns(sessions.Stats{}, http.StatusBadRequest)
+	b.get("/api/v1/chats/{id}/sessions", "sessions", "Session statistics of a chat").
+		id("Chat ID").
+		returns(sessions.Stats{}, http.StatusBadRequest)
+
+	b.get("/api/v1/engagement", "engagement", "Engagement report and top users").
+		offsetPage().
+		returns(engagement.ReportPage{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/users/{id}/engagement", "engagement", "Engagement score of a user").
+		id("User ID").
+		returns(engagement.UserScore{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/chats/{id}/engagement", "engagement", "Engagement distribution of a chat's members").
+		id("Chat ID").
+		returns(engagement.ChatReport{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/snapshots", "snapshots", "List snapshots").
+		describe("Saved snapshots, newest first.").
+		offsetPage().
+		returns(pagination.Page[snapshot.Info]{}, http.StatusBadRequest)
+	b.get("/api/v1/snapshots/diff", "snapshots", "Difference between two snapshots").
+		describe("Counters cover all changed chats; items is a page of changed chats by ascending ID. "+
+			"A cursor is bound to the two snapshots compared: pass the to of the first page when paging without to.").
+		snapshotRange().
+		offsetPage().
+		returns(snapshot.Diff{}, http.StatusBadRequest, http.StatusNotFound)
+	b.get("/api/v1/chats/{id}/diff", "snapshots", "Difference of a chat between two snapshots").
+		id("Chat ID").
+		snapshotRange().
+		offsetPage().
+		returns(snapshot.Diff{}, http.StatusBadRequest, http.StatusNotFound)
+
+	b.get("/api/v1/admin/reconcile", "admin", "Reconciliation status").
+		describe("Whether a reconciliation is running and the report of the last finished one. Poll it after starting a run.").
+		returns(reconcile.Status{}, http.StatusNotFound)
+	b.post("/api/v1/admin/reconcile", "admin", "Start reconciliation").
+		describe("Starts a rate-limited reconciliation in the background and returns 202 at once; poll GET for the result. "+
+			"Requires the admin token; disabled with 403 when ADMIN_TOKEN is not configured.").
+		header("Authorization", "Bearer followed by the admin token", str()).
+		query("repair", "Fix the counters that do not match; the configured default otherwise", boolean()).
+		returnsStatus(http.StatusAccepted, reconcile.Status{}, http.StatusBadRequest, http.StatusUnauthorized,
+			http.StatusForbidden, http.StatusConflict)
+
+	return b.doc
+}
+
+type (
+	builder struct {
+		doc     *Document
+		schemas *schemas
+	}
+
+	operation struct {
+		*Operation
+		b *builder
+	}
+)
+
+var statusResponses = map[int]string{
+	http.StatusBadRequest:          "BadRequest",
+	http.StatusUnauthorized:        "Unauthorized",
+	http.StatusForbidden:           "Forbidden",
+	http.StatusNotFound:            "NotFound",
+	http.StatusNotAcceptable:       "NotAcceptable",
+	http.StatusConflict:            "Conflict",
+	http.StatusInternalServerError: "InternalServerError",
+	http.StatusServiceUnavailable:  "ServiceUnavailable",
+}
+
+func newBuilder(info *entities.AppInfo) *builder {
+	s := newSchemas()
+	s.enum(membership.EventType(""), "join", "leave", "kick", "ban", "message")
+	s.enum(similarity.Metric(""), "jaccard", "cosine")
+	s.enum(distribution.Metric(""), "chat_users", "user_chats")
+	s.enum(churn.Status(""), "active", "at_risk", "churned")
+	s.enum(forecast.Method(""), "auto", "linear", "holt_winters")
+	s.enum(leaderboard.Board(""), "chats", "users")
+	s.enum(heavyhitters.Kind(""), "chats", "users")
+
+	// Тело ошибки из utils.RespondWithError, на которое ссылаются общие ответы
+	s.of(utils.ErrorResponse{})
+	responses := make(map[string]*Response, len(statusResponses))
+	for status, name := range statusResponses {
+		responses[name] = &Response{
+			Description: http.StatusText(status),
+			Content:     map[string]MediaType{"application/json": {Schema: &Schema{Ref: errorSchema}}},
+		}
+	}
+
+	return &builder{
+		doc: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:       info.Name,
+				Description: "Statistics of chats and their members. Errors are returned as {\"error\": \"message\"}.",
+				Version:     info.BuildVersion,
+			},
+			Paths:      make(map[string]*PathItem),
+			Components: Components{Schemas: s.components, Responses: responses},
+			types:      s.types,
+		},
+		schemas: s,
+	}
+}
+
+func (b *builder) get(path, tag, summary string) *operation {
+	return b.add(http.MethodGet, path, tag, summary)
+}
+
+func (b *builder) post(path, tag, summary string) *operation {
+	return b.add(http.MethodPost, path, tag, summary)
+}
+
+func (b *builder) add(method, path, tag, summary string) *operation {
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = &PathItem{}
+		b.doc.Paths[path] = item
+	}
+	op := &Operation{
+		Tags:        []string{tag},
+		Summary:     summary,
+		OperationID: operationID(method, path),
+		Responses:   make(map[string]*Response),
+	}
+	if strings.HasPrefix(path, "/api/") {
+		op.Responses[strconv.Itoa(http.StatusInternalServerError)] = responseRef(http.StatusInternalServerError)
+	}
+	if method == http.MethodPost {
+		item.Post = op
+	} else {
+		item.Get = op
+	}
+
+	known := false
+	for _, t := range b.doc.Tags {
+		known = known || t.Name == tag
+	}
+	if !known {
+		b.doc.Tags = append(b.doc.Tags, Tag{Name: tag})
+	}
+	return &operation{Operation: op, b: b}
+}
+
+func (o *operation) describe(description string) *operation {
+	o.Description = description
+	return o
+}
+
+func (o *operation) path(name, description string, schema *Schema) *operation {
+	o.Parameters = append(o.Parameters, Parameter{Name: name, In: "path", Description: description, Required: true, Schema: schema})
+	return o
+}
+
+func (o *operation) id(description string) *operation {
+	return o.path("id", description, &Schema{Type: "integer", Format: "int64"})
+}
+
+func (o *operation) query(name, description string, schema *Schema) *operation {
+	o.Parameters = append(o.Parameters, Parameter{Name: name, In: "query", Description: description, Schema: schema})
+	return o
+}
+
+func (o *operation) header(name, description string, schema *Schema) *operation {
+	o.Parameters = append(o.Parameters, Parameter{Name: name, In: "header", Description: description, Schema: schema})
+	return o
+}
+
+// required обязательный параметр запроса
+func (o *operation) required(name, description string, schema *Schema) *operation {
+	o.Parameters = append(o.Parameters, Parameter{Name: name, In: "query", Description: description, Required: true, Schema: schema})
+	return o
+}
+
+func (o *operation) body(value any) *operation {
+	o.RequestBody = &RequestBody{
+		Required: true,
+		Content:  map[string]MediaType{"application/json": {Schema: o.b.schemas.of(value)}},
+	}
+	return o
+}
+
+// returns описывает JSON-ответ 200 и ответы с ошибками
+func (o *operation) returns(value any, statuses ...int) *operation {
+	return o.returnsStatus(http.StatusOK, value, statuses...)
+}
+
+func (o *operation) returnsStatus(status int, value any, statuses ...int) *operation {
+	o.Responses[strconv.Itoa(status)] = &Response{
+		Description: http.StatusText(status),
+		Content:     map[string]MediaType{"application/json": {Schema: o.b.schemas.of(value)}},
+	}
+	return o.errors(statuses...)
+}
+
+// content описывает ответ, тело которого не JSON-схема
+func (o *operation) content(status int, description string, mediaTypes ...string) *operation {
+	content := make(map[string]MediaType, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = MediaType{Schema: str()}
+	}
+	o.Responses[strconv.Itoa(status)] = &Response{Description: description, Content: content}
+	return o
+}
+
+func (o *operation) errors(statuses ...int) *operation {
+	for _, status := range statuses {
+		o.Responses[strconv.Itoa(status)] = responseRef(status)
+	}
+	return o
+}
+
+func (o *operation) filter(fields, example string) *operation {
+	return o.
+		query("filter", "Filter expression over "+fields+" with =, !=, <, <=, >, >=, IN, NOT IN, AND, OR, NOT and parentheses, "+
+			"for example: "+example, str()).
+		query("sort", "Comma-separated fields; a leading - sorts in descending order", str())
+}
+
+// page параметры страниц по курсору
+func (o *operation) page() *operation {
+	return o.
+		query("limit", "Page size", integer(100, 1, 1000)).
+		query("cursor", "Cursor from the next field of the previous page", str())
+}
+
+// offsetPage параметры страниц по курсору или смещению
+func (o *operation) offsetPage() *operation {
+	return o.page().
+		query("offset", "Number of entries to skip; ignored when cursor is set", integer(0, 0, 0))
+}
+
+func (o *operation) timeRange() *operation {
+	return o.
+		query("from", "Start of the range, RFC 3339 or unix seconds; 24 hours before to by default", timestamp()).
+		query("to", "End of the range, RFC 3339 or unix seconds; now by default", timestamp()).
+		query("step", "Bucket size", withDefault(duration(), "1h"))
+}
+
+func (o *operation) anomalies() *operation {
+	return o.
+		query("since", "Only anomalies detected after this time, RFC 3339 or unix seconds; 24 hours ago by default", timestamp()).
+		page()
+}
+
+func (o *operation) forecast() *operation {
+	return o.
+		query("method", "Forecasting method", withDefault(enum("auto", "linear", "holt_winters"), "auto")).
+		query("level", "Confidence level of the prediction interval, between 0 and 1", withDefault(&Schema{Type: "number"}, 0.95)).
+		query("step", "Bucket size", withDefault(duration(), "1h")).
+		query("history", "How much history to fit", withDefault(duration(), "672h")).
+		query("horizon", "How far ahead to forecast", withDefault(duration(), "168h")).
+		query("season", "Season length for holt_winters", withDefault(duration(), "24h"))
+}
+
+func (o *operation) snapshotRange() *operation {
+	return o.
+		required("from", "Compare the snapshot taken at or before this time, RFC 3339 or unix seconds", timestamp()).
+		query("to", "With the snapshot taken at or before this time; now by default", timestamp())
+}
+
+func (o *operation) export() *operation {
+	o.Responses[strconv.Itoa(http.StatusOK)] = &Response{
+		Description: "Records, one per line",
+		Headers: map[string]Header{
+			"Content-Disposition": {Description: "Suggested file name", Schema: str()},
+			"X-Export-Status":     {Description: "Trailer: complete or error", Schema: enum("complete", "error")},
+		},
+		Content: map[string]MediaType{
+			"text/csv":             {Schema: str()},
+			"application/x-ndjson": {Schema: str()},
+		},
+	}
+	return o
+}
+
+func responseRef(status int) *Response {
+	return &Response{Ref: "#/components/responses/" + statusResponses[status]}
+}
+
+// operationID строит идентификатор операции из метода и пути:
+// GET /api/v1/chats/{id}/users -> getChatsByIdUsers
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.FieldsFunc(strings.TrimPrefix(path, "/api/v1"), func(r rune) bool {
+		return r == '/' || r == '.' || r == '_'
+	}) {
+		if name, ok := strings.CutPrefix(segment, "{"); ok {
+			b.WriteString("By")
+			segment = strings.TrimSuffix(name, "}")
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+	return b.String()
+}
+
+func str() *Schema {
+	return &Schema{Type: "string"}
+}
+
+func boolean() *Schema {
+	return &Schema{Type: "boolean", Default: false}
+}
+
+func enum(values ...string) *Schema {
+	return &Schema{Type: "string", Enum: values}
+}
+
+func date() *Schema {
+	return &Schema{Type: "string", Format: "date"}
+}
+
+func timestamp() *Schema {
+	return &Schema{Type: "string", Description: "RFC 3339 time or unix seconds"}
+}
+
+func duration() *Schema {
+	return &Schema{Type: "string", Description: "Go duration such as 5m or 1h"}
+}
+
+// integer целое с значением по умолчанию и границами; max = 0 — без верхней границы
+func integer(def, min, max int) *Schema {
+	schema := &Schema{Type: "integer", Default: def, Minimum: float(min)}
+	if max > 0 {
+		schema.Maximum = float(max)
+	}
+	return schema
+}
+
+func withDefault(schema *Schema, value any) *Schema {
+	schema.Default = value
+	return schema
+}
+
+func float(v int) *float64 {
+	f := float64(v)
+	return &f
+}
@@ -0,0 +1,115 @@
+package openapi
+
+import "reflect"
+
+// Version версия спецификации OpenAPI, которой соответствует документ
+const Version = "3.0.3"
+
+type (
+	// Document документ OpenAPI: описание всех маршрутов сервиса и схем их тел
+	Document struct {
+		OpenAPI    string               `json:"openapi"`
+		Info       Info                 `json:"info"`
+		Tags       []Tag                `json:"tags,omitempty"`
+		Paths      map[string]*PathItem `json:"paths"`
+		Components Components           `json:"components"`
+
+		types map[string]reflect.Type
+	}
+
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	Tag struct {
+		Name        string `json:"name"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// PathItem операции одного пути по HTTP-методам
+	PathItem struct {
+		Get  *Operation `json:"get,omitempty"`
+		Post *Operation `json:"post,omitempty"`
+	}
+
+	Operation struct {
+		Tags        []string             `json:"tags,omitempty"`
+		Summary     string               `json:"summary,omitempty"`
+		Description string               `json:"description,omitempty"`
+		OperationID string               `json:"operationId"`
+		Parameters  []Parameter          `json:"parameters,omitempty"`
+		RequestBody *RequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*Response `json:"responses"`
+	}
+
+	Parameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"`
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *Schema `json:"schema"`
+	}
+
+	RequestBody struct {
+		Required bool                 `json:"required,omitempty"`
+		Content  map[string]MediaType `json:"content"`
+	}
+
+	// Response ответ операции; Ref ссылается на общий ответ из Components.Responses
+	Response struct {
+		Ref         string               `json:"$ref,omitempty"`
+		Description string               `json:"description,omitempty"`
+		Headers     map[string]Header    `json:"headers,omitempty"`
+		Content     map[string]MediaType `json:"content,omitempty"`
+	}
+
+	Header struct {
+		Description string  `json:"description,omitempty"`
+		Schema      *Schema `json:"schema"`
+	}
+
+	MediaType struct {
+		Schema *Schema `json:"schema"`
+	}
+
+	Components struct {
+		Schemas   map[string]*Schema   `json:"schemas"`
+		Responses map[string]*Response `json:"responses"`
+	}
+
+	// Schema подмножество JSON Schema, которое использует OpenAPI 3.0
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Description          string             `json:"description,omitempty"`
+		Enum                 []string           `json:"enum,omitempty"`
+		Default              any                `json:"default,omitempty"`
+		Minimum              *float64           `json:"minimum,omitempty"`
+		Maximum              *float64           `json:"maximum,omitempty"`
+		Nullable             bool               `json:"nullable,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	}
+)
+
+// Operations возвращает операции пути по методам
+func (p *PathItem) Operations() map[string]*Operation {
+	operations := make(map[string]*Operation, 2)
+	if p.Get != nil {
+		operations["GET"] = p.Get
+	}
+	if p.Post != nil {
+		operations["POST"] = p.Post
+	}
+	return operations
+}
+
+// Type возвращает тип Go, по которому построена схема name из Components.Schemas, или nil
+func (d *Document) Type(name string) reflect.Type {
+	return d.types[name]
+}
@@ -0,0 +1,193 @@
+"use strict";
+
+// Минимальный просмотрщик OpenAPI: операции по тегам, схемы ответов и отправка запросов
+
+const specURL = "../openapi.json";
+let spec;
+
+function el(tag, attrs, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs || {})) {
+    if (key === "class") node.className = value;
+    else node.setAttribute(key, value);
+  }
+  for (const child of children) {
+    if (child !== null && child !== undefined) node.append(child);
+  }
+  return node;
+}
+
+function resolve(ref) {
+  return ref.replace(/^#\//, "").split("/").reduce((node, key) => node[key], spec);
+}
+
+// example строит пример значения по схеме; seen защищает от рекурсивных ссылок
+function example(schema, seen = new Set()) {
+  if (!schema) return null;
+  if (schema.$ref) {
+    if (seen.has(schema.$ref)) return {};
+    return example(resolve(schema.$ref), new Set(seen).add(schema.$ref));
+  }
+  if (schema.default !== undefined) return schema.default;
+  if (schema.enum) return schema.enum[0];
+  switch (schema.type) {
+    case "object": {
+      if (schema.additionalProperties) return { key: example(schema.additionalProperties, seen) };
+      const result = {};
+      for (const [name, property] of Object.entries(schema.properties || {})) {
+        result[name] = example(property, seen);
+      }
+      return result;
+    }
+    case "array": return [example(schema.items, seen)];
+    case "integer": return 0;
+    case "number": return 0.5;
+    case "boolean": return false;
+    case "string":
+      if (schema.format === "date-time") return "2024-01-01T00:00:00Z";
+      if (schema.format === "date") return "2024-01-01";
+      return "string";
+    default: return null;
+  }
+}
+
+function schemaName(schema) {
+  if (!schema) return "";
+  if (schema.$ref) return schema.$ref.split("/").pop();
+  if (schema.type === "array") return schemaName(schema.items) + "[]";
+  return schema.type || "any";
+}
+
+function parameterRows(op, inputs) {
+  const rows = (op.parameters || []).map((p) => {
+    const input = el("input", { placeholder: p.schema.default !== undefined ? String(p.schema.default) : "" });
+    inputs.push([p, input]);
+    const details = [p.description || ""];
+    if (p.schema.enum) details.push(" One of: " + p.schema.enum.join(", ") + ".");
+    return el("tr", null,
+      el("td", null, el("code", null, p.name), p.required ? el("span", { class: "required" }, " *") : null),
+      el("td", null, p.in),
+      el("td", null, details.join("")),
+      el("td", null, input));
+  });
+  if (rows.length === 0) return null;
+  return el("table", null,
+    el("tr", null, el("th", null, "Name"), el("th", null, "In"), el("th", null, "Description"), el("th", null, "Value")),
+    ...rows);
+}
+
+function responseBlocks(op) {
+  return Object.entries(op.responses).map(([status, response]) => {
+    if (response.$ref) response = resolve(response.$ref);
+    const blocks = [el("p", null, el("span", { class: "status" }, status), " " + (response.description || ""))];
+    for (const [type, media] of Object.entries(response.content || {})) {
+      if (media.schema && (media.schema.$ref || media.schema.type !== "string")) {
+        blocks.push(el("p", null, type + ": " + schemaName(media.schema)));
+        blocks.push(el("pre", null, JSON.stringify(example(media.schema), null, 2)));
+      } else {
+        blocks.push(el("p", null, type));
+      }
+    }
+    return el("div", null, ...blocks);
+  });
+}
+
+async function send(method, path, inputs, body, output) {
+  let url = path;
+  const query = new URLSearchParams();
+  for (const [p, input] of inputs) {
+    if (input.value === "") continue;
+    if (p.in === "path") url = url.replace("{" + p.name + "}", encodeURIComponent(input.value));
+    else query.append(p.name, input.value);
+  }
+  if ([...query].length > 0) url += "?" + query;
+
+  output.textContent = method + " " + url + "\n…";
+  try {
+    const options = { method };
+    if (body) {
+      options.body = body.value;
+      options.headers = { "Content-Type": "application/json" };
+    }
+    const response = await fetch(url, options);
+    let text = await response.text();
+    try {
+      text = JSON.stringify(JSON.parse(text), null, 2);
+    } catch (e) {
+      // Не JSON: показываем как есть
+    }
+    output.textContent = method + " " + url + "\n" + response.status + " " + response.statusText + "\n\n" + text;
+  } catch (e) {
+    output.textContent = method + " " + url + "\n" + e;
+  }
+}
+
+function operation(method, path, op) {
+  const inputs = [];
+  const output = el("pre", null, "");
+  let body = null;
+  if (op.requestBody) {
+    const schema = op.requestBody.content["application/json"].schema;
+    body = el("textarea", null, JSON.stringify(example(schema), null, 2));
+  }
+  const button = el("button", { type: "button" }, "Send request");
+  button.addEventListener("click", () => send(method.toUpperCase(), path, inputs, body, output));
+
+  return el("details", { class: "op", "data-search": (path + " " + (op.summary || "")).toLowerCase() },
+    el("summary", null,
+      el("span", { class: "method " + method }, method.toUpperCase()),
+      el("span", { class: "path" }, path),
+      el("span", { class: "summary" }, op.summary || "")),
+    el("div", { class: "body" },
+      op.description ? el("p", null, op.description) : null,
+      parameterRows(op, inputs),
+      body ? el("div", null, el("h4", null, "Request body"), body) : null,
+      el("h4", null, "Responses"),
+      ...responseBlocks(op),
+      el("p", null, button),
+      output));
+}
+
+function render() {
+  document.title = spec.info.title + " API";
+  document.getElementById("title").textContent = spec.info.title + " " + spec.info.version;
+  document.getElementById("description").textContent = spec.info.description || "";
+
+  const byTag = new Map((spec.tags || []).map((tag) => [tag.name, []]));
+  for (const path of Object.keys(spec.paths).sort()) {
+    for (const [method, op] of Object.entries(spec.paths[path])) {
+      const tag = (op.tags || ["other"])[0];
+      if (!byTag.has(tag)) byTag.set(tag, []);
+      byTag.get(tag).push(operation(method, path, op));
+    }
+  }
+
+  const main = document.getElementById("operations");
+  main.replaceChildren();
+  for (const [tag, operations] of byTag) {
+    if (operations.length > 0) main.append(el("section", null, el("h2", null, tag), ...operations));
+  }
+}
+
+document.getElementById("search").addEventListener("input", (event) => {
+  const term = event.target.value.toLowerCase();
+  for (const section of document.querySelectorAll("section")) {
+    let visible = 0;
+    for (const op of section.querySelectorAll("details.op")) {
+      const match = op.dataset.search.includes(term);
+      op.hidden = !match;
+      if (match) visible++;
+    }
+    section.hidden = visible === 0;
+  }
+});
+
+fetch(specURL)
+  .then((response) => response.json())
+  .then((doc) => {
+    spec = doc;
+    render();
+  })
+  .catch((error) => {
+    document.getElementById("operations").textContent = "Failed to load " + specURL + ": " + error;
+  });
@@ -0,0 +1,169 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stats-of/internal/utils"
+)
+
+// ErrInvalidCursor курсор повреждён, подписан другим ключом или выдан для другого списка
+var ErrInvalidCursor = errors.New("cursor is invalid or belongs to another list")
+
+// macSize длина подписи курсора в байтах
+const macSize = 12
+
+type (
+	// Cursor позиция в списке: курсор SCAN/SSCAN или смещение в упорядоченном множестве.
+	// Skip — сколько элементов порции, начинающейся с Position, уже отдано клиенту.
+	Cursor struct {
+		Position uint64
+		Skip     int
+	}
+
+	// Signer выдаёт и проверяет непрозрачные курсоры. Подпись покрывает и область —
+	// имя списка, например "chat_users:42", — поэтому курсор одного списка не принимается другим.
+	Signer struct {
+		key []byte
+	}
+
+	// Params параметры страницы из запроса
+	Params struct {
+		Limit  int
+		Cursor Cursor
+	}
+)
+
+// NewSigner создаёт подписчик курсоров. Пустой secret заменяется случайным ключом:
+// курсоры тогда действуют только до перезапуска и только на этом экземпляре.
+func NewSigner(secret string) *Signer {
+	if secret != "" {
+		return &Signer{key: []byte(secret)}
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("failed to generate pagination key: " + err.Error())
+	}
+	return &Signer{key: key}
+}
+
+// Encode возвращает курсор в виде строки для поля next
+func (s *Signer) Encode(scope string, c Cursor) string {
+	payload := binary.AppendUvarint(nil, c.Position)
+	payload = binary.AppendUvarint(payload, uint64(c.Skip))
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(s.sign(scope, payload))
+}
+
+// Decode проверяет подпись и разбирает курсор области scope
+func (s *Signer) Decode(scope, token string) (Cursor, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Cursor{}, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, ErrInvalidCursor
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(scope, payload)) {
+		return Cursor{}, ErrInvalidCursor
+	}
+
+	position, n := binary.Uvarint(payload)
+	if n <= 0 {
+		return Cursor{}, ErrInvalidCursor
+	}
+	skip, m := binary.Uvarint(payload[n:])
+	if m <= 0 || n+m != len(payload) || skip > maxSkip {
+		return Cursor{}, ErrInvalidCursor
+	}
+	return Cursor{Position: position, Skip: int(skip)}, nil
+}
+
+// Parse читает параметры limit и cursor запроса
+func (s *Signer) Parse(r *http.Request, scope string, defaultLimit, maxLimit int) (Params, error) {
+	limit, err := utils.QueryInt(r, "limit", defaultLimit, 1, maxLimit)
+	if err != nil {
+		return Params{}, err
+	}
+	params := Params{Limit: limit}
+	if token := r.URL.Query().Get("cursor"); token != "" {
+		if params.Cursor, err = s.Decode(scope, token); err != nil {
+			return Params{}, err
+		}
+	}
+	return params, nil
+}
+
+// Offset читает смещение списка с произвольным доступом: из cursor, если он передан,
+// иначе из параметра offset, который поддерживается для обратной совместимости
+func (s *Signer) Offset(r *http.Request, scope string, maxOffset int) (int, error) {
+	token := r.URL.Query().Get("cursor")
+	if token == "" {
+		return utils.QueryInt(r, "offset", 0, 0, maxOffset)
+	}
+	c, err := s.Decode(scope, token)
+	if err != nil {
+		return 0, err
+	}
+	if c.Position > uint64(maxOffset) {
+		return 0, ErrInvalidCursor
+	}
+	return int(c.Position), nil
+}
+
+// NextOffset курсор страницы, следующей за страницей [offset, offset+limit) списка длины total,
+// или "", если страница последняя
+func (s *Signer) NextOffset(scope string, offset, limit int, total int64) string {
+	if int64(offset+limit) >= total {
+		return ""
+	}
+	return s.Encode(scope, Cursor{Position: uint64(offset + limit)})
+}
+
+func (s *Signer) sign(scope string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(strconv.Itoa(len(scope))))
+	mac.Write([]byte(scope))
+	mac.Write(payload)
+	return mac.Sum(nil)[:macSize]
+}
+
+// Page страница списка. Эту форму имеют все списочные ответы API: элементы в items, размер
+// страницы в limit, курсор следующей страницы в next (пустой на последней) и total — размер
+// всего списка, если он известен без полного обхода.
+type Page[T any] struct {
+	Items []T    `json:"items"`
+	Limit int    `json:"limit"`
+	Total *int64 `json:"total,omitempty"`
+	Next  string `json:"next,omitempty"`
+}
+
+// NewPage собирает страницу и подписывает курсор продолжения
+func NewPage[T any](s *Signer, scope string, items []T, limit int, next *Cursor) Page[T] {
+	page := Page[T]{Items: nonNil(items), Limit: limit}
+	if next != nil {
+		page.Next = s.Encode(scope, *next)
+	}
+	return page
+}
+
+// NewOffsetPage собирает страницу [offset, offset+limit) списка из total элементов
+func NewOffsetPage[T any](s *Signer, scope string, items []T, offset, limit int, total int64) Page[T] {
+	return Page[T]{Items: nonNil(items), Limit: limit, Total: &total, Next: s.NextOffset(scope, offset, limit, total)}
+}
+
+// nonNil заменяет nil пустым срезом, чтобы пустая страница кодировалась как [], а не null
+func nonNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,163 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestCursorRoundTrip проверяет, что подписанный курсор декодируется в ту же позицию
+func TestCursorRoundTrip(t *testing.T) {
+	s := NewSigner("secret")
+	for _, c := range []Cursor{{}, {Position: 1}, {Position: 17, Skip: 3}, {Position: math.MaxUint64, Skip: maxSkip}} {
+		got, err := s.Decode("chats", s.Encode("chats", c))
+		if err != nil || got != c {
+			t.Errorf("round trip of %+v = %+v, %v", c, got, err)
+		}
+	}
+}
+
+// TestDecodeRejects проверяет, что изменённый, чужой или неверно собранный курсор не принимается
+func TestDecodeRejects(t *testing.T) {
+	s := NewSigner("secret")
+	valid := s.Encode("chat_users:1", Cursor{Position: 42, Skip: 2})
+	payload, signature, _ := strings.Cut(valid, ".")
+
+	// signed подписывает произвольные байты, чтобы проверить разбор содержимого с верной подписью
+	signed := func(raw []byte) string {
+		return base64.RawURLEncoding.EncodeToString(raw) + "." + base64.RawURLEncoding.EncodeToString(s.sign("chat_users:1", raw))
+	}
+	tampered := []byte(payload)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", payload},
+		{"tampered payload", string(tampered) + "." + signature},
+		{"tampered signature", payload + "." + strings.Repeat("A", len(signature))},
+		{"truncated signature", payload + "." + signature[:len(signature)-2]},
+		{"not base64", "!!!." + signature},
+		{"another scope", s.Encode("chat_users:2", Cursor{Position: 42, Skip: 2})},
+		{"scope prefix", s.Encode("chat_users:12", Cursor{Position: 42, Skip: 2})},
+		{"another key", NewSigner("other").Encode("chat_users:1", Cursor{Position: 42, Skip: 2})},
+		{"empty payload", signed(nil)},
+		{"missing skip", signed([]byte{42})},
+		{"trailing bytes", signed([]byte{42, 2, 0})},
+		{"skip too large", s.Encode("chat_users:1", Cursor{Position: 1, Skip: maxSkip + 1})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if c, err := s.Decode("chat_users:1", tt.token); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decoded %+v, %v; want ErrInvalidCursor", c, err)
+			}
+		})
+	}
+}
+
+// TestRandomKeySigners проверяет, что без секрета каждый экземпляр подписывает своим ключом
+func TestRandomKeySigners(t *testing.T) {
+	a, b := NewSigner(""), NewSigner("")
+	token := a.Encode("chats", Cursor{Position: 5})
+	if _, err := a.Decode("chats", token); err != nil {
+		t.Fatalf("own cursor rejected: %v", err)
+	}
+	if _, err := b.Decode("chats", token); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("cursor of another instance: error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+// TestParse проверяет чтение limit и cursor из запроса и привязку курсора к области
+func TestParse(t *testing.T) {
+	s := NewSigner("secret")
+	own := s.Encode("users", Cursor{Position: 9, Skip: 1})
+	foreign := s.Encode("chats", Cursor{Position: 9, Skip: 1})
+
+	tests := []struct {
+		query string
+		want  Params
+		ok    bool
+	}{
+		{query: "", want: Params{Limit: 100}, ok: true},
+		{query: "limit=5", want: Params{Limit: 5}, ok: true},
+		{query: "limit=1000&cursor=" + own, want: Params{Limit: 1000, Cursor: Cursor{Position: 9, Skip: 1}}, ok: true},
+		{query: "limit=0"},
+		{query: "limit=1001"},
+		{query: "limit=ten"},
+		{query: "cursor=" + foreign},
+		{query: "cursor=garbage"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := s.Parse(httptest.NewRequest("GET", "/api/v1/users?"+tt.query, nil), "users", 100, 1000)
+			if (err == nil) != tt.ok || (tt.ok && got != tt.want) {
+				t.Errorf("Parse = %+v, %v; want %+v, ok %v", got, err, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestOffset проверяет смещение из курсора и из устаревшего параметра offset
+func TestOffset(t *testing.T) {
+	s := NewSigner("secret")
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		ok    bool
+	}{
+		{name: "default", want: 0, ok: true},
+		{name: "offset", query: "offset=20", want: 20, ok: true},
+		{name: "cursor", query: "cursor=" + s.NextOffset("board", 0, 10, 100), want: 10, ok: true},
+		{name: "cursor wins over offset", query: "offset=50&cursor=" + s.NextOffset("board", 20, 10, 100), want: 30, ok: true},
+		{name: "negative offset", query: "offset=-1"},
+		{name: "offset too large", query: "offset=1001"},
+		{name: "cursor too large", query: "cursor=" + s.Encode("board", Cursor{Position: 1001})},
+		{name: "cursor of another list", query: "cursor=" + s.NextOffset("other", 0, 10, 100)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Offset(httptest.NewRequest("GET", "/?"+tt.query, nil), "board", 1000)
+			if (err == nil) != tt.ok || got != tt.want {
+				t.Errorf("Offset = %d, %v; want %d, ok %v", got, err, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestPages проверяет форму страниц: пустой список — [], total и next только когда известны
+func TestPages(t *testing.T) {
+	s := NewSigner("secret")
+	tests := []struct {
+		name string
+		page any
+		want string
+	}{
+		{"empty keyset page", NewPage[int](s, "l", nil, 10, nil), `{"items":[],"limit":10}`},
+		{"last offset page", NewOffsetPage(s, "l", []int{3}, 2, 2, 3), `{"items":[3],"limit":2,"total":3}`},
+		{"empty offset page", NewOffsetPage[int](s, "l", nil, 10, 10, 3), `{"items":[],"limit":10,"total":3}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.page)
+			if err != nil || string(data) != tt.want {
+				t.Errorf("page = %s, %v; want %s", data, err, tt.want)
+			}
+		})
+	}
+
+	page := NewOffsetPage(s, "l", []int{1, 2}, 0, 2, 3)
+	if c, err := s.Decode("l", page.Next); err != nil || c.Position != 2 {
+		t.Errorf("next of the first offset page = %+v, %v; want position 2", c, err)
+	}
+	page = NewPage(s, "l", []int{1}, 1, &Cursor{Position: 7, Skip: 1})
+	if c, err := s.Decode("l", page.Next); err != nil || c != (Cursor{Position: 7, Skip: 1}) {
+		t.Errorf("next of a keyset page = %+v, %v", c, err)
+	}
+}
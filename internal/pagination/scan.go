@@ -0,0 +1,46 @@
+package pagination
+
+// ScanCount подсказка COUNT для SCAN/SSCAN. Она постоянна, чтобы повторный запрос
+// с тем же курсором возвращал ту же порцию и Skip указывал на те же элементы.
+const ScanCount = 100
+
+// maxSkip наибольшее число пропускаемых элементов порции. Redis отдаёт небольшие множества
+// одной порцией независимо от COUNT, но не настолько большой.
+const maxSkip = 1 << 20
+
+// ScanFunc возвращает порцию элементов начиная с курсора и курсор следующей порции; 0 — конец
+type ScanFunc[T any] func(cursor uint64) ([]T, uint64, error)
+
+// Collect набирает до limit элементов, последовательно вызывая scan, и возвращает курсор
+// продолжения или nil, если элементы закончились. SCAN может вернуть порцию больше limit,
+// тогда курсор указывает на ту же порцию и число уже отданных из неё элементов.
+// Гарантии те же, что у SCAN: элементы, существовавшие всё время обхода, будут отданы,
+// добавленные или удалённые во время обхода — могут быть пропущены или повторены.
+func Collect[T any](start Cursor, limit int, scan ScanFunc[T]) ([]T, *Cursor, error) {
+	items := make([]T, 0, limit)
+	position, skip := start.Position, start.Skip
+	for {
+		batch, next, err := scan(position)
+		if err != nil {
+			return nil, nil, err
+		}
+		if skip < len(batch) {
+			batch = batch[skip:]
+		} else {
+			batch = nil
+		}
+
+		if room := limit - len(items); len(batch) > room {
+			items = append(items, batch[:room]...)
+			return items, &Cursor{Position: position, Skip: skip + room}, nil
+		}
+		items = append(items, batch...)
+		if next == 0 {
+			return items, nil, nil
+		}
+		position, skip = next, 0
+		if len(items) == limit {
+			return items, &Cursor{Position: position}, nil
+		}
+	}
+}
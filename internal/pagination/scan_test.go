@@ -0,0 +1,105 @@
+package pagination
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// batches порции фиктивного SCAN: порция i отдаётся по курсору i, следующий курсор — i+1,
+// после последней — 0. Есть пустая порция и порция больше типичного limit.
+var batches = [][]int{{1, 2, 3}, {}, {4}, {5, 6, 7, 8, 9, 10, 11}, {12, 13}}
+
+func fakeScan(cursor uint64) ([]int, uint64, error) {
+	next := cursor + 1
+	if next == uint64(len(batches)) {
+		next = 0
+	}
+	return batches[cursor], next, nil
+}
+
+func all() []int {
+	var items []int
+	for _, batch := range batches {
+		items = append(items, batch...)
+	}
+	return items
+}
+
+// TestCollectVisitsEveryItemOnce проверяет, что при любом limit страницы вместе отдают
+// все элементы по одному разу и в порядке обхода, в том числе из порций больше limit
+func TestCollectVisitsEveryItemOnce(t *testing.T) {
+	s := NewSigner("secret")
+	for limit := 1; limit <= len(all())+1; limit++ {
+		var got []int
+		cursor := Cursor{}
+		for pages := 0; ; pages++ {
+			if pages > len(all()) {
+				t.Fatalf("limit %d: pagination does not terminate", limit)
+			}
+			items, next, err := Collect(cursor, limit, fakeScan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(items) > limit {
+				t.Fatalf("limit %d: page of %d items", limit, len(items))
+			}
+			got = append(got, items...)
+			if next == nil {
+				break
+			}
+			// Курсор проходит через клиента в подписанном виде
+			if cursor, err = s.Decode("list", s.Encode("list", *next)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !reflect.DeepEqual(got, all()) {
+			t.Errorf("limit %d: got %v, want %v", limit, got, all())
+		}
+	}
+}
+
+// TestCollectCursors проверяет курсоры продолжения: Skip внутри большой порции
+// и переход к следующей порции, когда текущая отдана целиком
+func TestCollectCursors(t *testing.T) {
+	tests := []struct {
+		name  string
+		start Cursor
+		limit int
+		want  []int
+		next  *Cursor
+	}{
+		{"inside oversize batch", Cursor{Position: 3}, 2, []int{5, 6}, &Cursor{Position: 3, Skip: 2}},
+		{"resume oversize batch", Cursor{Position: 3, Skip: 2}, 3, []int{7, 8, 9}, &Cursor{Position: 3, Skip: 5}},
+		{"finish oversize batch", Cursor{Position: 3, Skip: 5}, 2, []int{10, 11}, &Cursor{Position: 4}},
+		{"across empty batch", Cursor{}, 4, []int{1, 2, 3, 4}, &Cursor{Position: 3}},
+		{"to the end", Cursor{Position: 3, Skip: 5}, 10, []int{10, 11, 12, 13}, nil},
+		{"exactly the end", Cursor{Position: 4}, 2, []int{12, 13}, nil},
+		{"skip past batch", Cursor{Position: 3, Skip: 100}, 5, []int{12, 13}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, next, err := Collect(tt.start, tt.limit, fakeScan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(items, tt.want) || !reflect.DeepEqual(next, tt.next) {
+				t.Errorf("got %v, next %+v; want %v, next %+v", items, next, tt.want, tt.next)
+			}
+		})
+	}
+}
+
+// TestCollectError проверяет, что ошибка обхода возвращается без частичного результата
+func TestCollectError(t *testing.T) {
+	failure := errors.New("scan failed")
+	items, next, err := Collect(Cursor{}, 10, func(cursor uint64) ([]int, uint64, error) {
+		if cursor == 0 {
+			return []int{1}, 1, nil
+		}
+		return nil, 0, failure
+	})
+	if !errors.Is(err, failure) || items != nil || next != nil {
+		t.Errorf("got %v, %+v, %v; want scan error only", items, next, err)
+	}
+}
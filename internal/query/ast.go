@@ -0,0 +1,142 @@
+package query
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Op оператор сравнения
+type Op string
+
+const (
+	OpEq Op = "="
+	OpNe Op = "!="
+	OpLt Op = "<"
+	OpLe Op = "<="
+	OpGt Op = ">"
+	OpGe Op = ">="
+)
+
+type (
+	// Expr узел дерева выражения фильтра
+	Expr interface {
+		// Match сообщает, подходит ли запись под выражение
+		Match(row Row) bool
+		String() string
+	}
+
+	// And истинно, если истинны оба операнда
+	And struct{ Left, Right Expr }
+
+	// Or истинно, если истинен хотя бы один операнд
+	Or struct{ Left, Right Expr }
+
+	// Not отрицание
+	Not struct{ Expr Expr }
+
+	// Compare сравнение поля с константой
+	Compare struct {
+		Field string
+		Op    Op
+		Value Value
+	}
+
+	// In проверка вхождения значения поля в список; Negated — NOT IN
+	In struct {
+		Field   string
+		Values  []Value
+		Negated bool
+	}
+
+	// Value константа выражения. Целая константа хранится в Int и сравнивается с полем точно,
+	// поэтому идентификаторы больше 2^53 не теряют точности; дробная или не помещающаяся
+	// в int64 хранится в Float и сравнивается с полем как float64.
+	Value struct {
+		Int     int64
+		Float   float64
+		IsFloat bool
+	}
+)
+
+// IntValue целая константа
+func IntValue(n int64) Value { return Value{Int: n} }
+
+// FloatValue дробная константа
+func FloatValue(f float64) Value { return Value{Float: f, IsFloat: true} }
+
+// compare сравнивает значение поля с константой: -1, 0 или 1
+func (v Value) compare(x int64) int {
+	if v.IsFloat {
+		return cmp.Compare(float64(x), v.Float)
+	}
+	return cmp.Compare(x, v.Int)
+}
+
+// float64 значение константы для индекса рейтинга, веса которого — float64
+func (v Value) float64() float64 {
+	if v.IsFloat {
+		return v.Float
+	}
+	return float64(v.Int)
+}
+
+func (v Value) String() string {
+	if v.IsFloat {
+		return strconv.FormatFloat(v.Float, 'f', -1, 64)
+	}
+	return strconv.FormatInt(v.Int, 10)
+}
+
+func (e *And) Match(row Row) bool { return e.Left.Match(row) && e.Right.Match(row) }
+func (e *Or) Match(row Row) bool  { return e.Left.Match(row) || e.Right.Match(row) }
+func (e *Not) Match(row Row) bool { return !e.Expr.Match(row) }
+
+func (e *Compare) Match(row Row) bool {
+	c := e.Value.compare(row[e.Field])
+	switch e.Op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	default:
+		return c >= 0
+	}
+}
+
+func (e *In) Match(row Row) bool {
+	v := row[e.Field]
+	for _, candidate := range e.Values {
+		if candidate.compare(v) == 0 {
+			return !e.Negated
+		}
+	}
+	return e.Negated
+}
+
+func (e *And) String() string { return "(" + e.Left.String() + " AND " + e.Right.String() + ")" }
+func (e *Or) String() string  { return "(" + e.Left.String() + " OR " + e.Right.String() + ")" }
+func (e *Not) String() string { return "NOT " + e.Expr.String() }
+
+func (e *Compare) String() string {
+	return fmt.Sprintf("%s %s %s", e.Field, e.Op, e.Value)
+}
+
+func (e *In) String() string {
+	values := make([]string, len(e.Values))
+	for i, v := range e.Values {
+		values[i] = v.String()
+	}
+	op := " IN "
+	if e.Negated {
+		op = " NOT IN "
+	}
+	return e.Field + op + "(" + strings.Join(values, ", ") + ")"
+}
@@ -0,0 +1,121 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenComma
+	tokenPlus
+	tokenMinus
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	// pos позиция начала токена в символах, начиная с единицы
+	pos int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+// SyntaxError ошибка разбора выражения с позицией, начиная с единицы
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("at position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...any) *SyntaxError {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// lex разбивает выражение на токены
+func lex(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		start := i
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(':
+			tokens = append(tokens, token{tokenLParen, "(", start + 1})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokenRParen, ")", start + 1})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{tokenComma, ",", start + 1})
+			i++
+		case r == '+':
+			tokens = append(tokens, token{tokenPlus, "+", start + 1})
+			i++
+		case r == '-':
+			tokens = append(tokens, token{tokenMinus, "-", start + 1})
+			i++
+		case r == '=' || r == '!' || r == '<' || r == '>':
+			i++
+			if i < len(runes) && runes[i] == '=' {
+				i++
+			} else if r == '!' {
+				return nil, errorAt(start+1, `unexpected "!", did you mean "!="?`)
+			}
+			op := string(runes[start:i])
+			if op == "==" {
+				op = "="
+			}
+			tokens = append(tokens, token{tokenOp, op, start + 1})
+		case r == '"' || r == '\'':
+			i++
+			for i < len(runes) && runes[i] != r {
+				i++
+			}
+			if i == len(runes) {
+				return nil, errorAt(start+1, "unterminated string")
+			}
+			tokens = append(tokens, token{tokenString, string(runes[start+1 : i]), start + 1})
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			// Число с необязательным суффиксом единицы длительности, например 7d
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.' || unicode.IsLetter(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i]), start + 1})
+		case unicode.IsLetter(r) || r == '_':
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenIdent, string(runes[start:i]), start + 1})
+		default:
+			return nil, errorAt(start+1, "unexpected character %q", r)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(runes) + 1}), nil
+}
+
+// isKeyword сравнивает идентификатор с ключевым словом без учёта регистра
+func (t token) isKeyword(word string) bool {
+	return t.kind == tokenIdent && strings.EqualFold(t.text, word)
+}
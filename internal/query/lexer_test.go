@@ -0,0 +1,67 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestLex проверяет виды, текст и позиции токенов; позиции считаются в символах, а не в байтах
+func TestLex(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []token
+	}{
+		{"", []token{{tokenEOF, "", 1}}},
+		{"size>=10", []token{{tokenIdent, "size", 1}, {tokenOp, ">=", 5}, {tokenNumber, "10", 7}, {tokenEOF, "", 9}}},
+		{"a == 1", []token{{tokenIdent, "a", 1}, {tokenOp, "=", 3}, {tokenNumber, "1", 6}, {tokenEOF, "", 7}}},
+		{"x != -2.5", []token{{tokenIdent, "x", 1}, {tokenOp, "!=", 3}, {tokenMinus, "-", 6}, {tokenNumber, "2.5", 7}, {tokenEOF, "", 10}}},
+		{"t > now-7d", []token{{tokenIdent, "t", 1}, {tokenOp, ">", 3}, {tokenIdent, "now", 5}, {tokenMinus, "-", 8}, {tokenNumber, "7d", 9}, {tokenEOF, "", 11}}},
+		{"id IN (1,2)", []token{
+			{tokenIdent, "id", 1}, {tokenIdent, "IN", 4}, {tokenLParen, "(", 7}, {tokenNumber, "1", 8},
+			{tokenComma, ",", 9}, {tokenNumber, "2", 10}, {tokenRParen, ")", 11}, {tokenEOF, "", 12},
+		}},
+		{`t < "2026-10-01" + 'a b'`, []token{
+			{tokenIdent, "t", 1}, {tokenOp, "<", 3}, {tokenString, "2026-10-01", 5}, {tokenPlus, "+", 18}, {tokenString, "a b", 20}, {tokenEOF, "", 25},
+		}},
+		{"ёж_1 <= 2", []token{{tokenIdent, "ёж_1", 1}, {tokenOp, "<=", 6}, {tokenNumber, "2", 9}, {tokenEOF, "", 10}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := lex(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokens %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLexErrors проверяет позиции и сообщения ошибок лексера
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+		msg   string
+	}{
+		{"size ! 1", 6, `unexpected "!", did you mean "!="?`},
+		{"size = 'abc", 8, "unterminated string"},
+		{`size = "abc'`, 8, "unterminated string"},
+		{"size = 1 @", 10, `unexpected character '@'`},
+		{"ёё = 1 $", 8, `unexpected character '$'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := lex(tt.input)
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Fatalf("error = %v, want SyntaxError", err)
+			}
+			if syntax.Pos != tt.pos || syntax.Msg != tt.msg {
+				t.Errorf("error at %d: %q; want at %d: %q", syntax.Pos, syntax.Msg, tt.pos, tt.msg)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxInValues наибольшее число значений в списке IN
+const maxInValues = 1000
+
+// maxDepth наибольшая вложенность скобок и NOT
+const maxDepth = 32
+
+// maxRelative наибольшее смещение относительного времени
+const maxRelative = 100 * 365 * 24 * time.Hour
+
+// Грамматика фильтра (ключевые слова без учёта регистра):
+//
+//	expr       = and { "OR" and }
+//	and        = unary { "AND" unary }
+//	unary      = "NOT" unary | "(" expr ")" | comparison
+//	comparison = field op value | field [ "NOT" ] "IN" "(" value { "," value } ")"
+//	op         = "=" | "==" | "!=" | "<" | "<=" | ">" | ">="
+//	value      = [ "-" ] number | string | "now" [ ("+" | "-") duration ]
+//
+// Строка — время в формате RFC 3339 или YYYY-MM-DD, длительность — число с единицей
+// s, m, h, d или w, например now-7d. Время допустимо только для полей времени.
+type parser struct {
+	schema *Schema
+	now    time.Time
+	tokens []token
+	pos    int
+	depth  int
+}
+
+// Parse разбирает выражение фильтра для полей schema; now — момент, от которого
+// отсчитываются относительные значения времени
+func Parse(schema *Schema, input string, now time.Time) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{schema: schema, now: now, tokens: tokens}
+	if p.peek().kind == tokenEOF {
+		return nil, errorAt(1, "filter is empty")
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorAt(t.pos, "unexpected %s, expected AND, OR or end of filter", t)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token { return p.tokens[p.pos] }
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("OR") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().isKeyword("AND") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	t := p.peek()
+	if t.isKeyword("NOT") || t.kind == tokenLParen {
+		p.depth++
+		defer func() { p.depth-- }()
+		if p.depth > maxDepth {
+			return nil, errorAt(t.pos, "filter is nested too deeply")
+		}
+	}
+
+	switch {
+	case t.isKeyword("NOT"):
+		p.next()
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Expr: expr}, nil
+	case t.kind == tokenLParen:
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, errorAt(closing.pos, "expected \")\" to close \"(\" at position %d, got %s", t.pos, closing)
+		}
+		return expr, nil
+	default:
+		return p.parseComparison()
+	}
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, errorAt(t.pos, "expected field name, got %s", t)
+	}
+	field, ok := p.schema.Lookup(t.text)
+	if !ok {
+		return nil, errorAt(t.pos, "unknown %s field %q; available fields: %s", p.schema.Entity, t.text, p.schema.names())
+	}
+
+	negated := false
+	if p.peek().isKeyword("NOT") {
+		p.next()
+		negated = true
+		if !p.peek().isKeyword("IN") {
+			return nil, errorAt(p.peek().pos, "expected IN after NOT, got %s", p.peek())
+		}
+	}
+	if p.peek().isKeyword("IN") {
+		p.next()
+		return p.parseIn(field, negated)
+	}
+
+	opToken := p.next()
+	if opToken.kind != tokenOp {
+		return nil, errorAt(opToken.pos, "expected comparison operator or IN after %q, got %s", t.text, opToken)
+	}
+	value, err := p.parseValue(field)
+	if err != nil {
+		return nil, err
+	}
+	return &Compare{Field: field.Name, Op: Op(opToken.text), Value: value}, nil
+}
+
+func (p *parser) parseIn(field Field, negated bool) (Expr, error) {
+	if t := p.next(); t.kind != tokenLParen {
+		return nil, errorAt(t.pos, "expected \"(\" after IN, got %s", t)
+	}
+	in := &In{Field: field.Name, Negated: negated}
+	for {
+		value, err := p.parseValue(field)
+		if err != nil {
+			return nil, err
+		}
+		in.Values = append(in.Values, value)
+		if len(in.Values) > maxInValues {
+			return nil, errorAt(p.peek().pos, "IN list must not exceed %d values", maxInValues)
+		}
+
+		t := p.next()
+		if t.kind == tokenRParen {
+			return in, nil
+		}
+		if t.kind != tokenComma {
+			return nil, errorAt(t.pos, "expected \",\" or \")\" in IN list, got %s", t)
+		}
+	}
+}
+
+// parseValue разбирает константу и проверяет, что её тип совпадает с типом поля
+func (p *parser) parseValue(field Field) (Value, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokenMinus || t.kind == tokenNumber:
+		negative := t.kind == tokenMinus
+		if negative {
+			t = p.next()
+			if t.kind != tokenNumber {
+				return Value{}, errorAt(t.pos, "expected number after \"-\", got %s", t)
+			}
+		}
+		if field.Type != TypeNumber {
+			return Value{}, errorAt(t.pos, "field %q is a time; use a date like \"2026-10-01\" or a relative time like now-7d", field.Name)
+		}
+		text := t.text
+		if negative {
+			text = "-" + text
+		}
+		if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return IntValue(n), nil
+		}
+		v, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return Value{}, errorAt(t.pos, "invalid number %q", t.text)
+		}
+		return FloatValue(v), nil
+
+	case t.kind == tokenString:
+		if field.Type != TypeTime {
+			return Value{}, errorAt(t.pos, "field %q is a number, got string %q", field.Name, t.text)
+		}
+		for _, layout := range []string{time.RFC3339, time.DateOnly} {
+			if at, err := time.Parse(layout, t.text); err == nil {
+				return IntValue(at.Unix()), nil
+			}
+		}
+		return Value{}, errorAt(t.pos, "invalid time %q, expected RFC 3339 or YYYY-MM-DD", t.text)
+
+	case t.isKeyword("now"):
+		if field.Type != TypeTime {
+			return Value{}, errorAt(t.pos, "field %q is a number, relative time is only allowed for time fields", field.Name)
+		}
+		at := p.now
+		if sign := p.peek(); sign.kind == tokenPlus || sign.kind == tokenMinus {
+			p.next()
+			d, err := p.parseDuration()
+			if err != nil {
+				return Value{}, err
+			}
+			if sign.kind == tokenMinus {
+				d = -d
+			}
+			at = at.Add(d)
+		}
+		return IntValue(at.Unix()), nil
+
+	default:
+		return Value{}, errorAt(t.pos, "expected value for %q, got %s", field.Name, t)
+	}
+}
+
+// parseDuration разбирает длительность с единицей s, m, h, d или w
+func (p *parser) parseDuration() (time.Duration, error) {
+	t := p.next()
+	if t.kind != tokenNumber {
+		return 0, errorAt(t.pos, "expected duration like 7d or 12h, got %s", t)
+	}
+	i := strings.IndexFunc(t.text, func(r rune) bool { return r < '0' || r > '9' })
+	if i <= 0 {
+		return 0, errorAt(t.pos, "duration %q needs a unit: s, m, h, d or w", t.text)
+	}
+	n, err := strconv.ParseInt(t.text[:i], 10, 64)
+	if err != nil {
+		return 0, errorAt(t.pos, "invalid duration %q", t.text)
+	}
+	units := map[string]time.Duration{
+		"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+	}
+	unit, ok := units[strings.ToLower(t.text[i:])]
+	if !ok {
+		return 0, errorAt(t.pos, "unknown duration unit %q, expected s, m, h, d or w", t.text[i:])
+	}
+	if n > int64(maxRelative/unit) {
+		return 0, errorAt(t.pos, "duration %q is too long", t.text)
+	}
+	return time.Duration(n) * unit, nil
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func unix(t time.Time) string {
+	return strconv.FormatInt(t.Unix(), 10)
+}
+
+// TestParse проверяет дерево разбора через его каноническую запись: приоритет OR и AND,
+// скобки, синонимы полей, IN и время
+func TestParse(t *testing.T) {
+	tests := []struct {
+		schema *Schema
+		input  string
+		want   string
+	}{
+		{ChatSchema, "chat_type = 4 AND count_of_users > 1000", "(chat_type = 4 AND count_of_users > 1000)"},
+		{ChatSchema, "type = 1 OR type = 2 AND size >= 3", "(chat_type = 1 OR (chat_type = 2 AND count_of_users >= 3))"},
+		{ChatSchema, "(type = 1 or type = 2) and size >= 3", "((chat_type = 1 OR chat_type = 2) AND count_of_users >= 3)"},
+		{ChatSchema, "size > 1 AND size < 9 AND id != 3", "((count_of_users > 1 AND count_of_users < 9) AND chat_id != 3)"},
+		{ChatSchema, "NOT id IN (1, -2, 3.5)", "NOT chat_id IN (1, -2, 3.5)"},
+		{ChatSchema, "id not in (5)", "chat_id NOT IN (5)"},
+		{ChatSchema, "not not users < 1", "NOT NOT count_of_users < 1"},
+		{ChatSchema, "size == 10", "count_of_users = 10"},
+		{ChatSchema, "CHAT_ID<=-100", "chat_id <= -100"},
+		{ChatSchema, "((size = 1))", "count_of_users = 1"},
+		{ChatSchema, "id = -1001234567890123457", "chat_id = -1001234567890123457"},
+		{ChatSchema, "size > 99999999999999999999", "count_of_users > 100000000000000000000"},
+		{UserSchema, "last_time > now-7d", "last_time > " + unix(testNow.Add(-7*24*time.Hour))},
+		{UserSchema, "last_seen <= now + 2w", "last_time <= " + unix(testNow.Add(14*24*time.Hour))},
+		{UserSchema, "last_time < now", "last_time < " + unix(testNow)},
+		{UserSchema, "last_time >= now-90M", "last_time >= " + unix(testNow.Add(-90*time.Minute))},
+		{UserSchema, "last_time >= '2026-09-01'", "last_time >= " + unix(time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC))},
+		{UserSchema, `last_time < "2026-09-01T12:00:00+03:00"`, "last_time < " + unix(time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC))},
+		{UserSchema, "chats in (1, 2) and last_time in ('2026-01-01')", "(count_of_chats IN (1, 2) AND last_time IN (" + unix(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) + "))"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			expr, err := Parse(tt.schema, tt.input, testNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := expr.String(); got != tt.want {
+				t.Errorf("parsed %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestParseErrors проверяет позицию и начало сообщения ошибки для неверных фильтров
+func TestParseErrors(t *testing.T) {
+	tooDeep := strings.Repeat("(", maxDepth+1) + "size = 1" + strings.Repeat(")", maxDepth+1)
+	tooMany := "id in (" + strings.Repeat("1, ", maxInValues) + "1)"
+
+	tests := []struct {
+		name   string
+		schema *Schema
+		input  string
+		pos    int
+		msg    string
+	}{
+		{"empty", ChatSchema, "", 1, "filter is empty"},
+		{"blank", ChatSchema, "   ", 1, "filter is empty"},
+		{"unknown field", ChatSchema, "name = 1", 1, `unknown chat field "name"; available fields: chat_id, chat_type, count_of_users`},
+		{"no operator", ChatSchema, "size 5", 6, `expected comparison operator or IN after "size", got "5"`},
+		{"no value", ChatSchema, "size >", 7, `expected value for "count_of_users", got end of input`},
+		{"dangling AND", ChatSchema, "size = 1 AND", 13, "expected field name, got end of input"},
+		{"missing AND", ChatSchema, "size = 1 size = 2", 10, `unexpected "size", expected AND, OR or end of filter`},
+		{"unclosed paren", ChatSchema, "(size = 1", 10, `expected ")" to close "(" at position 1, got end of input`},
+		{"extra paren", ChatSchema, "size = 1)", 9, `unexpected ")"`},
+		{"string for number", ChatSchema, "size = 'x'", 8, `field "count_of_users" is a number, got string "x"`},
+		{"relative time for number", ChatSchema, "size = now", 8, `field "count_of_users" is a number, relative time`},
+		{"minus without number", ChatSchema, "size = -x", 9, `expected number after "-", got "x"`},
+		{"invalid number", ChatSchema, "size = 1.2.3", 8, `invalid number "1.2.3"`},
+		{"NOT without IN", ChatSchema, "id not = 1", 8, `expected IN after NOT, got "="`},
+		{"IN without paren", ChatSchema, "id in 1", 7, `expected "(" after IN, got "1"`},
+		{"IN without comma", ChatSchema, "id in (1 2)", 10, `expected "," or ")" in IN list, got "2"`},
+		{"IN unclosed", ChatSchema, "id in (1,", 10, `expected value for "chat_id", got end of input`},
+		{"IN too long", ChatSchema, tooMany, 7 + 3*maxInValues + 2, "IN list must not exceed 1000 values"},
+		{"nested too deeply", ChatSchema, tooDeep, maxDepth + 1, "filter is nested too deeply"},
+		{"number for time", UserSchema, "last_time > 5", 13, `field "last_time" is a time`},
+		{"negative number for time", UserSchema, "last_time > -5", 14, `field "last_time" is a time`},
+		{"invalid time", UserSchema, "last_time > '2026-13-01'", 13, `invalid time "2026-13-01"`},
+		{"duration without unit", UserSchema, "last_time > now-7", 17, `duration "7" needs a unit`},
+		{"unknown unit", UserSchema, "last_time > now-7y", 17, `unknown duration unit "y"`},
+		{"duration is not a number", UserSchema, "last_time > now-d", 17, `expected duration like 7d or 12h, got "d"`},
+		{"duration too long", UserSchema, "last_time > now-100000w", 17, `duration "100000w" is too long`},
+		{"lexer error", UserSchema, "last_time ! now", 11, `unexpected "!"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.schema, tt.input, testNow)
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Fatalf("error = %v, want SyntaxError", err)
+			}
+			if syntax.Pos != tt.pos || !strings.HasPrefix(syntax.Msg, tt.msg) {
+				t.Errorf("error at %d: %q; want at %d: %q", syntax.Pos, syntax.Msg, tt.pos, tt.msg)
+			}
+		})
+	}
+}
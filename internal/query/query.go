@@ -0,0 +1,182 @@
+package query
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+type (
+	// SortKey поле сортировки
+	SortKey struct {
+		Field      string
+		Descending bool
+	}
+
+	// Query разобранные фильтр и сортировка списка; Filter = nil — все записи
+	Query struct {
+		Schema *Schema
+		Filter Expr
+		Sort   []SortKey
+	}
+)
+
+// maxSortKeys наибольшее число полей сортировки
+const maxSortKeys = 4
+
+// New разбирает параметры filter и sort. Сортировка — поля через запятую,
+// "-" перед полем означает убывание, например "-count_of_users,chat_id".
+func New(schema *Schema, filter, sortBy string, now time.Time) (*Query, error) {
+	q := &Query{Schema: schema}
+	if strings.TrimSpace(filter) != "" {
+		expr, err := Parse(schema, filter, now)
+		if err != nil {
+			return nil, err
+		}
+		q.Filter = expr
+	}
+	if strings.TrimSpace(sortBy) != "" {
+		keys, err := ParseSort(schema, sortBy)
+		if err != nil {
+			return nil, err
+		}
+		q.Sort = keys
+	}
+	return q, nil
+}
+
+// ParseSort разбирает список полей сортировки
+func ParseSort(schema *Schema, input string) ([]SortKey, error) {
+	parts := strings.Split(input, ",")
+	if len(parts) > maxSortKeys {
+		return nil, &SyntaxError{Pos: 1, Msg: "sort accepts at most 4 fields"}
+	}
+	keys := make([]SortKey, 0, len(parts))
+	pos := 1
+	for _, part := range parts {
+		name := strings.TrimSpace(part)
+		key := SortKey{}
+		if strings.HasPrefix(name, "-") {
+			key.Descending = true
+			name = name[1:]
+		} else {
+			name = strings.TrimPrefix(name, "+")
+		}
+		field, ok := schema.Lookup(name)
+		if !ok {
+			return nil, errorAt(pos, "unknown %s sort field %q; available fields: %s", schema.Entity, name, schema.names())
+		}
+		key.Field = field.Name
+		keys = append(keys, key)
+		pos += len(part) + 1
+	}
+	return keys, nil
+}
+
+// Match сообщает, подходит ли запись под фильтр
+func (q *Query) Match(row Row) bool {
+	return q.Filter == nil || q.Filter.Match(row)
+}
+
+// String запись разобранного запроса для журналов: поля приведены к каноническим именам, время — к unix-секундам
+func (q *Query) String() string {
+	var b strings.Builder
+	if q.Filter != nil {
+		b.WriteString(q.Filter.String())
+	}
+	b.WriteString("|")
+	for i, key := range q.Sort {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		if key.Descending {
+			b.WriteString("-")
+		}
+		b.WriteString(key.Field)
+	}
+	return b.String()
+}
+
+// Less сравнивает записи по полям сортировки; при равенстве — по идентификатору
+func (q *Query) Less(a, b Row) bool {
+	for _, key := range q.Sort {
+		x, y := a[key.Field], b[key.Field]
+		if x == y {
+			continue
+		}
+		if key.Descending {
+			return x > y
+		}
+		return x < y
+	}
+	return a[q.Schema.ID] < b[q.Schema.ID]
+}
+
+// IDs возвращает идентификаторы, которыми ограничен фильтр: условие id = x или id IN (...)
+// на верхнем уровне конъюнкции. ok = false, если такого условия нет.
+func (q *Query) IDs() (ids []int64, ok bool) {
+	for _, expr := range conjuncts(q.Filter) {
+		switch e := expr.(type) {
+		case *Compare:
+			if e.Field == q.Schema.ID && e.Op == OpEq {
+				return integers([]Value{e.Value}), true
+			}
+		case *In:
+			if e.Field == q.Schema.ID && !e.Negated {
+				return integers(e.Values), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// integers возвращает целые константы списка: дробной константе не равен ни один идентификатор
+func integers(values []Value) []int64 {
+	ids := make([]int64, 0, len(values))
+	for _, v := range values {
+		if !v.IsFloat {
+			ids = append(ids, v.Int)
+		}
+	}
+	return ids
+}
+
+// Range возвращает диапазон [min, max], которым конъюнкция фильтра ограничивает поле.
+// ok = false, если поле не ограничено ни сверху, ни снизу.
+func (q *Query) Range(field string) (min, max float64, ok bool) {
+	min, max = math.Inf(-1), math.Inf(1)
+	for _, expr := range conjuncts(q.Filter) {
+		e, isCompare := expr.(*Compare)
+		if !isCompare || e.Field != field {
+			continue
+		}
+		value := e.Value.float64()
+		switch e.Op {
+		case OpEq:
+			min, max = math.Max(min, value), math.Min(max, value)
+		case OpGt:
+			min = math.Max(min, math.Nextafter(value, math.Inf(1)))
+		case OpGe:
+			min = math.Max(min, value)
+		case OpLt:
+			max = math.Min(max, math.Nextafter(value, math.Inf(-1)))
+		case OpLe:
+			max = math.Min(max, value)
+		default:
+			continue
+		}
+		ok = true
+	}
+	return min, max, ok
+}
+
+// conjuncts раскладывает выражение на операнды верхнего уровня AND
+func conjuncts(expr Expr) []Expr {
+	if and, ok := expr.(*And); ok {
+		return append(conjuncts(and.Left), conjuncts(and.Right)...)
+	}
+	if expr == nil {
+		return nil
+	}
+	return []Expr{expr}
+}
@@ -0,0 +1,136 @@
+package query
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func mustNew(t *testing.T, schema *Schema, filter, sortBy string) *Query {
+	t.Helper()
+	q, err := New(schema, filter, sortBy, testNow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return q
+}
+
+// TestRange проверяет диапазон поля, выводимый из конъюнкции фильтра
+func TestRange(t *testing.T) {
+	inf := math.Inf(1)
+	tests := []struct {
+		filter   string
+		min, max float64
+		ok       bool
+	}{
+		{"", -inf, inf, false},
+		{"size >= 10", 10, inf, true},
+		{"size > 10 AND size <= 100", math.Nextafter(10, inf), 100, true},
+		{"size < 5", -inf, math.Nextafter(5, -inf), true},
+		{"size = 5 AND size >= 3", 5, 5, true},
+		{"type = 1 AND (size > 3) AND size < 8", math.Nextafter(3, inf), math.Nextafter(8, -inf), true},
+		{"size >= 10 AND size < 5", 10, math.Nextafter(5, -inf), true},
+		{"size > 10 OR size < 5", -inf, inf, false},
+		{"NOT size > 3", -inf, inf, false},
+		{"size != 3", -inf, inf, false},
+		{"size IN (1, 2)", -inf, inf, false},
+		{"type = 1", -inf, inf, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			min, max, ok := mustNew(t, ChatSchema, tt.filter, "").Range("count_of_users")
+			if min != tt.min || max != tt.max || ok != tt.ok {
+				t.Errorf("Range = [%v, %v], %v; want [%v, %v], %v", min, max, ok, tt.min, tt.max, tt.ok)
+			}
+		})
+	}
+}
+
+// TestIDs проверяет извлечение идентификаторов из условий id = x и id IN (...) верхнего уровня
+func TestIDs(t *testing.T) {
+	tests := []struct {
+		filter string
+		want   []int64
+		ok     bool
+	}{
+		{"", nil, false},
+		{"id = 5", []int64{5}, true},
+		{"size > 1 AND chat_id in (3, -4)", []int64{3, -4}, true},
+		{"(size > 1 AND id = 7) AND type = 2", []int64{7}, true},
+		{"id IN (9007199254740993, 1.5)", []int64{9007199254740993}, true},
+		{"id = 1.5", []int64{}, true},
+		{"id NOT IN (1)", nil, false},
+		{"id = 1 OR id = 2", nil, false},
+		{"NOT id = 1", nil, false},
+		{"id > 5", nil, false},
+		{"size = 5", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			ids, ok := mustNew(t, ChatSchema, tt.filter, "").IDs()
+			if !reflect.DeepEqual(ids, tt.want) || ok != tt.ok {
+				t.Errorf("IDs = %v, %v; want %v, %v", ids, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+// TestParseSort проверяет разбор сортировки и позиции ошибок в ней
+func TestParseSort(t *testing.T) {
+	keys, err := ParseSort(ChatSchema, "-size, +id,type")
+	want := []SortKey{{"count_of_users", true}, {"chat_id", false}, {"chat_type", false}}
+	if err != nil || !reflect.DeepEqual(keys, want) {
+		t.Errorf("ParseSort = %v, %v; want %v", keys, err, want)
+	}
+
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{"name", 1},
+		{"size,-name", 6},
+		{"size,,id", 6},
+		{"a,b,c,d,e", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := ParseSort(ChatSchema, tt.input)
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) || syntax.Pos != tt.pos {
+				t.Errorf("error = %v, want SyntaxError at %d", err, tt.pos)
+			}
+		})
+	}
+}
+
+// TestMatchAndLess проверяет отбор записей фильтром и порядок сортировки с дозаполнением по идентификатору
+func TestMatchAndLess(t *testing.T) {
+	rows := []Row{
+		{"chat_id": 1, "chat_type": 1, "count_of_users": 50},
+		{"chat_id": 2, "chat_type": 2, "count_of_users": 10},
+		{"chat_id": 3, "chat_type": 2, "count_of_users": 50},
+		{"chat_id": 4, "chat_type": 3, "count_of_users": 5},
+	}
+	q := mustNew(t, ChatSchema, "type IN (1, 2) AND NOT (size < 20 AND id != 2)", "-size")
+
+	var got []int64
+	var matched []Row
+	for _, row := range rows {
+		if q.Match(row) {
+			matched = append(matched, row)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return q.Less(matched[i], matched[j]) })
+	for _, row := range matched {
+		got = append(got, row["chat_id"])
+	}
+	if want := []int64{1, 3, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matched ids %v, want %v", got, want)
+	}
+
+	if all := mustNew(t, ChatSchema, "", ""); !all.Match(rows[3]) || !all.Less(rows[0], rows[1]) {
+		t.Error("query without filter and sort must match everything and order by id")
+	}
+}
@@ -0,0 +1,97 @@
+package query
+
+import (
+	"sort"
+	"strings"
+
+	"stats-of/internal/entities"
+)
+
+// Type тип значения поля
+type Type int
+
+const (
+	// TypeNumber целое или дробное число
+	TypeNumber Type = iota
+	// TypeTime момент времени; в выражениях хранится как unix-секунды
+	TypeTime
+)
+
+func (t Type) String() string {
+	if t == TypeTime {
+		return "time"
+	}
+	return "number"
+}
+
+type (
+	// Field поле записи, доступное в фильтре и сортировке
+	Field struct {
+		Name string
+		Type Type
+	}
+
+	// Schema поля списка; ID — поле идентификатора, Count — поле, по которому есть индекс рейтинга
+	Schema struct {
+		Entity  string
+		ID      string
+		Count   string
+		fields  map[string]Field
+		aliases map[string]string
+	}
+
+	// Row значения полей записи. Все поля целые, поэтому хранятся как int64 без потери точности.
+	Row map[string]int64
+)
+
+// ChatSchema поля чатов: chat_id, chat_type, count_of_users; id и size — синонимы
+var ChatSchema = newSchema("chat", "chat_id", "count_of_users",
+	[]Field{{"chat_id", TypeNumber}, {"chat_type", TypeNumber}, {"count_of_users", TypeNumber}},
+	map[string]string{"id": "chat_id", "type": "chat_type", "size": "count_of_users", "users": "count_of_users"})
+
+// UserSchema поля пользователей: user_id, last_time, count_of_chats; id и chats — синонимы
+var UserSchema = newSchema("user", "user_id", "count_of_chats",
+	[]Field{{"user_id", TypeNumber}, {"last_time", TypeTime}, {"count_of_chats", TypeNumber}},
+	map[string]string{"id": "user_id", "last_seen": "last_time", "chats": "count_of_chats"})
+
+func newSchema(entity, id, count string, fields []Field, aliases map[string]string) *Schema {
+	s := &Schema{Entity: entity, ID: id, Count: count, fields: make(map[string]Field, len(fields)), aliases: aliases}
+	for _, f := range fields {
+		s.fields[f.Name] = f
+	}
+	return s
+}
+
+// Lookup возвращает поле по имени или синониму без учёта регистра
+func (s *Schema) Lookup(name string) (Field, bool) {
+	name = strings.ToLower(name)
+	if canonical, ok := s.aliases[name]; ok {
+		name = canonical
+	}
+	f, ok := s.fields[name]
+	return f, ok
+}
+
+// names перечисляет поля для сообщений об ошибках
+func (s *Schema) names() string {
+	names := make([]string, 0, len(s.fields))
+	for name := range s.fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// ChatRow значения полей чата
+func ChatRow(c entities.Chat) Row {
+	return Row{"chat_id": int64(c.ChatID), "chat_type": int64(c.ChatType), "count_of_users": c.CountOfUsers}
+}
+
+// UserRow значения полей пользователя; нулевое LastTime соответствует нулю unix-секунд
+func UserRow(u entities.User) Row {
+	var lastTime int64
+	if !u.LastTime.IsZero() {
+		lastTime = u.LastTime.Unix()
+	}
+	return Row{"user_id": int64(u.UserID), "last_time": lastTime, "count_of_chats": u.CountOfChats}
+}
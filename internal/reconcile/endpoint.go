@@ -0,0 +1,54 @@
+package reconcile
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// MakeStatusHandler обработчик GET /api/v1/admin/reconcile: идёт ли сверка и итог последней завершённой
+func MakeStatusHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := service.Status()
+		if err != nil {
+			utils.RespondWith404(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, status)
+	}
+}
+
+// MakeRunHandler обработчик POST /api/v1/admin/reconcile?repair=true: запускает внеочередную сверку в фоне
+// и отвечает 202; ход и итог сверки клиент опрашивает через GET. Без repair используется настройка
+// RECONCILE_REPAIR. Маршрут регистрируется только за middlewares.AdminOnly.
+func MakeRunHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		repair := service.repair
+		if raw := r.URL.Query().Get("repair"); raw != "" {
+			var err error
+			repair, err = strconv.ParseBool(raw)
+			if err != nil {
+				utils.RespondWith400(w, "repair must be a boolean")
+				return
+			}
+		}
+
+		status, err := service.Start(repair)
+		if errors.Is(err, ErrRunning) {
+			utils.RespondWithError(w, http.StatusConflict, err.Error())
+			return
+		} else if err != nil {
+			logger.Log.Error("Failed to start reconciliation", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		logger.Log.Info("Reconciliation started", zap.Bool("repair", repair), zap.String("remote", r.RemoteAddr))
+		w.Header().Set("Location", r.URL.Path)
+		utils.RespondWithJSON(w, http.StatusAccepted, status)
+	}
+}
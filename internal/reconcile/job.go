@@ -0,0 +1,10 @@
+package reconcile
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewReconcileJob создаёт задачу периодической сверки счётчиков
+func NewReconcileJob(service *Service) jobs.Job {
+	return jobs.Func("reconcile", service.Run)
+}
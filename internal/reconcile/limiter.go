@@ -0,0 +1,46 @@
+package reconcile
+
+import (
+	"context"
+	"time"
+)
+
+// limiter ограничивает частоту проверок: не больше rate сущностей в секунду.
+// Нулевой rate отключает ограничение.
+type limiter struct {
+	interval time.Duration
+	next     time.Time
+}
+
+func newLimiter(rate int) *limiter {
+	if rate <= 0 {
+		return &limiter{}
+	}
+	return &limiter{interval: time.Second / time.Duration(rate)}
+}
+
+// wait блокируется до следующего разрешённого момента или отмены контекста
+func (l *limiter) wait(ctx context.Context) error {
+	if l.interval == 0 {
+		return ctx.Err()
+	}
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	if delay == 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,444 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// Kind вид расхождения
+type Kind string
+
+const (
+	// KindChatCount Chat.CountOfUsers не совпадает с размером chat_users:{id}
+	KindChatCount Kind = "chat_count"
+	// KindUserCount User.CountOfChats не совпадает с числом чатов, где пользователь состоит
+	KindUserCount Kind = "user_count"
+	// KindMissingUserChat чата нет в user_chats:{id}, хотя пользователь есть в chat_users чата
+	KindMissingUserChat Kind = "missing_user_chat"
+	// KindExtraUserChat чат есть в user_chats:{id}, но пользователя нет в chat_users чата
+	KindExtraUserChat Kind = "extra_user_chat"
+)
+
+var kinds = []Kind{KindChatCount, KindUserCount, KindMissingUserChat, KindExtraUserChat}
+
+// maxReportedMismatches сколько расхождений попадает в отчёт; счётчики по видам учитывают все
+const maxReportedMismatches = 1000
+
+// ErrRunning сверка уже выполняется
+var ErrRunning = errors.New("reconciliation is already running")
+
+type (
+	// Mismatch расхождение сохранённого значения с вычисленным по множествам участия.
+	// Для расхождений связей Stored и Actual — признак наличия связи (0 или 1).
+	Mismatch struct {
+		Kind     Kind  `json:"kind"`
+		ChatID   int64 `json:"chat_id,omitempty"`
+		UserID   int64 `json:"user_id,omitempty"`
+		Stored   int64 `json:"stored"`
+		Actual   int64 `json:"actual"`
+		Repaired bool  `json:"repaired"`
+	}
+
+	// Report итог последней сверки
+	Report struct {
+		StartedAt    time.Time    `json:"started_at"`
+		FinishedAt   time.Time    `json:"finished_at"`
+		Repair       bool         `json:"repair"`
+		ChatsChecked int          `json:"chats_checked"`
+		UsersChecked int          `json:"users_checked"`
+		Counts       map[Kind]int `json:"counts"`
+		Repaired     int          `json:"repaired"`
+		// Truncated сообщает, что в Mismatches попали не все расхождения
+		Truncated  bool       `json:"truncated"`
+		Mismatches []Mismatch `json:"mismatches"`
+	}
+
+	// Status состояние сверки для опроса после запуска через API
+	Status struct {
+		// Running сверка выполняется; StartedAt и Repair описывают текущий запуск
+		Running   bool       `json:"running"`
+		StartedAt *time.Time `json:"started_at,omitempty"`
+		Repair    bool       `json:"repair"`
+		// LastError ошибка последнего запуска, если он не завершился
+		LastError string `json:"last_error,omitempty"`
+		// Report итог последней завершённой сверки
+		Report *Report `json:"report,omitempty"`
+	}
+
+	// Service сверяет денормализованные счётчики и обратные связи с множествами участников чатов.
+	// Источником истины считаются множества chat_users:{id}.
+	//
+	// Исправление чата или пользователя перечитывает его состав и записывает результат под
+	// блокировкой приёма событий, поэтому не затирает вступления и выходы, принятые во время сверки.
+	// Исправления не попадают в журнал событий: пересборка из журнала (cmd/replay) вычисляет
+	// состояние заново и отменяет их, если журнал расходится с множествами участников.
+	Service struct {
+		repo   *storage.Repository
+		lock   sync.Locker
+		repair bool
+		rate   int
+
+		// ctx отменяется в Close и прерывает сверки, запущенные через Start
+		ctx    context.Context
+		cancel context.CancelFunc
+
+		running   sync.Mutex
+		mu        sync.RWMutex
+		current   *Status
+		report    *Report
+		lastError error
+
+		mismatches *prometheus.GaugeVec
+		repairs    *prometheus.CounterVec
+		lastRun    prometheus.Gauge
+	}
+)
+
+// NewService создаёт сверку; lock — блокировка приёма событий (membership.Service.Locker),
+// repair включает исправление по умолчанию, rate ограничивает число проверяемых чатов
+// и пользователей в секунду
+func NewService(repo *storage.Repository, lock sync.Locker, repair bool, rate int) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		repo:   repo,
+		lock:   lock,
+		repair: repair,
+		rate:   rate,
+		ctx:    ctx,
+		cancel: cancel,
+		mismatches: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "stats_of_reconcile_mismatches",
+			Help: "Number of counter and membership mismatches found by the last reconciliation.",
+		}, []string{"kind"}),
+		repairs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "stats_of_reconcile_repairs_total",
+			Help: "Number of mismatches repaired by reconciliation.",
+		}, []string{"kind"}),
+		lastRun: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "stats_of_reconcile_last_run_timestamp_seconds",
+			Help: "Unix time when the last reconciliation finished.",
+		}),
+	}
+}
+
+// Collectors метрики сверки для регистрации в Prometheus
+func (s *Service) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{s.mismatches, s.repairs, s.lastRun}
+}
+
+// Run выполняет сверку с настройкой исправления по умолчанию; используется задачей планировщика
+func (s *Service) Run(ctx context.Context) error {
+	_, err := s.Reconcile(ctx, s.repair)
+	return err
+}
+
+// Close прерывает сверку, запущенную через Start
+func (s *Service) Close() {
+	s.cancel()
+}
+
+// Status возвращает состояние сверки или ErrNotFound, если сверка ещё не запускалась
+func (s *Service) Status() (*Status, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.current == nil && s.report == nil && s.lastError == nil {
+		return nil, apperrors.ErrNotFound
+	}
+	status := &Status{Report: s.report}
+	if s.current != nil {
+		status.Running = true
+		status.StartedAt = s.current.StartedAt
+		status.Repair = s.current.Repair
+	}
+	if s.lastError != nil {
+		status.LastError = s.lastError.Error()
+	}
+	return status, nil
+}
+
+// Start запускает сверку в фоне и сразу возвращает её состояние; итог читается через Status.
+// Сверка ограничена по скорости и на большой базе идёт дольше любого тайм-аута HTTP-запроса.
+func (s *Service) Start(repair bool) (*Status, error) {
+	if !s.running.TryLock() {
+		return nil, ErrRunning
+	}
+	status := s.begin(repair)
+
+	go func() {
+		defer s.running.Unlock()
+		if _, err := s.run(s.ctx, repair); err != nil {
+			logger.Log.Error("Failed to reconcile counters", zap.Bool("repair", repair), zap.Error(err))
+		}
+	}()
+	return status, nil
+}
+
+// Reconcile обходит все чаты и пользователей, пересчитывает счётчики по множествам участия
+// и при repair исправляет расхождения. Одновременно выполняется не больше одной сверки.
+func (s *Service) Reconcile(ctx context.Context, repair bool) (*Report, error) {
+	if !s.running.TryLock() {
+		return nil, ErrRunning
+	}
+	defer s.running.Unlock()
+	s.begin(repair)
+	return s.run(ctx, repair)
+}
+
+// begin отмечает начало запуска и возвращает состояние на этот момент; вызывается под s.running
+func (s *Service) begin(repair bool) *Status {
+	startedAt := time.Now().UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = &Status{Running: true, StartedAt: &startedAt, Repair: repair}
+	return &Status{Running: true, StartedAt: &startedAt, Repair: repair, Report: s.report}
+}
+
+// finish сохраняет итог запуска; вызывается под s.running
+func (s *Service) finish(report *Report, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.current = nil
+	s.lastError = err
+	if report != nil {
+		s.report = report
+	}
+}
+
+// run выполняет сверку и сохраняет её итог; вызывается под s.running
+func (s *Service) run(ctx context.Context, repair bool) (*Report, error) {
+	report, err := s.reconcile(ctx, repair)
+	s.finish(report, err)
+	return report, err
+}
+
+func (s *Service) reconcile(ctx context.Context, repair bool) (*Report, error) {
+	report := &Report{StartedAt: time.Now().UTC(), Repair: repair, Counts: make(map[Kind]int, len(kinds)), Mismatches: []Mismatch{}}
+	for _, kind := range kinds {
+		report.Counts[kind] = 0
+	}
+	limit := newLimiter(s.rate)
+
+	// Чаты каждого пользователя по данным множеств участников на момент прохода по чатам;
+	// при проверке пользователя участие перечитывается
+	actual := make(map[entities.UserID]map[entities.ChatID]struct{})
+
+	chatIDs, err := s.repo.ChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Чаты, у которых есть участники, но нет хеша, тоже сверяются: иначе их участники считались бы
+	// лишними обратными связями и удалялись бы при исправлении
+	memberChatIDs, err := s.repo.MemberChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chatIDs = append(chatIDs, memberChatIDs...)
+	slices.Sort(chatIDs)
+	chatIDs = slices.Compact(chatIDs)
+	for _, chatID := range chatIDs {
+		if err := limit.wait(ctx); err != nil {
+			return nil, err
+		}
+		if err := s.checkChat(ctx, chatID, actual, repair, report); err != nil {
+			return nil, err
+		}
+		report.ChatsChecked++
+	}
+
+	userIDs, err := s.repo.UserIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// Участники чатов без хеша пользователя тоже проверяются
+	for userID := range actual {
+		userIDs = append(userIDs, userID)
+	}
+	checked := make(map[entities.UserID]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		if _, dup := checked[userID]; dup {
+			continue
+		}
+		checked[userID] = struct{}{}
+
+		if err := limit.wait(ctx); err != nil {
+			return nil, err
+		}
+		if err := s.checkUser(ctx, userID, actual[userID], repair, report); err != nil {
+			return nil, err
+		}
+		report.UsersChecked++
+	}
+
+	report.FinishedAt = time.Now().UTC()
+	for kind, count := range report.Counts {
+		s.mismatches.WithLabelValues(string(kind)).Set(float64(count))
+	}
+	s.lastRun.Set(float64(report.FinishedAt.Unix()))
+
+	total := 0
+	for _, count := range report.Counts {
+		total += count
+	}
+	logger.Log.Info("Reconciliation finished", zap.Int("chats", report.ChatsChecked), zap.Int("users", report.UsersChecked),
+		zap.Int("mismatches", total), zap.Int("repaired", report.Repaired), zap.Duration("duration", report.FinishedAt.Sub(report.StartedAt)))
+	return report, nil
+}
+
+func (s *Service) checkChat(ctx context.Context, chatID entities.ChatID, actual map[entities.UserID]map[entities.ChatID]struct{}, repair bool, report *Report) error {
+	if repair {
+		// Иначе событие, принятое между чтением состава и записью, затёрлось бы устаревшим счётчиком
+		s.lock.Lock()
+		defer s.lock.Unlock()
+	}
+
+	// У чата без хеша сохранённый счётчик считается нулевым
+	var stored int64
+	chat, err := s.repo.Chat(ctx, chatID)
+	switch {
+	case err == nil:
+		stored = chat.CountOfUsers
+	case errors.Is(err, apperrors.ErrNotFound):
+	default:
+		return err
+	}
+	members, err := s.repo.ChatUserIDs(ctx, chatID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range members {
+		chats, ok := actual[userID]
+		if !ok {
+			chats = make(map[entities.ChatID]struct{})
+			actual[userID] = chats
+		}
+		chats[chatID] = struct{}{}
+	}
+
+	if count := int64(len(members)); stored != count {
+		m := Mismatch{Kind: KindChatCount, ChatID: int64(chatID), Stored: stored, Actual: count}
+		if repair {
+			if err := s.repo.SetCountOfUsers(ctx, chatID, count); err != nil {
+				return err
+			}
+			m.Repaired = true
+		}
+		s.add(report, m)
+	}
+	return nil
+}
+
+func (s *Service) checkUser(ctx context.Context, userID entities.UserID, seen map[entities.ChatID]struct{}, repair bool, report *Report) error {
+	if repair {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+	}
+
+	var stored int64
+	user, err := s.repo.User(ctx, userID)
+	switch {
+	case err == nil:
+		stored = user.CountOfChats
+	case errors.Is(err, apperrors.ErrNotFound):
+	default:
+		return err
+	}
+
+	linked, err := s.repo.UserChatIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	// Проход по чатам мог закончиться давно: участие в чатах, найденных в нём или в обратных
+	// связях, проверяется заново по текущим множествам chat_users
+	actual := make(map[entities.ChatID]struct{}, len(seen))
+	candidates := slices.Clone(linked)
+	for chatID := range seen {
+		candidates = append(candidates, chatID)
+	}
+	for _, chatID := range candidates {
+		if _, ok := actual[chatID]; ok {
+			continue
+		}
+		member, err := s.repo.IsMember(ctx, chatID, userID)
+		if err != nil {
+			return err
+		}
+		if member {
+			actual[chatID] = struct{}{}
+		}
+	}
+
+	linkedSet := make(map[entities.ChatID]struct{}, len(linked))
+	for _, chatID := range linked {
+		linkedSet[chatID] = struct{}{}
+		if _, ok := actual[chatID]; ok {
+			continue
+		}
+		m := Mismatch{Kind: KindExtraUserChat, ChatID: int64(chatID), UserID: int64(userID), Stored: 1, Actual: 0}
+		if repair {
+			if err := s.repo.UnlinkUserChat(ctx, userID, chatID); err != nil {
+				return err
+			}
+			m.Repaired = true
+		}
+		s.add(report, m)
+	}
+
+	missing := make([]entities.ChatID, 0)
+	for chatID := range actual {
+		if _, ok := linkedSet[chatID]; !ok {
+			missing = append(missing, chatID)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool { return missing[i] < missing[j] })
+	for _, chatID := range missing {
+		m := Mismatch{Kind: KindMissingUserChat, ChatID: int64(chatID), UserID: int64(userID), Stored: 0, Actual: 1}
+		if repair {
+			if err := s.repo.LinkUserChat(ctx, userID, chatID); err != nil {
+				return err
+			}
+			m.Repaired = true
+		}
+		s.add(report, m)
+	}
+
+	if count := int64(len(actual)); stored != count {
+		m := Mismatch{Kind: KindUserCount, UserID: int64(userID), Stored: stored, Actual: count}
+		if repair {
+			if err := s.repo.SetCountOfChats(ctx, userID, count); err != nil {
+				return err
+			}
+			m.Repaired = true
+		}
+		s.add(report, m)
+	}
+	return nil
+}
+
+func (s *Service) add(report *Report, m Mismatch) {
+	report.Counts[m.Kind]++
+	if m.Repaired {
+		report.Repaired++
+		s.repairs.WithLabelValues(string(m.Kind)).Inc()
+	}
+	if len(report.Mismatches) < maxReportedMismatches {
+		report.Mismatches = append(report.Mismatches, m)
+	} else {
+		report.Truncated = true
+	}
+}
@@ -0,0 +1,244 @@
+package reconcile
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// seedDiverged записывает чат 1 с участниками 10 и 11 и три расхождения: неверный счётчик чата,
+// лишнюю обратную связь пользователя 10 с чатом 2 и отсутствующую связь пользователя 11 с чатом 1
+func seedDiverged(t *testing.T, repo *storage.Repository) {
+	t.Helper()
+	ctx := context.Background()
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 1, CountOfUsers: 5}, []entities.UserID{10, 11}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUser(ctx, entities.User{UserID: 10, CountOfChats: 2}, []entities.ChatID{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUser(ctx, entities.User{UserID: 11, CountOfChats: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReconcileReportsWithoutRepair(t *testing.T) {
+	repo := memtest.NewRepository(t)
+	seedDiverged(t, repo)
+
+	report, err := NewService(repo, &sync.Mutex{}, false, 0).Reconcile(context.Background(), false)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+
+	want := map[Kind]int{KindChatCount: 1, KindUserCount: 1, KindMissingUserChat: 1, KindExtraUserChat: 1}
+	for _, kind := range kinds {
+		if report.Counts[kind] != want[kind] {
+			t.Errorf("counts[%s] = %d, want %d", kind, report.Counts[kind], want[kind])
+		}
+	}
+	if report.Repaired != 0 {
+		t.Errorf("repaired = %d without repair", report.Repaired)
+	}
+	if report.ChatsChecked != 1 || report.UsersChecked != 2 {
+		t.Errorf("checked %d chats and %d users, want 1 and 2", report.ChatsChecked, report.UsersChecked)
+	}
+
+	chat, err := repo.Chat(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.CountOfUsers != 5 {
+		t.Errorf("chat count changed to %d without repair", chat.CountOfUsers)
+	}
+}
+
+func TestReconcileRepairs(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	seedDiverged(t, repo)
+	service := NewService(repo, &sync.Mutex{}, false, 0)
+
+	report, err := service.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Repaired != 4 {
+		t.Errorf("repaired = %d, want 4", report.Repaired)
+	}
+
+	chat, err := repo.Chat(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.CountOfUsers != 2 {
+		t.Errorf("chat count = %d, want 2", chat.CountOfUsers)
+	}
+	for _, userID := range []entities.UserID{10, 11} {
+		user, err := repo.User(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chats, err := repo.UserChatIDs(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.CountOfChats != 1 || !slices.Equal(chats, entities.ChatIds{1}) {
+			t.Errorf("user %d: count %d, chats %v; want 1 and [1]", userID, user.CountOfChats, chats)
+		}
+	}
+
+	again, err := service.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("second reconcile: %v", err)
+	}
+	for kind, count := range again.Counts {
+		if count != 0 {
+			t.Errorf("second run found %d %s mismatches", count, kind)
+		}
+	}
+}
+
+func TestStartRunsInBackground(t *testing.T) {
+	repo := memtest.NewRepository(t)
+	seedDiverged(t, repo)
+	service := NewService(repo, &sync.Mutex{}, false, 0)
+	defer service.Close()
+
+	if _, err := service.Status(); err == nil {
+		t.Fatal("status before the first run should be ErrNotFound")
+	}
+	status, err := service.Start(true)
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if status.StartedAt == nil {
+		t.Error("started_at is missing")
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for status.Running || status.Report == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("reconciliation did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+		if status, err = service.Status(); err != nil {
+			t.Fatalf("status: %v", err)
+		}
+	}
+	if status.LastError != "" || !status.Report.Repair || status.Report.Repaired != 4 {
+		t.Errorf("status = %+v, report = %+v; want a finished repair of 4 mismatches", status, status.Report)
+	}
+}
+
+// TestReconcileKeepsChatsWithoutHash проверяет, что участники чата без хеша chat:{id}
+// не считаются лишними связями: исправление восстанавливает счётчик, а не удаляет связи
+func TestReconcileKeepsChatsWithoutHash(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	if _, err := repo.Storage().SetAdd(ctx, storage.ChatUsersKey(7), "20"); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveUser(ctx, entities.User{UserID: 20, CountOfChats: 1}, []entities.ChatID{7}); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := NewService(repo, &sync.Mutex{}, false, 0).Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Counts[KindExtraUserChat] != 0 || report.Counts[KindChatCount] != 1 {
+		t.Errorf("counts = %v; want one chat_count and no extra_user_chat", report.Counts)
+	}
+
+	chats, err := repo.UserChatIDs(ctx, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(chats, entities.ChatIds{7}) {
+		t.Errorf("user chats = %v after repair, want [7]", chats)
+	}
+	chat, err := repo.Chat(ctx, 7)
+	if err != nil {
+		t.Fatalf("chat hash was not restored: %v", err)
+	}
+	if chat.CountOfUsers != 1 {
+		t.Errorf("chat count = %d, want 1", chat.CountOfUsers)
+	}
+}
+
+// hookStorage вызывает beforeUsers, когда сверка запрашивает список пользователей,
+// то есть между проходом по чатам и проходом по пользователям
+type hookStorage struct {
+	storage.Storage
+	beforeUsers func()
+}
+
+func (s *hookStorage) FindKeysByPattern(pattern string) ([]string, error) {
+	if strings.HasPrefix(pattern, "user:") && s.beforeUsers != nil {
+		s.beforeUsers()
+		s.beforeUsers = nil
+	}
+	return s.Storage.FindKeysByPattern(pattern)
+}
+
+// TestReconcileSeesMembershipChangesBetweenPasses проверяет, что вступление и выход между
+// проходом по чатам и проходом по пользователям не считаются расхождениями и не откатываются
+func TestReconcileSeesMembershipChangesBetweenPasses(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	if _, err := repo.AddMembership(ctx, 1, 11); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.AddMembership(ctx, 2, 12); err != nil {
+		t.Fatal(err)
+	}
+
+	hooked := &hookStorage{Storage: repo.Storage()}
+	hooked.beforeUsers = func() {
+		// Пользователь 10 вступает в чат 1, пользователь 11 выходит из него
+		if _, err := repo.AddMembership(ctx, 1, 10); err != nil {
+			t.Error(err)
+		}
+		if _, err := repo.RemoveMembership(ctx, 1, 11); err != nil {
+			t.Error(err)
+		}
+	}
+
+	report, err := NewService(storage.NewRepository(hooked), &sync.Mutex{}, false, 0).Reconcile(ctx, true)
+	if err != nil {
+		t.Fatalf("reconcile: %v", err)
+	}
+	if report.Repaired != 0 {
+		t.Errorf("repaired %d mismatches: %+v; want none", report.Repaired, report.Mismatches)
+	}
+
+	want := map[entities.UserID]entities.ChatIds{10: {1}, 11: {}, 12: {2}}
+	for userID, chats := range want {
+		user, err := repo.User(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		linked, err := repo.UserChatIDs(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.CountOfChats != int64(len(chats)) || !slices.Equal(linked, chats) {
+			t.Errorf("user %d: count %d, chats %v; want %d and %v", userID, user.CountOfChats, linked, len(chats), chats)
+		}
+	}
+	chat, err := repo.Chat(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.CountOfUsers != 1 {
+		t.Errorf("chat 1 count = %d, want 1", chat.CountOfUsers)
+	}
+}
@@ -0,0 +1,50 @@
+package sessions
+
+import (
+	"net/http"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// MakeUserHandler обработчик GET /api/v1/users/{id}/sessions: число сессий, средняя длительность
+// и сообщения за сессию пользователя по всем чатам
+func MakeUserHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		stats, err := service.UserStats(r.Context(), entities.UserID(id))
+		if err != nil {
+			logger.Log.Error("Failed to read user sessions", zap.Int64("userID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, stats)
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/sessions: итоги сессий пользователей внутри чата
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		stats, err := service.ChatStats(r.Context(), entities.ChatID(id))
+		if err != nil {
+			logger.Log.Error("Failed to read chat sessions", zap.Int64("chatID", id), zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, stats)
+	}
+}
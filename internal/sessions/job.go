@@ -0,0 +1,10 @@
+package sessions
+
+import (
+	"stats-of/internal/jobs"
+)
+
+// NewFlushJob создаёт задачу периодического закрытия сессий без активности
+func NewFlushJob(service *Service) jobs.Job {
+	return jobs.Func("sessions-flush", service.Flush)
+}
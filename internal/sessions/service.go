@@ -0,0 +1,279 @@
+package sessions
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Схема ключей:
+//
+//	sessions:open               отсортированное множество открытых сессий, score — время последней активности
+//	sessions:open:state         хеш: сессия -> "start,last,messages"
+//	sessions:open:chat:{id}     открытые сессии пользователей в чате, score — время последней активности
+//	sessions:stats:user:{id}    хеш итогов закрытых сессий пользователя: sessions, seconds, messages
+//	sessions:stats:chat:{id}    то же для сессий пользователей внутри чата
+//
+// Сессия пользователя обозначается "user:{user}", сессия пользователя в чате — "chat:{chat}:{user}".
+const (
+	openKey           = "sessions:open"
+	openStateKey      = "sessions:open:state"
+	openChatKeyPrefix = "sessions:open:chat:"
+	statsKeyPrefix    = "sessions:stats:"
+	fieldSessions     = "sessions"
+	fieldSeconds      = "seconds"
+	fieldMessages     = "messages"
+	scopeUser         = "user"
+	scopeChat         = "chat"
+	separator         = ":"
+)
+
+type (
+	// Stats итоги сессий пользователя или чата
+	Stats struct {
+		Sessions int64 `json:"sessions"`
+		// AvgSessionSeconds средняя длительность закрытой сессии в секундах
+		AvgSessionSeconds  float64  `json:"avg_session_seconds"`
+		TotalActiveSeconds int64    `json:"total_active_seconds"`
+		Messages           int64    `json:"messages"`
+		MessagesPerSession float64  `json:"messages_per_session"`
+		OpenSessions       int      `json:"open_sessions"`
+		CurrentSession     *Session `json:"current_session,omitempty"`
+	}
+
+	// Service собирает активность в сессии по порогу простоя: активность позже чем через
+	// idleGap после предыдущей начинает новую сессию. Закрытые сессии сворачиваются в итоги.
+	Service struct {
+		store   storage.Storage
+		idleGap time.Duration
+
+		mu sync.Mutex
+	}
+)
+
+func NewService(store storage.Storage, idleGap time.Duration) *Service {
+	return &Service{store: store, idleGap: idleGap}
+}
+
+// OnChange учитывает вступление и сообщение как активность пользователя и его активность в чате
+func (s *Service) OnChange(ctx context.Context, change *membership.Change) error {
+	if change.Event.Type.Removes() {
+		return nil
+	}
+
+	at := change.Event.Time.UTC().Truncate(time.Second)
+	message := change.Event.Type == membership.EventMessage
+	userID := strconv.FormatInt(int64(change.Event.UserID), 10)
+	chatID := strconv.FormatInt(int64(change.Event.ChatID), 10)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, session := range []string{
+		scopeUser + separator + userID,
+		scopeChat + separator + chatID + separator + userID,
+	} {
+		if err := s.track(ctx, session, at, message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush закрывает сессии без активности дольше порога простоя
+func (s *Service) Flush(ctx context.Context) error {
+	cutoff := time.Now().UTC().Add(-s.idleGap)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired, err := s.store.SortedSetRevRangeByScore(ctx, openKey, math.Inf(-1), float64(cutoff.Unix()), 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to list idle sessions: %w", err)
+	}
+	for _, m := range expired {
+		session, ok, err := s.open(ctx, m.Member)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := s.close(ctx, m.Member, session); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, key := range openIndexes(m.Member) {
+			if err := s.store.SortedSetRemove(ctx, key, m.Member); err != nil {
+				return fmt.Errorf("failed to drop session %s: %w", m.Member, err)
+			}
+		}
+	}
+
+	logger.Log.Info("Idle sessions closed", zap.Int("sessions", len(expired)))
+	return nil
+}
+
+// UserStats итоги сессий пользователя и его текущая сессия
+func (s *Service) UserStats(ctx context.Context, id entities.UserID) (*Stats, error) {
+	userID := strconv.FormatInt(int64(id), 10)
+	stats, err := s.stats(ctx, scopeUser+separator+userID)
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok, err := s.open(ctx, scopeUser+separator+userID)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		stats.OpenSessions = 1
+		stats.CurrentSession = &current
+	}
+	return stats, nil
+}
+
+// ChatStats итоги сессий пользователей в чате; открытые сессии учитываются только числом
+func (s *Service) ChatStats(ctx context.Context, id entities.ChatID) (*Stats, error) {
+	chatID := strconv.FormatInt(int64(id), 10)
+	stats, err := s.stats(ctx, scopeChat+separator+chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	open, err := s.store.SortedSetCard(ctx, openChatKeyPrefix+chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count open sessions of chat %d: %w", id, err)
+	}
+	stats.OpenSessions = int(open)
+	return stats, nil
+}
+
+// track продлевает открытую сессию или закрывает её и открывает новую. Опоздавшая активность
+// раньше начала открытой сессии больше чем на порог простоя в неё не входит: она учитывается
+// как отдельная закрытая сессия.
+func (s *Service) track(ctx context.Context, session string, at time.Time, message bool) error {
+	current, ok, err := s.open(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	if ok && current.Start.Sub(at) > s.idleGap {
+		late := Session{Start: at, Last: at}
+		late.add(at, message)
+		return s.fold(ctx, session, late)
+	}
+	if ok && at.Sub(current.Last) > s.idleGap {
+		if err := s.close(ctx, session, current); err != nil {
+			return err
+		}
+		ok = false
+	}
+	if !ok {
+		current = Session{Start: at, Last: at}
+	}
+	current.add(at, message)
+
+	if err := s.store.HashSet(ctx, openStateKey, map[string]string{session: current.encode()}); err != nil {
+		return fmt.Errorf("failed to save session %s: %w", session, err)
+	}
+	for _, key := range openIndexes(session) {
+		if err := s.store.SortedSetAdd(ctx, key, session, float64(current.Last.Unix())); err != nil {
+			return fmt.Errorf("failed to index session %s: %w", session, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) open(ctx context.Context, session string) (Session, bool, error) {
+	raw, err := s.store.HashGet(ctx, openStateKey, session)
+	if err != nil {
+		return Session{}, false, fmt.Errorf("failed to read session %s: %w", session, err)
+	}
+	if raw == "" {
+		return Session{}, false, nil
+	}
+	current, err := decodeSession(raw)
+	if err != nil {
+		logger.Log.Warn("Dropping malformed session", zap.String("session", session), zap.Error(err))
+		return Session{}, false, nil
+	}
+	return current, true, nil
+}
+
+// close сворачивает сессию в итоги пользователя или чата и удаляет её из открытых
+func (s *Service) close(ctx context.Context, session string, current Session) error {
+	if err := s.fold(ctx, session, current); err != nil {
+		return err
+	}
+
+	if err := s.store.HashDelete(ctx, openStateKey, session); err != nil {
+		return fmt.Errorf("failed to close session %s: %w", session, err)
+	}
+	for _, key := range openIndexes(session) {
+		if err := s.store.SortedSetRemove(ctx, key, session); err != nil {
+			return fmt.Errorf("failed to close session %s: %w", session, err)
+		}
+	}
+	return nil
+}
+
+// fold добавляет сессию к итогам пользователя или чата
+func (s *Service) fold(ctx context.Context, session string, current Session) error {
+	key := statsKeyPrefix + statsScope(session)
+	increments := map[string]int64{
+		fieldSessions: 1,
+		fieldSeconds:  int64(current.Length() / time.Second),
+		fieldMessages: current.Messages,
+	}
+	for field, delta := range increments {
+		if _, err := s.store.HashIncrBy(ctx, key, field, delta); err != nil {
+			return fmt.Errorf("failed to update %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) stats(ctx context.Context, scope string) (*Stats, error) {
+	fields, err := s.store.HashGetAll(ctx, statsKeyPrefix+scope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session stats of %s: %w", scope, err)
+	}
+
+	stats := &Stats{}
+	stats.Sessions, _ = strconv.ParseInt(fields[fieldSessions], 10, 64)
+	stats.TotalActiveSeconds, _ = strconv.ParseInt(fields[fieldSeconds], 10, 64)
+	stats.Messages, _ = strconv.ParseInt(fields[fieldMessages], 10, 64)
+	if stats.Sessions > 0 {
+		stats.AvgSessionSeconds = float64(stats.TotalActiveSeconds) / float64(stats.Sessions)
+		stats.MessagesPerSession = float64(stats.Messages) / float64(stats.Sessions)
+	}
+	return stats, nil
+}
+
+// openIndexes отсортированные множества, в которых числится открытая сессия: общее и,
+// для сессии в чате, множество открытых сессий чата
+func openIndexes(session string) []string {
+	if scope := statsScope(session); scope != session {
+		return []string{openKey, openChatKeyPrefix + strings.TrimPrefix(scope, scopeChat+separator)}
+	}
+	return []string{openKey}
+}
+
+// statsScope область итогов сессии: "user:{user}" для пользователя, "chat:{chat}" для сессии в чате
+func statsScope(session string) string {
+	if strings.HasPrefix(session, scopeChat+separator) {
+		return session[:strings.LastIndex(session, separator)]
+	}
+	return session
+}
@@ -0,0 +1,81 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService(memtest.NewStorage(t), 30*time.Minute)
+}
+
+func message(t *testing.T, service *Service, chatID entities.ChatID, userID entities.UserID, at time.Time) {
+	t.Helper()
+	change := &membership.Change{Event: membership.Event{Type: membership.EventMessage, ChatID: chatID, UserID: userID, Time: at}}
+	if err := service.OnChange(context.Background(), change); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestLateActivityIsSeparateSession проверяет, что опоздавшее сообщение не растягивает открытую сессию
+// назад на часы, а учитывается отдельной сессией
+func TestLateActivityIsSeparateSession(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	message(t, service, 1, 10, now.Add(-5*time.Minute))
+	message(t, service, 1, 10, now)
+	message(t, service, 1, 10, now.Add(-3*time.Hour))
+	// Опоздавшее в пределах порога простоя сообщение продлевает начало сессии
+	message(t, service, 1, 10, now.Add(-20*time.Minute))
+
+	stats, err := service.UserStats(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Sessions != 1 || stats.Messages != 1 || stats.TotalActiveSeconds != 0 {
+		t.Errorf("closed sessions = %+v; want the late message as one empty-length session", stats)
+	}
+	current := stats.CurrentSession
+	if current == nil || !current.Start.Equal(now.Add(-20*time.Minute)) || !current.Last.Equal(now) || current.Messages != 3 {
+		t.Errorf("current session = %+v; want 3 messages from %s to %s", current, now.Add(-20*time.Minute), now)
+	}
+}
+
+func TestChatStatsCountsOpenSessionsOfChat(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	now := time.Now().UTC()
+
+	message(t, service, 1, 10, now)
+	message(t, service, 1, 11, now)
+	message(t, service, 2, 10, now)
+	// Сессия в чате 1, которую закроет Flush
+	message(t, service, 1, 12, now.Add(-time.Hour))
+
+	stats, err := service.ChatStats(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.OpenSessions != 3 {
+		t.Errorf("open sessions of chat 1 = %d, want 3", stats.OpenSessions)
+	}
+
+	if err := service.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stats, err = service.ChatStats(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.OpenSessions != 2 || stats.Sessions != 1 {
+		t.Errorf("after flush: open %d, closed %d; want 2 and 1", stats.OpenSessions, stats.Sessions)
+	}
+}
@@ -0,0 +1,54 @@
+package sessions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session открытая сессия: непрерывная активность без пауз длиннее порога простоя
+type Session struct {
+	Start    time.Time `json:"start"`
+	Last     time.Time `json:"last"`
+	Messages int64     `json:"messages"`
+}
+
+// Length длительность сессии от первой до последней активности
+func (s Session) Length() time.Duration {
+	return s.Last.Sub(s.Start)
+}
+
+// add учитывает активность в сессии; активность раньше последней не сдвигает её конец
+func (s *Session) add(at time.Time, message bool) {
+	if at.Before(s.Start) {
+		s.Start = at
+	}
+	if at.After(s.Last) {
+		s.Last = at
+	}
+	if message {
+		s.Messages++
+	}
+}
+
+// encode "start,last,messages" в unix-секундах
+func (s Session) encode() string {
+	return strconv.FormatInt(s.Start.Unix(), 10) + "," + strconv.FormatInt(s.Last.Unix(), 10) + "," + strconv.FormatInt(s.Messages, 10)
+}
+
+func decodeSession(value string) (Session, error) {
+	parts := strings.Split(value, ",")
+	if len(parts) != 3 {
+		return Session{}, fmt.Errorf("malformed session %q", value)
+	}
+	var nums [3]int64
+	for i, part := range parts {
+		n, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return Session{}, fmt.Errorf("malformed session %q: %w", value, err)
+		}
+		nums[i] = n
+	}
+	return Session{Start: time.Unix(nums[0], 0).UTC(), Last: time.Unix(nums[1], 0).UTC(), Messages: nums[2]}, nil
+}
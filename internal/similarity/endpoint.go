@@ -0,0 +1,93 @@
+package similarity
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultTopK = 10
+	maxTopK     = 100
+)
+
+// Response похожие чаты
+type Response struct {
+	ChatID     entities.ChatID `json:"chat_id"`
+	Metric     Metric          `json:"metric"`
+	Exact      bool            `json:"exact"`
+	ComputedAt time.Time       `json:"computed_at"`
+	Results    []Result        `json:"results"`
+}
+
+// MakeHandler обработчик GET /api/v1/chats/{id}/similar?k=10&metric=jaccard|cosine&exact=false.
+// При exact=true оценки MinHash пересчитываются точно по множествам участников из хранилища.
+func MakeHandler(index *Index, repo *storage.Repository) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+		if err != nil {
+			utils.RespondWith400(w, "chat id must be an integer")
+			return
+		}
+
+		query := r.URL.Query()
+		k := defaultTopK
+		if v := query.Get("k"); v != "" {
+			k, err = strconv.Atoi(v)
+			if err != nil || k <= 0 || k > maxTopK {
+				utils.RespondWith400(w, "k must be an integer between 1 and "+strconv.Itoa(maxTopK))
+				return
+			}
+		}
+
+		metric := MetricJaccard
+		if v := query.Get("metric"); v != "" {
+			metric = Metric(v)
+			if !metric.Valid() {
+				utils.RespondWith400(w, "metric must be one of: jaccard, cosine")
+				return
+			}
+		}
+
+		exact := false
+		if v := query.Get("exact"); v != "" {
+			exact, err = strconv.ParseBool(v)
+			if err != nil {
+				utils.RespondWith400(w, "exact must be a boolean")
+				return
+			}
+		}
+
+		results, err := index.TopK(entities.ChatID(chatID), k, metric)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		}
+
+		if exact {
+			results, err = Rescore(r.Context(), repo.ChatUserIDs, entities.ChatID(chatID), results, metric)
+			if err != nil {
+				logger.Log.Error("Failed to rescore similar chats", zap.Int64("chatID", chatID), zap.Error(err))
+				utils.RespondWith500(w)
+				return
+			}
+		}
+
+		utils.SuccessRespondWith200(w, &Response{
+			ChatID:     entities.ChatID(chatID),
+			Metric:     metric,
+			Exact:      exact,
+			ComputedAt: index.BuiltAt(),
+			Results:    results,
+		})
+	}
+}
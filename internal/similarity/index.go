@@ -0,0 +1,209 @@
+package similarity
+
+import (
+	"context"
+	"encoding/binary"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultNumHashes = 128
+	DefaultBands     = 32
+
+	hasherSeed = 0x5eed
+)
+
+type (
+	// Result похожий чат и его оценка схожести
+	Result struct {
+		ChatID      entities.ChatID `json:"chat_id"`
+		Score       float64         `json:"score"`
+		SharedUsers int64           `json:"shared_users"`
+	}
+
+	// Index хранит MinHash-сигнатуры всех чатов и LSH-корзины для быстрого поиска кандидатов.
+	// Сигнатура длины bands·rows разбивается на bands полос; чаты, совпавшие хотя бы
+	// в одной полосе, считаются кандидатами в похожие.
+	Index struct {
+		hasher *MinHasher
+		bands  int
+		rows   int
+
+		mu         sync.RWMutex
+		signatures map[entities.ChatID]Signature
+		sizes      map[entities.ChatID]int
+		buckets    map[uint64][]entities.ChatID
+		builtAt    time.Time
+	}
+)
+
+func NewIndex(numHashes, bands int) *Index {
+	if numHashes <= 0 {
+		numHashes = DefaultNumHashes
+	}
+	if bands <= 0 || bands > numHashes {
+		bands = DefaultBands
+	}
+	rows := numHashes / bands
+
+	return &Index{
+		hasher:     NewMinHasher(bands*rows, hasherSeed),
+		bands:      bands,
+		rows:       rows,
+		signatures: make(map[entities.ChatID]Signature),
+		sizes:      make(map[entities.ChatID]int),
+		buckets:    make(map[uint64][]entities.ChatID),
+	}
+}
+
+// Build полностью перестраивает индекс по графу участия
+func (i *Index) Build(list entities.ChatList) {
+	signatures := make(map[entities.ChatID]Signature, len(list))
+	sizes := make(map[entities.ChatID]int, len(list))
+	buckets := make(map[uint64][]entities.ChatID)
+
+	for chatID, users := range list {
+		ids := make(entities.UserIds, len(users))
+		for j, user := range users {
+			ids[j] = user.UserID
+		}
+
+		sig := i.hasher.Signature(ids)
+		signatures[chatID] = sig
+		sizes[chatID] = len(ids)
+		if len(ids) == 0 {
+			continue
+		}
+		for band := 0; band < i.bands; band++ {
+			key := i.bandKey(band, sig)
+			buckets[key] = append(buckets[key], chatID)
+		}
+	}
+
+	i.mu.Lock()
+	i.signatures = signatures
+	i.sizes = sizes
+	i.buckets = buckets
+	i.builtAt = time.Now().UTC()
+	i.mu.Unlock()
+
+	logger.Log.Info("Similarity index built", zap.Int("chats", len(signatures)), zap.Int("buckets", len(buckets)))
+}
+
+// BuiltAt возвращает время последнего построения индекса
+func (i *Index) BuiltAt() time.Time {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.builtAt
+}
+
+// TopK возвращает k чатов с наиболее похожей аудиторией.
+// Кандидаты берутся из LSH-корзин; если их меньше k, просматриваются все сигнатуры.
+func (i *Index) TopK(chatID entities.ChatID, k int, metric Metric) ([]Result, error) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	sig, ok := i.signatures[chatID]
+	if !ok {
+		return nil, apperrors.ErrNotFound
+	}
+	size := i.sizes[chatID]
+
+	candidates := make(map[entities.ChatID]struct{})
+	for band := 0; band < i.bands; band++ {
+		for _, id := range i.buckets[i.bandKey(band, sig)] {
+			if id != chatID {
+				candidates[id] = struct{}{}
+			}
+		}
+	}
+	if len(candidates) < k {
+		for id := range i.signatures {
+			if id != chatID {
+				candidates[id] = struct{}{}
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(candidates))
+	for id := range candidates {
+		jaccard := EstimateJaccard(sig, i.signatures[id])
+		if jaccard == 0 {
+			continue
+		}
+
+		otherSize := i.sizes[id]
+		score := jaccard
+		if metric == MetricCosine {
+			score = estimateCosine(jaccard, size, otherSize)
+		}
+		results = append(results, Result{
+			ChatID:      id,
+			Score:       score,
+			SharedUsers: int64(math.Round(estimateIntersection(jaccard, size, otherSize))),
+		})
+	}
+
+	sortResults(results)
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Rescore пересчитывает оценки точно по множествам участников и пересортировывает результаты
+func Rescore(ctx context.Context, members func(context.Context, entities.ChatID) (entities.UserIds, error),
+	chatID entities.ChatID, results []Result, metric Metric) ([]Result, error) {
+	base, err := members(ctx, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	rescored := make([]Result, 0, len(results))
+	for _, result := range results {
+		other, err := members(ctx, result.ChatID)
+		if err != nil {
+			return nil, err
+		}
+		inter, _, _ := intersect(base, other)
+		rescored = append(rescored, Result{
+			ChatID:      result.ChatID,
+			Score:       Score(metric, base, other),
+			SharedUsers: int64(inter),
+		})
+	}
+
+	sortResults(rescored)
+	return rescored, nil
+}
+
+func (i *Index) bandKey(band int, sig Signature) uint64 {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], uint64(band))
+	h.Write(buf[:])
+	for _, v := range sig[band*i.rows : (band+1)*i.rows] {
+		binary.LittleEndian.PutUint64(buf[:], v)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+func sortResults(results []Result) {
+	sort.Slice(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].ChatID < results[b].ChatID
+	})
+}
@@ -0,0 +1,21 @@
+package similarity
+
+import (
+	"context"
+	"fmt"
+
+	"stats-of/internal/jobs"
+	"stats-of/internal/storage"
+)
+
+// NewRefreshJob создаёт задачу, которая перестраивает индекс по текущему графу участия
+func NewRefreshJob(repo *storage.Repository, index *Index) jobs.Job {
+	return jobs.Func("similarity-index", func(ctx context.Context) error {
+		list, err := repo.ChatList(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to load chat list: %w", err)
+		}
+		index.Build(list)
+		return nil
+	})
+}
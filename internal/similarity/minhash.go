@@ -0,0 +1,71 @@
+package similarity
+
+import (
+	"math"
+
+	"stats-of/internal/entities"
+)
+
+// Signature MinHash-сигнатура множества участников чата
+type Signature []uint64
+
+// MinHasher вычисляет MinHash-сигнатуры фиксированной длины.
+// Каждая хеш-функция задаётся своим seed и перемешиванием splitmix64.
+type MinHasher struct {
+	seeds []uint64
+}
+
+func NewMinHasher(numHashes int, seed uint64) *MinHasher {
+	seeds := make([]uint64, numHashes)
+	state := seed
+	for i := range seeds {
+		state += 0x9e3779b97f4a7c15
+		seeds[i] = splitmix64(state)
+	}
+	return &MinHasher{seeds: seeds}
+}
+
+// Size возвращает длину сигнатур, которые строит MinHasher
+func (m *MinHasher) Size() int {
+	return len(m.seeds)
+}
+
+// Signature строит сигнатуру множества пользователей
+func (m *MinHasher) Signature(users entities.UserIds) Signature {
+	sig := make(Signature, len(m.seeds))
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+
+	for _, user := range users {
+		for i, seed := range m.seeds {
+			h := splitmix64(uint64(user) ^ seed)
+			if h < sig[i] {
+				sig[i] = h
+			}
+		}
+	}
+	return sig
+}
+
+// EstimateJaccard оценивает коэффициент Жаккара по доле совпадающих позиций сигнатур
+func EstimateJaccard(a, b Signature) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+
+	var equal int
+	for i := range a {
+		if a[i] == b[i] && a[i] != math.MaxUint64 {
+			equal++
+		}
+	}
+	return float64(equal) / float64(len(a))
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
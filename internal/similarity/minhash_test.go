@@ -0,0 +1,142 @@
+package similarity
+
+import (
+	"math"
+	"testing"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// users возвращает пользователей с номерами [from, to)
+func users(from, to int) entities.UserIds {
+	ids := make(entities.UserIds, 0, to-from)
+	for id := from; id < to; id++ {
+		ids = append(ids, entities.UserID(id))
+	}
+	return ids
+}
+
+// TestEstimateJaccard проверяет, что оценка MinHash укладывается в четыре стандартных ошибки
+// sqrt(J(1-J)/n) от точного коэффициента Жаккара
+func TestEstimateJaccard(t *testing.T) {
+	const numHashes = 256
+	hasher := NewMinHasher(numHashes, hasherSeed)
+
+	tests := []struct {
+		name string
+		a, b entities.UserIds
+		want float64
+	}{
+		{"identical", users(0, 500), users(0, 500), 1},
+		{"disjoint", users(0, 500), users(500, 1000), 0},
+		{"subset", users(0, 1000), users(0, 250), 0.25},
+		{"half overlap", users(0, 600), users(200, 800), 0.5},
+		{"small overlap", users(0, 900), users(800, 1000), 0.1},
+		{"large overlap", users(0, 950), users(50, 1000), 0.9},
+		{"one empty", users(0, 100), nil, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if exact := Jaccard(tt.a, tt.b); math.Abs(exact-tt.want) > 1e-9 {
+				t.Fatalf("exact jaccard = %v, want %v", exact, tt.want)
+			}
+
+			got := EstimateJaccard(hasher.Signature(tt.a), hasher.Signature(tt.b))
+			tolerance := 4 * math.Sqrt(tt.want*(1-tt.want)/numHashes)
+			if math.Abs(got-tt.want) > tolerance {
+				t.Errorf("estimate = %v, want %v ± %v", got, tt.want, tolerance)
+			}
+		})
+	}
+}
+
+// TestEstimateJaccardMismatchedSignatures проверяет, что сигнатуры разной длины несравнимы
+func TestEstimateJaccardMismatchedSignatures(t *testing.T) {
+	a := NewMinHasher(64, hasherSeed).Signature(users(0, 10))
+	b := NewMinHasher(128, hasherSeed).Signature(users(0, 10))
+	if got := EstimateJaccard(a, b); got != 0 {
+		t.Errorf("estimate = %v, want 0", got)
+	}
+}
+
+// TestBandCandidateRecall проверяет долю чатов, попадающих в кандидаты LSH хотя бы по одной полосе.
+// При b полосах по r строк вероятность равна 1-(1-J^r)^b: для 32×4 это около 0.87 при J = 0.5
+// и около 0.0002 при J = 0.05.
+func TestBandCandidateRecall(t *testing.T) {
+	logger.Log = zap.NewNop()
+
+	const (
+		base    = entities.ChatID(0)
+		perCase = 200
+	)
+	tests := []struct {
+		name     string
+		shared   int
+		min, max float64
+	}{
+		{"near duplicates", 80, 0.99, 1},
+		{"half", 50, 0.75, 0.97},
+		{"unrelated", 5, 0, 0.02},
+	}
+
+	// Базовый чат — пользователи 0..99; чат с shared общими пользователями и коэффициентом
+	// Жаккара shared/100 получает сдвинутое окно из shared базовых пользователей
+	list := entities.ChatList{base: members(users(0, 100))}
+	next := entities.ChatID(1)
+	cases := make([][]entities.ChatID, len(tests))
+	for i, tt := range tests {
+		for j := 0; j < perCase; j++ {
+			ids := make(entities.UserIds, 0, tt.shared)
+			for k := 0; k < tt.shared; k++ {
+				ids = append(ids, entities.UserID((j+k)%100))
+			}
+			list[next] = members(ids)
+			cases[i] = append(cases[i], next)
+			next++
+		}
+	}
+
+	index := NewIndex(DefaultNumHashes, DefaultBands)
+	index.Build(list)
+	found := candidates(index, base)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits int
+			for _, id := range cases[i] {
+				if _, ok := found[id]; ok {
+					hits++
+				}
+			}
+			recall := float64(hits) / perCase
+			if recall < tt.min || recall > tt.max {
+				t.Errorf("recall = %v, want between %v and %v", recall, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+// candidates чаты, совпавшие с chatID хотя бы в одной полосе
+func candidates(index *Index, chatID entities.ChatID) map[entities.ChatID]struct{} {
+	sig := index.signatures[chatID]
+	result := make(map[entities.ChatID]struct{})
+	for band := 0; band < index.bands; band++ {
+		for _, id := range index.buckets[index.bandKey(band, sig)] {
+			if id != chatID {
+				result[id] = struct{}{}
+			}
+		}
+	}
+	return result
+}
+
+func members(ids entities.UserIds) []entities.User {
+	result := make([]entities.User, len(ids))
+	for i, id := range ids {
+		result[i] = entities.User{UserID: id}
+	}
+	return result
+}
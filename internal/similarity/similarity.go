@@ -0,0 +1,81 @@
+package similarity
+
+import (
+	"math"
+
+	"stats-of/internal/entities"
+)
+
+// Metric мера схожести аудиторий двух чатов
+type Metric string
+
+const (
+	MetricJaccard Metric = "jaccard"
+	MetricCosine  Metric = "cosine"
+)
+
+// Valid сообщает, поддерживается ли мера
+func (m Metric) Valid() bool {
+	return m == MetricJaccard || m == MetricCosine
+}
+
+// Jaccard точный коэффициент Жаккара |A∩B| / |A∪B|
+func Jaccard(a, b entities.UserIds) float64 {
+	inter, sizeA, sizeB := intersect(a, b)
+	union := sizeA + sizeB - inter
+	if union == 0 {
+		return 0
+	}
+	return float64(inter) / float64(union)
+}
+
+// Cosine точная косинусная мера для бинарных векторов участия |A∩B| / sqrt(|A|·|B|)
+func Cosine(a, b entities.UserIds) float64 {
+	inter, sizeA, sizeB := intersect(a, b)
+	if sizeA == 0 || sizeB == 0 {
+		return 0
+	}
+	return float64(inter) / math.Sqrt(float64(sizeA)*float64(sizeB))
+}
+
+// Score вычисляет выбранную меру для двух множеств пользователей
+func Score(metric Metric, a, b entities.UserIds) float64 {
+	if metric == MetricCosine {
+		return Cosine(a, b)
+	}
+	return Jaccard(a, b)
+}
+
+// estimateIntersection восстанавливает размер пересечения из оценки Жаккара и размеров множеств:
+// J = I / (|A| + |B| - I)  =>  I = J·(|A| + |B|) / (1 + J)
+func estimateIntersection(jaccard float64, sizeA, sizeB int) float64 {
+	return jaccard * float64(sizeA+sizeB) / (1 + jaccard)
+}
+
+// estimateCosine оценивает косинусную меру через оценку пересечения
+func estimateCosine(jaccard float64, sizeA, sizeB int) float64 {
+	if sizeA == 0 || sizeB == 0 {
+		return 0
+	}
+	return estimateIntersection(jaccard, sizeA, sizeB) / math.Sqrt(float64(sizeA)*float64(sizeB))
+}
+
+// intersect возвращает размер пересечения и размеры множеств без учёта повторов
+func intersect(a, b entities.UserIds) (inter, sizeA, sizeB int) {
+	set := make(map[entities.UserID]struct{}, len(a))
+	for _, id := range a {
+		set[id] = struct{}{}
+	}
+
+	seen := make(map[entities.UserID]struct{}, len(b))
+	for _, id := range b {
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		if _, ok := set[id]; ok {
+			inter++
+		}
+	}
+	return inter, len(set), len(seen)
+}
@@ -0,0 +1,139 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+	"sort"
+)
+
+// DefaultRelativeAccuracy относительная точность квантилей по умолчанию (1%)
+const DefaultRelativeAccuracy = 0.01
+
+// DDSketch квантильный скетч с гарантированной относительной ошибкой.
+// Положительные значения раскладываются по логарифмическим корзинам с основанием
+// gamma = (1+α)/(1−α), поэтому любая оценка квантиля отличается от истинного
+// значения не более чем в (1±α) раз. Скетчи с одинаковой точностью сливаются
+// сложением счётчиков корзин, что позволяет считать их по частям.
+type DDSketch struct {
+	alpha     float64
+	gamma     float64
+	logGamma  float64
+	buckets   map[int]uint64
+	zeroCount uint64
+	count     uint64
+	sum       float64
+	min, max  float64
+}
+
+var ErrIncompatible = errors.New("sketches have different relative accuracy")
+
+func NewDDSketch(relativeAccuracy float64) *DDSketch {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		relativeAccuracy = DefaultRelativeAccuracy
+	}
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &DDSketch{
+		alpha:    relativeAccuracy,
+		gamma:    gamma,
+		logGamma: math.Log(gamma),
+		buckets:  make(map[int]uint64),
+		min:      math.Inf(1),
+		max:      math.Inf(-1),
+	}
+}
+
+// Add добавляет неотрицательное значение; отрицательные значения учитываются как ноль
+func (s *DDSketch) Add(value float64) {
+	if value <= 0 {
+		s.zeroCount++
+		value = 0
+	} else {
+		s.buckets[s.index(value)]++
+	}
+
+	s.count++
+	s.sum += value
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+}
+
+// Merge добавляет к скетчу все значения другого скетча
+func (s *DDSketch) Merge(other *DDSketch) error {
+	if other.alpha != s.alpha {
+		return ErrIncompatible
+	}
+	for index, count := range other.buckets {
+		s.buckets[index] += count
+	}
+	s.zeroCount += other.zeroCount
+	s.count += other.count
+	s.sum += other.sum
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	return nil
+}
+
+// Quantile оценивает q-квантиль, q в [0, 1]. Для пустого скетча возвращает 0.
+func (s *DDSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+
+	rank := uint64(q * float64(s.count-1))
+	if rank < s.zeroCount {
+		return 0
+	}
+
+	indexes := make([]int, 0, len(s.buckets))
+	for index := range s.buckets {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	seen := s.zeroCount
+	for _, index := range indexes {
+		seen += s.buckets[index]
+		if seen > rank {
+			return s.clamp(s.value(index))
+		}
+	}
+	return s.max
+}
+
+func (s *DDSketch) Count() uint64 { return s.count }
+func (s *DDSketch) Sum() float64  { return s.sum }
+
+// Min возвращает минимальное значение или 0 для пустого скетча
+func (s *DDSketch) Min() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.min
+}
+
+// Max возвращает максимальное значение или 0 для пустого скетча
+func (s *DDSketch) Max() float64 {
+	if s.count == 0 {
+		return 0
+	}
+	return s.max
+}
+
+func (s *DDSketch) index(value float64) int {
+	return int(math.Ceil(math.Log(value) / s.logGamma))
+}
+
+// value возвращает представителя корзины, равноудалённого в относительных единицах от её границ
+func (s *DDSketch) value(index int) float64 {
+	return 2 * math.Pow(s.gamma, float64(index)) / (1 + s.gamma)
+}
+
+func (s *DDSketch) clamp(value float64) float64 {
+	return math.Max(s.min, math.Min(s.max, value))
+}
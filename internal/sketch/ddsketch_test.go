@@ -0,0 +1,149 @@
+package sketch
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+var testQuantiles = []float64{0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 0.999}
+
+// datasets детерминированные наборы значений с разной формой распределения
+func datasets() map[string][]float64 {
+	rng := rand.New(rand.NewSource(1))
+	sets := map[string][]float64{
+		"uniform":     make([]float64, 10000),
+		"exponential": make([]float64, 10000),
+		"lognormal":   make([]float64, 10000),
+		"with zeros":  make([]float64, 1000),
+	}
+	for i := range sets["uniform"] {
+		sets["uniform"][i] = float64(i + 1)
+		sets["exponential"][i] = rng.ExpFloat64() * 100
+		sets["lognormal"][i] = math.Exp(rng.NormFloat64() * 2)
+	}
+	for i := range sets["with zeros"] {
+		if i%3 != 0 {
+			sets["with zeros"][i] = float64(i)
+		}
+	}
+	return sets
+}
+
+// exactQuantile значение того же ранга, что выбирает скетч
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+// TestDDSketchRelativeError проверяет, что оценка квантиля отличается от точного значения
+// не более чем в (1±α) раз при разной точности и форме распределения
+func TestDDSketchRelativeError(t *testing.T) {
+	for name, values := range datasets() {
+		sorted := append([]float64(nil), values...)
+		sort.Float64s(sorted)
+
+		for _, alpha := range []float64{0.01, 0.02, 0.05} {
+			s := NewDDSketch(alpha)
+			for _, v := range values {
+				s.Add(v)
+			}
+
+			for _, q := range testQuantiles {
+				want := exactQuantile(sorted, q)
+				got := s.Quantile(q)
+				if math.Abs(got-want) > alpha*want+1e-12 {
+					t.Errorf("%s, alpha %v: q%v = %v, want %v within %v%%", name, alpha, q, got, want, alpha*100)
+				}
+			}
+			if s.Quantile(0) != sorted[0] || s.Quantile(1) != sorted[len(sorted)-1] {
+				t.Errorf("%s, alpha %v: extremes %v, %v, want %v, %v",
+					name, alpha, s.Quantile(0), s.Quantile(1), sorted[0], sorted[len(sorted)-1])
+			}
+		}
+	}
+}
+
+// TestDDSketchMerge проверяет, что слияние скетчей частей равно скетчу всех значений
+func TestDDSketchMerge(t *testing.T) {
+	for name, values := range datasets() {
+		whole := NewDDSketch(DefaultRelativeAccuracy)
+		for _, v := range values {
+			whole.Add(v)
+		}
+
+		merged := NewDDSketch(DefaultRelativeAccuracy)
+		for part := 0; part < 4; part++ {
+			s := NewDDSketch(DefaultRelativeAccuracy)
+			for i := part; i < len(values); i += 4 {
+				s.Add(values[i])
+			}
+			if err := merged.Merge(s); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if merged.Count() != whole.Count() || merged.Min() != whole.Min() || merged.Max() != whole.Max() {
+			t.Errorf("%s: merged count/min/max %d/%v/%v, want %d/%v/%v", name,
+				merged.Count(), merged.Min(), merged.Max(), whole.Count(), whole.Min(), whole.Max())
+		}
+		if math.Abs(merged.Sum()-whole.Sum()) > 1e-9*math.Abs(whole.Sum()) {
+			t.Errorf("%s: merged sum %v, want %v", name, merged.Sum(), whole.Sum())
+		}
+		for _, q := range testQuantiles {
+			if got, want := merged.Quantile(q), whole.Quantile(q); got != want {
+				t.Errorf("%s: merged q%v = %v, want %v", name, q, got, want)
+			}
+		}
+	}
+}
+
+// TestDDSketchMergeEmpty проверяет слияние с пустым скетчем в обе стороны
+func TestDDSketchMergeEmpty(t *testing.T) {
+	s := NewDDSketch(DefaultRelativeAccuracy)
+	for _, v := range []float64{3, 1, 2} {
+		s.Add(v)
+	}
+	if err := s.Merge(NewDDSketch(DefaultRelativeAccuracy)); err != nil {
+		t.Fatal(err)
+	}
+	if s.Count() != 3 || s.Min() != 1 || s.Max() != 3 {
+		t.Errorf("after merging empty: count %d, min %v, max %v", s.Count(), s.Min(), s.Max())
+	}
+
+	empty := NewDDSketch(DefaultRelativeAccuracy)
+	if err := empty.Merge(s); err != nil {
+		t.Fatal(err)
+	}
+	if empty.Count() != 3 || empty.Min() != 1 || empty.Max() != 3 {
+		t.Errorf("merged into empty: count %d, min %v, max %v", empty.Count(), empty.Min(), empty.Max())
+	}
+}
+
+// TestDDSketchIncompatibleMerge проверяет, что скетчи разной точности не сливаются
+func TestDDSketchIncompatibleMerge(t *testing.T) {
+	s := NewDDSketch(0.01)
+	s.Add(1)
+	if err := s.Merge(NewDDSketch(0.02)); !errors.Is(err, ErrIncompatible) {
+		t.Fatalf("error = %v, want ErrIncompatible", err)
+	}
+	if s.Count() != 1 {
+		t.Errorf("count changed after failed merge: %d", s.Count())
+	}
+}
+
+// TestDDSketchEmptyAndNonPositive проверяет пустой скетч и учёт отрицательных значений как нуля
+func TestDDSketchEmptyAndNonPositive(t *testing.T) {
+	s := NewDDSketch(DefaultRelativeAccuracy)
+	if s.Quantile(0.5) != 0 || s.Min() != 0 || s.Max() != 0 {
+		t.Errorf("empty sketch: q0.5 %v, min %v, max %v, want zeros", s.Quantile(0.5), s.Min(), s.Max())
+	}
+
+	for _, v := range []float64{-5, 0, 10} {
+		s.Add(v)
+	}
+	if s.Min() != 0 || s.Sum() != 10 || s.Quantile(0.5) != 0 {
+		t.Errorf("min %v, sum %v, q0.5 %v; want 0, 10, 0", s.Min(), s.Sum(), s.Quantile(0.5))
+	}
+}
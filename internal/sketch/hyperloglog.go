@@ -0,0 +1,157 @@
+package sketch
+
+import (
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+const (
+	// HyperLogLogPrecision число бит индекса регистра; совпадает с Redis (16384 регистра)
+	HyperLogLogPrecision = 14
+
+	hllRegisters = 1 << HyperLogLogPrecision
+	hllQ         = 64 - HyperLogLogPrecision
+)
+
+// HyperLogLogStandardError относительная стандартная ошибка оценки: 1.04/√m ≈ 0.81%
+var HyperLogLogStandardError = 1.04 / math.Sqrt(hllRegisters)
+
+// HyperLogLog оценивает число различных элементов в фиксированной памяти.
+// Параметры, хеш-функция (MurmurHash64A) и оценщик Эртла повторяют реализацию
+// PFADD/PFCOUNT/PFMERGE в Redis, поэтому оценки совпадают с Redis по точности.
+type HyperLogLog struct {
+	registers [hllRegisters]uint8
+}
+
+func NewHyperLogLog() *HyperLogLog {
+	return &HyperLogLog{}
+}
+
+// Add добавляет элемент и сообщает, изменилось ли состояние скетча
+func (h *HyperLogLog) Add(element []byte) bool {
+	hash := murmurHash64A(element, 0xadc83b19)
+	index := hash & (hllRegisters - 1)
+	// Длина серии нулей в оставшихся битах; старший бит-ограничитель гарантирует значение не больше q+1
+	rank := uint8(bits.TrailingZeros64(hash>>HyperLogLogPrecision|1<<hllQ)) + 1
+
+	if rank > h.registers[index] {
+		h.registers[index] = rank
+		return true
+	}
+	return false
+}
+
+// Merge объединяет скетч с другим: итог оценивает мощность объединения множеств
+func (h *HyperLogLog) Merge(other *HyperLogLog) {
+	for i, v := range other.registers {
+		if v > h.registers[i] {
+			h.registers[i] = v
+		}
+	}
+}
+
+// Clone возвращает независимую копию скетча
+func (h *HyperLogLog) Clone() *HyperLogLog {
+	clone := *h
+	return &clone
+}
+
+// Count оценивает число различных добавленных элементов
+func (h *HyperLogLog) Count() uint64 {
+	var histogram [hllQ + 2]int
+	for _, v := range h.registers {
+		histogram[v]++
+	}
+
+	m := float64(hllRegisters)
+	z := m * hllTau((m-float64(histogram[hllQ+1]))/m)
+	for k := hllQ; k >= 1; k-- {
+		z += float64(histogram[k])
+		z *= 0.5
+	}
+	z += m * hllSigma(float64(histogram[0])/m)
+
+	alphaInf := 0.5 / math.Ln2
+	return uint64(math.Round(alphaInf * m * m / z))
+}
+
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if prev == z {
+			return z
+		}
+	}
+}
+
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if prev == z {
+			return z / 3
+		}
+	}
+}
+
+// murmurHash64A вариант MurmurHash2 для 64-битных платформ, который использует Redis
+func murmurHash64A(data []byte, seed uint64) uint64 {
+	const (
+		m = 0xc6a4a7935bd1e995
+		r = 47
+	)
+
+	h := seed ^ uint64(len(data))*m
+	for len(data) >= 8 {
+		k := binary.LittleEndian.Uint64(data)
+		k *= m
+		k ^= k >> r
+		k *= m
+		h ^= k
+		h *= m
+		data = data[8:]
+	}
+
+	switch len(data) {
+	case 7:
+		h ^= uint64(data[6]) << 48
+		fallthrough
+	case 6:
+		h ^= uint64(data[5]) << 40
+		fallthrough
+	case 5:
+		h ^= uint64(data[4]) << 32
+		fallthrough
+	case 4:
+		h ^= uint64(data[3]) << 24
+		fallthrough
+	case 3:
+		h ^= uint64(data[2]) << 16
+		fallthrough
+	case 2:
+		h ^= uint64(data[1]) << 8
+		fallthrough
+	case 1:
+		h ^= uint64(data[0])
+		h *= m
+	}
+
+	h ^= h >> r
+	h *= m
+	h ^= h >> r
+	return h
+}
@@ -0,0 +1,81 @@
+package sketch
+
+import (
+	"math"
+	"strconv"
+	"testing"
+)
+
+// addRange добавляет в скетч элементы с номерами [from, to)
+func addRange(h *HyperLogLog, from, to int) {
+	for i := from; i < to; i++ {
+		h.Add([]byte("user:" + strconv.Itoa(i)))
+	}
+}
+
+// checkEstimate сравнивает оценку с точной мощностью: допуск — четыре стандартных ошибки,
+// но не меньше одного элемента, чтобы малые мощности сравнивались почти точно
+func checkEstimate(t *testing.T, got uint64, want int) {
+	t.Helper()
+	tolerance := math.Max(4*HyperLogLogStandardError*float64(want), 1)
+	if math.Abs(float64(got)-float64(want)) > tolerance {
+		t.Errorf("count = %d, want %d ± %.0f", got, want, tolerance)
+	}
+}
+
+// TestHyperLogLogStandardError проверяет, что оценка укладывается в четыре стандартных ошибки
+// на мощностях от пустого множества до миллиона элементов
+func TestHyperLogLogStandardError(t *testing.T) {
+	for _, n := range []int{0, 1, 10, 100, 1000, 10000, 100000, 1000000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			h := NewHyperLogLog()
+			addRange(h, 0, n)
+			checkEstimate(t, h.Count(), n)
+		})
+	}
+}
+
+// TestHyperLogLogDuplicates проверяет, что повторы не увеличивают оценку
+// и Add сообщает об изменении состояния только для новых элементов
+func TestHyperLogLogDuplicates(t *testing.T) {
+	h := NewHyperLogLog()
+	if !h.Add([]byte("user:1")) {
+		t.Error("first add did not change the sketch")
+	}
+	if h.Add([]byte("user:1")) {
+		t.Error("repeated add changed the sketch")
+	}
+
+	addRange(h, 0, 5000)
+	before := h.Count()
+	addRange(h, 0, 5000)
+	if got := h.Count(); got != before {
+		t.Errorf("count after repeats = %d, want %d", got, before)
+	}
+}
+
+// TestHyperLogLogMerge проверяет, что слияние оценивает мощность объединения пересекающихся
+// множеств и совпадает со скетчем, в который добавлены все элементы
+func TestHyperLogLogMerge(t *testing.T) {
+	a, b, whole := NewHyperLogLog(), NewHyperLogLog(), NewHyperLogLog()
+	addRange(a, 0, 60000)
+	addRange(b, 40000, 100000)
+	addRange(whole, 0, 100000)
+
+	a.Merge(b)
+	if a.Count() != whole.Count() {
+		t.Errorf("merged count = %d, want %d as for the whole set", a.Count(), whole.Count())
+	}
+	checkEstimate(t, a.Count(), 100000)
+}
+
+// TestHyperLogLogClone проверяет, что копия не зависит от исходного скетча
+func TestHyperLogLogClone(t *testing.T) {
+	h := NewHyperLogLog()
+	addRange(h, 0, 1000)
+	clone := h.Clone()
+	addRange(clone, 1000, 2000)
+
+	checkEstimate(t, h.Count(), 1000)
+	checkEstimate(t, clone.Count(), 2000)
+}
@@ -0,0 +1,144 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"stats-of/internal/entities"
+)
+
+// formatVersion версия бинарного формата снимка
+const formatVersion = 1
+
+var errCorrupted = errors.New("snapshot is corrupted")
+
+type (
+	// ChatState состояние чата в снимке; Members упорядочены по возрастанию
+	ChatState struct {
+		ChatType     uint
+		CountOfUsers int64
+		Members      []entities.UserID
+	}
+
+	// Snapshot состав и счётчики всех чатов на момент Time
+	Snapshot struct {
+		Time  time.Time
+		Chats map[entities.ChatID]*ChatState
+	}
+)
+
+// encode сериализует снимок компактно: чаты и участники упорядочены, идентификаторы
+// записываются разностями с предыдущим в varint, результат сжимается deflate.
+//
+//	version | time | chats | { Δchat_id | chat_type | count_of_users | members | { Δuser_id } }
+func encode(s *Snapshot) ([]byte, error) {
+	ids := make([]entities.ChatID, 0, len(s.Chats))
+	for id := range s.Chats {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var raw bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+	putUvarint := func(v uint64) { raw.Write(buf[:binary.PutUvarint(buf, v)]) }
+	putVarint := func(v int64) { raw.Write(buf[:binary.PutVarint(buf, v)]) }
+
+	raw.WriteByte(formatVersion)
+	putVarint(s.Time.Unix())
+	putUvarint(uint64(len(ids)))
+
+	var prevChat int64
+	for _, id := range ids {
+		chat := s.Chats[id]
+		putVarint(int64(id) - prevChat)
+		prevChat = int64(id)
+		putUvarint(uint64(chat.ChatType))
+		putVarint(chat.CountOfUsers)
+		putUvarint(uint64(len(chat.Members)))
+
+		var prevUser int64
+		for _, userID := range chat.Members {
+			putVarint(int64(userID) - prevUser)
+			prevUser = int64(userID)
+		}
+	}
+
+	var compressed bytes.Buffer
+	w, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(raw.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return compressed.Bytes(), nil
+}
+
+func decode(data []byte) (*Snapshot, error) {
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress snapshot: %w", err)
+	}
+	r := bytes.NewReader(raw)
+
+	version, err := r.ReadByte()
+	if err != nil {
+		return nil, errCorrupted
+	}
+	if version != formatVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d", version)
+	}
+
+	ts, err := binary.ReadVarint(r)
+	if err != nil {
+		return nil, errCorrupted
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil || count > uint64(len(raw)) {
+		return nil, errCorrupted
+	}
+
+	s := &Snapshot{Time: time.Unix(ts, 0).UTC(), Chats: make(map[entities.ChatID]*ChatState, count)}
+	var chatID int64
+	for i := uint64(0); i < count; i++ {
+		delta, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, errCorrupted
+		}
+		chatID += delta
+		chatType, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, errCorrupted
+		}
+		countOfUsers, err := binary.ReadVarint(r)
+		if err != nil {
+			return nil, errCorrupted
+		}
+		members, err := binary.ReadUvarint(r)
+		if err != nil || members > uint64(r.Len()) {
+			return nil, errCorrupted
+		}
+
+		chat := &ChatState{ChatType: uint(chatType), CountOfUsers: countOfUsers, Members: make([]entities.UserID, members)}
+		var userID int64
+		for j := range chat.Members {
+			delta, err := binary.ReadVarint(r)
+			if err != nil {
+				return nil, errCorrupted
+			}
+			userID += delta
+			chat.Members[j] = entities.UserID(userID)
+		}
+		s.Chats[entities.ChatID(chatID)] = chat
+	}
+	return s, nil
+}
@@ -0,0 +1,176 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/flate"
+	"math"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+)
+
+func userIDs(ids ...int64) []entities.UserID {
+	result := make([]entities.UserID, len(ids))
+	for i, id := range ids {
+		result[i] = entities.UserID(id)
+	}
+	return result
+}
+
+// checkEqual сравнивает снимки; пустой и отсутствующий список участников считаются равными
+func checkEqual(t *testing.T, got, want *Snapshot) {
+	t.Helper()
+	if !got.Time.Equal(want.Time) {
+		t.Errorf("time = %v, want %v", got.Time, want.Time)
+	}
+	if len(got.Chats) != len(want.Chats) {
+		t.Fatalf("got %d chats, want %d", len(got.Chats), len(want.Chats))
+	}
+	for id, w := range want.Chats {
+		g, ok := got.Chats[id]
+		if !ok {
+			t.Errorf("chat %d is missing", id)
+			continue
+		}
+		if g.ChatType != w.ChatType || g.CountOfUsers != w.CountOfUsers || len(g.Members) != len(w.Members) {
+			t.Errorf("chat %d = %+v, want %+v", id, g, w)
+			continue
+		}
+		for i := range w.Members {
+			if g.Members[i] != w.Members[i] {
+				t.Errorf("chat %d members = %v, want %v", id, g.Members, w.Members)
+				break
+			}
+		}
+	}
+}
+
+// TestCodecRoundTrip проверяет, что снимок после кодирования и декодирования не меняется,
+// в том числе с отрицательными и крайними идентификаторами и пустыми составами
+func TestCodecRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 1, 12, 30, 0, 0, time.UTC)
+	many := make([]entities.UserID, 5000)
+	for i := range many {
+		many[i] = entities.UserID(int64(i)*7 - 10000)
+	}
+
+	tests := []struct {
+		name  string
+		chats map[entities.ChatID]*ChatState
+	}{
+		{"no chats", map[entities.ChatID]*ChatState{}},
+		{"empty members", map[entities.ChatID]*ChatState{
+			1: {ChatType: 1, CountOfUsers: 0, Members: nil},
+			2: {ChatType: 2, CountOfUsers: 0, Members: []entities.UserID{}},
+		}},
+		{"negative ids", map[entities.ChatID]*ChatState{
+			-1001234567890: {ChatType: 3, CountOfUsers: 3, Members: userIDs(-500, -20, 7)},
+			-42:            {ChatType: 1, CountOfUsers: 1, Members: userIDs(-1)},
+			15:             {ChatType: 2, CountOfUsers: 2, Members: userIDs(-3, 3)},
+		}},
+		{"extreme ids", map[entities.ChatID]*ChatState{
+			math.MinInt64: {CountOfUsers: 2, Members: userIDs(math.MinInt64, math.MaxInt64)},
+			math.MaxInt64: {CountOfUsers: 3, Members: userIDs(math.MinInt64, 0, math.MaxInt64)},
+		}},
+		{"counter differs from members", map[entities.ChatID]*ChatState{
+			10: {ChatType: 1, CountOfUsers: -1, Members: userIDs(1, 2)},
+			11: {ChatType: 1, CountOfUsers: 1 << 40, Members: nil},
+		}},
+		{"large chat", map[entities.ChatID]*ChatState{
+			-100: {ChatType: 4, CountOfUsers: int64(len(many)), Members: many},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := &Snapshot{Time: at, Chats: tt.chats}
+			data, err := encode(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decode(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkEqual(t, got, want)
+
+			again, err := encode(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(again, data) {
+				t.Error("re-encoding the decoded snapshot produced different bytes")
+			}
+		})
+	}
+}
+
+// TestCodecTruncatesTime проверяет, что время снимка хранится с точностью до секунды в UTC
+func TestCodecTruncatesTime(t *testing.T) {
+	at := time.Date(2026, 10, 1, 15, 30, 45, 999, time.FixedZone("MSK", 3*3600))
+	data, err := encode(&Snapshot{Time: at, Chats: map[entities.ChatID]*ChatState{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := at.Truncate(time.Second).UTC(); got.Time != want {
+		t.Errorf("time = %v, want %v", got.Time, want)
+	}
+}
+
+// TestDecodeCorrupted проверяет, что повреждённые данные не декодируются
+func TestDecodeCorrupted(t *testing.T) {
+	valid, err := encode(&Snapshot{Time: time.Unix(1700000000, 0), Chats: map[entities.ChatID]*ChatState{
+		-5: {ChatType: 1, CountOfUsers: 3, Members: userIDs(1, 2, 3)},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw := inflate(t, valid)
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not deflate", []byte("not a snapshot")},
+		{"empty", deflate(t, nil)},
+		{"unknown version", deflate(t, append([]byte{formatVersion + 1}, raw[1:]...))},
+		{"truncated", deflate(t, raw[:len(raw)-1])},
+		{"huge chat count", deflate(t, []byte{formatVersion, 0, 0xff, 0xff, 0xff, 0xff, 0x0f})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s, err := decode(tt.data); err == nil {
+				t.Fatalf("decoded %+v, want error", s)
+			}
+		})
+	}
+}
+
+func deflate(t *testing.T, raw []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func inflate(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(flate.NewReader(bytes.NewReader(data))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,123 @@
+package snapshot
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 1000
+	maxOffset    = 1 << 30
+)
+
+// MakeListHandler обработчик GET /api/v1/snapshots?limit=100&cursor=...: сохранённые снимки,
+// новые первыми; вместо cursor можно передать offset
+func MakeListHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const scope = "snapshots"
+		offset, err := cursors.Offset(r, scope, maxOffset)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		limit, err := utils.QueryInt(r, "limit", defaultLimit, 1, maxLimit)
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		list, total, err := service.List(r.Context(), offset, limit)
+		if err != nil {
+			logger.Log.Error("Failed to list snapshots", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, pagination.NewOffsetPage(cursors, scope, list, offset, limit, total))
+	}
+}
+
+// MakeDiffHandler обработчик GET /api/v1/snapshots/diff?from=2026-10-12T00:00:00Z&to=2026-10-16T00:00:00Z&limit=100&cursor=...:
+// сводка изменений между ближайшими к from и to снимками и страница изменившихся чатов
+func MakeDiffHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		diff(w, r, service, cursors, nil)
+	}
+}
+
+// MakeChatDiffHandler обработчик GET /api/v1/chats/{id}/diff?from=&to=: вступившие и вышедшие
+// пользователи и изменение счётчика одного чата
+func MakeChatDiffHandler(service *Service, cursors *pagination.Signer) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		chatID := entities.ChatID(id)
+		diff(w, r, service, cursors, &chatID)
+	}
+}
+
+// diff отвечает сравнением снимков. Курсор страницы привязан к паре найденных снимков: если без to
+// между страницами появился новый снимок, курсор отклоняется, и продолжать нужно с to из первой страницы.
+func diff(w http.ResponseWriter, r *http.Request, service *Service, cursors *pagination.Signer, chatID *entities.ChatID) {
+	limit, err := utils.QueryInt(r, "limit", defaultLimit, 1, maxLimit)
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	to, err := utils.QueryTime(r, "to", time.Now().UTC())
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	if r.URL.Query().Get("from") == "" {
+		utils.RespondWith400(w, "from is required")
+		return
+	}
+	from, err := utils.QueryTime(r, "from", time.Time{})
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	if !from.Before(to) {
+		utils.RespondWith400(w, "from must be before to")
+		return
+	}
+
+	result, err := service.Diff(r.Context(), from, to, chatID)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrNotFound) {
+			utils.RespondWith404(w)
+			return
+		}
+		logger.Log.Error("Failed to diff snapshots", zap.Error(err))
+		utils.RespondWith500(w)
+		return
+	}
+
+	scope := fmt.Sprintf("snapshots_diff:%d:%d", result.From.Unix(), result.To.Unix())
+	if chatID != nil {
+		scope += fmt.Sprintf(":%d", *chatID)
+	}
+	offset, err := cursors.Offset(r, scope, maxOffset)
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	chats := result.Items
+	result.Page = pagination.NewOffsetPage(cursors, scope, chats[min(offset, len(chats)):min(offset+limit, len(chats))],
+		offset, limit, int64(len(chats)))
+	utils.SuccessRespondWith200(w, result)
+}
@@ -0,0 +1,118 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// base время первого снимка в тестах; снимки идут с шагом в час
+var base = time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+
+// newTestService сохраняет снимки в base, base+1h, … и возвращает сервис над ними. В i-м снимке
+// у каждого из чатов 1–chats счётчик counts[i] и единственный участник с тем же идентификатором.
+func newTestService(t *testing.T, counts []int64, chats int) *Service {
+	t.Helper()
+	repo := memtest.NewRepository(t)
+	service := NewService(repo, 24*time.Hour)
+	for i, count := range counts {
+		snapshot := &Snapshot{Time: base.Add(time.Duration(i) * time.Hour), Chats: make(map[entities.ChatID]*ChatState)}
+		for id := 1; id <= chats; id++ {
+			snapshot.Chats[entities.ChatID(id)] = &ChatState{CountOfUsers: count, Members: userIDs(count)}
+		}
+		data, err := encode(snapshot)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts := strconv.FormatInt(snapshot.Time.Unix(), 10)
+		ctx := context.Background()
+		if err := repo.Storage().SetValue(ctx, keyPrefix+ts, string(data), 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.Storage().SortedSetAdd(ctx, indexKey, ts, float64(snapshot.Time.Unix())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return service
+}
+
+// get выполняет запрос и разбирает ответ 200 в out
+func get(t *testing.T, handler http.HandlerFunc, target string, out any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET %s: status %d: %s", target, rec.Code, rec.Body)
+	}
+	if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestListPages проверяет, что список снимков отдаётся страницами, новые первыми
+func TestListPages(t *testing.T) {
+	cursors := pagination.NewSigner("secret")
+	handler := MakeListHandler(newTestService(t, []int64{1, 2, 3}, 1), cursors)
+
+	var first pagination.Page[Info]
+	get(t, handler, "/api/v1/snapshots?limit=2", &first)
+	if len(first.Items) != 2 || !first.Items[0].Time.Equal(base.Add(2*time.Hour)) || !first.Items[1].Time.Equal(base.Add(time.Hour)) {
+		t.Errorf("first page = %+v, want the two newest snapshots", first.Items)
+	}
+	if first.Total == nil || *first.Total != 3 || first.Next == "" {
+		t.Fatalf("first page total %v, next %q; want 3 and a cursor", first.Total, first.Next)
+	}
+
+	var second pagination.Page[Info]
+	get(t, handler, "/api/v1/snapshots?limit=2&cursor="+first.Next, &second)
+	if len(second.Items) != 1 || !second.Items[0].Time.Equal(base) || second.Next != "" {
+		t.Errorf("second page = %+v, next %q; want the oldest snapshot and no cursor", second.Items, second.Next)
+	}
+}
+
+// TestDiffPages проверяет, что сводка диффа охватывает все чаты, а список изменений отдаётся страницами,
+// и что курсор не принимается для другой пары снимков
+func TestDiffPages(t *testing.T) {
+	cursors := pagination.NewSigner("secret")
+	handler := MakeDiffHandler(newTestService(t, []int64{1, 2, 3}, 3), cursors)
+	query := url.Values{"from": {base.Format(time.RFC3339)}, "to": {base.Add(time.Hour).Format(time.RFC3339)}, "limit": {"2"}}
+
+	var ids []entities.ChatID
+	for page := 0; ; page++ {
+		var diff Diff
+		get(t, handler, "/api/v1/snapshots/diff?"+query.Encode(), &diff)
+		if diff.ChangedChats != 3 || diff.UsersDelta != 3 || diff.Total == nil || *diff.Total != 3 {
+			t.Errorf("page %d: changed %d, users delta %d, total %v; want 3, 3, 3", page, diff.ChangedChats, diff.UsersDelta, diff.Total)
+		}
+		for _, chat := range diff.Items {
+			ids = append(ids, chat.ChatID)
+		}
+		if diff.Next == "" {
+			break
+		}
+		query.Set("cursor", diff.Next)
+	}
+	if len(ids) != 3 || ids[0] != 1 || ids[1] != 2 || ids[2] != 3 {
+		t.Errorf("paged chats = %v, want [1 2 3]", ids)
+	}
+
+	var first Diff
+	query.Del("cursor")
+	get(t, handler, "/api/v1/snapshots/diff?"+query.Encode(), &first)
+	query.Set("cursor", first.Next)
+	query.Set("to", base.Add(2*time.Hour).Format(time.RFC3339))
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/api/v1/snapshots/diff?"+query.Encode(), nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("cursor of another snapshot pair: status %d, want 400", rec.Code)
+	}
+}
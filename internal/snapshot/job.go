@@ -0,0 +1,15 @@
+package snapshot
+
+import (
+	"context"
+
+	"stats-of/internal/jobs"
+)
+
+// NewSnapshotJob создаёт задачу периодического снятия снимков
+func NewSnapshotJob(service *Service) jobs.Job {
+	return jobs.Func("snapshot", func(ctx context.Context) error {
+		_, err := service.Take(ctx)
+		return err
+	})
+}
@@ -0,0 +1,262 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/pagination"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Схема ключей:
+//
+//	snapshots        отсортированное множество времён снимков (unix), score — то же время
+//	snapshot:{unix}  строка со сжатым бинарным снимком
+const (
+	indexKey  = "snapshots"
+	keyPrefix = "snapshot:"
+)
+
+// ChatStatus изменение чата между снимками
+type ChatStatus string
+
+const (
+	ChatAdded   ChatStatus = "added"
+	ChatRemoved ChatStatus = "removed"
+	ChatChanged ChatStatus = "changed"
+)
+
+type (
+	// Info сведения о сохранённом снимке
+	Info struct {
+		Time time.Time `json:"time"`
+	}
+
+	// ChatDiff изменения одного чата; списки пользователей заполняются только в диффе чата
+	ChatDiff struct {
+		ChatID      entities.ChatID   `json:"chat_id"`
+		Status      ChatStatus        `json:"status"`
+		UsersBefore int64             `json:"users_before"`
+		UsersAfter  int64             `json:"users_after"`
+		Delta       int64             `json:"delta"`
+		JoinedCount int               `json:"joined_count"`
+		LeftCount   int               `json:"left_count"`
+		Joined      []entities.UserID `json:"joined,omitempty"`
+		Left        []entities.UserID `json:"left,omitempty"`
+	}
+
+	// Diff изменения между двумя снимками: сводка по всем изменившимся чатам
+	// и страница их изменений по возрастанию идентификатора
+	Diff struct {
+		From         time.Time `json:"from"`
+		To           time.Time `json:"to"`
+		AddedChats   int       `json:"added_chats"`
+		RemovedChats int       `json:"removed_chats"`
+		ChangedChats int       `json:"changed_chats"`
+		UsersDelta   int64     `json:"users_delta"`
+		pagination.Page[ChatDiff]
+	}
+
+	// Service сохраняет снимки состава и счётчиков чатов и сравнивает их между собой
+	Service struct {
+		repo      *storage.Repository
+		retention time.Duration
+	}
+)
+
+func NewService(repo *storage.Repository, retention time.Duration) *Service {
+	return &Service{repo: repo, retention: retention}
+}
+
+// Take сохраняет снимок текущего состава всех чатов и удаляет снимки старше срока хранения.
+// Обходит все чаты, поэтому вызывается только задачей планировщика, а не из запроса.
+func (s *Service) Take(ctx context.Context) (*Info, error) {
+	now := time.Now().UTC().Truncate(time.Second)
+	snapshot := &Snapshot{Time: now, Chats: make(map[entities.ChatID]*ChatState)}
+
+	chatIDs, err := s.repo.ChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, chatID := range chatIDs {
+		chat, err := s.repo.Chat(ctx, chatID)
+		if errors.Is(err, apperrors.ErrNotFound) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		members, err := s.repo.ChatUserIDs(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i] < members[j] })
+		snapshot.Chats[chatID] = &ChatState{ChatType: chat.ChatType, CountOfUsers: chat.CountOfUsers, Members: members}
+	}
+
+	data, err := encode(snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	ts := strconv.FormatInt(now.Unix(), 10)
+	store := s.repo.Storage()
+	if err := store.SetValue(ctx, keyPrefix+ts, string(data), s.retention); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	if err := store.SortedSetAdd(ctx, indexKey, ts, float64(now.Unix())); err != nil {
+		return nil, fmt.Errorf("failed to index snapshot: %w", err)
+	}
+	// Сами снимки истекают по TTL, из индекса устаревшие записи удаляются явно
+	cutoff := float64(now.Add(-s.retention).Unix())
+	if err := store.SortedSetRemoveRangeByScore(ctx, indexKey, math.Inf(-1), cutoff); err != nil {
+		return nil, fmt.Errorf("failed to trim snapshot index: %w", err)
+	}
+
+	logger.Log.Info("Snapshot taken", zap.Time("time", now), zap.Int("chats", len(snapshot.Chats)), zap.Int("bytes", len(data)))
+	return &Info{Time: now}, nil
+}
+
+// List возвращает страницу [offset, offset+limit) сохранённых снимков, новые первыми,
+// и общее число снимков
+func (s *Service) List(ctx context.Context, offset, limit int) ([]Info, int64, error) {
+	store := s.repo.Storage()
+	total, err := store.SortedSetCard(ctx, indexKey)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+	members, err := store.SortedSetRevRange(ctx, indexKey, int64(offset), int64(offset+limit-1))
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	result := make([]Info, 0, len(members))
+	for _, m := range members {
+		result = append(result, Info{Time: time.Unix(int64(m.Score), 0).UTC()})
+	}
+	return result, total, nil
+}
+
+// At загружает последний снимок, сделанный не позже at, или возвращает ErrNotFound
+func (s *Service) At(ctx context.Context, at time.Time) (*Snapshot, error) {
+	members, err := s.repo.Storage().SortedSetRevRangeByScore(ctx, indexKey, math.Inf(-1), float64(at.Unix()), 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find snapshot: %w", err)
+	}
+	if len(members) == 0 {
+		return nil, apperrors.ErrNotFound
+	}
+
+	raw, err := s.repo.Storage().FindKeyByGetRequest(keyPrefix + members[0].Member)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load snapshot %s: %w", members[0].Member, err)
+	}
+	if raw == "" {
+		// Снимок истёк, а индекс ещё не подрезан
+		return nil, apperrors.ErrNotFound
+	}
+	return decode([]byte(raw))
+}
+
+// Diff сравнивает снимки, ближайшие к from и to (не позже каждого из моментов).
+// Без chatID возвращает сводку по всем изменившимся чатам, с chatID — подробный дифф одного чата.
+// Items содержит изменения всех чатов; страницу из них выбирает обработчик.
+func (s *Service) Diff(ctx context.Context, from, to time.Time, chatID *entities.ChatID) (*Diff, error) {
+	before, err := s.At(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	after, err := s.At(ctx, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &Diff{From: before.Time, To: after.Time}
+	ids := make(map[entities.ChatID]struct{})
+	if chatID != nil {
+		ids[*chatID] = struct{}{}
+	} else {
+		for id := range before.Chats {
+			ids[id] = struct{}{}
+		}
+		for id := range after.Chats {
+			ids[id] = struct{}{}
+		}
+	}
+
+	for id := range ids {
+		d, ok := compareChat(id, before.Chats[id], after.Chats[id], chatID != nil)
+		if !ok {
+			continue
+		}
+		switch d.Status {
+		case ChatAdded:
+			diff.AddedChats++
+		case ChatRemoved:
+			diff.RemovedChats++
+		case ChatChanged:
+			diff.ChangedChats++
+		}
+		diff.UsersDelta += d.Delta
+		diff.Items = append(diff.Items, d)
+	}
+	sort.Slice(diff.Items, func(i, j int) bool { return diff.Items[i].ChatID < diff.Items[j].ChatID })
+	return diff, nil
+}
+
+// compareChat сравнивает состояния чата; ok = false, если чат не изменился или отсутствует в обоих снимках
+func compareChat(id entities.ChatID, before, after *ChatState, details bool) (ChatDiff, bool) {
+	d := ChatDiff{ChatID: id, Status: ChatChanged}
+	switch {
+	case before == nil && after == nil:
+		return d, false
+	case before == nil:
+		d.Status = ChatAdded
+		before = &ChatState{}
+	case after == nil:
+		d.Status = ChatRemoved
+		after = &ChatState{}
+	}
+
+	d.UsersBefore, d.UsersAfter = before.CountOfUsers, after.CountOfUsers
+	d.Delta = after.CountOfUsers - before.CountOfUsers
+	joined, left := mergeDiff(before.Members, after.Members)
+	d.JoinedCount, d.LeftCount = len(joined), len(left)
+	if details {
+		d.Joined, d.Left = joined, left
+	}
+
+	if d.Status == ChatChanged && d.Delta == 0 && len(joined) == 0 && len(left) == 0 {
+		return d, false
+	}
+	return d, true
+}
+
+// mergeDiff находит добавленные и удалённые элементы двух упорядоченных списков за один проход
+func mergeDiff(before, after []entities.UserID) (added, removed []entities.UserID) {
+	added, removed = make([]entities.UserID, 0), make([]entities.UserID, 0)
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case j == len(after) || i < len(before) && before[i] < after[j]:
+			removed = append(removed, before[i])
+			i++
+		case i == len(before) || after[j] < before[i]:
+			added = append(added, after[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return added, removed
+}
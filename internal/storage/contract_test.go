@@ -0,0 +1,371 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory"
+	"stats-of/internal/storage/redis"
+
+	"go.uber.org/zap"
+)
+
+// TestStorageContract проверяет, что оба хранилища одинаково выполняют контракт storage.Storage.
+// Redis проверяется, только если задан REDIS_ADDR и сервер отвечает.
+func TestStorageContract(t *testing.T) {
+	logger.Log = zap.NewNop()
+	prefix := "contract:" + strconv.FormatInt(time.Now().UnixNano(), 36) + ":"
+
+	t.Run("memory", func(t *testing.T) {
+		testContract(t, memory.NewStorage(), prefix)
+	})
+	t.Run("redis", func(t *testing.T) {
+		addr := os.Getenv("REDIS_ADDR")
+		if addr == "" {
+			t.Skip("REDIS_ADDR is not set")
+		}
+		db, _ := strconv.Atoi(os.Getenv("REDIS_DB"))
+		store := redis.NewRedisClient(&redis.Options{Addr: addr, Password: os.Getenv("REDIS_PASSWORD"), DB: db})
+		if err := store.Ping(context.Background()); err != nil {
+			t.Skipf("redis is unavailable: %v", err)
+		}
+		testContract(t, store, prefix)
+	})
+}
+
+func testContract(t *testing.T, store storage.Storage, prefix string) {
+	ctx := context.Background()
+	t.Cleanup(func() {
+		keys, err := store.FindKeysByPattern(prefix + "*")
+		if err == nil {
+			_ = store.Delete(ctx, keys...)
+		}
+	})
+	key := func(name string) string { return prefix + name }
+
+	t.Run("hash", func(t *testing.T) {
+		h := key("hash")
+		must(t, store.HashSet(ctx, h, map[string]string{"a": "1", "b": "2"}))
+		if got, err := store.HashGet(ctx, h, "a"); err != nil || got != "1" {
+			t.Errorf("HashGet = %q, %v; want 1", got, err)
+		}
+		if got, err := store.HashGet(ctx, h, "missing"); err != nil || got != "" {
+			t.Errorf("HashGet of a missing field = %q, %v; want empty", got, err)
+		}
+		if got, err := store.HashIncrBy(ctx, h, "a", 5); err != nil || got != 6 {
+			t.Errorf("HashIncrBy = %d, %v; want 6", got, err)
+		}
+		if got, err := store.HashIncrByFloat(ctx, h, "f", 1.5); err != nil || got != 1.5 {
+			t.Errorf("HashIncrByFloat = %v, %v; want 1.5", got, err)
+		}
+		must(t, store.HashDelete(ctx, h, "b"))
+		if got, err := store.HashGetAll(ctx, h); err != nil || !reflect.DeepEqual(got, map[string]string{"a": "6", "f": "1.5"}) {
+			t.Errorf("HashGetAll = %v, %v", got, err)
+		}
+
+		many, err := store.HashGetAllMany(ctx, h, key("no-hash"))
+		if err != nil || len(many) != 2 || len(many[0]) != 2 || len(many[1]) != 0 {
+			t.Errorf("HashGetAllMany = %v, %v; want the hash and an empty map", many, err)
+		}
+	})
+
+	t.Run("hash max and min", func(t *testing.T) {
+		h := key("extremes")
+		steps := []struct {
+			max   bool
+			value float64
+			want  bool
+		}{
+			{true, 10, true}, {true, 5, false}, {true, 15, true},
+			{false, 10, true}, {false, 12, false}, {false, 3, true},
+		}
+		for _, step := range steps {
+			field, set := "min", store.HashSetMin
+			if step.max {
+				field, set = "max", store.HashSetMax
+			}
+			if got, err := set(ctx, h, field, step.value); err != nil || got != step.want {
+				t.Errorf("set %s to %v = %v, %v; want %v", field, step.value, got, err, step.want)
+			}
+		}
+		if got, err := store.HashGetAll(ctx, h); err != nil || !reflect.DeepEqual(got, map[string]string{"max": "15", "min": "3"}) {
+			t.Errorf("HashGetAll = %v, %v", got, err)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		s := key("set")
+		members := make([]string, 100)
+		for i := range members {
+			members[i] = strconv.Itoa(i)
+		}
+		if added, err := store.SetAdd(ctx, s, members...); err != nil || added != 100 {
+			t.Errorf("SetAdd = %d, %v; want 100", added, err)
+		}
+		if added, err := store.SetAdd(ctx, s, "1", "100"); err != nil || added != 1 {
+			t.Errorf("SetAdd of one new member = %d, %v; want 1", added, err)
+		}
+		if removed, err := store.SetRemove(ctx, s, "100", "missing"); err != nil || removed != 1 {
+			t.Errorf("SetRemove = %d, %v; want 1", removed, err)
+		}
+		if ok, err := store.SetIsMember(ctx, s, "42"); err != nil || !ok {
+			t.Errorf("SetIsMember = %v, %v; want true", ok, err)
+		}
+
+		var scanned []string
+		for cursor := uint64(0); ; {
+			page, next, err := store.SetScan(ctx, s, cursor, 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scanned = append(scanned, page...)
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+		if !sameMembers(scanned, members) {
+			t.Errorf("SetScan visited %d members, want all %d once", len(scanned), len(members))
+		}
+
+		many, err := store.SetMembersMany(ctx, s, key("no-set"))
+		if err != nil || len(many) != 2 || !sameMembers(many[0], members) || len(many[1]) != 0 {
+			t.Errorf("SetMembersMany returned %d sets, %v; want the set and an empty one", len(many), err)
+		}
+	})
+
+	t.Run("set link", func(t *testing.T) {
+		link := entities.SetLink{
+			Key: key("chat_users"), Member: "7",
+			ReverseKey: key("user_chats"), ReverseMember: "1",
+			Counters: []entities.HashField{{Key: key("chat"), Field: "count"}},
+		}
+		for i, want := range []bool{true, false} {
+			if changed, err := store.SetLinkAdd(ctx, link); err != nil || changed != want {
+				t.Errorf("SetLinkAdd #%d = %v, %v; want %v", i+1, changed, err, want)
+			}
+		}
+		if ok, err := store.SetIsMember(ctx, link.ReverseKey, link.ReverseMember); err != nil || !ok {
+			t.Errorf("reverse member present = %v, %v; want true", ok, err)
+		}
+		if got, err := store.HashGet(ctx, key("chat"), "count"); err != nil || got != "1" {
+			t.Errorf("counter after add = %q, %v; want 1", got, err)
+		}
+		for i, want := range []bool{true, false} {
+			if changed, err := store.SetLinkRemove(ctx, link); err != nil || changed != want {
+				t.Errorf("SetLinkRemove #%d = %v, %v; want %v", i+1, changed, err, want)
+			}
+		}
+		if got, err := store.HashGet(ctx, key("chat"), "count"); err != nil || got != "0" {
+			t.Errorf("counter after remove = %q, %v; want 0", got, err)
+		}
+	})
+
+	t.Run("sorted set", func(t *testing.T) {
+		z := key("zset")
+		for i := 1; i <= 5; i++ {
+			must(t, store.SortedSetAdd(ctx, z, "m"+strconv.Itoa(i), float64(i*10)))
+		}
+		if got, err := store.SortedSetRevRange(ctx, z, 0, 1); err != nil || !reflect.DeepEqual(scored(got), []string{"m5:50", "m4:40"}) {
+			t.Errorf("SortedSetRevRange = %v, %v", got, err)
+		}
+		if got, err := store.SortedSetRevRange(ctx, z, -2, -1); err != nil || !reflect.DeepEqual(scored(got), []string{"m2:20", "m1:10"}) {
+			t.Errorf("SortedSetRevRange with negative indexes = %v, %v", got, err)
+		}
+		if got, err := store.SortedSetRevRangeByScore(ctx, z, 15, 45, 1, 2); err != nil || !reflect.DeepEqual(scored(got), []string{"m3:30", "m2:20"}) {
+			t.Errorf("SortedSetRevRangeByScore = %v, %v", got, err)
+		}
+		if rank, score, err := store.SortedSetRevRank(ctx, z, "m4"); err != nil || rank != 1 || score != 40 {
+			t.Errorf("SortedSetRevRank = %d, %v, %v; want 1, 40", rank, score, err)
+		}
+		if _, _, err := store.SortedSetRevRank(ctx, z, "missing"); !errors.Is(err, apperrors.ErrNotFound) {
+			t.Errorf("SortedSetRevRank of a missing member: error = %v, want ErrNotFound", err)
+		}
+		if ranks, scores, err := store.SortedSetRevRankMany(ctx, z, "m1", "missing", "m4"); err != nil ||
+			!reflect.DeepEqual(ranks, []int64{4, -1, 1}) || !reflect.DeepEqual(scores, []float64{10, 0, 40}) {
+			t.Errorf("SortedSetRevRankMany = %v, %v, %v; want [4 -1 1], [10 0 40]", ranks, scores, err)
+		}
+		if ranks, _, err := store.SortedSetRevRankMany(ctx, key("no-zset"), "m1"); err != nil || !reflect.DeepEqual(ranks, []int64{-1}) {
+			t.Errorf("SortedSetRevRankMany of a missing key = %v, %v; want [-1]", ranks, err)
+		}
+
+		must(t, store.SortedSetRemoveRangeByScore(ctx, z, 0, 20))
+		must(t, store.SortedSetRemove(ctx, z, "m5"))
+		if n, err := store.SortedSetCard(ctx, z); err != nil || n != 2 {
+			t.Errorf("SortedSetCard = %d, %v; want 2", n, err)
+		}
+		if got, err := store.SortedSetRevRange(ctx, key("no-zset"), 0, -1); err != nil || len(got) != 0 {
+			t.Errorf("SortedSetRevRange of a missing key = %v, %v; want empty", got, err)
+		}
+	})
+
+	t.Run("hyperloglog", func(t *testing.T) {
+		a, b, union := key("hll:a"), key("hll:b"), key("hll:union")
+		for i := 0; i < 1000; i++ {
+			must(t, store.HyperLogLogAdd(ctx, a, strconv.Itoa(i)))
+			must(t, store.HyperLogLogAdd(ctx, b, strconv.Itoa(i+500)))
+		}
+		if n, err := store.HyperLogLogCount(ctx, a); err != nil || !near(n, 1000) {
+			t.Errorf("HyperLogLogCount = %d, %v; want about 1000", n, err)
+		}
+		if n, err := store.HyperLogLogCount(ctx, a, b); err != nil || !near(n, 1500) {
+			t.Errorf("HyperLogLogCount of two keys = %d, %v; want about 1500", n, err)
+		}
+		must(t, store.HyperLogLogMerge(ctx, union, a, b))
+		if n, err := store.HyperLogLogCount(ctx, union); err != nil || !near(n, 1500) {
+			t.Errorf("count after HyperLogLogMerge = %d, %v; want about 1500", n, err)
+		}
+		if n, err := store.HyperLogLogCount(ctx, key("no-hll")); err != nil || n != 0 {
+			t.Errorf("HyperLogLogCount of a missing key = %d, %v; want 0", n, err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		l := key("list")
+		if n, err := store.ListAppend(ctx, l, "a", "b", "c"); err != nil || n != 3 {
+			t.Errorf("ListAppend = %d, %v; want 3", n, err)
+		}
+		if n, err := store.ListAppend(ctx, l, "d"); err != nil || n != 4 {
+			t.Errorf("ListAppend = %d, %v; want 4", n, err)
+		}
+		if got, err := store.ListRange(ctx, l, 1, -2); err != nil || !reflect.DeepEqual(got, []string{"b", "c"}) {
+			t.Errorf("ListRange = %v, %v; want [b c]", got, err)
+		}
+		if n, err := store.ListLength(ctx, key("no-list")); err != nil || n != 0 {
+			t.Errorf("ListLength of a missing key = %d, %v; want 0", n, err)
+		}
+	})
+
+	t.Run("keys", func(t *testing.T) {
+		must(t, store.SetValue(ctx, key("keys:value"), "v", 0))
+		if got, err := store.FindKeyByGetRequest(key("keys:value")); err != nil || got != "v" {
+			t.Errorf("FindKeyByGetRequest = %q, %v; want v", got, err)
+		}
+		if got, err := store.FindKeyByGetRequest(key("keys:missing")); err != nil || got != "" {
+			t.Errorf("FindKeyByGetRequest of a missing key = %q, %v; want empty", got, err)
+		}
+
+		want := []string{key("keys:value")}
+		for i := 0; i < 30; i++ {
+			k := key("keys:h" + strconv.Itoa(i))
+			must(t, store.HashSet(ctx, k, map[string]string{"f": "v"}))
+			want = append(want, k)
+		}
+		var scanned []string
+		for cursor := uint64(0); ; {
+			page, next, err := store.Scan(ctx, cursor, key("keys:*"), 5)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scanned = append(scanned, page...)
+			if cursor = next; cursor == 0 {
+				break
+			}
+		}
+		if !sameMembers(scanned, want) {
+			t.Errorf("Scan found %d keys, want %d", len(unique(scanned)), len(want))
+		}
+		if found, err := store.FindKeysByPattern(key("keys:h*")); err != nil || len(found) != 30 {
+			t.Errorf("FindKeysByPattern found %d keys, %v; want 30", len(found), err)
+		}
+
+		must(t, store.Rename(ctx, key("keys:h0"), key("keys:renamed")))
+		if got, err := store.HashGet(ctx, key("keys:renamed"), "f"); err != nil || got != "v" {
+			t.Errorf("renamed hash field = %q, %v; want v", got, err)
+		}
+		if err := store.Rename(ctx, key("keys:h0"), key("keys:other")); err == nil {
+			t.Error("Rename of a missing key succeeded")
+		}
+
+		must(t, store.Delete(ctx, key("keys:renamed"), key("keys:missing")))
+		if got, err := store.HashGetAll(ctx, key("keys:renamed")); err != nil || len(got) != 0 {
+			t.Errorf("deleted hash = %v, %v; want empty", got, err)
+		}
+
+		must(t, store.Expire(ctx, key("keys:h1"), time.Hour))
+		if got, err := store.HashGet(ctx, key("keys:h1"), "f"); err != nil || got != "v" {
+			t.Errorf("hash with a TTL = %q, %v; want v", got, err)
+		}
+		for i, want := range []bool{true, false} {
+			if written, err := store.SetValueIfAbsent(ctx, key("keys:once"), strconv.Itoa(i), time.Hour); err != nil || written != want {
+				t.Errorf("SetValueIfAbsent #%d = %v, %v; want %v", i, written, err, want)
+			}
+		}
+		if got, err := store.FindKeyByGetRequest(key("keys:once")); err != nil || got != "0" {
+			t.Errorf("value written if absent = %q, %v; want 0", got, err)
+		}
+		must(t, store.SetValue(ctx, key("keys:short"), "v", time.Second))
+		time.Sleep(1100 * time.Millisecond)
+		if got, err := store.FindKeyByGetRequest(key("keys:short")); err != nil || got != "" {
+			t.Errorf("expired value = %q, %v; want empty", got, err)
+		}
+	})
+
+	t.Run("wrong type", func(t *testing.T) {
+		s := key("typed:set")
+		if _, err := store.SetAdd(ctx, s, "a"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.HashGet(ctx, s, "a"); err == nil {
+			t.Error("HashGet of a set succeeded")
+		}
+		if _, err := store.SortedSetCard(ctx, s); err == nil {
+			t.Error("SortedSetCard of a set succeeded")
+		}
+		if _, err := store.ListAppend(ctx, s, "a"); err == nil {
+			t.Error("ListAppend to a set succeeded")
+		}
+	})
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// near сообщает, отличается ли оценка HyperLogLog от точного значения не больше чем на 3%
+func near(got, want int64) bool {
+	diff := got - want
+	if diff < 0 {
+		diff = -diff
+	}
+	return diff*100 <= want*3
+}
+
+func scored(members []entities.ScoredMember) []string {
+	result := make([]string, len(members))
+	for i, m := range members {
+		result[i] = m.Member + ":" + strconv.FormatFloat(m.Score, 'f', -1, 64)
+	}
+	return result
+}
+
+// sameMembers сообщает, совпадают ли множества; допускает повторы в got, как SCAN в Redis
+func sameMembers(got, want []string) bool {
+	got, want = unique(got), unique(want)
+	return reflect.DeepEqual(got, want)
+}
+
+func unique(values []string) []string {
+	seen := make(map[string]struct{}, len(values))
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			result = append(result, v)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,25 @@
+// Package memtest общие заготовки тестов над хранилищем в памяти
+package memtest
+
+import (
+	"testing"
+
+	"stats-of/internal/logger"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory"
+
+	"go.uber.org/zap"
+)
+
+// NewStorage отключает журнал и возвращает пустое хранилище в памяти
+func NewStorage(t testing.TB) *memory.Storage {
+	t.Helper()
+	logger.Log = zap.NewNop()
+	return memory.NewStorage()
+}
+
+// NewRepository возвращает репозиторий над пустым хранилищем в памяти; журнал отключается
+func NewRepository(t testing.TB) *storage.Repository {
+	t.Helper()
+	return storage.NewRepository(NewStorage(t))
+}
@@ -0,0 +1,875 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/sketch"
+
+	"go.uber.org/zap"
+)
+
+// ErrWrongType возвращается при обращении к ключу как к значению другого типа, аналогично WRONGTYPE в Redis
+var ErrWrongType = errors.New("operation against a key holding the wrong kind of value")
+
+// ErrNoSuchKey возвращается при переименовании отсутствующего ключа, аналогично ошибке RENAME в Redis
+var ErrNoSuchKey = errors.New("no such key")
+
+type (
+	// value значение ключа; заполнено ровно одно из полей
+	value struct {
+		str       *string
+		hash      map[string]string
+		set       map[string]struct{}
+		sortedSet map[string]float64
+		hll       *sketch.HyperLogLog
+		list      []string
+		expiresAt time.Time
+	}
+
+	// Storage хранилище в памяти процесса с той же семантикой, что и Redis.
+	// Подходит для разработки, тестов и небольших установок без Redis;
+	// данные не переживают перезапуск.
+	Storage struct {
+		mu   sync.Mutex
+		data map[string]*value
+	}
+)
+
+func NewStorage() *Storage {
+	logger.Log.Info("Creating new in-memory storage")
+	return &Storage{data: make(map[string]*value)}
+}
+
+func (s *Storage) Ping(_ context.Context) error {
+	return nil
+}
+
+// FindKeysByPattern поиск ключей по glob-шаблону в духе команды KEYS
+func (s *Storage) FindKeysByPattern(pattern string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.data {
+		if s.expired(key) {
+			continue
+		}
+		if ok, err := path.Match(pattern, key); err != nil {
+			return nil, err
+		} else if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// Scan порция ключей по шаблону. Курсор — хеш ключа, с которого начинается порция: ключи
+// обходятся в порядке хешей, поэтому ключи, существующие всё время обхода, не пропускаются.
+func (s *Storage) Scan(_ context.Context, cursor uint64, pattern string, count int64) ([]string, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var keys []string
+	for key := range s.data {
+		if s.expired(key) {
+			continue
+		}
+		if ok, err := path.Match(pattern, key); err != nil {
+			return nil, 0, err
+		} else if ok {
+			keys = append(keys, key)
+		}
+	}
+	page, next := scanPage(keys, cursor, count)
+	return page, next, nil
+}
+
+func (s *Storage) FindKeyByGetRequest(key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, func(v *value) bool { return v.str != nil })
+	if err != nil || v == nil {
+		return "", err
+	}
+	return *v.str, nil
+}
+
+func (s *Storage) SetValue(_ context.Context, key, val string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Как и SET в Redis, запись заменяет значение любого типа
+	v := &value{str: &val}
+	if ttl > 0 {
+		v.expiresAt = time.Now().Add(ttl)
+	}
+	s.data[key] = v
+	return nil
+}
+
+func (s *Storage) SetValueIfAbsent(_ context.Context, key, val string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data[key]; ok && !s.expired(key) {
+		return false, nil
+	}
+	v := &value{str: &val}
+	if ttl > 0 {
+		v.expiresAt = time.Now().Add(ttl)
+	}
+	s.data[key] = v
+	return true, nil
+}
+
+func (s *Storage) HashGetAll(_ context.Context, key string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isHash)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string)
+	if v != nil {
+		for field, val := range v.hash {
+			result[field] = val
+		}
+	}
+	return result, nil
+}
+
+func (s *Storage) HashGet(_ context.Context, key, field string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isHash)
+	if err != nil || v == nil {
+		return "", err
+	}
+	return v.hash[field], nil
+}
+
+func (s *Storage) HashSet(_ context.Context, key string, fields map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(fields) == 0 {
+		return nil
+	}
+	v, err := s.getOrCreate(key, isHash, func() *value { return &value{hash: make(map[string]string)} })
+	if err != nil {
+		return err
+	}
+	for field, val := range fields {
+		v.hash[field] = val
+	}
+	return nil
+}
+
+func (s *Storage) HashIncrBy(_ context.Context, key, field string, delta int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isHash, func() *value { return &value{hash: make(map[string]string)} })
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+	if raw, ok := v.hash[field]; ok {
+		current, err = strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return 0, errors.New("hash value is not an integer")
+		}
+	}
+	current += delta
+	v.hash[field] = strconv.FormatInt(current, 10)
+	return current, nil
+}
+
+func (s *Storage) HashIncrByFloat(_ context.Context, key, field string, delta float64) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isHash, func() *value { return &value{hash: make(map[string]string)} })
+	if err != nil {
+		return 0, err
+	}
+
+	var current float64
+	if raw, ok := v.hash[field]; ok {
+		current, err = strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, errors.New("hash value is not a float")
+		}
+	}
+	current += delta
+	v.hash[field] = strconv.FormatFloat(current, 'f', -1, 64)
+	return current, nil
+}
+
+func (s *Storage) HashSetMax(_ context.Context, key, field string, val float64) (bool, error) {
+	return s.hashSetIf(key, field, val, func(current float64) bool { return current < val })
+}
+
+func (s *Storage) HashSetMin(_ context.Context, key, field string, val float64) (bool, error) {
+	return s.hashSetIf(key, field, val, func(current float64) bool { return current > val })
+}
+
+// hashSetIf записывает val в поле хеша, если поля нет или replace(текущее значение) истинно
+func (s *Storage) hashSetIf(key, field string, val float64, replace func(current float64) bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isHash, func() *value { return &value{hash: make(map[string]string)} })
+	if err != nil {
+		return false, err
+	}
+	if raw, ok := v.hash[field]; ok {
+		current, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return false, errors.New("hash value is not a float")
+		}
+		if !replace(current) {
+			return false, nil
+		}
+	}
+	v.hash[field] = strconv.FormatFloat(val, 'f', -1, 64)
+	return true, nil
+}
+
+func (s *Storage) HashDelete(_ context.Context, key string, fields ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isHash)
+	if err != nil || v == nil {
+		return err
+	}
+	for _, field := range fields {
+		delete(v.hash, field)
+	}
+	s.dropIfEmpty(key, len(v.hash))
+	return nil
+}
+
+func (s *Storage) SetMembers(_ context.Context, key string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSet)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0)
+	if v != nil {
+		for member := range v.set {
+			members = append(members, member)
+		}
+	}
+	return members, nil
+}
+
+func (s *Storage) HashGetAllMany(ctx context.Context, keys ...string) ([]map[string]string, error) {
+	result := make([]map[string]string, len(keys))
+	for i, key := range keys {
+		hash, err := s.HashGetAll(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = hash
+	}
+	return result, nil
+}
+
+func (s *Storage) SetMembersMany(ctx context.Context, keys ...string) ([][]string, error) {
+	result := make([][]string, len(keys))
+	for i, key := range keys {
+		members, err := s.SetMembers(ctx, key)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = members
+	}
+	return result, nil
+}
+
+// SetScan порция элементов множества, аналог SSCAN; курсор устроен так же, как у Scan
+func (s *Storage) SetScan(_ context.Context, key string, cursor uint64, count int64) ([]string, uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSet)
+	if err != nil {
+		return nil, 0, err
+	}
+	if v == nil {
+		return []string{}, 0, nil
+	}
+	members := make([]string, 0, len(v.set))
+	for member := range v.set {
+		members = append(members, member)
+	}
+	page, next := scanPage(members, cursor, count)
+	return page, next, nil
+}
+
+func (s *Storage) SetAdd(_ context.Context, key string, members ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isSet, func() *value { return &value{set: make(map[string]struct{})} })
+	if err != nil {
+		return 0, err
+	}
+	var added int64
+	for _, member := range members {
+		if _, ok := v.set[member]; !ok {
+			v.set[member] = struct{}{}
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (s *Storage) SetIsMember(_ context.Context, key, member string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSet)
+	if err != nil || v == nil {
+		return false, err
+	}
+	_, ok := v.set[member]
+	return ok, nil
+}
+
+func (s *Storage) SetRemove(_ context.Context, key string, members ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	var removed int64
+	for _, member := range members {
+		if _, ok := v.set[member]; ok {
+			delete(v.set, member)
+			removed++
+		}
+	}
+	s.dropIfEmpty(key, len(v.set))
+	return removed, nil
+}
+
+// SetLinkAdd и SetLinkRemove сначала проверяют типы всех ключей, чтобы ошибка не оставила связь изменённой наполовину
+func (s *Storage) SetLinkAdd(_ context.Context, link entities.SetLink) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkLink(link); err != nil {
+		return false, err
+	}
+	set, _ := s.getOrCreate(link.Key, isSet, func() *value { return &value{set: make(map[string]struct{})} })
+	if _, ok := set.set[link.Member]; ok {
+		return false, nil
+	}
+	set.set[link.Member] = struct{}{}
+	reverse, _ := s.getOrCreate(link.ReverseKey, isSet, func() *value { return &value{set: make(map[string]struct{})} })
+	reverse.set[link.ReverseMember] = struct{}{}
+	s.incrCounters(link.Counters, 1)
+	return true, nil
+}
+
+func (s *Storage) SetLinkRemove(_ context.Context, link entities.SetLink) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.checkLink(link); err != nil {
+		return false, err
+	}
+	set, _ := s.get(link.Key, isSet)
+	if set == nil {
+		return false, nil
+	}
+	if _, ok := set.set[link.Member]; !ok {
+		return false, nil
+	}
+	delete(set.set, link.Member)
+	s.dropIfEmpty(link.Key, len(set.set))
+	if reverse, _ := s.get(link.ReverseKey, isSet); reverse != nil {
+		delete(reverse.set, link.ReverseMember)
+		s.dropIfEmpty(link.ReverseKey, len(reverse.set))
+	}
+	s.incrCounters(link.Counters, -1)
+	return true, nil
+}
+
+// checkLink проверяет типы ключей связи и что счётчики целые
+func (s *Storage) checkLink(link entities.SetLink) error {
+	for _, key := range []string{link.Key, link.ReverseKey} {
+		if _, err := s.get(key, isSet); err != nil {
+			return err
+		}
+	}
+	for _, counter := range link.Counters {
+		v, err := s.get(counter.Key, isHash)
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			continue
+		}
+		if raw, ok := v.hash[counter.Field]; ok {
+			if _, err := strconv.ParseInt(raw, 10, 64); err != nil {
+				return errors.New("hash value is not an integer")
+			}
+		}
+	}
+	return nil
+}
+
+// incrCounters меняет счётчики, уже проверенные checkLink
+func (s *Storage) incrCounters(counters []entities.HashField, delta int64) {
+	for _, counter := range counters {
+		v, _ := s.getOrCreate(counter.Key, isHash, func() *value { return &value{hash: make(map[string]string)} })
+		current, _ := strconv.ParseInt(v.hash[counter.Field], 10, 64)
+		v.hash[counter.Field] = strconv.FormatInt(current+delta, 10)
+	}
+}
+
+func (s *Storage) SortedSetAdd(_ context.Context, key, member string, score float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isSortedSet, func() *value { return &value{sortedSet: make(map[string]float64)} })
+	if err != nil {
+		return err
+	}
+	v.sortedSet[member] = score
+	return nil
+}
+
+func (s *Storage) SortedSetRemove(_ context.Context, key string, members ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil || v == nil {
+		return err
+	}
+	for _, member := range members {
+		delete(v.sortedSet, member)
+	}
+	s.dropIfEmpty(key, len(v.sortedSet))
+	return nil
+}
+
+// SortedSetRevRange элементы с start по stop включительно по убыванию веса;
+// отрицательные индексы отсчитываются с конца, как в ZREVRANGE
+func (s *Storage) SortedSetRevRange(_ context.Context, key string, start, stop int64) ([]entities.ScoredMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return []entities.ScoredMember{}, nil
+	}
+
+	members := revSorted(v.sortedSet)
+	n := int64(len(members))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []entities.ScoredMember{}, nil
+	}
+	return members[start : stop+1], nil
+}
+
+// SortedSetRevRangeByScore элементы с весом в [min, max] по убыванию веса, как ZREVRANGEBYSCORE ... LIMIT
+func (s *Storage) SortedSetRevRangeByScore(_ context.Context, key string, min, max float64, offset, count int64) ([]entities.ScoredMember, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]entities.ScoredMember, 0)
+	if v == nil {
+		return result, nil
+	}
+
+	var skipped int64
+	for _, m := range revSorted(v.sortedSet) {
+		if m.Score < min || m.Score > max {
+			continue
+		}
+		if skipped < offset {
+			skipped++
+			continue
+		}
+		if count > 0 && int64(len(result)) >= count {
+			break
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+func (s *Storage) SortedSetRemoveRangeByScore(_ context.Context, key string, min, max float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil || v == nil {
+		return err
+	}
+	for member, score := range v.sortedSet {
+		if score >= min && score <= max {
+			delete(v.sortedSet, member)
+		}
+	}
+	s.dropIfEmpty(key, len(v.sortedSet))
+	return nil
+}
+
+func (s *Storage) SortedSetRevRank(_ context.Context, key, member string) (int64, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil {
+		return 0, 0, err
+	}
+	if v == nil {
+		return 0, 0, apperrors.ErrNotFound
+	}
+	if _, ok := v.sortedSet[member]; !ok {
+		return 0, 0, apperrors.ErrNotFound
+	}
+
+	for rank, m := range revSorted(v.sortedSet) {
+		if m.Member == member {
+			return int64(rank), m.Score, nil
+		}
+	}
+	return 0, 0, apperrors.ErrNotFound
+}
+
+func (s *Storage) SortedSetRevRankMany(_ context.Context, key string, members ...string) ([]int64, []float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil {
+		return nil, nil, err
+	}
+	ranks := make([]int64, len(members))
+	scores := make([]float64, len(members))
+	positions := make(map[string]int64)
+	if v != nil {
+		for rank, m := range revSorted(v.sortedSet) {
+			positions[m.Member] = int64(rank)
+		}
+	}
+	for i, member := range members {
+		rank, ok := positions[member]
+		if !ok {
+			ranks[i] = -1
+			continue
+		}
+		ranks[i] = rank
+		scores[i] = v.sortedSet[member]
+	}
+	return ranks, scores, nil
+}
+
+func (s *Storage) SortedSetCard(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isSortedSet)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return int64(len(v.sortedSet)), nil
+}
+
+func (s *Storage) HyperLogLogAdd(_ context.Context, key string, elements ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isHyperLogLog, func() *value { return &value{hll: sketch.NewHyperLogLog()} })
+	if err != nil {
+		return err
+	}
+	for _, element := range elements {
+		v.hll.Add([]byte(element))
+	}
+	return nil
+}
+
+// HyperLogLogCount оценка мощности объединения, как PFCOUNT с несколькими ключами
+func (s *Storage) HyperLogLogCount(_ context.Context, keys ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	union, err := s.union(keys)
+	if err != nil {
+		return 0, err
+	}
+	return int64(union.Count()), nil
+}
+
+func (s *Storage) HyperLogLogMerge(_ context.Context, dest string, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	union, err := s.union(append([]string{dest}, keys...))
+	if err != nil {
+		return err
+	}
+	v, err := s.getOrCreate(dest, isHyperLogLog, func() *value { return &value{} })
+	if err != nil {
+		return err
+	}
+	v.hll = union
+	return nil
+}
+
+func (s *Storage) ListAppend(_ context.Context, key string, values ...string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.getOrCreate(key, isList, func() *value { return &value{list: make([]string, 0, len(values))} })
+	if err != nil {
+		return 0, err
+	}
+	v.list = append(v.list, values...)
+	return int64(len(v.list)), nil
+}
+
+// ListRange элементы с start по stop включительно; отрицательные индексы отсчитываются с конца, как в LRANGE
+func (s *Storage) ListRange(_ context.Context, key string, start, stop int64) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isList)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return []string{}, nil
+	}
+
+	n := int64(len(v.list))
+	if start < 0 {
+		start += n
+	}
+	if stop < 0 {
+		stop += n
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= n {
+		stop = n - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string(nil), v.list[start:stop+1]...), nil
+}
+
+func (s *Storage) ListLength(_ context.Context, key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, err := s.get(key, isList)
+	if err != nil || v == nil {
+		return 0, err
+	}
+	return int64(len(v.list)), nil
+}
+
+func (s *Storage) Delete(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.data, key)
+	}
+	return nil
+}
+
+func (s *Storage) Rename(_ context.Context, key, newKey string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.expired(key) {
+		return ErrNoSuchKey
+	}
+	v, ok := s.data[key]
+	if !ok {
+		return ErrNoSuchKey
+	}
+	delete(s.data, key)
+	s.data[newKey] = v
+	return nil
+}
+
+func (s *Storage) Expire(_ context.Context, key string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if v, ok := s.data[key]; ok && !s.expired(key) {
+		v.expiresAt = time.Now().Add(ttl)
+	}
+	return nil
+}
+
+func (s *Storage) union(keys []string) (*sketch.HyperLogLog, error) {
+	union := sketch.NewHyperLogLog()
+	for _, key := range keys {
+		v, err := s.get(key, isHyperLogLog)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil {
+			union.Merge(v.hll)
+		}
+	}
+	return union, nil
+}
+
+// get возвращает значение ключа, nil для отсутствующего ключа или ErrWrongType
+func (s *Storage) get(key string, ofType func(*value) bool) (*value, error) {
+	if s.expired(key) {
+		return nil, nil
+	}
+	v, ok := s.data[key]
+	if !ok {
+		return nil, nil
+	}
+	if !ofType(v) {
+		logger.Log.Error("Wrong key type", zap.String("key", key))
+		return nil, ErrWrongType
+	}
+	return v, nil
+}
+
+func (s *Storage) getOrCreate(key string, ofType func(*value) bool, create func() *value) (*value, error) {
+	v, err := s.get(key, ofType)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		v = create()
+		s.data[key] = v
+	}
+	return v, nil
+}
+
+// expired удаляет ключ с истёкшим временем жизни и сообщает об этом
+func (s *Storage) expired(key string) bool {
+	v, ok := s.data[key]
+	if !ok || v.expiresAt.IsZero() || time.Now().Before(v.expiresAt) {
+		return false
+	}
+	delete(s.data, key)
+	return true
+}
+
+// dropIfEmpty удаляет опустевшую коллекцию: в Redis пустых коллекций не бывает
+func (s *Storage) dropIfEmpty(key string, size int) {
+	if size == 0 {
+		delete(s.data, key)
+	}
+}
+
+// scanPage возвращает до count элементов с хешем не меньше cursor в порядке хешей и курсор
+// следующей порции; 0 — элементов больше нет. Элементы с одинаковым хешем отдаются одной порцией.
+func scanPage(members []string, cursor uint64, count int64) ([]string, uint64) {
+	if count <= 0 {
+		count = 10
+	}
+	hashes := make(map[string]uint64, len(members))
+	for _, m := range members {
+		hashes[m] = scanHash(m)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if hashes[members[i]] != hashes[members[j]] {
+			return hashes[members[i]] < hashes[members[j]]
+		}
+		return members[i] < members[j]
+	})
+
+	start := sort.Search(len(members), func(i int) bool { return hashes[members[i]] >= cursor })
+	end := start
+	for end < len(members) && (int64(end-start) < count || hashes[members[end]] == hashes[members[end-1]]) {
+		end++
+	}
+	if end == len(members) {
+		return members[start:end], 0
+	}
+	return members[start:end], hashes[members[end]]
+}
+
+// scanHash FNV-1a хеш элемента; ноль зарезервирован за началом обхода
+func scanHash(member string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(member))
+	if sum := h.Sum64(); sum != 0 {
+		return sum
+	}
+	return 1
+}
+
+// revSorted упорядочивает элементы как ZREVRANGE: по убыванию веса, затем по убыванию имени
+func revSorted(set map[string]float64) []entities.ScoredMember {
+	members := make([]entities.ScoredMember, 0, len(set))
+	for member, score := range set {
+		members = append(members, entities.ScoredMember{Member: member, Score: score})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Score != members[j].Score {
+			return members[i].Score > members[j].Score
+		}
+		return members[i].Member > members[j].Member
+	})
+	return members
+}
+
+func isHash(v *value) bool        { return v.hash != nil }
+func isSet(v *value) bool         { return v.set != nil }
+func isSortedSet(v *value) bool   { return v.sortedSet != nil }
+func isHyperLogLog(v *value) bool { return v.hll != nil }
+func isList(v *value) bool        { return v.list != nil }
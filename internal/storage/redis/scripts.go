@@ -0,0 +1,40 @@
+package redis
+
+import "github.com/go-redis/redis"
+
+// Скрипты выполняются в Redis целиком, поэтому изменения внутри них видны другим клиентам
+// только все вместе
+var (
+	// hashSetMaxScript KEYS[1] — хеш, ARGV[1] — поле, ARGV[2] — новое значение
+	hashSetMaxScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+if current ~= nil and current >= tonumber(ARGV[2]) then return 0 end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+	// hashSetMinScript аргументы как у hashSetMaxScript
+	hashSetMinScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], ARGV[1]))
+if current ~= nil and current <= tonumber(ARGV[2]) then return 0 end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+	// setLinkAddScript KEYS[1], KEYS[2] — множества связи, ARGV[1], ARGV[2] — их элементы;
+	// KEYS[3..] и ARGV[3..] — хеши и поля счётчиков
+	setLinkAddScript = redis.NewScript(`
+if redis.call('SADD', KEYS[1], ARGV[1]) == 0 then return 0 end
+redis.call('SADD', KEYS[2], ARGV[2])
+for i = 3, #KEYS do redis.call('HINCRBY', KEYS[i], ARGV[i], 1) end
+return 1
+`)
+
+	// setLinkRemoveScript аргументы как у setLinkAddScript
+	setLinkRemoveScript = redis.NewScript(`
+if redis.call('SREM', KEYS[1], ARGV[1]) == 0 then return 0 end
+redis.call('SREM', KEYS[2], ARGV[2])
+for i = 3, #KEYS do redis.call('HINCRBY', KEYS[i], ARGV[i], -1) end
+return 1
+`)
+)
@@ -2,7 +2,13 @@ package redis
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
 	"stats-of/internal/logger"
+	"strconv"
+	"time"
 
 	"github.com/go-redis/redis"
 	"go.uber.org/zap"
@@ -86,6 +92,18 @@ func (r *Storage) FindKeysByPattern(pattern string) ([]string, error) {
 	return keys, nil
 }
 
+// Scan метод для получения одной порции ключей по шаблону командой SCAN
+func (r *Storage) Scan(ctx context.Context, cursor uint64, pattern string, count int64) ([]string, uint64, error) {
+	logger.Log.Debug("Scanning keys", zap.String("pattern", pattern), zap.Uint64("cursor", cursor))
+
+	keys, next, err := r.Client.WithContext(ctx).Scan(cursor, pattern, count).Result()
+	if err != nil {
+		logger.Log.Error("Failed to scan keys", zap.String("pattern", pattern), zap.Error(err))
+		return nil, 0, err
+	}
+	return keys, next, nil
+}
+
 func (r *Storage) FindKeyByGetRequest(key string) (string, error) {
 	// Логирование попытки получения значения по ключу
 	logger.Log.Info("Attempting to retrieve key", zap.String("key", key))
@@ -104,3 +122,537 @@ func (r *Storage) FindKeyByGetRequest(key string) (string, error) {
 	logger.Log.Info("Key retrieved successfully", zap.String("key", key), zap.String("value", result))
 	return result, nil
 }
+
+// HashGetAll метод для получения всех полей хеша по ключу
+func (r *Storage) HashGetAll(ctx context.Context, key string) (map[string]string, error) {
+	logger.Log.Debug("Retrieving hash", zap.String("key", key))
+
+	result, err := r.Client.WithContext(ctx).HGetAll(key).Result()
+	if err != nil {
+		logger.Log.Error("Error retrieving hash", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetMembers метод для получения всех элементов множества по ключу
+func (r *Storage) SetMembers(ctx context.Context, key string) ([]string, error) {
+	logger.Log.Debug("Retrieving set members", zap.String("key", key))
+
+	result, err := r.Client.WithContext(ctx).SMembers(key).Result()
+	if err != nil {
+		logger.Log.Error("Error retrieving set members", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// HashGetAllMany метод для получения нескольких хешей одним конвейером HGETALL
+func (r *Storage) HashGetAllMany(ctx context.Context, keys ...string) ([]map[string]string, error) {
+	logger.Log.Debug("Retrieving hashes", zap.Int("keys", len(keys)))
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	pipe := r.Client.WithContext(ctx).Pipeline()
+	cmds := make([]*redis.StringStringMapCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.HGetAll(key)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		logger.Log.Error("Error retrieving hashes", zap.Int("keys", len(keys)), zap.Error(err))
+		return nil, err
+	}
+
+	result := make([]map[string]string, len(keys))
+	for i, cmd := range cmds {
+		result[i] = cmd.Val()
+	}
+	return result, nil
+}
+
+// SetMembersMany метод для получения элементов нескольких множеств одним конвейером SMEMBERS
+func (r *Storage) SetMembersMany(ctx context.Context, keys ...string) ([][]string, error) {
+	logger.Log.Debug("Retrieving members of sets", zap.Int("keys", len(keys)))
+
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	pipe := r.Client.WithContext(ctx).Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.SMembers(key)
+	}
+	if _, err := pipe.Exec(); err != nil {
+		logger.Log.Error("Error retrieving members of sets", zap.Int("keys", len(keys)), zap.Error(err))
+		return nil, err
+	}
+
+	result := make([][]string, len(keys))
+	for i, cmd := range cmds {
+		result[i] = cmd.Val()
+	}
+	return result, nil
+}
+
+// SetScan метод для получения одной порции элементов множества командой SSCAN
+func (r *Storage) SetScan(ctx context.Context, key string, cursor uint64, count int64) ([]string, uint64, error) {
+	logger.Log.Debug("Scanning set members", zap.String("key", key), zap.Uint64("cursor", cursor))
+
+	members, next, err := r.Client.WithContext(ctx).SScan(key, cursor, "", count).Result()
+	if err != nil {
+		logger.Log.Error("Error scanning set members", zap.String("key", key), zap.Error(err))
+		return nil, 0, err
+	}
+	return members, next, nil
+}
+
+// HashSet метод для записи полей хеша
+func (r *Storage) HashSet(ctx context.Context, key string, fields map[string]string) error {
+	logger.Log.Debug("Writing hash", zap.String("key", key), zap.Int("fields", len(fields)))
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	values := make(map[string]interface{}, len(fields))
+	for field, value := range fields {
+		values[field] = value
+	}
+	if err := r.Client.WithContext(ctx).HMSet(key, values).Err(); err != nil {
+		logger.Log.Error("Error writing hash", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Delete метод для удаления ключей
+func (r *Storage) Delete(ctx context.Context, keys ...string) error {
+	logger.Log.Debug("Deleting keys", zap.Strings("keys", keys))
+
+	if len(keys) == 0 {
+		return nil
+	}
+	if err := r.Client.WithContext(ctx).Del(keys...).Err(); err != nil {
+		logger.Log.Error("Error deleting keys", zap.Strings("keys", keys), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Rename метод для атомарной замены значения newKey значением key
+func (r *Storage) Rename(ctx context.Context, key, newKey string) error {
+	logger.Log.Debug("Renaming key", zap.String("key", key), zap.String("newKey", newKey))
+
+	if err := r.Client.WithContext(ctx).Rename(key, newKey).Err(); err != nil {
+		logger.Log.Error("Error renaming key", zap.String("key", key), zap.String("newKey", newKey), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetAdd метод для добавления элементов в множество, возвращает число действительно добавленных
+func (r *Storage) SetAdd(ctx context.Context, key string, members ...string) (int64, error) {
+	logger.Log.Debug("Adding set members", zap.String("key", key), zap.Strings("members", members))
+
+	added, err := r.Client.WithContext(ctx).SAdd(key, toInterfaces(members)...).Result()
+	if err != nil {
+		logger.Log.Error("Error adding set members", zap.String("key", key), zap.Error(err))
+		return 0, err
+	}
+	return added, nil
+}
+
+// SetIsMember метод для проверки, входит ли элемент в множество
+func (r *Storage) SetIsMember(ctx context.Context, key, member string) (bool, error) {
+	logger.Log.Debug("Checking set membership", zap.String("key", key), zap.String("member", member))
+
+	ok, err := r.Client.WithContext(ctx).SIsMember(key, member).Result()
+	if err != nil {
+		logger.Log.Error("Error checking set membership", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	return ok, nil
+}
+
+// SetRemove метод для удаления элементов из множества, возвращает число действительно удалённых
+func (r *Storage) SetRemove(ctx context.Context, key string, members ...string) (int64, error) {
+	logger.Log.Debug("Removing set members", zap.String("key", key), zap.Strings("members", members))
+
+	removed, err := r.Client.WithContext(ctx).SRem(key, toInterfaces(members)...).Result()
+	if err != nil {
+		logger.Log.Error("Error removing set members", zap.String("key", key), zap.Error(err))
+		return 0, err
+	}
+	return removed, nil
+}
+
+// HashIncrBy метод для атомарного изменения целочисленного поля хеша
+func (r *Storage) HashIncrBy(ctx context.Context, key, field string, delta int64) (int64, error) {
+	logger.Log.Debug("Incrementing hash field", zap.String("key", key), zap.String("field", field), zap.Int64("delta", delta))
+
+	value, err := r.Client.WithContext(ctx).HIncrBy(key, field, delta).Result()
+	if err != nil {
+		logger.Log.Error("Error incrementing hash field", zap.String("key", key), zap.String("field", field), zap.Error(err))
+		return 0, err
+	}
+	return value, nil
+}
+
+// HashIncrByFloat метод для атомарного изменения дробного поля хеша
+func (r *Storage) HashIncrByFloat(ctx context.Context, key, field string, delta float64) (float64, error) {
+	logger.Log.Debug("Incrementing hash field by float", zap.String("key", key), zap.String("field", field), zap.Float64("delta", delta))
+
+	value, err := r.Client.WithContext(ctx).HIncrByFloat(key, field, delta).Result()
+	if err != nil {
+		logger.Log.Error("Error incrementing hash field by float", zap.String("key", key), zap.String("field", field), zap.Error(err))
+		return 0, err
+	}
+	return value, nil
+}
+
+// HashSetMax метод для атомарной записи значения в поле хеша, если поля нет или оно меньше
+func (r *Storage) HashSetMax(ctx context.Context, key, field string, value float64) (bool, error) {
+	return r.hashSetIf(ctx, hashSetMaxScript, key, field, value)
+}
+
+// HashSetMin метод для атомарной записи значения в поле хеша, если поля нет или оно больше
+func (r *Storage) HashSetMin(ctx context.Context, key, field string, value float64) (bool, error) {
+	return r.hashSetIf(ctx, hashSetMinScript, key, field, value)
+}
+
+func (r *Storage) hashSetIf(ctx context.Context, script *redis.Script, key, field string, value float64) (bool, error) {
+	logger.Log.Debug("Conditionally setting hash field", zap.String("key", key), zap.String("field", field), zap.Float64("value", value))
+
+	updated, err := script.Run(r.Client.WithContext(ctx), []string{key}, field, strconv.FormatFloat(value, 'f', -1, 64)).Int64()
+	if err != nil {
+		logger.Log.Error("Error conditionally setting hash field", zap.String("key", key), zap.String("field", field), zap.Error(err))
+		return false, err
+	}
+	return updated == 1, nil
+}
+
+// SetLinkAdd метод для атомарного добавления связи в оба множества с увеличением её счётчиков
+func (r *Storage) SetLinkAdd(ctx context.Context, link entities.SetLink) (bool, error) {
+	return r.setLink(ctx, setLinkAddScript, link)
+}
+
+// SetLinkRemove метод для атомарного удаления связи из обоих множеств с уменьшением её счётчиков
+func (r *Storage) SetLinkRemove(ctx context.Context, link entities.SetLink) (bool, error) {
+	return r.setLink(ctx, setLinkRemoveScript, link)
+}
+
+func (r *Storage) setLink(ctx context.Context, script *redis.Script, link entities.SetLink) (bool, error) {
+	logger.Log.Debug("Changing set link", zap.String("key", link.Key), zap.String("member", link.Member),
+		zap.String("reverse_key", link.ReverseKey), zap.String("reverse_member", link.ReverseMember))
+
+	keys := []string{link.Key, link.ReverseKey}
+	args := []interface{}{link.Member, link.ReverseMember}
+	for _, counter := range link.Counters {
+		keys = append(keys, counter.Key)
+		args = append(args, counter.Field)
+	}
+	changed, err := script.Run(r.Client.WithContext(ctx), keys, args...).Int64()
+	if err != nil {
+		logger.Log.Error("Error changing set link", zap.String("key", link.Key), zap.String("member", link.Member), zap.Error(err))
+		return false, err
+	}
+	return changed == 1, nil
+}
+
+// SortedSetAdd метод для добавления элемента в упорядоченное множество или обновления его веса
+func (r *Storage) SortedSetAdd(ctx context.Context, key, member string, score float64) error {
+	logger.Log.Debug("Adding sorted set member", zap.String("key", key), zap.String("member", member), zap.Float64("score", score))
+
+	if err := r.Client.WithContext(ctx).ZAdd(key, redis.Z{Score: score, Member: member}).Err(); err != nil {
+		logger.Log.Error("Error adding sorted set member", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SortedSetRemove метод для удаления элементов из упорядоченного множества
+func (r *Storage) SortedSetRemove(ctx context.Context, key string, members ...string) error {
+	logger.Log.Debug("Removing sorted set members", zap.String("key", key), zap.Strings("members", members))
+
+	if err := r.Client.WithContext(ctx).ZRem(key, toInterfaces(members)...).Err(); err != nil {
+		logger.Log.Error("Error removing sorted set members", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SortedSetRevRange метод для получения элементов с start по stop в порядке убывания веса
+func (r *Storage) SortedSetRevRange(ctx context.Context, key string, start, stop int64) ([]entities.ScoredMember, error) {
+	logger.Log.Debug("Retrieving sorted set range", zap.String("key", key), zap.Int64("start", start), zap.Int64("stop", stop))
+
+	result, err := r.Client.WithContext(ctx).ZRevRangeWithScores(key, start, stop).Result()
+	if err != nil {
+		logger.Log.Error("Error retrieving sorted set range", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	members := make([]entities.ScoredMember, 0, len(result))
+	for _, z := range result {
+		members = append(members, entities.ScoredMember{Member: fmt.Sprint(z.Member), Score: z.Score})
+	}
+	return members, nil
+}
+
+// SortedSetRevRank метод для получения позиции элемента (с нуля) в порядке убывания веса
+// вместе с его весом. Если элемента нет, возвращается ErrNotFound.
+func (r *Storage) SortedSetRevRank(ctx context.Context, key, member string) (int64, float64, error) {
+	logger.Log.Debug("Retrieving sorted set rank", zap.String("key", key), zap.String("member", member))
+
+	client := r.Client.WithContext(ctx)
+	rank, err := client.ZRevRank(key, member).Result()
+	if err == redis.Nil {
+		return 0, 0, apperrors.ErrNotFound
+	} else if err != nil {
+		logger.Log.Error("Error retrieving sorted set rank", zap.String("key", key), zap.Error(err))
+		return 0, 0, err
+	}
+
+	score, err := client.ZScore(key, member).Result()
+	if err == redis.Nil {
+		// Элемент удалён между двумя запросами
+		return 0, 0, apperrors.ErrNotFound
+	} else if err != nil {
+		logger.Log.Error("Error retrieving sorted set score", zap.String("key", key), zap.Error(err))
+		return 0, 0, err
+	}
+	return rank, score, nil
+}
+
+// SortedSetRevRankMany метод для получения позиций и весов нескольких элементов одним конвейером
+// ZREVRANK и ZSCORE; отсутствующему элементу соответствует позиция -1
+func (r *Storage) SortedSetRevRankMany(ctx context.Context, key string, members ...string) ([]int64, []float64, error) {
+	logger.Log.Debug("Retrieving sorted set ranks", zap.String("key", key), zap.Int("members", len(members)))
+
+	if len(members) == 0 {
+		return nil, nil, nil
+	}
+	pipe := r.Client.WithContext(ctx).Pipeline()
+	rankCmds := make([]*redis.IntCmd, len(members))
+	scoreCmds := make([]*redis.FloatCmd, len(members))
+	for i, member := range members {
+		rankCmds[i] = pipe.ZRevRank(key, member)
+		scoreCmds[i] = pipe.ZScore(key, member)
+	}
+	// Exec возвращает redis.Nil, если какого-то элемента нет; ошибки проверяются по командам
+	if _, err := pipe.Exec(); err != nil && err != redis.Nil {
+		logger.Log.Error("Error retrieving sorted set ranks", zap.String("key", key), zap.Error(err))
+		return nil, nil, err
+	}
+
+	ranks := make([]int64, len(members))
+	scores := make([]float64, len(members))
+	for i := range members {
+		rank, rankErr := rankCmds[i].Result()
+		score, scoreErr := scoreCmds[i].Result()
+		if rankErr == redis.Nil || scoreErr == redis.Nil {
+			// Элемента нет или он удалён между двумя командами конвейера
+			ranks[i] = -1
+			continue
+		}
+		for _, err := range []error{rankErr, scoreErr} {
+			if err != nil {
+				logger.Log.Error("Error retrieving sorted set rank", zap.String("key", key), zap.String("member", members[i]), zap.Error(err))
+				return nil, nil, err
+			}
+		}
+		ranks[i], scores[i] = rank, score
+	}
+	return ranks, scores, nil
+}
+
+// SortedSetCard метод для получения числа элементов упорядоченного множества
+func (r *Storage) SortedSetCard(ctx context.Context, key string) (int64, error) {
+	count, err := r.Client.WithContext(ctx).ZCard(key).Result()
+	if err != nil {
+		logger.Log.Error("Error retrieving sorted set size", zap.String("key", key), zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+func toInterfaces(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// HashDelete метод для удаления полей хеша
+func (r *Storage) HashDelete(ctx context.Context, key string, fields ...string) error {
+	logger.Log.Debug("Deleting hash fields", zap.String("key", key), zap.Int("fields", len(fields)))
+
+	if len(fields) == 0 {
+		return nil
+	}
+	if err := r.Client.WithContext(ctx).HDel(key, fields...).Err(); err != nil {
+		logger.Log.Error("Error deleting hash fields", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// HashGet метод для получения одного поля хеша; для отсутствующего поля возвращает пустую строку
+func (r *Storage) HashGet(ctx context.Context, key, field string) (string, error) {
+	logger.Log.Debug("Retrieving hash field", zap.String("key", key), zap.String("field", field))
+
+	result, err := r.Client.WithContext(ctx).HGet(key, field).Result()
+	if err == redis.Nil {
+		return "", nil
+	} else if err != nil {
+		logger.Log.Error("Error retrieving hash field", zap.String("key", key), zap.String("field", field), zap.Error(err))
+		return "", err
+	}
+	return result, nil
+}
+
+// HyperLogLogAdd метод для добавления элементов в HyperLogLog (PFADD)
+func (r *Storage) HyperLogLogAdd(ctx context.Context, key string, elements ...string) error {
+	logger.Log.Debug("Adding HyperLogLog elements", zap.String("key", key), zap.Int("elements", len(elements)))
+
+	if err := r.Client.WithContext(ctx).PFAdd(key, toInterfaces(elements)...).Err(); err != nil {
+		logger.Log.Error("Error adding HyperLogLog elements", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// HyperLogLogCount метод для оценки числа различных элементов в объединении HyperLogLog (PFCOUNT)
+func (r *Storage) HyperLogLogCount(ctx context.Context, keys ...string) (int64, error) {
+	logger.Log.Debug("Counting HyperLogLog", zap.Int("keys", len(keys)))
+
+	count, err := r.Client.WithContext(ctx).PFCount(keys...).Result()
+	if err != nil {
+		logger.Log.Error("Error counting HyperLogLog", zap.Strings("keys", keys), zap.Error(err))
+		return 0, err
+	}
+	return count, nil
+}
+
+// HyperLogLogMerge метод для объединения HyperLogLog в ключ dest (PFMERGE)
+func (r *Storage) HyperLogLogMerge(ctx context.Context, dest string, keys ...string) error {
+	logger.Log.Debug("Merging HyperLogLog", zap.String("dest", dest), zap.Strings("keys", keys))
+
+	if err := r.Client.WithContext(ctx).PFMerge(dest, keys...).Err(); err != nil {
+		logger.Log.Error("Error merging HyperLogLog", zap.String("dest", dest), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Expire метод для установки времени жизни ключа
+func (r *Storage) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if err := r.Client.WithContext(ctx).Expire(key, ttl).Err(); err != nil {
+		logger.Log.Error("Error setting key expiration", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetValue метод для записи строкового значения; ttl = 0 означает хранение без срока
+func (r *Storage) SetValue(ctx context.Context, key, value string, ttl time.Duration) error {
+	logger.Log.Debug("Writing key", zap.String("key", key))
+
+	if err := r.Client.WithContext(ctx).Set(key, value, ttl).Err(); err != nil {
+		logger.Log.Error("Error writing key", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// SetValueIfAbsent метод для записи строкового значения, если ключа ещё нет (SET NX)
+func (r *Storage) SetValueIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	logger.Log.Debug("Writing key if absent", zap.String("key", key))
+
+	written, err := r.Client.WithContext(ctx).SetNX(key, value, ttl).Result()
+	if err != nil {
+		logger.Log.Error("Error writing key", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	return written, nil
+}
+
+// SortedSetRevRangeByScore метод для получения элементов с весом в [min, max] по убыванию веса,
+// пропуская offset элементов и возвращая не более count (count = 0 — без ограничения)
+func (r *Storage) SortedSetRevRangeByScore(ctx context.Context, key string, min, max float64, offset, count int64) ([]entities.ScoredMember, error) {
+	logger.Log.Debug("Retrieving sorted set range by score", zap.String("key", key), zap.Float64("min", min), zap.Float64("max", max))
+
+	opt := redis.ZRangeBy{Min: formatScore(min), Max: formatScore(max), Offset: offset, Count: count}
+	if count == 0 && offset != 0 {
+		opt.Count = -1
+	}
+	result, err := r.Client.WithContext(ctx).ZRevRangeByScoreWithScores(key, opt).Result()
+	if err != nil {
+		logger.Log.Error("Error retrieving sorted set range by score", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+
+	members := make([]entities.ScoredMember, 0, len(result))
+	for _, z := range result {
+		members = append(members, entities.ScoredMember{Member: fmt.Sprint(z.Member), Score: z.Score})
+	}
+	return members, nil
+}
+
+// SortedSetRemoveRangeByScore метод для удаления элементов с весом в [min, max]
+func (r *Storage) SortedSetRemoveRangeByScore(ctx context.Context, key string, min, max float64) error {
+	logger.Log.Debug("Removing sorted set range by score", zap.String("key", key), zap.Float64("min", min), zap.Float64("max", max))
+
+	if err := r.Client.WithContext(ctx).ZRemRangeByScore(key, formatScore(min), formatScore(max)).Err(); err != nil {
+		logger.Log.Error("Error removing sorted set range by score", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListAppend метод для добавления элементов в конец списка, возвращает новую длину списка
+func (r *Storage) ListAppend(ctx context.Context, key string, values ...string) (int64, error) {
+	logger.Log.Debug("Appending to list", zap.String("key", key), zap.Int("count", len(values)))
+
+	length, err := r.Client.WithContext(ctx).RPush(key, toInterfaces(values)...).Result()
+	if err != nil {
+		logger.Log.Error("Error appending to list", zap.String("key", key), zap.Error(err))
+		return 0, err
+	}
+	return length, nil
+}
+
+// ListRange метод для получения элементов списка с start по stop включительно
+func (r *Storage) ListRange(ctx context.Context, key string, start, stop int64) ([]string, error) {
+	logger.Log.Debug("Getting list range", zap.String("key", key), zap.Int64("start", start), zap.Int64("stop", stop))
+
+	values, err := r.Client.WithContext(ctx).LRange(key, start, stop).Result()
+	if err != nil {
+		logger.Log.Error("Error getting list range", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return values, nil
+}
+
+// ListLength метод для получения длины списка
+func (r *Storage) ListLength(ctx context.Context, key string) (int64, error) {
+	logger.Log.Debug("Getting list length", zap.String("key", key))
+
+	length, err := r.Client.WithContext(ctx).LLen(key).Result()
+	if err != nil {
+		logger.Log.Error("Error getting list length", zap.String("key", key), zap.Error(err))
+		return 0, err
+	}
+	return length, nil
+}
+
+func formatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "+inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	}
+	return strconv.FormatFloat(score, 'f', -1, 64)
+}
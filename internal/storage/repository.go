@@ -0,0 +1,573 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+
+	"go.uber.org/zap"
+)
+
+// Схема ключей в хранилище:
+//
+//	chat:{id}        хеш с полями chat_type и count_of_users
+//	chat_users:{id}  множество идентификаторов участников чата
+//	user:{id}        хеш с полями last_time (unix-время) и count_of_chats
+//	user_chats:{id}  множество идентификаторов чатов пользователя
+//	chat_bans:{id}   множество идентификаторов пользователей, забаненных в чате
+const (
+	chatKeyPrefix      = "chat:"
+	chatUsersKeyPrefix = "chat_users:"
+	userKeyPrefix      = "user:"
+	userChatsKeyPrefix = "user_chats:"
+	chatBansKeyPrefix  = "chat_bans:"
+
+	fieldChatType     = "chat_type"
+	fieldCountOfUsers = "count_of_users"
+	fieldLastTime     = "last_time"
+	fieldCountOfChats = "count_of_chats"
+)
+
+// ChatKey возвращает ключ хеша чата
+func ChatKey(id entities.ChatID) string {
+	return chatKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+// ChatUsersKey возвращает ключ множества участников чата
+func ChatUsersKey(id entities.ChatID) string {
+	return chatUsersKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+// UserKey возвращает ключ хеша пользователя
+func UserKey(id entities.UserID) string {
+	return userKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+// UserChatsKey возвращает ключ множества чатов пользователя
+func UserChatsKey(id entities.UserID) string {
+	return userChatsKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+// ChatBansKey возвращает ключ множества забаненных в чате пользователей
+func ChatBansKey(id entities.ChatID) string {
+	return chatBansKeyPrefix + strconv.FormatInt(int64(id), 10)
+}
+
+// Repository предоставляет доступ к чатам и пользователям поверх Storage
+type Repository struct {
+	storage Storage
+}
+
+func NewRepository(s Storage) *Repository {
+	return &Repository{storage: s}
+}
+
+// Storage возвращает хранилище, с которым работает репозиторий
+func (r *Repository) Storage() Storage {
+	return r.storage
+}
+
+// Chat возвращает чат по идентификатору или ErrNotFound
+func (r *Repository) Chat(ctx context.Context, id entities.ChatID) (*entities.Chat, error) {
+	fields, err := r.storage.HashGetAll(ctx, ChatKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chat %d: %w", id, err)
+	}
+	if len(fields) == 0 {
+		return nil, apperrors.ErrNotFound
+	}
+	return decodeChat(id, fields)
+}
+
+// User возвращает пользователя по идентификатору или ErrNotFound
+func (r *Repository) User(ctx context.Context, id entities.UserID) (*entities.User, error) {
+	fields, err := r.storage.HashGetAll(ctx, UserKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load user %d: %w", id, err)
+	}
+	if len(fields) == 0 {
+		return nil, apperrors.ErrNotFound
+	}
+	return decodeUser(id, fields)
+}
+
+// Chats загружает несколько чатов за один запрос к хранилищу; отсутствующих чатов нет в результате
+func (r *Repository) Chats(ctx context.Context, ids []entities.ChatID) (map[entities.ChatID]*entities.Chat, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = ChatKey(id)
+	}
+	hashes, err := r.storage.HashGetAllMany(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %d chats: %w", len(ids), err)
+	}
+
+	chats := make(map[entities.ChatID]*entities.Chat, len(ids))
+	for i, fields := range hashes {
+		if len(fields) == 0 {
+			continue
+		}
+		chat, err := decodeChat(ids[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		chats[ids[i]] = chat
+	}
+	return chats, nil
+}
+
+// Users загружает нескольких пользователей за один запрос к хранилищу; отсутствующих нет в результате
+func (r *Repository) Users(ctx context.Context, ids []entities.UserID) (map[entities.UserID]*entities.User, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = UserKey(id)
+	}
+	hashes, err := r.storage.HashGetAllMany(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %d users: %w", len(ids), err)
+	}
+
+	users := make(map[entities.UserID]*entities.User, len(ids))
+	for i, fields := range hashes {
+		if len(fields) == 0 {
+			continue
+		}
+		user, err := decodeUser(ids[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		users[ids[i]] = user
+	}
+	return users, nil
+}
+
+// ChatIDs возвращает идентификаторы всех чатов в хранилище
+func (r *Repository) ChatIDs(ctx context.Context) ([]entities.ChatID, error) {
+	keys, err := r.storage.FindKeysByPattern(chatKeyPrefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chats: %w", err)
+	}
+
+	ids := make([]entities.ChatID, 0, len(keys))
+	for _, key := range keys {
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, chatKeyPrefix), 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed chat key", zap.String("key", key))
+			continue
+		}
+		ids = append(ids, entities.ChatID(id))
+	}
+	return ids, nil
+}
+
+// MemberChatIDs возвращает идентификаторы всех чатов, у которых есть множество участников,
+// в том числе чатов без хеша chat:{id}
+func (r *Repository) MemberChatIDs(ctx context.Context) ([]entities.ChatID, error) {
+	keys, err := r.storage.FindKeysByPattern(chatUsersKeyPrefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat members: %w", err)
+	}
+	return parseIDs[entities.ChatID](keys, chatUsersKeyPrefix, "Skipping malformed chat members key"), nil
+}
+
+// UserIDs возвращает идентификаторы всех пользователей в хранилище
+func (r *Repository) UserIDs(ctx context.Context) ([]entities.UserID, error) {
+	keys, err := r.storage.FindKeysByPattern(userKeyPrefix + "*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	ids := make([]entities.UserID, 0, len(keys))
+	for _, key := range keys {
+		id, err := strconv.ParseInt(strings.TrimPrefix(key, userKeyPrefix), 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed user key", zap.String("key", key))
+			continue
+		}
+		ids = append(ids, entities.UserID(id))
+	}
+	return ids, nil
+}
+
+// ChatUserIDs возвращает идентификаторы участников чата
+func (r *Repository) ChatUserIDs(ctx context.Context, id entities.ChatID) (entities.UserIds, error) {
+	members, err := r.storage.SetMembers(ctx, ChatUsersKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load members of chat %d: %w", id, err)
+	}
+
+	ids := make(entities.UserIds, 0, len(members))
+	for _, member := range members {
+		uid, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed chat member", zap.Int64("chatID", int64(id)), zap.String("member", member))
+			continue
+		}
+		ids = append(ids, entities.UserID(uid))
+	}
+	return ids, nil
+}
+
+// UserChatIDs возвращает идентификаторы чатов, в которых состоит пользователь
+func (r *Repository) UserChatIDs(ctx context.Context, id entities.UserID) (entities.ChatIds, error) {
+	members, err := r.storage.SetMembers(ctx, UserChatsKey(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chats of user %d: %w", id, err)
+	}
+
+	ids := make(entities.ChatIds, 0, len(members))
+	for _, member := range members {
+		cid, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed user chat", zap.Int64("userID", int64(id)), zap.String("member", member))
+			continue
+		}
+		ids = append(ids, entities.ChatID(cid))
+	}
+	return ids, nil
+}
+
+// ChatUserIDsMany возвращает участников нескольких чатов за один запрос к хранилищу
+func (r *Repository) ChatUserIDsMany(ctx context.Context, ids []entities.ChatID) (map[entities.ChatID]entities.UserIds, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = ChatUsersKey(id)
+	}
+	sets, err := r.storage.SetMembersMany(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load members of %d chats: %w", len(ids), err)
+	}
+
+	members := make(map[entities.ChatID]entities.UserIds, len(ids))
+	for i, set := range sets {
+		members[ids[i]] = parseIDs[entities.UserID](set, "", "Skipping malformed chat member")
+	}
+	return members, nil
+}
+
+// UserChatIDsMany возвращает чаты нескольких пользователей за один запрос к хранилищу
+func (r *Repository) UserChatIDsMany(ctx context.Context, ids []entities.UserID) (map[entities.UserID]entities.ChatIds, error) {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = UserChatsKey(id)
+	}
+	sets, err := r.storage.SetMembersMany(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chats of %d users: %w", len(ids), err)
+	}
+
+	chats := make(map[entities.UserID]entities.ChatIds, len(ids))
+	for i, set := range sets {
+		chats[ids[i]] = parseIDs[entities.ChatID](set, "", "Skipping malformed user chat")
+	}
+	return chats, nil
+}
+
+// ScanChatIDs возвращает порцию идентификаторов чатов начиная с курсора SCAN и курсор следующей порции
+func (r *Repository) ScanChatIDs(ctx context.Context, cursor uint64, count int64) ([]entities.ChatID, uint64, error) {
+	keys, next, err := r.storage.Scan(ctx, cursor, chatKeyPrefix+"*", count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan chats: %w", err)
+	}
+	return parseIDs[entities.ChatID](keys, chatKeyPrefix, "Skipping malformed chat key"), next, nil
+}
+
+// ScanUserIDs возвращает порцию идентификаторов пользователей начиная с курсора SCAN
+func (r *Repository) ScanUserIDs(ctx context.Context, cursor uint64, count int64) ([]entities.UserID, uint64, error) {
+	keys, next, err := r.storage.Scan(ctx, cursor, userKeyPrefix+"*", count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan users: %w", err)
+	}
+	return parseIDs[entities.UserID](keys, userKeyPrefix, "Skipping malformed user key"), next, nil
+}
+
+// ScanChatUserIDs возвращает порцию участников чата начиная с курсора SSCAN
+func (r *Repository) ScanChatUserIDs(ctx context.Context, id entities.ChatID, cursor uint64, count int64) ([]entities.UserID, uint64, error) {
+	members, next, err := r.storage.SetScan(ctx, ChatUsersKey(id), cursor, count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan members of chat %d: %w", id, err)
+	}
+	return parseIDs[entities.UserID](members, "", "Skipping malformed chat member"), next, nil
+}
+
+// ScanUserChatIDs возвращает порцию чатов пользователя начиная с курсора SSCAN
+func (r *Repository) ScanUserChatIDs(ctx context.Context, id entities.UserID, cursor uint64, count int64) ([]entities.ChatID, uint64, error) {
+	members, next, err := r.storage.SetScan(ctx, UserChatsKey(id), cursor, count)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to scan chats of user %d: %w", id, err)
+	}
+	return parseIDs[entities.ChatID](members, "", "Skipping malformed user chat"), next, nil
+}
+
+// ChatList загружает полный граф участия: для каждого чата список его пользователей.
+// Каждый пользователь читается из хранилища один раз, даже если состоит во многих чатах.
+func (r *Repository) ChatList(ctx context.Context) (entities.ChatList, error) {
+	chatIDs, err := r.ChatIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[entities.UserID]entities.User)
+	list := make(entities.ChatList, len(chatIDs))
+	for _, chatID := range chatIDs {
+		userIDs, err := r.ChatUserIDs(ctx, chatID)
+		if err != nil {
+			return nil, err
+		}
+
+		members := make([]entities.User, 0, len(userIDs))
+		for _, userID := range userIDs {
+			user, ok := users[userID]
+			if !ok {
+				loaded, err := r.User(ctx, userID)
+				switch {
+				case err == nil:
+					user = *loaded
+				case errors.Is(err, apperrors.ErrNotFound):
+					// Участник есть в множестве, но хеш пользователя отсутствует
+					user = entities.User{UserID: userID}
+				default:
+					return nil, err
+				}
+				users[userID] = user
+			}
+			members = append(members, user)
+		}
+		list[chatID] = members
+	}
+
+	logger.Log.Info("Chat list loaded", zap.Int("chats", len(list)), zap.Int("users", len(users)))
+	return list, nil
+}
+
+// AddMembership атомарно добавляет пользователя в чат и увеличивает оба счётчика.
+// Возвращает false, если пользователь уже состоял в чате: счётчики в этом случае не меняются.
+func (r *Repository) AddMembership(ctx context.Context, chatID entities.ChatID, userID entities.UserID) (bool, error) {
+	added, err := r.storage.SetLinkAdd(ctx, membershipLink(chatID, userID))
+	if err != nil {
+		return false, fmt.Errorf("failed to add user %d to chat %d: %w", userID, chatID, err)
+	}
+	return added, nil
+}
+
+// RemoveMembership атомарно удаляет пользователя из чата и уменьшает оба счётчика.
+// Возвращает false, если пользователь не состоял в чате.
+func (r *Repository) RemoveMembership(ctx context.Context, chatID entities.ChatID, userID entities.UserID) (bool, error) {
+	removed, err := r.storage.SetLinkRemove(ctx, membershipLink(chatID, userID))
+	if err != nil {
+		return false, fmt.Errorf("failed to remove user %d from chat %d: %w", userID, chatID, err)
+	}
+	return removed, nil
+}
+
+// TouchUser обновляет время последней активности пользователя, если at новее сохранённого.
+// Сравнение и запись выполняет хранилище, поэтому параллельные события не откатывают время назад.
+func (r *Repository) TouchUser(ctx context.Context, userID entities.UserID, at time.Time) error {
+	if _, err := r.storage.HashSetMax(ctx, UserKey(userID), fieldLastTime, float64(at.Unix())); err != nil {
+		return fmt.Errorf("failed to update last time of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// SetChatType сохраняет тип чата
+func (r *Repository) SetChatType(ctx context.Context, chatID entities.ChatID, chatType uint) error {
+	err := r.storage.HashSet(ctx, ChatKey(chatID), map[string]string{fieldChatType: strconv.FormatUint(uint64(chatType), 10)})
+	if err != nil {
+		return fmt.Errorf("failed to update type of chat %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// SetCountOfUsers перезаписывает счётчик участников чата; используется при сверке счётчиков
+func (r *Repository) SetCountOfUsers(ctx context.Context, chatID entities.ChatID, count int64) error {
+	err := r.storage.HashSet(ctx, ChatKey(chatID), map[string]string{fieldCountOfUsers: strconv.FormatInt(count, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to set users count of chat %d: %w", chatID, err)
+	}
+	return nil
+}
+
+// SetCountOfChats перезаписывает счётчик чатов пользователя; используется при сверке счётчиков
+func (r *Repository) SetCountOfChats(ctx context.Context, userID entities.UserID, count int64) error {
+	err := r.storage.HashSet(ctx, UserKey(userID), map[string]string{fieldCountOfChats: strconv.FormatInt(count, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to set chats count of user %d: %w", userID, err)
+	}
+	return nil
+}
+
+// LinkUserChat добавляет чат в множество чатов пользователя, не трогая состав чата и счётчики
+func (r *Repository) LinkUserChat(ctx context.Context, userID entities.UserID, chatID entities.ChatID) error {
+	if _, err := r.storage.SetAdd(ctx, UserChatsKey(userID), strconv.FormatInt(int64(chatID), 10)); err != nil {
+		return fmt.Errorf("failed to add chat %d to user %d: %w", chatID, userID, err)
+	}
+	return nil
+}
+
+// UnlinkUserChat удаляет чат из множества чатов пользователя, не трогая состав чата и счётчики
+func (r *Repository) UnlinkUserChat(ctx context.Context, userID entities.UserID, chatID entities.ChatID) error {
+	if _, err := r.storage.SetRemove(ctx, UserChatsKey(userID), strconv.FormatInt(int64(chatID), 10)); err != nil {
+		return fmt.Errorf("failed to remove chat %d from user %d: %w", chatID, userID, err)
+	}
+	return nil
+}
+
+// Ban запоминает, что пользователю запрещено возвращаться в чат
+func (r *Repository) Ban(ctx context.Context, chatID entities.ChatID, userID entities.UserID) error {
+	if _, err := r.storage.SetAdd(ctx, ChatBansKey(chatID), strconv.FormatInt(int64(userID), 10)); err != nil {
+		return fmt.Errorf("failed to ban user %d in chat %d: %w", userID, chatID, err)
+	}
+	return nil
+}
+
+// IsBanned сообщает, забанен ли пользователь в чате
+func (r *Repository) IsBanned(ctx context.Context, chatID entities.ChatID, userID entities.UserID) (bool, error) {
+	banned, err := r.storage.SetIsMember(ctx, ChatBansKey(chatID), strconv.FormatInt(int64(userID), 10))
+	if err != nil {
+		return false, fmt.Errorf("failed to check ban of user %d in chat %d: %w", userID, chatID, err)
+	}
+	return banned, nil
+}
+
+// IsMember сообщает, состоит ли пользователь в чате по множеству chat_users:{id}
+func (r *Repository) IsMember(ctx context.Context, chatID entities.ChatID, userID entities.UserID) (bool, error) {
+	member, err := r.storage.SetIsMember(ctx, ChatUsersKey(chatID), strconv.FormatInt(int64(userID), 10))
+	if err != nil {
+		return false, fmt.Errorf("failed to check membership of user %d in chat %d: %w", userID, chatID, err)
+	}
+	return member, nil
+}
+
+// DeleteAggregates удаляет все чаты, пользователей, составы и баны перед восстановлением из журнала событий
+func (r *Repository) DeleteAggregates(ctx context.Context) (int, error) {
+	var deleted int
+	for _, prefix := range []string{chatKeyPrefix, chatUsersKeyPrefix, userKeyPrefix, userChatsKeyPrefix, chatBansKeyPrefix} {
+		keys, err := r.storage.FindKeysByPattern(prefix + "*")
+		if err != nil {
+			return deleted, fmt.Errorf("failed to list %s keys: %w", prefix, err)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		if err := r.storage.Delete(ctx, keys...); err != nil {
+			return deleted, fmt.Errorf("failed to delete %s keys: %w", prefix, err)
+		}
+		deleted += len(keys)
+	}
+	return deleted, nil
+}
+
+// SaveChat записывает чат целиком: хеш со счётчиком и состав
+func (r *Repository) SaveChat(ctx context.Context, chat entities.Chat, members []entities.UserID) error {
+	err := r.storage.HashSet(ctx, ChatKey(chat.ChatID), map[string]string{
+		fieldChatType:     strconv.FormatUint(uint64(chat.ChatType), 10),
+		fieldCountOfUsers: strconv.FormatInt(chat.CountOfUsers, 10),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save chat %d: %w", chat.ChatID, err)
+	}
+	if len(members) > 0 {
+		if _, err := r.storage.SetAdd(ctx, ChatUsersKey(chat.ChatID), formatIDs(members)...); err != nil {
+			return fmt.Errorf("failed to save members of chat %d: %w", chat.ChatID, err)
+		}
+	}
+	return nil
+}
+
+// SaveUser записывает пользователя целиком: хеш с временем активности и счётчиком и множество чатов
+func (r *Repository) SaveUser(ctx context.Context, user entities.User, chats []entities.ChatID) error {
+	fields := map[string]string{fieldCountOfChats: strconv.FormatInt(user.CountOfChats, 10)}
+	if !user.LastTime.IsZero() {
+		fields[fieldLastTime] = strconv.FormatInt(user.LastTime.Unix(), 10)
+	}
+	if err := r.storage.HashSet(ctx, UserKey(user.UserID), fields); err != nil {
+		return fmt.Errorf("failed to save user %d: %w", user.UserID, err)
+	}
+	if len(chats) > 0 {
+		if _, err := r.storage.SetAdd(ctx, UserChatsKey(user.UserID), formatIDs(chats)...); err != nil {
+			return fmt.Errorf("failed to save chats of user %d: %w", user.UserID, err)
+		}
+	}
+	return nil
+}
+
+// membershipLink связь пользователя с чатом: множества chat_users и user_chats и их счётчики
+func membershipLink(chatID entities.ChatID, userID entities.UserID) entities.SetLink {
+	return entities.SetLink{
+		Key:           ChatUsersKey(chatID),
+		Member:        strconv.FormatInt(int64(userID), 10),
+		ReverseKey:    UserChatsKey(userID),
+		ReverseMember: strconv.FormatInt(int64(chatID), 10),
+		Counters: []entities.HashField{
+			{Key: ChatKey(chatID), Field: fieldCountOfUsers},
+			{Key: UserKey(userID), Field: fieldCountOfChats},
+		},
+	}
+}
+
+func decodeChat(id entities.ChatID, fields map[string]string) (*entities.Chat, error) {
+	chat := &entities.Chat{ChatID: id}
+	if v, ok := fields[fieldChatType]; ok {
+		chatType, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of chat %d: %w", fieldChatType, id, err)
+		}
+		chat.ChatType = uint(chatType)
+	}
+	if v, ok := fields[fieldCountOfUsers]; ok {
+		count, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of chat %d: %w", fieldCountOfUsers, id, err)
+		}
+		chat.CountOfUsers = count
+	}
+	return chat, nil
+}
+
+func decodeUser(id entities.UserID, fields map[string]string) (*entities.User, error) {
+	user := &entities.User{UserID: id}
+	if v, ok := fields[fieldLastTime]; ok {
+		ts, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of user %d: %w", fieldLastTime, id, err)
+		}
+		user.LastTime = time.Unix(ts, 0).UTC()
+	}
+	if v, ok := fields[fieldCountOfChats]; ok {
+		count, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s of user %d: %w", fieldCountOfChats, id, err)
+		}
+		user.CountOfChats = count
+	}
+	return user, nil
+}
+
+func formatIDs[T ~int64](ids []T) []string {
+	result := make([]string, len(ids))
+	for i, id := range ids {
+		result[i] = strconv.FormatInt(int64(id), 10)
+	}
+	return result
+}
+
+// parseIDs разбирает идентификаторы из ключей или элементов множеств, пропуская некорректные
+func parseIDs[T ~int64](values []string, prefix, warning string) []T {
+	ids := make([]T, 0, len(values))
+	for _, value := range values {
+		id, err := strconv.ParseInt(strings.TrimPrefix(value, prefix), 10, 64)
+		if err != nil {
+			logger.Log.Warn(warning, zap.String("value", value))
+			continue
+		}
+		ids = append(ids, T(id))
+	}
+	return ids
+}
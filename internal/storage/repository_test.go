@@ -0,0 +1,120 @@
+package storage_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+// TestMembershipCountersUnderConcurrency проверяет, что параллельные добавления и удаления одной связи
+// оставляют счётчики равными размерам множеств
+func TestMembershipCountersUnderConcurrency(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 64; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			userID := entities.UserID(i % 4)
+			if i%3 == 2 {
+				_, _ = repo.RemoveMembership(ctx, 1, userID)
+				return
+			}
+			_, _ = repo.AddMembership(ctx, 1, userID)
+		}()
+	}
+	wg.Wait()
+
+	members, err := repo.ChatUserIDs(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chat, err := repo.Chat(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.CountOfUsers != int64(len(members)) {
+		t.Errorf("count_of_users = %d, chat has %d members", chat.CountOfUsers, len(members))
+	}
+	for userID := entities.UserID(0); userID < 4; userID++ {
+		user, err := repo.User(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chats, err := repo.UserChatIDs(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.CountOfChats != int64(len(chats)) {
+			t.Errorf("user %d: count_of_chats = %d, has %d chats", userID, user.CountOfChats, len(chats))
+		}
+	}
+}
+
+func TestMembershipReportsChanges(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+
+	steps := []struct {
+		add  bool
+		want bool
+	}{
+		{add: true, want: true},
+		{add: true, want: false},
+		{add: false, want: true},
+		{add: false, want: false},
+	}
+	for i, step := range steps {
+		change := repo.RemoveMembership
+		if step.add {
+			change = repo.AddMembership
+		}
+		changed, err := change(ctx, 1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if changed != step.want {
+			t.Errorf("step %d: changed = %v, want %v", i, changed, step.want)
+		}
+	}
+	chat, err := repo.Chat(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chat.CountOfUsers != 0 {
+		t.Errorf("count_of_users = %d after add and remove, want 0", chat.CountOfUsers)
+	}
+}
+
+func TestTouchUserKeepsLatestTime(t *testing.T) {
+	ctx := context.Background()
+	repo := memtest.NewRepository(t)
+	base := time.Unix(1_700_000_000, 0)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = repo.TouchUser(ctx, 10, base.Add(time.Duration(i)*time.Minute))
+		}()
+	}
+	wg.Wait()
+	if err := repo.TouchUser(ctx, 10, base); err != nil {
+		t.Fatal(err)
+	}
+
+	user, err := repo.User(ctx, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := base.Add(49 * time.Minute); !user.LastTime.Equal(want) {
+		t.Errorf("last_time = %s, want %s", user.LastTime, want)
+	}
+}
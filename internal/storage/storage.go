@@ -3,7 +3,11 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
+
+	"stats-of/internal/entities"
 	"stats-of/internal/logger"
+	"stats-of/internal/storage/memory"
 	"stats-of/internal/storage/redis"
 
 	"go.uber.org/zap"
@@ -16,13 +20,63 @@ type (
 		// Open() error
 		Ping(ctx context.Context) error
 		FindKeysByPattern(pattern string) ([]string, error)
+		// Scan и SetScan возвращают одну порцию ключей или элементов множества и курсор
+		// следующей порции, 0 — обход завершён
+		Scan(ctx context.Context, cursor uint64, pattern string, count int64) ([]string, uint64, error)
+		SetScan(ctx context.Context, key string, cursor uint64, count int64) ([]string, uint64, error)
 		FindKeyByGetRequest(key string) (string, error)
+		SetValue(ctx context.Context, key, value string, ttl time.Duration) error
+		// SetValueIfAbsent записывает значение, только если ключа нет; сообщает, записано ли оно
+		SetValueIfAbsent(ctx context.Context, key, value string, ttl time.Duration) (bool, error)
+		HashGetAll(ctx context.Context, key string) (map[string]string, error)
+		HashGet(ctx context.Context, key, field string) (string, error)
+		SetMembers(ctx context.Context, key string) ([]string, error)
+		// HashGetAllMany и SetMembersMany читают несколько ключей за один запрос к хранилищу;
+		// результаты идут в порядке ключей, отсутствующему ключу соответствует пустое значение
+		HashGetAllMany(ctx context.Context, keys ...string) ([]map[string]string, error)
+		SetMembersMany(ctx context.Context, keys ...string) ([][]string, error)
+		HashSet(ctx context.Context, key string, fields map[string]string) error
+		Delete(ctx context.Context, keys ...string) error
+		// Rename атомарно заменяет значение newKey значением key; key должен существовать
+		Rename(ctx context.Context, key, newKey string) error
+		HashIncrBy(ctx context.Context, key, field string, delta int64) (int64, error)
+		HashIncrByFloat(ctx context.Context, key, field string, delta float64) (float64, error)
+		// HashSetMax и HashSetMin атомарно записывают value в поле хеша, если поля нет
+		// или оно меньше (больше) value; сообщают, записано ли значение
+		HashSetMax(ctx context.Context, key, field string, value float64) (bool, error)
+		HashSetMin(ctx context.Context, key, field string, value float64) (bool, error)
+		HashDelete(ctx context.Context, key string, fields ...string) error
+		SetAdd(ctx context.Context, key string, members ...string) (int64, error)
+		SetIsMember(ctx context.Context, key, member string) (bool, error)
+		SetRemove(ctx context.Context, key string, members ...string) (int64, error)
+		// SetLinkAdd и SetLinkRemove атомарно добавляют или удаляют связь в обоих множествах
+		// и меняют её счётчики на 1. Если связь уже в нужном состоянии, ничего не меняется
+		// и возвращается false.
+		SetLinkAdd(ctx context.Context, link entities.SetLink) (bool, error)
+		SetLinkRemove(ctx context.Context, link entities.SetLink) (bool, error)
+		SortedSetAdd(ctx context.Context, key, member string, score float64) error
+		SortedSetRemove(ctx context.Context, key string, members ...string) error
+		SortedSetRevRange(ctx context.Context, key string, start, stop int64) ([]entities.ScoredMember, error)
+		SortedSetRevRangeByScore(ctx context.Context, key string, min, max float64, offset, count int64) ([]entities.ScoredMember, error)
+		SortedSetRemoveRangeByScore(ctx context.Context, key string, min, max float64) error
+		SortedSetRevRank(ctx context.Context, key, member string) (int64, float64, error)
+		// SortedSetRevRankMany читает позиции и веса нескольких элементов за один запрос к хранилищу;
+		// результаты идут в порядке элементов, отсутствующему элементу соответствует позиция -1
+		SortedSetRevRankMany(ctx context.Context, key string, members ...string) ([]int64, []float64, error)
+		SortedSetCard(ctx context.Context, key string) (int64, error)
+		HyperLogLogAdd(ctx context.Context, key string, elements ...string) error
+		HyperLogLogCount(ctx context.Context, keys ...string) (int64, error)
+		HyperLogLogMerge(ctx context.Context, dest string, keys ...string) error
+		Expire(ctx context.Context, key string, ttl time.Duration) error
+		ListAppend(ctx context.Context, key string, values ...string) (int64, error)
+		ListRange(ctx context.Context, key string, start, stop int64) ([]string, error)
+		ListLength(ctx context.Context, key string) (int64, error)
 	}
 )
 
 const (
-	Redis StorageType = "redis"
-	// Map   StorageType = "map"
+	Redis  StorageType = "redis"
+	Memory StorageType = "memory"
 )
 
 var client Storage
@@ -45,6 +99,11 @@ func NewStorage(storageType StorageType) (Storage, error) {
 		return client, nil
 	}
 
+	if storageType == Memory {
+		// Хранилище в памяти процесса: данные теряются при перезапуске
+		return memory.NewStorage(), nil
+	}
+
 	// Если тип хранилища не поддерживается или не указан
 	logger.Log.Warn("Storage type not supported or not specified", zap.String("storageType", string(storageType)))
 	return nil, fmt.Errorf("storage type '%s' is not supported", storageType)
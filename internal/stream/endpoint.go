@@ -0,0 +1,179 @@
+package stream
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxChats наибольшее число чатов в одной подписке
+	maxChats = 1000
+
+	// writeWindow время на отправку одного события; срок записи продлевается перед каждым событием,
+	// поэтому общий WriteTimeout сервера не обрывает поток
+	writeWindow = 30 * time.Second
+
+	// retryDelay через сколько миллисекунд EventSource переподключается после обрыва
+	retryDelay = 3000
+)
+
+// MakeSSEHandler обработчик GET /api/v1/stream?chats=1,2,3 — поток Server-Sent Events.
+// Событие snapshot содержит текущие значения глобальных счётчиков и перечисленных чатов,
+// события counters — обновления после каждого изменения состава чатов; поле chat в них есть
+// только для перечисленных чатов. Пока событий нет, раз в heartbeat отправляется комментарий.
+// С заголовком Last-Event-ID поток продолжается с пропущенных событий; если их уже нет в буфере,
+// вместо них снова приходит snapshot.
+func MakeSSEHandler(hub *Hub, heartbeat time.Duration) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chats, err := parseChats(r.URL.Query().Get("chats"))
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		subscribed := make(map[entities.ChatID]struct{}, len(chats))
+		for _, id := range chats {
+			subscribed[id] = struct{}{}
+		}
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		sub, backlog, err := hub.Subscribe(func(id entities.ChatID) bool {
+			_, ok := subscribed[id]
+			return ok
+		}, lastEventID)
+		if err != nil {
+			utils.RespondWithError(w, http.StatusServiceUnavailable, "server is shutting down")
+			return
+		}
+		defer hub.Unsubscribe(sub)
+
+		var snapshot *Snapshot
+		if lastEventID == "" || backlog.Missed {
+			snapshot, err = hub.Snapshot(r.Context(), chats)
+			if err != nil {
+				logger.Log.Error("Failed to read stream snapshot", zap.Error(err))
+				utils.RespondWith500(w)
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// Отключает буферизацию ответа в nginx
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		out := &sseWriter{w: w, rc: http.NewResponseController(w)}
+		out.retry(retryDelay)
+		if snapshot != nil {
+			out.event(backlog.LastID, "snapshot", snapshot)
+		}
+		for _, event := range backlog.Events {
+			out.event(event.ID, "counters", event.Update)
+		}
+		if out.flush() != nil {
+			return
+		}
+
+		ticker := time.NewTicker(heartbeat)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					if errors.Is(sub.Err(), ErrLagged) {
+						logger.Log.Warn("Dropping slow stream subscriber", zap.String("remote", r.RemoteAddr))
+					}
+					return
+				}
+				out.event(event.ID, "counters", event.Update)
+			case <-ticker.C:
+				out.comment("heartbeat")
+			}
+			if out.flush() != nil {
+				return
+			}
+		}
+	}
+}
+
+// sseWriter пишет события в формате text/event-stream; первая ошибка записи сохраняется,
+// и последующие записи пропускаются
+type sseWriter struct {
+	w   http.ResponseWriter
+	rc  *http.ResponseController
+	err error
+}
+
+func (s *sseWriter) retry(ms int) {
+	s.write("retry: " + strconv.Itoa(ms) + "\n\n")
+}
+
+func (s *sseWriter) event(id, name string, payload any) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		s.err = err
+		return
+	}
+	var b strings.Builder
+	if id != "" {
+		b.WriteString("id: " + id + "\n")
+	}
+	b.WriteString("event: " + name + "\n")
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	s.write(b.String())
+}
+
+func (s *sseWriter) comment(text string) {
+	s.write(": " + text + "\n\n")
+}
+
+func (s *sseWriter) write(chunk string) {
+	if s.err != nil {
+		return
+	}
+	// Не все ResponseWriter умеют продлевать срок записи; поток продолжается и без этого
+	_ = s.rc.SetWriteDeadline(time.Now().Add(writeWindow))
+	_, s.err = fmt.Fprint(s.w, chunk)
+}
+
+func (s *sseWriter) flush() error {
+	if s.err == nil {
+		s.err = s.rc.Flush()
+	}
+	return s.err
+}
+
+// parseChats разбирает список идентификаторов чатов через запятую
+func parseChats(raw string) ([]entities.ChatID, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxChats {
+		return nil, fmt.Errorf("at most %d chats can be subscribed", maxChats)
+	}
+	ids := make([]entities.ChatID, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("chats must be a comma-separated list of chat IDs, got %q", part)
+		}
+		ids = append(ids, entities.ChatID(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,322 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+	"stats-of/internal/timeseries"
+)
+
+// subscriberBuffer сколько событий может ждать отправки одному подписчику. Подписчик,
+// отставший сильнее, отключается и продолжает с Last-Event-ID из буфера хаба.
+const subscriberBuffer = 256
+
+var (
+	// ErrClosed хаб остановлен вместе с сервером
+	ErrClosed = errors.New("stream is closed")
+	// ErrLagged подписчик не успевал забирать события
+	ErrLagged = errors.New("subscriber is too slow")
+)
+
+type (
+	// Global глобальные счётчики после события
+	Global struct {
+		Chats       int64 `json:"chats"`
+		Users       int64 `json:"users"`
+		Memberships int64 `json:"memberships"`
+	}
+
+	// ChatCounter число участников чата после события
+	ChatCounter struct {
+		ChatID       entities.ChatID `json:"chat_id"`
+		CountOfUsers int64           `json:"count_of_users"`
+		// Delta изменение числа участников: 1 или -1
+		Delta int64 `json:"delta"`
+	}
+
+	// Update изменение счётчиков после одного события участия. Значения абсолютные,
+	// поэтому повторно полученное обновление применяется без искажений.
+	Update struct {
+		// Seq номер события в журнале
+		Seq    int64        `json:"seq"`
+		Time   time.Time    `json:"time"`
+		Global Global       `json:"global"`
+		Chat   *ChatCounter `json:"chat,omitempty"`
+	}
+
+	// Event обновление с идентификатором для возобновления потока
+	Event struct {
+		ID     string
+		Update Update
+	}
+
+	// Snapshot текущие значения счётчиков для начала потока
+	Snapshot struct {
+		Time   time.Time       `json:"time"`
+		Global Global          `json:"global"`
+		Chats  []entities.Chat `json:"chats"`
+	}
+
+	// Filter отбирает чаты, обновления которых нужны подписчику; глобальные счётчики получают все
+	Filter func(entities.ChatID) bool
+
+	// Backlog положение новой подписки в потоке
+	Backlog struct {
+		// Events события после Last-Event-ID, которые клиент пропустил
+		Events []Event
+		// Missed часть пропущенных событий уже вытеснена из буфера или Last-Event-ID выдан
+		// до перезапуска: клиенту нужно заново получить текущее состояние
+		Missed bool
+		// LastID идентификатор последнего события на момент подписки
+		LastID string
+	}
+
+	// Subscription поток событий одного клиента
+	Subscription struct {
+		events chan Event
+		filter Filter
+		err    error
+	}
+
+	entry struct {
+		id     uint64
+		update Update
+	}
+
+	// Hub получает изменения участия, хранит последние обновления в кольцевом буфере
+	// и рассылает их подписчикам. Идентификаторы событий имеют вид {epoch}-{n}, где epoch
+	// отличает запуски процесса: буфер живёт только в памяти.
+	Hub struct {
+		repo     *storage.Repository
+		counters *timeseries.Service
+		epoch    string
+
+		mu     sync.Mutex
+		buffer []entry
+		start  int
+		size   int
+		last   uint64
+		// seqs номер в журнале последнего опубликованного события каждого чата
+		seqs        map[entities.ChatID]int64
+		subscribers map[*Subscription]struct{}
+		closed      bool
+	}
+)
+
+// NewHub создаёт хаб с буфером на capacity последних обновлений. Хаб должен быть подписан
+// на изменения участия после сервиса временных рядов: он читает уже обновлённые глобальные счётчики.
+func NewHub(repo *storage.Repository, counters *timeseries.Service, capacity int) *Hub {
+	return &Hub{
+		repo:        repo,
+		counters:    counters,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:      make([]entry, capacity),
+		seqs:        make(map[entities.ChatID]int64),
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// OnChange публикует обновление счётчиков после изменения состава чата. membership.Service
+// уведомляет в порядке журнала; если изменение чата всё же пришло после более позднего изменения
+// того же чата, оно не публикуется: его значение уже устарело, а счётчики абсолютные.
+// Опоздавшие изменения других чатов публикуются.
+func (h *Hub) OnChange(ctx context.Context, change *membership.Change) error {
+	if !change.MembershipChanged {
+		return nil
+	}
+
+	delta := int64(1)
+	if change.Event.Type.Removes() {
+		delta = -1
+	}
+	return h.publish(ctx, Update{
+		Seq:  change.Seq,
+		Time: change.Event.Time,
+		Chat: &ChatCounter{
+			ChatID:       change.Chat.ChatID,
+			CountOfUsers: change.Chat.CountOfUsers,
+			Delta:        delta,
+		},
+	})
+}
+
+// Snapshot возвращает глобальные счётчики и перечисленные чаты; отсутствующих чатов в ответе нет
+func (h *Hub) Snapshot(ctx context.Context, chatIDs []entities.ChatID) (*Snapshot, error) {
+	global, err := h.global(ctx)
+	if err != nil {
+		return nil, err
+	}
+	found, err := h.repo.Chats(ctx, chatIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{Time: time.Now().UTC(), Global: global, Chats: make([]entities.Chat, 0, len(found))}
+	for _, id := range chatIDs {
+		if chat, ok := found[id]; ok {
+			snapshot.Chats = append(snapshot.Chats, *chat)
+		}
+	}
+	return snapshot, nil
+}
+
+// Subscribe регистрирует подписчика и возвращает события после lastEventID, если он указан.
+// Подписка и выборка из буфера выполняются атомарно, поэтому между ними события не теряются.
+func (h *Hub) Subscribe(filter Filter, lastEventID string) (*Subscription, Backlog, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, Backlog{}, ErrClosed
+	}
+
+	var backlog Backlog
+	if h.last > 0 {
+		backlog.LastID = h.id(h.last)
+	}
+	if lastEventID != "" {
+		after, ok := h.parseID(lastEventID)
+		if !ok || after > h.last || after+1 < h.oldest() {
+			backlog.Missed = true
+		} else {
+			for i := 0; i < h.size; i++ {
+				e := h.buffer[(h.start+i)%len(h.buffer)]
+				if e.id > after {
+					backlog.Events = append(backlog.Events, Event{ID: h.id(e.id), Update: e.update.filter(filter)})
+				}
+			}
+		}
+	}
+
+	sub := &Subscription{events: make(chan Event, subscriberBuffer), filter: filter}
+	h.subscribers[sub] = struct{}{}
+	return sub, backlog, nil
+}
+
+// Unsubscribe отключает подписчика; повторный вызов ничего не делает
+func (h *Hub) Unsubscribe(sub *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(sub, nil)
+}
+
+// Close отключает всех подписчиков и перестаёт принимать новых. Вызывается до остановки
+// HTTP-сервера, иначе открытые потоки не дали бы ему завершиться.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subscribers {
+		h.drop(sub, ErrClosed)
+	}
+}
+
+// Events канал событий подписки; закрывается при отключении подписчика
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Err причина закрытия канала событий: ErrClosed, ErrLagged или nil после Unsubscribe
+func (s *Subscription) Err() error {
+	return s.err
+}
+
+// publish рассылает обновление, если оно новее опубликованных обновлений того же чата.
+// Глобальные счётчики читаются под h.mu, чтобы опубликованные значения не шли назад.
+func (h *Hub) publish(ctx context.Context, update Update) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil
+	}
+	if update.Chat != nil && update.Seq <= h.seqs[update.Chat.ChatID] {
+		return nil
+	}
+	global, err := h.global(ctx)
+	if err != nil {
+		return err
+	}
+	update.Global = global
+	if update.Chat != nil {
+		h.seqs[update.Chat.ChatID] = update.Seq
+	}
+	h.last++
+	if len(h.buffer) > 0 {
+		if h.size < len(h.buffer) {
+			h.size++
+		} else {
+			h.start = (h.start + 1) % len(h.buffer)
+		}
+		h.buffer[(h.start+h.size-1)%len(h.buffer)] = entry{id: h.last, update: update}
+	}
+
+	id := h.id(h.last)
+	for sub := range h.subscribers {
+		select {
+		case sub.events <- Event{ID: id, Update: update.filter(sub.filter)}:
+		default:
+			h.drop(sub, ErrLagged)
+		}
+	}
+	return nil
+}
+
+// drop удаляет подписчика и закрывает его канал; вызывается под h.mu
+func (h *Hub) drop(sub *Subscription, reason error) {
+	if _, ok := h.subscribers[sub]; !ok {
+		return
+	}
+	delete(h.subscribers, sub)
+	sub.err = reason
+	close(sub.events)
+}
+
+// oldest номер самого старого события в буфере; вызывается под h.mu
+func (h *Hub) oldest() uint64 {
+	if h.size == 0 {
+		return h.last + 1
+	}
+	return h.buffer[h.start].id
+}
+
+func (h *Hub) id(n uint64) string {
+	return h.epoch + "-" + strconv.FormatUint(n, 10)
+}
+
+func (h *Hub) parseID(id string) (uint64, bool) {
+	epoch, raw, ok := strings.Cut(id, "-")
+	if !ok || epoch != h.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(raw, 10, 64)
+	return n, err == nil
+}
+
+func (h *Hub) global(ctx context.Context) (Global, error) {
+	counters, err := h.counters.Counters(ctx)
+	if err != nil {
+		return Global{}, err
+	}
+	return Global{
+		Chats:       counters[timeseries.SeriesChats],
+		Users:       counters[timeseries.SeriesUsers],
+		Memberships: counters[timeseries.SeriesMemberships],
+	}, nil
+}
+
+// filter убирает из обновления чат, на который подписчик не подписан
+func (u Update) filter(f Filter) Update {
+	if u.Chat != nil && (f == nil || !f(u.Chat.ChatID)) {
+		u.Chat = nil
+	}
+	return u
+}
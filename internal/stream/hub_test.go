@@ -0,0 +1,69 @@
+package stream
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+	"stats-of/internal/timeseries"
+)
+
+func newTestHub(t *testing.T) *Hub {
+	t.Helper()
+	store := memtest.NewStorage(t)
+	counters := timeseries.NewService(store, timeseries.Retention{Minute: time.Hour, Hour: time.Hour, Day: time.Hour})
+	return NewHub(storage.NewRepository(store), counters, 16)
+}
+
+func joined(seq int64, chatID entities.ChatID, count int64) *membership.Change {
+	return &membership.Change{
+		Seq:               seq,
+		Event:             membership.Event{Type: membership.EventJoin, ChatID: chatID, Time: time.Now().UTC()},
+		Chat:              entities.Chat{ChatID: chatID, CountOfUsers: count},
+		MembershipChanged: true,
+	}
+}
+
+// TestHubDropsOutOfOrderChanges проверяет, что устаревшее изменение чата, пришедшее после более
+// позднего изменения того же чата, не публикуется, а опоздавшие изменения других чатов публикуются
+func TestHubDropsOutOfOrderChanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []*membership.Change
+		want    []int64
+	}{
+		{"one chat", []*membership.Change{joined(2, 1, 2), joined(1, 1, 1), joined(3, 1, 3)}, []int64{2, 3}},
+		{"two chats", []*membership.Change{joined(2, 1, 2), joined(1, 2, 1), joined(3, 2, 2), joined(4, 1, 3)}, []int64{2, 1, 3, 4}},
+		{"late change of another chat", []*membership.Change{joined(3, 1, 1), joined(2, 2, 5), joined(1, 1, 7)}, []int64{3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			hub := newTestHub(t)
+			sub, _, err := hub.Subscribe(func(entities.ChatID) bool { return true }, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, change := range tt.changes {
+				if err := hub.OnChange(ctx, change); err != nil {
+					t.Fatal(err)
+				}
+			}
+			hub.Close()
+
+			var seqs []int64
+			for event := range sub.Events() {
+				seqs = append(seqs, event.Update.Seq)
+			}
+			if !slices.Equal(seqs, tt.want) {
+				t.Fatalf("published seqs %v, want %v", seqs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package stream
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+const (
+	wsWriteWait      = 10 * time.Second
+	wsPongWait       = 60 * time.Second
+	wsPingPeriod     = wsPongWait * 9 / 10
+	wsMaxMessageSize = 4096
+	wsMaxPatternLen  = 32
+
+	// wsMaxPending сколько разных чатов может ждать отправки одному клиенту. Пока клиент читает
+	// медленно, дельты одного чата складываются; клиент, у которого накопилось больше чатов,
+	// отключается с кодом 1013.
+	wsMaxPending = 10000
+
+	MessageSubscribe     = "subscribe"
+	MessageUnsubscribe   = "unsubscribe"
+	MessageSubscriptions = "subscriptions"
+	MessageDeltas        = "deltas"
+	MessageError         = "error"
+)
+
+var upgrader = websocket.Upgrader{ReadBufferSize: 1024, WriteBufferSize: 4096}
+
+type (
+	// ClientMessage команда клиента: subscribe или unsubscribe. Шаблоны сопоставляются
+	// с десятичной записью идентификатора чата по правилам path.Match, например "12*" или "*".
+	ClientMessage struct {
+		Type     string            `json:"type"`
+		ChatIDs  []entities.ChatID `json:"chat_ids,omitempty"`
+		Patterns []string          `json:"patterns,omitempty"`
+	}
+
+	// ServerMessage сообщение сервера: subscriptions — текущий набор подписок после команды,
+	// deltas — изменения числа участников с прошлой отправки, error — отклонённая команда
+	ServerMessage struct {
+		Type     string            `json:"type"`
+		ChatIDs  []entities.ChatID `json:"chat_ids,omitempty"`
+		Patterns []string          `json:"patterns,omitempty"`
+		// Seq номер последнего учтённого в дельтах события журнала
+		Seq    int64         `json:"seq,omitempty"`
+		Deltas []ChatCounter `json:"deltas,omitempty"`
+		Error  string        `json:"error,omitempty"`
+	}
+
+	// watchlist чаты и шаблоны, на которые подписано соединение
+	watchlist struct {
+		mu       sync.RWMutex
+		limit    int
+		ids      map[entities.ChatID]struct{}
+		patterns map[string]struct{}
+	}
+
+	// pending дельты, полученные из хаба и ещё не отправленные клиенту: по одной записи на чат
+	pending struct {
+		mu     sync.Mutex
+		chats  map[entities.ChatID]*ChatCounter
+		seq    int64
+		ready  chan struct{}
+		closed chan struct{}
+		err    error
+	}
+)
+
+// MakeWebSocketHandler обработчик GET /api/v1/stream/ws. Клиент управляет подписками командами
+// ClientMessage, сервер присылает ServerMessage. Дельты одного чата, накопившиеся, пока клиент
+// не успевал читать, объединяются в одну. Сервер пингует соединение и закрывает его, если pong
+// не приходит дольше wsPongWait. У одного клиента не больше maxSubscriptions чатов и шаблонов.
+func MakeWebSocketHandler(hub *Hub, maxSubscriptions int) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// При ошибке Upgrade сам отвечает клиенту кодом 4xx
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			logger.Log.Debug("WebSocket upgrade failed", zap.Error(err))
+			return
+		}
+		defer conn.Close()
+
+		watch := &watchlist{
+			limit:    maxSubscriptions,
+			ids:      make(map[entities.ChatID]struct{}),
+			patterns: make(map[string]struct{}),
+		}
+		sub, _, err := hub.Subscribe(watch.matches, "")
+		if err != nil {
+			closeWith(conn, websocket.CloseGoingAway, "server is shutting down")
+			return
+		}
+		defer hub.Unsubscribe(sub)
+
+		queue := &pending{
+			chats:  make(map[entities.ChatID]*ChatCounter),
+			ready:  make(chan struct{}, 1),
+			closed: make(chan struct{}),
+		}
+		go queue.pump(sub)
+
+		replies := make(chan ServerMessage, 16)
+		readerDone := make(chan struct{})
+		writerDone := make(chan struct{})
+		defer close(writerDone)
+		go func() {
+			defer close(readerDone)
+			readCommands(conn, watch, replies, writerDone)
+		}()
+
+		ticker := time.NewTicker(wsPingPeriod)
+		defer ticker.Stop()
+		for {
+			var msg *ServerMessage
+			select {
+			case <-readerDone:
+				return
+			case <-queue.closed:
+				if errors.Is(queue.err, ErrClosed) {
+					closeWith(conn, websocket.CloseGoingAway, "server is shutting down")
+				} else {
+					logger.Log.Warn("Dropping slow WebSocket client", zap.String("remote", r.RemoteAddr))
+					closeWith(conn, websocket.CloseTryAgainLater, "client is too slow")
+				}
+				return
+			case reply := <-replies:
+				msg = &reply
+			case <-queue.ready:
+				msg = queue.take(watch)
+				if msg == nil {
+					continue
+				}
+			case <-ticker.C:
+				_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+				continue
+			}
+
+			_ = conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				logger.Log.Debug("WebSocket write failed", zap.String("remote", r.RemoteAddr), zap.Error(err))
+				return
+			}
+		}
+	}
+}
+
+// readCommands читает команды клиента до ошибки чтения или закрытия соединения.
+// Ответы передаются пишущей горутине, пока она не завершилась.
+func readCommands(conn *websocket.Conn, watch *watchlist, replies chan<- ServerMessage, writerDone <-chan struct{}) {
+	reply := func(msg ServerMessage) bool {
+		select {
+		case replies <- msg:
+			return true
+		case <-writerDone:
+			return false
+		}
+	}
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	_ = conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		var cmd ClientMessage
+		if err := conn.ReadJSON(&cmd); err != nil {
+			var syntax *json.SyntaxError
+			var unmarshal *json.UnmarshalTypeError
+			if (errors.As(err, &syntax) || errors.As(err, &unmarshal)) && reply(ServerMessage{Type: MessageError, Error: "invalid message: " + err.Error()}) {
+				continue
+			}
+			return
+		}
+
+		var err error
+		switch cmd.Type {
+		case MessageSubscribe:
+			err = watch.add(cmd.ChatIDs, cmd.Patterns)
+		case MessageUnsubscribe:
+			watch.remove(cmd.ChatIDs, cmd.Patterns)
+		default:
+			err = fmt.Errorf("unknown message type %q; expected subscribe or unsubscribe", cmd.Type)
+		}
+		if err != nil {
+			if !reply(ServerMessage{Type: MessageError, Error: err.Error()}) {
+				return
+			}
+			continue
+		}
+		if !reply(watch.state()) {
+			return
+		}
+	}
+}
+
+func closeWith(conn *websocket.Conn, code int, reason string) {
+	_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+}
+
+// add добавляет подписки целиком или отклоняет команду, если она превышает лимит
+func (w *watchlist) add(ids []entities.ChatID, patterns []string) error {
+	for _, pattern := range patterns {
+		if len(pattern) > wsMaxPatternLen {
+			return fmt.Errorf("pattern %q is longer than %d characters", pattern, wsMaxPatternLen)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	newIDs := make(map[entities.ChatID]struct{})
+	for _, id := range ids {
+		if _, ok := w.ids[id]; !ok {
+			newIDs[id] = struct{}{}
+		}
+	}
+	newPatterns := make(map[string]struct{})
+	for _, pattern := range patterns {
+		if _, ok := w.patterns[pattern]; !ok {
+			newPatterns[pattern] = struct{}{}
+		}
+	}
+	if len(w.ids)+len(w.patterns)+len(newIDs)+len(newPatterns) > w.limit {
+		return fmt.Errorf("subscription limit of %d chats and patterns exceeded", w.limit)
+	}
+
+	for id := range newIDs {
+		w.ids[id] = struct{}{}
+	}
+	for pattern := range newPatterns {
+		w.patterns[pattern] = struct{}{}
+	}
+	return nil
+}
+
+func (w *watchlist) remove(ids []entities.ChatID, patterns []string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, id := range ids {
+		delete(w.ids, id)
+	}
+	for _, pattern := range patterns {
+		delete(w.patterns, pattern)
+	}
+}
+
+// matches фильтр подписки в хабе
+func (w *watchlist) matches(id entities.ChatID) bool {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if _, ok := w.ids[id]; ok {
+		return true
+	}
+	if len(w.patterns) == 0 {
+		return false
+	}
+	decimal := strconv.FormatInt(int64(id), 10)
+	for pattern := range w.patterns {
+		if ok, _ := path.Match(pattern, decimal); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *watchlist) state() ServerMessage {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	msg := ServerMessage{
+		Type:     MessageSubscriptions,
+		ChatIDs:  make([]entities.ChatID, 0, len(w.ids)),
+		Patterns: make([]string, 0, len(w.patterns)),
+	}
+	for id := range w.ids {
+		msg.ChatIDs = append(msg.ChatIDs, id)
+	}
+	for pattern := range w.patterns {
+		msg.Patterns = append(msg.Patterns, pattern)
+	}
+	slices.Sort(msg.ChatIDs)
+	slices.Sort(msg.Patterns)
+	return msg
+}
+
+// pump переносит события из хаба в очередь, складывая дельты одного чата. Канал хаба
+// при этом не переполняется, даже если запись клиенту временно заблокирована.
+func (p *pending) pump(sub *Subscription) {
+	for event := range sub.Events() {
+		chat := event.Update.Chat
+		if chat == nil {
+			continue
+		}
+
+		full := false
+		p.mu.Lock()
+		if queued, ok := p.chats[chat.ChatID]; ok {
+			queued.CountOfUsers = chat.CountOfUsers
+			queued.Delta += chat.Delta
+		} else if len(p.chats) < wsMaxPending {
+			queued := *chat
+			p.chats[chat.ChatID] = &queued
+		} else {
+			full = true
+		}
+		// Опоздавшее обновление другого чата не уменьшает номер
+		p.seq = max(p.seq, event.Update.Seq)
+		p.mu.Unlock()
+
+		if full {
+			p.err = ErrLagged
+			close(p.closed)
+			return
+		}
+		select {
+		case p.ready <- struct{}{}:
+		default:
+		}
+	}
+	p.err = sub.Err()
+	close(p.closed)
+}
+
+// take забирает накопленные дельты чатов, на которые клиент всё ещё подписан
+func (p *pending) take(watch *watchlist) *ServerMessage {
+	p.mu.Lock()
+	chats := p.chats
+	seq := p.seq
+	p.chats = make(map[entities.ChatID]*ChatCounter)
+	p.mu.Unlock()
+
+	msg := &ServerMessage{Type: MessageDeltas, Seq: seq}
+	for id, counter := range chats {
+		if counter.Delta != 0 && watch.matches(id) {
+			msg.Deltas = append(msg.Deltas, *counter)
+		}
+	}
+	if len(msg.Deltas) == 0 {
+		return nil
+	}
+	slices.SortFunc(msg.Deltas, func(a, b ChatCounter) int { return cmp.Compare(a.ChatID, b.ChatID) })
+	return msg
+}
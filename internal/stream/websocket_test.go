@@ -0,0 +1,280 @@
+package stream
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/membership"
+
+	"github.com/gorilla/websocket"
+)
+
+const wsTestTimeout = 5 * time.Second
+
+// newTestWebSocket запускает обработчик на тестовом сервере и подключает к нему клиента
+func newTestWebSocket(t *testing.T, maxSubscriptions int) (*Hub, *websocket.Conn) {
+	t.Helper()
+	hub := newTestHub(t)
+	server := httptest.NewServer(http.HandlerFunc(MakeWebSocketHandler(hub, maxSubscriptions)))
+	t.Cleanup(server.Close)
+	t.Cleanup(hub.Close)
+
+	dialer := websocket.Dialer{
+		HandshakeTimeout: wsTestTimeout,
+		// Маленький буфер приёма, чтобы клиент, который не читает, быстро заполнял соединение
+		NetDial: func(network, addr string) (net.Conn, error) {
+			conn, err := net.Dial(network, addr)
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				_ = tcp.SetReadBuffer(4096)
+			}
+			return conn, err
+		},
+	}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return hub, conn
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg ClientMessage) {
+	t.Helper()
+	if err := conn.WriteJSON(msg); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func receive(t *testing.T, conn *websocket.Conn) ServerMessage {
+	t.Helper()
+	_ = conn.SetReadDeadline(time.Now().Add(wsTestTimeout))
+	var msg ServerMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+// command отправляет команду и возвращает ответ на неё
+func command(t *testing.T, conn *websocket.Conn, msg ClientMessage) ServerMessage {
+	t.Helper()
+	send(t, conn, msg)
+	return receive(t, conn)
+}
+
+func left(seq int64, chatID entities.ChatID, count int64) *membership.Change {
+	change := joined(seq, chatID, count)
+	change.Event.Type = membership.EventLeave
+	return change
+}
+
+func publish(t *testing.T, hub *Hub, changes ...*membership.Change) {
+	t.Helper()
+	for _, change := range changes {
+		if err := hub.OnChange(context.Background(), change); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestWebSocketSubscriptionCap проверяет, что команда сверх лимита подписок отклоняется целиком,
+// а повторы и отписки учитываются в лимите
+func TestWebSocketSubscriptionCap(t *testing.T) {
+	_, conn := newTestWebSocket(t, 3)
+
+	steps := []struct {
+		name     string
+		msg      ClientMessage
+		ids      []entities.ChatID
+		patterns []string
+		err      string
+	}{
+		{name: "within limit", msg: ClientMessage{Type: MessageSubscribe, ChatIDs: []entities.ChatID{2, 1}},
+			ids: []entities.ChatID{1, 2}},
+		{name: "over limit", msg: ClientMessage{Type: MessageSubscribe, ChatIDs: []entities.ChatID{2, 3}, Patterns: []string{"4*"}},
+			err: "subscription limit of 3 chats and patterns exceeded"},
+		{name: "repeats are free", msg: ClientMessage{Type: MessageSubscribe, ChatIDs: []entities.ChatID{1, 2, 2}, Patterns: []string{"4*"}},
+			ids: []entities.ChatID{1, 2}, patterns: []string{"4*"}},
+		{name: "full", msg: ClientMessage{Type: MessageSubscribe, ChatIDs: []entities.ChatID{3}},
+			err: "subscription limit of 3 chats and patterns exceeded"},
+		{name: "unsubscribe frees a slot", msg: ClientMessage{Type: MessageUnsubscribe, ChatIDs: []entities.ChatID{1, 9}},
+			ids: []entities.ChatID{2}, patterns: []string{"4*"}},
+		{name: "reuse the slot", msg: ClientMessage{Type: MessageSubscribe, Patterns: []string{"-1?"}},
+			ids: []entities.ChatID{2}, patterns: []string{"-1?", "4*"}},
+		{name: "unknown type", msg: ClientMessage{Type: MessageUnsubscribe + "x"},
+			err: `unknown message type "unsubscribex"`},
+		{name: "malformed pattern", msg: ClientMessage{Type: MessageSubscribe, Patterns: []string{"["}},
+			err: `invalid pattern "["`},
+		{name: "long pattern", msg: ClientMessage{Type: MessageSubscribe, Patterns: []string{strings.Repeat("1", wsMaxPatternLen+1)}},
+			err: "pattern " + `"` + strings.Repeat("1", wsMaxPatternLen+1) + `" is longer than 32 characters`},
+	}
+	for _, step := range steps {
+		reply := command(t, conn, step.msg)
+		if step.err != "" {
+			if reply.Type != MessageError || !strings.HasPrefix(reply.Error, step.err) {
+				t.Errorf("%s: reply %+v, want error %q", step.name, reply, step.err)
+			}
+			continue
+		}
+		if reply.Type != MessageSubscriptions || !reflect.DeepEqual(reply.ChatIDs, step.ids) || !reflect.DeepEqual(reply.Patterns, step.patterns) {
+			t.Errorf("%s: reply %+v, want chats %v and patterns %v", step.name, reply, step.ids, step.patterns)
+		}
+	}
+
+	// Неверное сообщение отклоняется, но соединение остаётся открытым
+	for _, raw := range []string{"[}", `{"type": 1}`} {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(raw)); err != nil {
+			t.Fatal(err)
+		}
+		if reply := receive(t, conn); reply.Type != MessageError || !strings.HasPrefix(reply.Error, "invalid message") {
+			t.Errorf("reply to %s: %+v, want invalid message error", raw, reply)
+		}
+	}
+	if reply := command(t, conn, ClientMessage{Type: MessageSubscribe}); reply.Type != MessageSubscriptions {
+		t.Errorf("reply after invalid messages %+v, want subscriptions", reply)
+	}
+}
+
+// TestWebSocketPatternMatching проверяет, что клиент получает дельты только чатов из подписки
+// и чатов, десятичная запись которых подходит под шаблон
+func TestWebSocketPatternMatching(t *testing.T) {
+	hub, conn := newTestWebSocket(t, 10)
+	command(t, conn, ClientMessage{Type: MessageSubscribe, ChatIDs: []entities.ChatID{5}, Patterns: []string{"12*", "-1?"}})
+
+	ids := []entities.ChatID{7, 5, 312, 12, -150, 123, -15, 21, -1, 1200}
+	want := map[entities.ChatID]bool{5: true, 12: true, 123: true, -15: true, 1200: true}
+	for i, id := range ids {
+		publish(t, hub, joined(int64(i+1), id, 1))
+	}
+
+	got := make(map[entities.ChatID]bool)
+	for len(got) < len(want) {
+		msg := receive(t, conn)
+		if msg.Type != MessageDeltas {
+			t.Fatalf("unexpected message %+v", msg)
+		}
+		for _, delta := range msg.Deltas {
+			if !want[delta.ChatID] {
+				t.Errorf("received delta of chat %d outside the subscription", delta.ChatID)
+			}
+			got[delta.ChatID] = true
+		}
+	}
+
+	// После отписки шаблон больше не совпадает
+	command(t, conn, ClientMessage{Type: MessageUnsubscribe, Patterns: []string{"12*"}})
+	publish(t, hub, joined(100, 124, 2), joined(101, 5, 2))
+	if msg := receive(t, conn); len(msg.Deltas) != 1 || msg.Deltas[0].ChatID != 5 {
+		t.Errorf("deltas after unsubscribe %+v, want only chat 5", msg.Deltas)
+	}
+}
+
+// TestWebSocketDeltasCoalesce проверяет, что сумма полученных дельт чата равна итоговому изменению,
+// а последнее значение счётчика и номер события — последним опубликованным, сколько бы
+// сообщений сервер ни объединил
+func TestWebSocketDeltasCoalesce(t *testing.T) {
+	hub, conn := newTestWebSocket(t, 10)
+	command(t, conn, ClientMessage{Type: MessageSubscribe, ChatIDs: []entities.ChatID{1, 2}})
+
+	// Чат 1: +5 -2 = 3, чат 2: +1 -1 = 0
+	var seq int64
+	for count := int64(1); count <= 5; count++ {
+		seq++
+		publish(t, hub, joined(seq, 1, count))
+	}
+	seq++
+	publish(t, hub, joined(seq, 2, 1))
+	seq++
+	publish(t, hub, left(seq, 2, 0))
+	for count := int64(4); count >= 3; count-- {
+		seq++
+		publish(t, hub, left(seq, 1, count))
+	}
+
+	sums := make(map[entities.ChatID]int64)
+	counts := make(map[entities.ChatID]int64)
+	var messages int64
+	for lastSeq := int64(0); lastSeq < seq; {
+		msg := receive(t, conn)
+		messages++
+		lastSeq = msg.Seq
+		for _, delta := range msg.Deltas {
+			sums[delta.ChatID] += delta.Delta
+			counts[delta.ChatID] = delta.CountOfUsers
+		}
+	}
+	if sums[1] != 3 || counts[1] != 3 || sums[2] != 0 {
+		t.Errorf("deltas sum to %v with last counts %v; want chat 1: 3 and 3, chat 2: 0", sums, counts)
+	}
+	if messages > seq {
+		t.Errorf("received %d messages for %d events", messages, seq)
+	}
+}
+
+// TestPendingCoalesces проверяет объединение дельт в очереди соединения: по одной записи на чат
+// с суммарной дельтой, без чатов с нулевым итогом и без чатов, от которых клиент отписался.
+// Обновление без чата не учитывается в дельтах и не сдвигает их номер.
+func TestPendingCoalesces(t *testing.T) {
+	watch := &watchlist{limit: 10, ids: make(map[entities.ChatID]struct{}), patterns: make(map[string]struct{})}
+	if err := watch.add([]entities.ChatID{1, 2, 3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	sub := &Subscription{events: make(chan Event, 16)}
+	for i, counter := range []ChatCounter{
+		{ChatID: 1, CountOfUsers: 11, Delta: 1},
+		{ChatID: 2, CountOfUsers: 21, Delta: 1},
+		{ChatID: 1, CountOfUsers: 12, Delta: 1},
+		{ChatID: 2, CountOfUsers: 20, Delta: -1},
+		{ChatID: 3, CountOfUsers: 30, Delta: -1},
+		{ChatID: 1, CountOfUsers: 11, Delta: -1},
+	} {
+		sub.events <- Event{Update: Update{Seq: int64(i + 1), Chat: &counter}}
+	}
+	sub.events <- Event{Update: Update{Seq: 7}}
+	close(sub.events)
+
+	queue := &pending{chats: make(map[entities.ChatID]*ChatCounter), ready: make(chan struct{}, 1), closed: make(chan struct{})}
+	queue.pump(sub)
+	watch.remove([]entities.ChatID{3}, nil)
+
+	msg := queue.take(watch)
+	want := &ServerMessage{Type: MessageDeltas, Seq: 6, Deltas: []ChatCounter{{ChatID: 1, CountOfUsers: 11, Delta: 1}}}
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("take = %+v, want %+v", msg, want)
+	}
+	if msg := queue.take(watch); msg != nil {
+		t.Errorf("second take = %+v, want nothing", msg)
+	}
+}
+
+// TestWebSocketDisconnectsSlowClient проверяет, что клиент, который не читает, пока копятся дельты
+// больше wsMaxPending чатов, отключается с кодом 1013
+func TestWebSocketDisconnectsSlowClient(t *testing.T) {
+	hub, conn := newTestWebSocket(t, 10)
+	command(t, conn, ClientMessage{Type: MessageSubscribe, Patterns: []string{"*"}})
+
+	// Клиент не читает: сервер упирается в заполненное соединение, а чаты копятся в очереди
+	for i := 0; i < 40*wsMaxPending; i++ {
+		publish(t, hub, joined(int64(i+1), entities.ChatID(i), 1))
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(wsWriteWait + wsTestTimeout))
+	for {
+		var msg ServerMessage
+		err := conn.ReadJSON(&msg)
+		if err == nil {
+			continue
+		}
+		if !websocket.IsCloseError(err, websocket.CloseTryAgainLater) {
+			t.Fatalf("connection ended with %v, want close code %d", err, websocket.CloseTryAgainLater)
+		}
+		return
+	}
+}
@@ -0,0 +1,69 @@
+package timeseries
+
+import (
+	"net/http"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// MakeHandler обработчик GET /api/v1/timeseries?series=global:memberships&from=&to=&step=1h.
+// from и to принимаются в RFC 3339 или unix-секундах; по умолчанию — последние сутки.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		series := r.URL.Query().Get("series")
+		if series == "" {
+			utils.RespondWith400(w, "series is required")
+			return
+		}
+		query(w, r, service, series)
+	}
+}
+
+// MakeChatHandler обработчик GET /api/v1/chats/{id}/timeseries?from=&to=&step=1h
+// для ряда числа участников чата
+func MakeChatHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := utils.PathInt64(r, "id")
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+		query(w, r, service, ChatUsersSeries(entities.ChatID(id)))
+	}
+}
+
+func query(w http.ResponseWriter, r *http.Request, service *Service, series string) {
+	to, err := utils.QueryTime(r, "to", time.Time{})
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	from, err := utils.QueryTime(r, "from", time.Time{})
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	step, err := utils.QueryDuration(r, "step", 0)
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+	rng, err := NewRange(from, to, step, time.Now().UTC())
+	if err != nil {
+		utils.RespondWith400(w, err.Error())
+		return
+	}
+
+	result, err := service.Query(r.Context(), series, rng.From, rng.To, rng.Step)
+	if err != nil {
+		logger.Log.Error("Failed to query time series", zap.String("series", series), zap.Error(err))
+		utils.RespondWith500(w)
+		return
+	}
+	utils.SuccessRespondWith200(w, result)
+}
@@ -0,0 +1,23 @@
+package timeseries
+
+import (
+	"context"
+	"sync"
+
+	"stats-of/internal/jobs"
+)
+
+// NewRollupJob создаёт задачу сворачивания и очистки рядов
+func NewRollupJob(service *Service) jobs.Job {
+	return jobs.Func("timeseries-rollup", service.Rollup)
+}
+
+// NewRecountJob создаёт задачу пересчёта глобальных счётчиков; lock останавливает приём событий
+// на время пересчёта (membership.Service.Locker). Планировщик запускает задачу и при старте.
+func NewRecountJob(service *Service, lock sync.Locker) jobs.Job {
+	return jobs.Func("timeseries-recount", func(ctx context.Context) error {
+		lock.Lock()
+		defer lock.Unlock()
+		return service.Recount(ctx)
+	})
+}
@@ -0,0 +1,87 @@
+package timeseries
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Point агрегат значений счётчика за интервал: последнее значение, минимум, максимум и среднее
+type Point struct {
+	Time   time.Time `json:"time"`
+	Last   float64   `json:"last"`
+	Min    float64   `json:"min"`
+	Max    float64   `json:"max"`
+	Avg    float64   `json:"avg"`
+	Filled bool      `json:"filled,omitempty"`
+
+	sum   float64
+	count int64
+}
+
+func newPoint(at time.Time, value float64) Point {
+	return Point{Time: at, Last: value, Min: value, Max: value, Avg: value, sum: value, count: 1}
+}
+
+// merge добавляет к агрегату более поздний агрегат
+func (p *Point) merge(other Point) {
+	if p.count == 0 {
+		t := p.Time
+		*p = other
+		p.Time = t
+		return
+	}
+
+	p.Last = other.Last
+	p.Min = math.Min(p.Min, other.Min)
+	p.Max = math.Max(p.Max, other.Max)
+	p.sum += other.sum
+	p.count += other.count
+	p.Avg = p.sum / float64(p.count)
+}
+
+// Поля хеша интервала
+const (
+	fieldLast  = "last"
+	fieldMin   = "min"
+	fieldMax   = "max"
+	fieldSum   = "sum"
+	fieldCount = "count"
+)
+
+// fields сериализует агрегат в поля хеша интервала
+func (p Point) fields() map[string]string {
+	return map[string]string{
+		fieldLast:  strconv.FormatFloat(p.Last, 'f', -1, 64),
+		fieldMin:   strconv.FormatFloat(p.Min, 'f', -1, 64),
+		fieldMax:   strconv.FormatFloat(p.Max, 'f', -1, 64),
+		fieldSum:   strconv.FormatFloat(p.sum, 'f', -1, 64),
+		fieldCount: strconv.FormatInt(p.count, 10),
+	}
+}
+
+// decodePoint читает агрегат интервала, начинающегося в ts
+func decodePoint(ts int64, fields map[string]string) (Point, error) {
+	count, err := strconv.ParseInt(fields[fieldCount], 10, 64)
+	if err != nil || count <= 0 {
+		return Point{}, fmt.Errorf("invalid count %q of bucket %d", fields[fieldCount], ts)
+	}
+	var numbers [4]float64
+	for i, field := range []string{fieldLast, fieldMin, fieldMax, fieldSum} {
+		numbers[i], err = strconv.ParseFloat(fields[field], 64)
+		if err != nil {
+			return Point{}, fmt.Errorf("invalid %s %q of bucket %d: %w", field, fields[field], ts, err)
+		}
+	}
+
+	return Point{
+		Time:  time.Unix(ts, 0).UTC(),
+		Last:  numbers[0],
+		Min:   numbers[1],
+		Max:   numbers[2],
+		Avg:   numbers[3] / float64(count),
+		sum:   numbers[3],
+		count: count,
+	}, nil
+}
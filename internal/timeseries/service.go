@@ -0,0 +1,509 @@
+package timeseries
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/logger"
+	"stats-of/internal/membership"
+	"stats-of/internal/storage"
+
+	"go.uber.org/zap"
+)
+
+// Схема ключей:
+//
+//	timeseries:series                     множество имён всех рядов
+//	timeseries:{tier}:{series}:buckets    упорядоченное множество начал интервалов (unix) с тем же весом
+//	timeseries:{tier}:{series}:{unix}     хеш агрегата интервала: last, min, max, sum, count
+//	timeseries:global                     хеш глобальных счётчиков memberships, chats, users
+const (
+	seriesRegistryKey = "timeseries:series"
+	globalCountersKey = "timeseries:global"
+	keyPrefix         = "timeseries:"
+
+	// Ряды глобальных счётчиков
+	SeriesMemberships = "global:memberships"
+	SeriesChats       = "global:chats"
+	SeriesUsers       = "global:users"
+
+	// recountBatch сколько чатов или пользователей читается одним запросом при пересчёте
+	recountBatch = 1000
+
+	// rollupLookback сколько интервалов старшего уровня пересчитывается при каждом сворачивании
+	rollupLookback = 2
+
+	// MaxQueryPoints наибольшее число шагов в одном запросе ряда
+	MaxQueryPoints = 10000
+
+	defaultRange = 24 * time.Hour
+	defaultStep  = time.Hour
+)
+
+type (
+	// Tier уровень хранения: разрешение и срок хранения точек
+	Tier struct {
+		Name       string
+		Resolution time.Duration
+		Retention  time.Duration
+	}
+
+	// Retention сроки хранения точек каждого уровня
+	Retention struct {
+		Minute time.Duration
+		Hour   time.Duration
+		Day    time.Duration
+	}
+
+	// Range интервал и шаг запроса ряда
+	Range struct {
+		From time.Time
+		To   time.Time
+		Step time.Duration
+	}
+
+	// Result ответ на запрос диапазона
+	Result struct {
+		Series string    `json:"series"`
+		Tier   string    `json:"tier"`
+		From   time.Time `json:"from"`
+		To     time.Time `json:"to"`
+		Step   string    `json:"step"`
+		Points []Point   `json:"points"`
+	}
+
+	// Service записывает значения счётчиков с минутным разрешением и сворачивает их
+	// в часовые и суточные интервалы с отдельным сроком хранения для каждого уровня
+	Service struct {
+		store storage.Storage
+		tiers []Tier
+	}
+)
+
+// ChatUsersSeries имя ряда числа участников чата
+func ChatUsersSeries(id entities.ChatID) string {
+	return "chat:" + strconv.FormatInt(int64(id), 10) + ":users"
+}
+
+func NewService(store storage.Storage, retention Retention) *Service {
+	return &Service{
+		store: store,
+		tiers: []Tier{
+			{Name: "minute", Resolution: time.Minute, Retention: retention.Minute},
+			{Name: "hour", Resolution: time.Hour, Retention: retention.Hour},
+			{Name: "day", Resolution: 24 * time.Hour, Retention: retention.Day},
+		},
+	}
+}
+
+// Tiers возвращает уровни хранения от мелкого к крупному
+func (s *Service) Tiers() []Tier {
+	return s.tiers
+}
+
+// Record записывает значение счётчика в минутный интервал, содержащий at.
+// Каждое поле агрегата меняется своей атомарной командой, поэтому параллельные записи
+// в один интервал не теряют друг друга. Интервал попадает в индекс последним:
+// читатели не видят его, пока все поля не записаны.
+func (s *Service) Record(ctx context.Context, series string, at time.Time, value float64) error {
+	tier := s.tiers[0]
+	ts := at.UTC().Truncate(tier.Resolution).Unix()
+	key := bucketKey(tier, series, ts)
+
+	if err := s.store.HashSet(ctx, key, map[string]string{fieldLast: strconv.FormatFloat(value, 'f', -1, 64)}); err != nil {
+		return fmt.Errorf("failed to write point of %s: %w", series, err)
+	}
+	if _, err := s.store.HashSetMin(ctx, key, fieldMin, value); err != nil {
+		return fmt.Errorf("failed to write point of %s: %w", series, err)
+	}
+	if _, err := s.store.HashSetMax(ctx, key, fieldMax, value); err != nil {
+		return fmt.Errorf("failed to write point of %s: %w", series, err)
+	}
+	if _, err := s.store.HashIncrByFloat(ctx, key, fieldSum, value); err != nil {
+		return fmt.Errorf("failed to write point of %s: %w", series, err)
+	}
+	if _, err := s.store.HashIncrBy(ctx, key, fieldCount, 1); err != nil {
+		return fmt.Errorf("failed to write point of %s: %w", series, err)
+	}
+	if err := s.store.SortedSetAdd(ctx, indexKey(tier, series), strconv.FormatInt(ts, 10), float64(ts)); err != nil {
+		return fmt.Errorf("failed to index point of %s: %w", series, err)
+	}
+	if _, err := s.store.SetAdd(ctx, seriesRegistryKey, series); err != nil {
+		return fmt.Errorf("failed to register series %s: %w", series, err)
+	}
+	return nil
+}
+
+// OnChange записывает новое число участников чата и глобальные счётчики после изменения состава
+func (s *Service) OnChange(ctx context.Context, change *membership.Change) error {
+	if !change.MembershipChanged {
+		return nil
+	}
+
+	delta := int64(1)
+	if change.Event.Type.Removes() {
+		delta = -1
+	}
+
+	at := change.Event.Time
+	if err := s.Record(ctx, ChatUsersSeries(change.Chat.ChatID), at, float64(change.Chat.CountOfUsers)); err != nil {
+		return err
+	}
+
+	// Чат или пользователь появляются в глобальных счётчиках с первым участием и исчезают с последним
+	counters := map[string]bool{
+		SeriesMemberships: true,
+		SeriesChats:       change.Chat.CountOfUsers == 1 && delta > 0 || change.Chat.CountOfUsers == 0 && delta < 0,
+		SeriesUsers:       change.User.CountOfChats == 1 && delta > 0 || change.User.CountOfChats == 0 && delta < 0,
+	}
+	for series, changed := range counters {
+		if !changed {
+			continue
+		}
+		value, err := s.store.HashIncrBy(ctx, globalCountersKey, series, delta)
+		if err != nil {
+			return fmt.Errorf("failed to update global counter %s: %w", series, err)
+		}
+		if err := s.Record(ctx, series, at, float64(value)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recount пересчитывает глобальные счётчики по чатам и пользователям хранилища и записывает
+// новые значения в их ряды. Между пересчётами счётчики меняются только приращениями в OnChange,
+// поэтому без него они начинались бы с нуля на базе, где уже есть чаты, и расходились бы
+// с хранилищем после пересборки из журнала или исправлений сверки. На время пересчёта приём
+// событий должен быть остановлен, иначе одновременное приращение потерялось бы или учлось дважды.
+func (s *Service) Recount(ctx context.Context) error {
+	repo := storage.NewRepository(s.store)
+	var chats, users, memberships int64
+
+	chatIDs, err := repo.ChatIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(chatIDs); start += recountBatch {
+		found, err := repo.Chats(ctx, chatIDs[start:min(start+recountBatch, len(chatIDs))])
+		if err != nil {
+			return err
+		}
+		for _, chat := range found {
+			if chat.CountOfUsers > 0 {
+				chats++
+				memberships += chat.CountOfUsers
+			}
+		}
+	}
+
+	userIDs, err := repo.UserIDs(ctx)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(userIDs); start += recountBatch {
+		found, err := repo.Users(ctx, userIDs[start:min(start+recountBatch, len(userIDs))])
+		if err != nil {
+			return err
+		}
+		for _, user := range found {
+			if user.CountOfChats > 0 {
+				users++
+			}
+		}
+	}
+
+	counters := map[string]int64{SeriesMemberships: memberships, SeriesChats: chats, SeriesUsers: users}
+	fields := make(map[string]string, len(counters))
+	for series, value := range counters {
+		fields[series] = strconv.FormatInt(value, 10)
+	}
+	if err := s.store.HashSet(ctx, globalCountersKey, fields); err != nil {
+		return fmt.Errorf("failed to write global counters: %w", err)
+	}
+	now := time.Now()
+	for series, value := range counters {
+		if err := s.Record(ctx, series, now, float64(value)); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Info("Global counters recounted", zap.Int64("chats", chats), zap.Int64("users", users), zap.Int64("memberships", memberships))
+	return nil
+}
+
+// Counters текущие значения глобальных счётчиков по именам их рядов
+func (s *Service) Counters(ctx context.Context) (map[string]int64, error) {
+	fields, err := s.store.HashGetAll(ctx, globalCountersKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read global counters: %w", err)
+	}
+
+	counters := make(map[string]int64, len(fields))
+	for series, raw := range fields {
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			logger.Log.Warn("Skipping malformed global counter", zap.String("series", series), zap.String("value", raw))
+			continue
+		}
+		counters[series] = value
+	}
+	return counters, nil
+}
+
+// Rollup сворачивает свежие минутные точки в часовые, часовые — в суточные,
+// и удаляет точки старше срока хранения своего уровня
+func (s *Service) Rollup(ctx context.Context) error {
+	series, err := s.store.SetMembers(ctx, seriesRegistryKey)
+	if err != nil {
+		return fmt.Errorf("failed to list series: %w", err)
+	}
+
+	now := time.Now().UTC()
+	for _, name := range series {
+		for i := 1; i < len(s.tiers); i++ {
+			if err := s.rollupTier(ctx, name, s.tiers[i-1], s.tiers[i], now); err != nil {
+				return err
+			}
+		}
+		for _, tier := range s.tiers {
+			if err := s.trim(ctx, name, tier, now); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Log.Info("Time series rolled up", zap.Int("series", len(series)))
+	return nil
+}
+
+// NewRange подставляет значения по умолчанию вместо нулевых параметров — to = now, from = сутки до to,
+// step = час — и проверяет интервал. Общая проверка для HTTP и gRPC API.
+func NewRange(from, to time.Time, step time.Duration, now time.Time) (Range, error) {
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultRange)
+	}
+	if step == 0 {
+		step = defaultStep
+	}
+	switch {
+	case step < 0:
+		return Range{}, apperrors.InvalidArgument("step must be positive")
+	case !from.Before(to):
+		return Range{}, apperrors.InvalidArgument("from must be before to")
+	case to.Sub(from)/step > MaxQueryPoints:
+		return Range{}, apperrors.InvalidArgument("range is too large for the requested step")
+	}
+	return Range{From: from, To: to, Step: step}, nil
+}
+
+// Query возвращает точки ряда в диапазоне [from, to) с шагом step.
+// Используется самый мелкий уровень, который ещё хранит from и не мельче шага; если такого нет —
+// самый мелкий уровень, который хранит from. Пустые шаги после первой точки заполняются
+// последним известным значением.
+func (s *Service) Query(ctx context.Context, series string, from, to time.Time, step time.Duration) (*Result, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("from must be before to")
+	}
+	if to.Sub(from)/step > MaxQueryPoints {
+		return nil, fmt.Errorf("range contains more than %d steps", MaxQueryPoints)
+	}
+
+	// Шаги выравниваются по границам, кратным step, независимо от переданного from
+	from = from.Truncate(step)
+	tier := s.selectTier(from, step, time.Now().UTC())
+	points, err := s.loadRange(ctx, series, tier, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Series: series, Tier: tier.Name, From: from, To: to, Step: step.String()}
+	result.Points = resample(points, from, to, step)
+	return result, nil
+}
+
+// Points возвращает все сохранённые точки ряда на уровне tier в порядке времени
+func (s *Service) Points(ctx context.Context, series string, tierName string) ([]Point, error) {
+	for _, tier := range s.tiers {
+		if tier.Name == tierName {
+			return s.load(ctx, series, tier, math.Inf(-1), math.Inf(1))
+		}
+	}
+	return nil, fmt.Errorf("unknown tier %q", tierName)
+}
+
+// selectTier выбирает самый мелкий уровень не крупнее step, который хранит from. Если from старше
+// срока хранения всех таких уровней, берётся самый мелкий уровень, который его хранит,
+// даже если он крупнее шага: грубые точки лучше пустого ответа. Если from не хранит
+// ни один уровень, используется самый крупный.
+func (s *Service) selectTier(from time.Time, step time.Duration, now time.Time) Tier {
+	var fallback *Tier
+	for i, tier := range s.tiers {
+		if from.Before(now.Add(-tier.Retention)) {
+			continue
+		}
+		if tier.Resolution <= step {
+			return tier
+		}
+		if fallback == nil {
+			fallback = &s.tiers[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback
+	}
+	return s.tiers[len(s.tiers)-1]
+}
+
+func (s *Service) rollupTier(ctx context.Context, series string, source, target Tier, now time.Time) error {
+	// Читаются только интервалы источника, которые попадают в пересчитываемые интервалы уровня
+	since := now.Truncate(target.Resolution).Add(-rollupLookback * target.Resolution)
+	points, err := s.load(ctx, series, source, float64(since.Unix()), math.Inf(1))
+	if err != nil {
+		return err
+	}
+
+	buckets := make(map[int64]*Point)
+	for _, p := range points {
+		start := p.Time.Truncate(target.Resolution)
+		agg, ok := buckets[start.Unix()]
+		if !ok {
+			agg = &Point{Time: start}
+			buckets[start.Unix()] = agg
+		}
+		agg.merge(p)
+	}
+
+	index := indexKey(target, series)
+	for ts, agg := range buckets {
+		if err := s.store.HashSet(ctx, bucketKey(target, series, ts), agg.fields()); err != nil {
+			return fmt.Errorf("failed to write %s rollup of %s: %w", target.Name, series, err)
+		}
+		if err := s.store.SortedSetAdd(ctx, index, strconv.FormatInt(ts, 10), float64(ts)); err != nil {
+			return fmt.Errorf("failed to index %s rollup of %s: %w", target.Name, series, err)
+		}
+	}
+	return nil
+}
+
+// trim удаляет интервалы старше срока хранения уровня; индекс отдаёт только их
+func (s *Service) trim(ctx context.Context, series string, tier Tier, now time.Time) error {
+	index := indexKey(tier, series)
+	cutoff := float64(now.Add(-tier.Retention).Unix())
+	expired, err := s.store.SortedSetRevRangeByScore(ctx, index, math.Inf(-1), cutoff-1, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to read expired %s points of %s: %w", tier.Name, series, err)
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	keys := make([]string, len(expired))
+	for i, m := range expired {
+		keys[i] = bucketKey(tier, series, int64(m.Score))
+	}
+	if err := s.store.Delete(ctx, keys...); err != nil {
+		return fmt.Errorf("failed to trim %s points of %s: %w", tier.Name, series, err)
+	}
+	if err := s.store.SortedSetRemoveRangeByScore(ctx, index, math.Inf(-1), cutoff-1); err != nil {
+		return fmt.Errorf("failed to trim %s index of %s: %w", tier.Name, series, err)
+	}
+	return nil
+}
+
+// loadRange читает точки уровня tier в [from, to) и последнюю точку до from, которая задаёт
+// значение для заполнения первых шагов
+func (s *Service) loadRange(ctx context.Context, series string, tier Tier, from, to time.Time) ([]Point, error) {
+	previous, err := s.store.SortedSetRevRangeByScore(ctx, indexKey(tier, series), math.Inf(-1), float64(from.Unix()-1), 0, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s index of %s: %w", tier.Name, series, err)
+	}
+	min := float64(from.Unix())
+	if len(previous) > 0 {
+		min = previous[0].Score
+	}
+	return s.load(ctx, series, tier, min, float64(to.Add(-time.Nanosecond).Unix()))
+}
+
+// load читает точки уровня tier с началом интервала в [min, max] (unix) в порядке времени
+func (s *Service) load(ctx context.Context, series string, tier Tier, min, max float64) ([]Point, error) {
+	buckets, err := s.store.SortedSetRevRangeByScore(ctx, indexKey(tier, series), min, max, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s index of %s: %w", tier.Name, series, err)
+	}
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, len(buckets))
+	for i, m := range buckets {
+		keys[i] = bucketKey(tier, series, int64(m.Score))
+	}
+	values, err := s.store.HashGetAllMany(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s points of %s: %w", tier.Name, series, err)
+	}
+
+	points := make([]Point, 0, len(buckets))
+	// Индекс отдаёт интервалы по убыванию времени
+	for i := len(buckets) - 1; i >= 0; i-- {
+		p, err := decodePoint(int64(buckets[i].Score), values[i])
+		if err != nil {
+			logger.Log.Warn("Skipping malformed time series point", zap.String("series", series), zap.Error(err))
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// resample группирует точки по шагам; points должны быть упорядочены по времени
+func resample(points []Point, from, to time.Time, step time.Duration) []Point {
+	var (
+		result = make([]Point, 0)
+		last   *Point
+		i      int
+	)
+
+	// Последняя точка до начала диапазона задаёт значение для заполнения первых шагов
+	for i < len(points) && points[i].Time.Before(from) {
+		last = &points[i]
+		i++
+	}
+
+	for start := from; start.Before(to); start = start.Add(step) {
+		end := start.Add(step)
+		agg := Point{Time: start}
+		for i < len(points) && points[i].Time.Before(end) {
+			agg.merge(points[i])
+			last = &points[i]
+			i++
+		}
+
+		switch {
+		case agg.count > 0:
+			result = append(result, agg)
+		case last != nil:
+			result = append(result, Point{
+				Time: start, Last: last.Last, Min: last.Last, Max: last.Last, Avg: last.Last, Filled: true,
+			})
+		}
+	}
+	return result
+}
+
+func indexKey(tier Tier, series string) string {
+	return keyPrefix + tier.Name + ":" + series + ":buckets"
+}
+
+func bucketKey(tier Tier, series string, ts int64) string {
+	return keyPrefix + tier.Name + ":" + series + ":" + strconv.FormatInt(ts, 10)
+}
@@ -0,0 +1,203 @@
+package timeseries
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/storage"
+	"stats-of/internal/storage/memory/memtest"
+)
+
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	return NewService(memtest.NewStorage(t), Retention{Minute: 2 * time.Hour, Hour: 48 * time.Hour, Day: 30 * 24 * time.Hour})
+}
+
+// TestRecordConcurrentWrites проверяет, что параллельные записи в один интервал не теряются
+func TestRecordConcurrentWrites(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	at := time.Now().UTC().Truncate(time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := service.Record(ctx, "test", at.Add(time.Second), float64(i)); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	points, err := service.Points(ctx, "test", "minute")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 1 {
+		t.Fatalf("got %d points, want 1", len(points))
+	}
+	p := points[0]
+	if p.count != 100 || p.Min != 1 || p.Max != 100 || p.Avg != 50.5 || !p.Time.Equal(at) {
+		t.Errorf("point = %+v; want 100 values between 1 and 100 with average 50.5 at %s", p, at)
+	}
+}
+
+func TestSelectTier(t *testing.T) {
+	service := newTestService(t)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		from time.Duration
+		step time.Duration
+		want string
+	}{
+		{name: "minute step within minute retention", from: time.Hour, step: time.Minute, want: "minute"},
+		{name: "hour step within minute retention", from: time.Hour, step: time.Hour, want: "minute"},
+		{name: "minute step beyond minute retention", from: 10 * time.Hour, step: time.Minute, want: "hour"},
+		{name: "five minute step beyond hour retention", from: 10 * 24 * time.Hour, step: 5 * time.Minute, want: "day"},
+		{name: "day step within hour retention", from: 10 * time.Hour, step: 24 * time.Hour, want: "hour"},
+		{name: "beyond every retention", from: 100 * 24 * time.Hour, step: time.Minute, want: "day"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := service.selectTier(now.Add(-tt.from), tt.step, now); got.Name != tt.want {
+				t.Errorf("selectTier = %s, want %s", got.Name, tt.want)
+			}
+		})
+	}
+}
+
+// TestRollupReadsRecentBuckets проверяет, что сворачивание пересчитывает только последние интервалы,
+// а запрос диапазона заполняет первые шаги последней точкой до него
+func TestRollupReadsRecentBuckets(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	now := time.Now().UTC()
+	hour := now.Truncate(time.Hour)
+
+	// Точка за пределами окна сворачивания не должна попасть в часовой уровень
+	if err := service.Record(ctx, "test", hour.Add(-5*time.Hour), 1); err != nil {
+		t.Fatal(err)
+	}
+	for i, value := range []float64{2, 4} {
+		if err := service.Record(ctx, "test", hour.Add(-time.Hour+time.Duration(i)*time.Minute), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := service.Rollup(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	hourly, err := service.Points(ctx, "test", "hour")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hourly) != 1 || !hourly[0].Time.Equal(hour.Add(-time.Hour)) || hourly[0].Avg != 3 || hourly[0].Last != 4 {
+		t.Fatalf("hourly points = %+v; want one point at %s with avg 3 and last 4", hourly, hour.Add(-time.Hour))
+	}
+
+	result, err := service.Query(ctx, "test", hour.Add(-3*time.Hour), hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Tier != "hour" || len(result.Points) != 1 || result.Points[0].Last != 4 {
+		t.Fatalf("query = %+v; want a single hourly point", result)
+	}
+
+	result, err = service.Query(ctx, "test", hour.Add(-30*time.Minute), hour.Add(-28*time.Minute), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Points) != 2 || !result.Points[0].Filled || result.Points[0].Last != 4 {
+		t.Errorf("points = %+v; want two steps filled with 4", result.Points)
+	}
+}
+
+// TestNewRange проверяет значения по умолчанию и проверки интервала запроса
+func TestNewRange(t *testing.T) {
+	now := time.Date(2026, 10, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		from, to time.Time
+		step     time.Duration
+		want     Range
+		wantErr  bool
+	}{
+		{name: "defaults", want: Range{From: now.Add(-24 * time.Hour), To: now, Step: time.Hour}},
+		{name: "from before to", from: now.Add(-time.Hour), to: now, step: time.Minute,
+			want: Range{From: now.Add(-time.Hour), To: now, Step: time.Minute}},
+		{name: "empty range", from: now, to: now, wantErr: true},
+		{name: "negative step", step: -time.Minute, wantErr: true},
+		{name: "too many points", from: now.Add(-365 * 24 * time.Hour), step: time.Minute, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewRange(tt.from, tt.to, tt.step, now)
+			if tt.wantErr {
+				if !apperrors.IsInvalidArgument(err) {
+					t.Fatalf("error = %v, want invalid argument", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestRecount проверяет, что пересчёт заменяет разошедшиеся глобальные счётчики значениями
+// по хранилищу, не учитывая пустые чаты и пользователей без чатов, и записывает их в ряды
+func TestRecount(t *testing.T) {
+	ctx := context.Background()
+	service := newTestService(t)
+	repo := storage.NewRepository(service.store)
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 1, CountOfUsers: 2}, []entities.UserID{10, 11}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 2, CountOfUsers: 1}, []entities.UserID{10}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.SaveChat(ctx, entities.Chat{ChatID: 3}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for userID, chats := range map[entities.UserID][]entities.ChatID{10: {1, 2}, 11: {1}, 12: nil} {
+		if err := repo.SaveUser(ctx, entities.User{UserID: userID, CountOfChats: int64(len(chats))}, chats); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := service.store.HashIncrBy(ctx, globalCountersKey, SeriesChats, -4); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.Recount(ctx); err != nil {
+		t.Fatal(err)
+	}
+	counters, err := service.Counters(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{SeriesChats: 2, SeriesUsers: 2, SeriesMemberships: 3}
+	for series, value := range want {
+		if counters[series] != value {
+			t.Errorf("counter %s = %d, want %d", series, counters[series], value)
+		}
+		points, err := service.Points(ctx, series, "minute")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(points) != 1 || points[0].Last != float64(value) {
+			t.Errorf("series %s points %+v, want one point with %d", series, points, value)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package uniques
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"stats-of/internal/entities"
+	"stats-of/internal/logger"
+	"stats-of/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// MakeHandler обработчик GET /api/v1/uniques?chats=1,2,3&from=2026-10-01&to=2026-10-07.
+// Даты включительные (UTC) в формате YYYY-MM-DD; по умолчанию — последние 7 дней.
+// Без chats считаются уникальные пользователи всех чатов.
+func MakeHandler(service *Service) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var chats []entities.ChatID
+		if raw := query.Get("chats"); raw != "" {
+			for _, part := range strings.Split(raw, ",") {
+				id, err := strconv.ParseInt(strings.TrimSpace(part), 10, 64)
+				if err != nil {
+					utils.RespondWith400(w, "chats must be a comma-separated list of integers")
+					return
+				}
+				chats = append(chats, entities.ChatID(id))
+			}
+		}
+
+		req, err := NewRequest(chats, query.Get("from"), query.Get("to"), time.Now().UTC())
+		if err != nil {
+			utils.RespondWith400(w, err.Error())
+			return
+		}
+
+		estimate, err := service.Count(r.Context(), req.Chats, req.From, req.To)
+		if err != nil {
+			logger.Log.Error("Failed to count unique users", zap.Error(err))
+			utils.RespondWith500(w)
+			return
+		}
+		utils.SuccessRespondWith200(w, estimate)
+	}
+}
@@ -0,0 +1,182 @@
+package uniques
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"stats-of/internal/entities"
+	apperrors "stats-of/internal/errors"
+	"stats-of/internal/membership"
+	"stats-of/internal/sketch"
+	"stats-of/internal/storage"
+)
+
+// Схема ключей: HyperLogLog пользователей, активных за сутки (UTC)
+//
+//	uniques:chat:{id}:{yyyymmdd}  в чате
+//	uniques:global:{yyyymmdd}     во всех чатах
+const (
+	keyPrefix  = "uniques:"
+	dateLayout = "20060102"
+
+	// MaxKeys предельное число скетчей (чаты × дни), объединяемых одним запросом
+	MaxKeys = 10000
+	// defaultDays сколько последних дней считается, если from не указан
+	defaultDays = 7
+	// z-значение для 95% доверительного интервала
+	confidenceZ = 1.96
+)
+
+type (
+	// Estimate оценка числа различных пользователей с границами ошибки
+	Estimate struct {
+		Chats []entities.ChatID `json:"chats,omitempty"`
+		From  string            `json:"from"`
+		To    string            `json:"to"`
+		Days  int               `json:"days"`
+
+		Estimate int64 `json:"estimate"`
+		// RelativeStandardError относительная стандартная ошибка HyperLogLog (1.04/√m)
+		RelativeStandardError float64 `json:"relative_standard_error"`
+		// Lower и Upper границы 95% доверительного интервала
+		Lower int64 `json:"lower"`
+		Upper int64 `json:"upper"`
+	}
+
+	// Request параметры подсчёта: чаты без повторов и дни с From по To включительно
+	Request struct {
+		Chats []entities.ChatID
+		From  time.Time
+		To    time.Time
+	}
+
+	// Service ведёт суточные HyperLogLog активных пользователей по чатам и в целом
+	// и оценивает число уникальных пользователей в любом объединении чатов и дней
+	Service struct {
+		store     storage.Storage
+		retention time.Duration
+	}
+)
+
+func NewService(store storage.Storage, retention time.Duration) *Service {
+	return &Service{store: store, retention: retention}
+}
+
+// OnChange учитывает пользователя как активного в чате в день события.
+// Выход и удаление из чата активностью не считаются.
+func (s *Service) OnChange(ctx context.Context, change *membership.Change) error {
+	if change.Event.Type.Removes() {
+		return nil
+	}
+
+	day := change.Event.Time.UTC()
+	user := strconv.FormatInt(int64(change.Event.UserID), 10)
+	for _, key := range []string{chatKey(change.Event.ChatID, day), globalKey(day)} {
+		if err := s.store.HyperLogLogAdd(ctx, key, user); err != nil {
+			return fmt.Errorf("failed to add user to %s: %w", key, err)
+		}
+		// Скетч хранится срок хранения плюс сутки, чтобы последний день окна был полным
+		if err := s.store.Expire(ctx, key, s.retention+24*time.Hour); err != nil {
+			return fmt.Errorf("failed to set expiration of %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// NewRequest убирает повторы чатов, разбирает даты YYYY-MM-DD (по умолчанию — последние 7 дней
+// по now) и проверяет, что запрос объединяет не больше MaxKeys скетчей. Общая проверка для HTTP и gRPC API.
+func NewRequest(chats []entities.ChatID, from, to string, now time.Time) (Request, error) {
+	var req Request
+	var err error
+	if req.To, err = parseDate(to, now); err != nil {
+		return Request{}, apperrors.InvalidArgument("to must be a date in YYYY-MM-DD format")
+	}
+	if req.From, err = parseDate(from, req.To.AddDate(0, 0, 1-defaultDays)); err != nil {
+		return Request{}, apperrors.InvalidArgument("from must be a date in YYYY-MM-DD format")
+	}
+
+	seen := make(map[entities.ChatID]struct{}, len(chats))
+	for _, id := range chats {
+		if _, dup := seen[id]; !dup {
+			seen[id] = struct{}{}
+			req.Chats = append(req.Chats, id)
+		}
+	}
+	if err := checkRange(len(req.Chats), req.From, req.To); err != nil {
+		return Request{}, err
+	}
+	return req, nil
+}
+
+// Count оценивает число различных пользователей, активных в любом из чатов за дни с from по to
+// включительно. Пустой список чатов означает все чаты.
+func (s *Service) Count(ctx context.Context, chats []entities.ChatID, from, to time.Time) (*Estimate, error) {
+	from, to = truncateDay(from), truncateDay(to)
+	if err := checkRange(len(chats), from, to); err != nil {
+		return nil, err
+	}
+
+	days := int(to.Sub(from)/(24*time.Hour)) + 1
+	scopes := max(len(chats), 1)
+
+	keys := make([]string, 0, days*scopes)
+	for day := from; !day.After(to); day = day.Add(24 * time.Hour) {
+		if len(chats) == 0 {
+			keys = append(keys, globalKey(day))
+			continue
+		}
+		for _, chat := range chats {
+			keys = append(keys, chatKey(chat, day))
+		}
+	}
+
+	count, err := s.store.HyperLogLogCount(ctx, keys...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count unique users: %w", err)
+	}
+
+	margin := confidenceZ * sketch.HyperLogLogStandardError * float64(count)
+	return &Estimate{
+		Chats:                 chats,
+		From:                  from.Format(time.DateOnly),
+		To:                    to.Format(time.DateOnly),
+		Days:                  days,
+		Estimate:              count,
+		RelativeStandardError: sketch.HyperLogLogStandardError,
+		Lower:                 int64(math.Max(0, math.Floor(float64(count)-margin))),
+		Upper:                 int64(math.Ceil(float64(count) + margin)),
+	}, nil
+}
+
+func chatKey(id entities.ChatID, day time.Time) string {
+	return keyPrefix + "chat:" + strconv.FormatInt(int64(id), 10) + ":" + day.UTC().Format(dateLayout)
+}
+
+func globalKey(day time.Time) string {
+	return keyPrefix + "global:" + day.UTC().Format(dateLayout)
+}
+
+// checkRange проверяет порядок дат и число скетчей запроса; пустой список чатов — один глобальный скетч в день
+func checkRange(chats int, from, to time.Time) error {
+	if to.Before(from) {
+		return apperrors.InvalidArgument("from must not be after to")
+	}
+	if days := int(to.Sub(from)/(24*time.Hour)) + 1; days*max(chats, 1) > MaxKeys {
+		return apperrors.InvalidArgument("query covers too many chat-days, limit is %d", MaxKeys)
+	}
+	return nil
+}
+
+func parseDate(raw string, defaultValue time.Time) (time.Time, error) {
+	if raw == "" {
+		return truncateDay(defaultValue), nil
+	}
+	return time.Parse(time.DateOnly, raw)
+}
+
+func truncateDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
@@ -5,10 +5,17 @@ import (
 	"fmt"
 	"net/http"
 	"stats-of/internal/logger"
+	"strconv"
+	"time"
 
 	"go.uber.org/zap"
 )
 
+// ErrorResponse тело ответа с ошибкой
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) error {
 	// Логирование попытки преобразования payload в JSON
 	logger.Log.Info("Attempting to marshal payload to JSON", zap.Any("payload", payload))
@@ -48,13 +55,7 @@ func RespondWithError(w http.ResponseWriter, code int, message string) error {
 	// Логирование попытки отправить ошибочный ответ
 	logger.Log.Info("Attempting to respond with error", zap.Int("statusCode", code), zap.String("errorMessage", message))
 
-	err := RespondWithJSON(w,
-		code,
-		struct {
-			Error string `json:"error"`
-		}{
-			Error: message,
-		})
+	err := RespondWithJSON(w, code, ErrorResponse{Error: message})
 
 	if err != nil {
 		// Логирование ошибки при попытке отправить JSON ответ об ошибке
@@ -111,3 +112,60 @@ func SuccessRespondWith201(w http.ResponseWriter, payload interface{}) error {
 	}
 	return err
 }
+
+// PathInt64 разбирает целочисленный параметр пути, например {id} в шаблоне маршрута
+func PathInt64(r *http.Request, name string) (int64, error) {
+	value, err := strconv.ParseInt(r.PathValue(name), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", name)
+	}
+	return value, nil
+}
+
+// QueryInt разбирает целочисленный параметр запроса в диапазоне [min, max].
+// Если параметр не передан, возвращается defaultValue.
+func QueryInt(r *http.Request, name string, defaultValue, min, max int) (int, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < min || value > max {
+		return 0, fmt.Errorf("%s must be an integer between %d and %d", name, min, max)
+	}
+	return value, nil
+}
+
+// QueryTime разбирает время из параметра запроса в формате RFC 3339 или unix-секунд.
+// Если параметр не передан, возвращается defaultValue.
+func QueryTime(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	if seconds, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s must be an RFC 3339 time or unix seconds", name)
+	}
+	return t.UTC(), nil
+}
+
+// QueryDuration разбирает длительность из параметра запроса в формате time.ParseDuration.
+// Если параметр не передан, возвращается defaultValue.
+func QueryDuration(r *http.Request, name string, defaultValue time.Duration) (time.Duration, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return defaultValue, nil
+	}
+
+	d, err := time.ParseDuration(raw)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%s must be a positive duration such as 5m or 1h", name)
+	}
+	return d, nil
+}